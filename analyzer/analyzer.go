@@ -0,0 +1,175 @@
+// Package analyzer reports printf-style log calls as a go/analysis
+// Analyzer, with the structured call transform would generate as a
+// suggested fix, so the check can run inside golangci-lint, go vet and
+// gopls, where the fix shows up as a quick fix.
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+
+	"logrefactor/internal/config"
+	"logrefactor/internal/naming"
+	"logrefactor/internal/sarif"
+	"logrefactor/pkg/collector"
+	"logrefactor/pkg/transformer"
+)
+
+// Options configure the analyzer. Left empty, the project configuration
+// (.logrefactor.yaml) is looked up from each package's directory and calls
+// are matched with collector.UnstructuredPattern.
+type Options struct {
+	Pattern       string   `json:"pattern"`       // Regex matching unstructured logging calls
+	Imports       []string `json:"imports"`       // Packages matched calls must belong to (default: the project's imports)
+	Config        string   `json:"config"`        // Template configuration file (JSON) for the suggested calls
+	ProjectConfig string   `json:"projectConfig"` // Project configuration file
+	Profile       string   `json:"profile"`       // Named profile from the project configuration
+}
+
+// Analyzer reports unstructured log calls with the default options
+var Analyzer = New(Options{})
+
+// New returns an analyzer using opts. The options can also be set with the
+// analyzer's flags (-pattern, -imports, -config, -project-config,
+// -profile).
+func New(opts Options) *analysis.Analyzer {
+	s := &state{opts: opts, projects: make(map[string]*project)}
+	a := &analysis.Analyzer{
+		Name: "logrefactor",
+		Doc:  "reports printf-style log calls that should use structured logging\n\nEach diagnostic names the structured call logrefactor transform would generate.",
+		URL:  "https://github.com/mallardduck/logrefactor",
+		Run:  s.run,
+	}
+	a.Flags.StringVar(&s.opts.Pattern, "pattern", opts.Pattern, "Regex matching unstructured logging calls")
+	a.Flags.Func("imports", "Comma-separated import paths matched calls must belong to (\"default\" for the supported logging libraries)", func(value string) error {
+		s.opts.Imports = []string{}
+		for _, path := range strings.Split(value, ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				s.opts.Imports = append(s.opts.Imports, path)
+			}
+		}
+		return nil
+	})
+	a.Flags.StringVar(&s.opts.Config, "config", opts.Config, "Template configuration file (JSON) for the suggested calls")
+	a.Flags.StringVar(&s.opts.ProjectConfig, "project-config", opts.ProjectConfig, "Project configuration file (default: .logrefactor.yaml above each package)")
+	a.Flags.StringVar(&s.opts.Profile, "profile", opts.Profile, "Named profile from the project configuration")
+	return a
+}
+
+// state is shared by the runs of one analyzer, which may be concurrent
+type state struct {
+	opts Options
+
+	once    sync.Once
+	pattern *regexp.Regexp
+	err     error
+
+	mu       sync.Mutex
+	projects map[string]*project // Package directory -> settings
+}
+
+// project holds the settings that apply to a package
+type project struct {
+	keyStyle string
+	imports  []string
+	template *transformer.TemplateConfig
+}
+
+func (s *state) run(pass *analysis.Pass) (interface{}, error) {
+	s.once.Do(func() {
+		pattern := s.opts.Pattern
+		if pattern == "" {
+			pattern = collector.UnstructuredPattern
+		}
+		s.pattern, s.err = regexp.Compile(pattern)
+	})
+	if s.err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", s.err)
+	}
+
+	for _, file := range pass.Files {
+		name := pass.Fset.Position(file.Pos()).Filename
+		p, err := s.project(filepath.Dir(name))
+		if err != nil {
+			return nil, err
+		}
+
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			if funcName := collector.CallName(call); funcName == "" || !s.pattern.MatchString(funcName) {
+				return true
+			}
+			if len(p.imports) > 0 {
+				if path, ok := collector.CallTarget(call, file, pass.TypesInfo); ok && !collector.ImportMatch(p.imports, path) {
+					return true
+				}
+			}
+
+			update := sarif.Update(collector.Entry(call, pass.Fset, file.Name.Name, p.keyStyle))
+			d := analysis.Diagnostic{
+				Pos:      call.Pos(),
+				End:      call.End(),
+				Category: sarif.UnstructuredCall.ID,
+				Message:  sarif.Message(update),
+			}
+			// The fix replaces the call only; imports for the new logger
+			// are left to goimports or the editor
+			if fix := sarif.Fix(update, p.template); fix != "" {
+				d.Message += "; use " + fix
+				d.SuggestedFixes = []analysis.SuggestedFix{{
+					Message:   "Convert to structured logging",
+					TextEdits: []analysis.TextEdit{{Pos: call.Pos(), End: call.End(), NewText: []byte(fix)}},
+				}}
+			}
+			pass.Report(d)
+			return true
+		})
+	}
+	return nil, nil
+}
+
+// project loads the settings for the package in dir, once per directory
+func (s *state) project(dir string) (*project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.projects[dir]; ok {
+		return p, nil
+	}
+
+	var cfg *config.Config
+	var err error
+	if s.opts.ProjectConfig != "" {
+		cfg, err = config.Load(s.opts.ProjectConfig)
+	} else {
+		cfg, err = config.Discover(dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load project config: %w", err)
+	}
+	if cfg, err = cfg.WithProfile(s.opts.Profile); err != nil {
+		return nil, err
+	}
+	template, err := transformer.LoadTemplateConfig(s.opts.Config, &cfg.TemplateConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load template config: %w", err)
+	}
+
+	p := &project{keyStyle: cfg.KeyStyle, imports: cfg.Imports, template: template}
+	if s.opts.Imports != nil {
+		p.imports = s.opts.Imports
+	}
+	if p.keyStyle == "" {
+		p.keyStyle = naming.SnakeCase
+	}
+	s.projects[dir] = p
+	return p, nil
+}
@@ -0,0 +1,41 @@
+// Package golangci registers the logrefactor analyzer as a golangci-lint
+// module plugin. Build it into golangci-lint with `golangci-lint custom`
+// (see the README) and enable the "logrefactor" linter.
+package golangci
+
+import (
+	"github.com/golangci/plugin-module-register/register"
+	"golang.org/x/tools/go/analysis"
+
+	"logrefactor/analyzer"
+)
+
+func init() {
+	register.Plugin("logrefactor", New)
+}
+
+// plugin is the linter golangci-lint builds from the settings
+type plugin struct {
+	options analyzer.Options
+}
+
+// New decodes the linter settings from .golangci.yml (the fields of
+// analyzer.Options, e.g. pattern and config)
+func New(settings any) (register.LinterPlugin, error) {
+	options, err := register.DecodeSettings[analyzer.Options](settings)
+	if err != nil {
+		return nil, err
+	}
+	return &plugin{options: options}, nil
+}
+
+func (p *plugin) BuildAnalyzers() ([]*analysis.Analyzer, error) {
+	return []*analysis.Analyzer{analyzer.New(p.options)}, nil
+}
+
+// GetLoadMode is types info: besides the AST, the analyzer needs
+// pass.TypesInfo to tell which package a call belongs to (see
+// collector.CallTarget) when imports are configured
+func (p *plugin) GetLoadMode() string {
+	return register.LoadModeTypesInfo
+}
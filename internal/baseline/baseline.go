@@ -0,0 +1,119 @@
+// Package baseline records the unstructured log calls a project already has,
+// so check can fail only on calls added since. The file is meant to be
+// committed and shrinks as the backlog is migrated.
+package baseline
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"logrefactor/pkg/collector"
+)
+
+// Version is the baseline file format version
+const Version = 1
+
+// Entry is a known call. ID is stable across runs: it is derived from the
+// file, the call and its message (and which occurrence of them it is in the
+// file), not from the line, so unrelated edits don't invalidate it. File,
+// Call and Message are kept for reviewers.
+type Entry struct {
+	ID      string `json:"id"`
+	File    string `json:"file"`
+	Call    string `json:"call"`
+	Message string `json:"message"`
+}
+
+type file struct {
+	Version int     `json:"version"`
+	Entries []Entry `json:"entries"`
+}
+
+// Entries makes the baseline entries for scanned calls. File paths are made
+// relative to root, which should be the repository root so the IDs don't
+// depend on where check runs from.
+func Entries(entries []collector.LogEntry, root string) ([]Entry, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Entry, 0, len(entries))
+	occurrences := make(map[string]int)
+	for _, e := range entries {
+		abs, err := filepath.Abs(e.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(absRoot, abs)
+		if err != nil {
+			return nil, err
+		}
+		rel = filepath.ToSlash(rel)
+
+		key := rel + "\x00" + e.OriginalCall + "\x00" + e.MessageTemplate
+		occurrences[key]++
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d", key, occurrences[key])))
+		result = append(result, Entry{
+			ID:      hex.EncodeToString(sum[:8]),
+			File:    rel,
+			Call:    e.OriginalCall,
+			Message: e.MessageTemplate,
+		})
+	}
+	return result, nil
+}
+
+// Write writes entries to path, sorted by file so the file diffs well
+func Write(path string, entries []Entry) error {
+	sorted := append([]Entry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].File < sorted[j].File })
+
+	data, err := json.MarshalIndent(file{Version: Version, Entries: sorted}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Load reads a baseline file
+func Load(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if f.Version != Version {
+		return nil, fmt.Errorf("%s: unsupported baseline version %d", path, f.Version)
+	}
+	return f.Entries, nil
+}
+
+// Compare splits the current entries by the baseline: fresh[i] reports
+// whether current[i] is not in it, and stale lists the baseline entries no
+// longer found (migrated or deleted calls the baseline can drop).
+func Compare(known, current []Entry) (fresh []bool, stale []Entry) {
+	seen := make(map[string]bool, len(current))
+	for _, e := range current {
+		seen[e.ID] = true
+	}
+	ids := make(map[string]bool, len(known))
+	for _, e := range known {
+		ids[e.ID] = true
+		if !seen[e.ID] {
+			stale = append(stale, e)
+		}
+	}
+	fresh = make([]bool, len(current))
+	for i, e := range current {
+		fresh[i] = !ids[e.ID]
+	}
+	return fresh, stale
+}
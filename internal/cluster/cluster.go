@@ -0,0 +1,130 @@
+// Package cluster lists the entries of a collected CSV whose messages are
+// alike (see Clusters), so reviewers can pick one canonical
+// message for each group and fill it in for all of them at once.
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"logrefactor/internal/table"
+)
+
+// Member is an entry of a cluster
+type Member struct {
+	ID         string `json:"id"`
+	Location   string `json:"location"`
+	Message    string `json:"message"`
+	NewMessage string `json:"newMessage,omitempty"`
+}
+
+// Cluster is a group of entries with alike messages
+type Cluster struct {
+	ID      string   `json:"id"`
+	Members []Member `json:"members"`
+}
+
+// Read returns the clusters of csvFile with at least minSize entries,
+// largest first and then by ID. The ClusterID column collect wrote is used;
+// a CSV without one is clustered in row order.
+func Read(csvFile string, minSize int) ([]Cluster, error) {
+	t, err := table.Read(csvFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Require("ID", "MessageTemplate"); err != nil {
+		return nil, err
+	}
+
+	ids := clusterIDs(t)
+	byID := make(map[string]*Cluster)
+	var clusters []*Cluster
+	for i, row := range t.Rows {
+		if ids[i] == "" {
+			continue
+		}
+		c, ok := byID[ids[i]]
+		if !ok {
+			c = &Cluster{ID: ids[i]}
+			byID[ids[i]] = c
+			clusters = append(clusters, c)
+		}
+		c.Members = append(c.Members, Member{
+			ID:         t.Get(row, "ID"),
+			Location:   t.Get(row, "FilePath") + ":" + t.Get(row, "Line"),
+			Message:    t.Get(row, "MessageTemplate"),
+			NewMessage: t.Get(row, "NewMessage"),
+		})
+	}
+
+	var result []Cluster
+	for _, c := range clusters {
+		if len(c.Members) >= minSize {
+			result = append(result, *c)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if len(result[i].Members) != len(result[j].Members) {
+			return len(result[i].Members) > len(result[j].Members)
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
+
+// SetMessage fills in message as the NewMessage of every entry in the
+// cluster id and writes the result to outputFile. Entries that already
+// have a NewMessage are left alone unless force is set. It returns the IDs
+// of the entries set; with outputFile empty nothing is written.
+func SetMessage(csvFile, outputFile, id, message string, force bool) ([]string, error) {
+	t, err := table.Read(csvFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Require("ID", "MessageTemplate"); err != nil {
+		return nil, err
+	}
+
+	ids := clusterIDs(t)
+	var set []string
+	found := false
+	for i, row := range t.Rows {
+		if ids[i] != id {
+			continue
+		}
+		found = true
+		if t.Get(row, "NewMessage") != "" && !force {
+			continue
+		}
+		t.Rows[i] = t.Set(row, "NewMessage", message)
+		set = append(set, t.Get(row, "ID"))
+	}
+	if !found {
+		return nil, fmt.Errorf("cluster %s not found in %s", id, csvFile)
+	}
+
+	if outputFile != "" {
+		if err := t.Write(outputFile); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+// clusterIDs returns the ClusterID of every row: the column's, or if the
+// table has none, the clusters of the rows' messages in order
+func clusterIDs(t *table.Table) []string {
+	ids := make([]string, len(t.Rows))
+	if t.Has("ClusterID") {
+		for i, row := range t.Rows {
+			ids[i] = strings.TrimSpace(t.Get(row, "ClusterID"))
+		}
+		return ids
+	}
+	clusters := NewClusters()
+	for i, row := range t.Rows {
+		ids[i] = clusters.Add(t.Get(row, "MessageTemplate"))
+	}
+	return ids
+}
@@ -0,0 +1,129 @@
+package cluster
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ClusterSimilarity is how alike the words of two messages must be to share
+// a cluster, as the share of their distinct words they have in common
+// (the Jaccard index)
+const ClusterSimilarity = 0.7
+
+// clusterValue matches what a message interpolates or quotes rather than
+// says: format verbs, numbers and quoted text
+var clusterValue = regexp.MustCompile(`%[-+# 0]*[\d]*\.?[\d]*[vTtbcdoqxXUeEfFgGsp]|'[^']*'|"[^"]*"|\b\d+\w*`)
+
+// clusterStopWords carry no meaning of their own in a log message
+var clusterStopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "to": true, "of": true, "for": true, "in": true, "on": true,
+	"at": true, "from": true, "with": true, "by": true, "into": true, "and": true, "or": true,
+	"is": true, "are": true, "was": true, "were": true, "be": true, "been": true, "being": true,
+	"has": true, "have": true, "had": true, "it": true, "its": true, "this": true, "that": true,
+	"while": true, "when": true, "during": true, "could": true, "would": true, "will": true,
+	"can": true, "did": true, "do": true, "does": true, "t": true,
+}
+
+// clusterSynonyms map abbreviations and words that report the same thing
+// to one word, so "db connection failed" and "database connect error" read
+// alike
+var clusterSynonyms = map[string]string{
+	"db": "database", "conn": "connection", "cfg": "configuration", "conf": "configuration",
+	"config": "configuration", "msg": "message", "req": "request", "resp": "response",
+	"auth": "authentication", "dir": "directory", "srv": "server", "svr": "server",
+	"ctx": "context", "pkg": "package", "addr": "address", "tx": "transaction",
+	"err": "fail", "error": "fail", "errors": "fail", "failed": "fail", "fails": "fail",
+	"failure": "fail", "unable": "fail", "cannot": "fail", "couldn": "fail",
+}
+
+// Clusters groups messages that differ only in their values or in minor
+// wording: "failed to connect to db", "db connection failed" and "database
+// connect error" are one cluster. Messages are compared on their words,
+// with values, stop words and word endings dropped and synonyms mapped to
+// one word. Add them in order: each joins the cluster of the first message
+// it is alike enough to (see ClusterSimilarity), so the clusters only
+// depend on the order of the messages.
+type Clusters struct {
+	words [][]string       // Words of the first message of each cluster
+	index map[string][]int // Clusters by word
+}
+
+// NewClusters returns an empty set of clusters
+func NewClusters() *Clusters {
+	return &Clusters{index: make(map[string][]int)}
+}
+
+// Add returns the ClusterID of a MessageTemplate, e.g. "MSG-0003", starting
+// a new cluster if it is like none so far. A template that isn't a string
+// literal, or has no words once the values are dropped, has none ("").
+func (c *Clusters) Add(template string) string {
+	words := messageWords(template)
+	if len(words) == 0 {
+		return ""
+	}
+
+	shared := make(map[int]int)
+	for _, w := range words {
+		for _, i := range c.index[w] {
+			shared[i]++
+		}
+	}
+	best, bestScore := -1, 0.0
+	for i, n := range shared {
+		score := float64(n) / float64(len(words)+len(c.words[i])-n)
+		if score > bestScore || score == bestScore && i < best {
+			best, bestScore = i, score
+		}
+	}
+	if best < 0 || bestScore < ClusterSimilarity {
+		best = len(c.words)
+		c.words = append(c.words, words)
+		for _, w := range words {
+			c.index[w] = append(c.index[w], best)
+		}
+	}
+	return fmt.Sprintf("MSG-%04d", best+1)
+}
+
+// messageWords returns the distinct words of a message template, sorted,
+// as Clusters compares them
+func messageWords(template string) []string {
+	text, err := strconv.Unquote(template)
+	if err != nil {
+		return nil
+	}
+	text = clusterValue.ReplaceAllString(text, " ")
+
+	var words []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		if clusterStopWords[w] {
+			continue
+		}
+		if synonym, ok := clusterSynonyms[w]; ok {
+			w = synonym
+		}
+		words = append(words, stem(w))
+	}
+	slices.Sort(words)
+	return slices.Compact(words)
+}
+
+// stem drops a common ending from a word, so "connect", "connected",
+// "connecting" and "connection" are one word. It is crude but consistent,
+// which is all comparing messages needs.
+func stem(word string) string {
+	for _, suffix := range []string{"ions", "ion", "ing", "ed", "s"} {
+		if strings.HasSuffix(word, suffix) && len(word)-len(suffix) >= 4 && !strings.HasSuffix(word, "ss") {
+			word = strings.TrimSuffix(word, suffix)
+			break
+		}
+	}
+	if len(word) > 3 {
+		word = strings.TrimSuffix(word, "e")
+	}
+	return word
+}
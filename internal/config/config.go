@@ -0,0 +1,252 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"logrefactor/internal/schema"
+	"logrefactor/pkg/transformer"
+)
+
+// FileNames are the project configuration file names looked up, in order
+var FileNames = []string{".logrefactor.yaml", ".logrefactor.yml", ".logrefactor.json"}
+
+// Config is the project-level configuration shared by all subcommands.
+// Template settings (style, loggerVar, keyStyle, levelMap, ...) sit at the top
+// level next to the collect and transform settings.
+type Config struct {
+	Path           string   `yaml:"path"`           // Project or package path to scan/transform
+	CSV            string   `yaml:"csv"`            // Entries file: collect output and transform input
+	Pattern        string   `yaml:"pattern"`        // Regex pattern to match logging calls
+	Exclude        []string `yaml:"exclude"`        // Paths or globs skipped by collect (e.g. vendor, testdata)
+	SkipTests      *bool    `yaml:"skipTests"`      // Skip _test.go files
+	AutoMap        *bool    `yaml:"autoMap"`        // Auto-generate fields from ArgumentDetails
+	OnlyApproved   *bool    `yaml:"onlyApproved"`   // Transform only entries approved in the CSV
+	TestLogs       *bool    `yaml:"testLogs"`       // Also transform test output such as t.Logf (see transformer.Options.TestLogs)
+	MinConfidence  *float64 `yaml:"minConfidence"`  // Hold auto-mapped entries scoring below this (see transformer.Options.MinConfidence)
+	AllowSensitive *bool    `yaml:"allowSensitive"` // Also transform entries logging credentials (see transformer.Options.AllowSensitive)
+	LogAndReturn   string   `yaml:"logAndReturn"`   // keep or wrap entries logging an error they return (see transformer.Options.LogAndReturn)
+	RewriteHelpers *bool    `yaml:"rewriteHelpers"` // Rewrite logging helpers instead of migrating their calls (see transformer.Options.RewriteHelpers)
+	KeyConstants   string   `yaml:"keyConstants"`   // Go file for shared key constants
+	Matcher        string   `yaml:"matcher"`        // WASM plugin that decides which calls collect records
+	Imports        []string `yaml:"imports"`        // Packages matched calls must belong to (see collector.Options.Imports)
+	Helpers        *bool    `yaml:"helpers"`        // Record calls to logging helpers (see collector.Options.Helpers)
+	HotPaths       []string `yaml:"hotPaths"`       // Functions whose calls are hot (see collector.Options.HotPaths)
+	MessageRules   []string `yaml:"messageRules"`   // Message style rules collect, lint and transform check (see lint.ParseRules)
+	Baseline       string   `yaml:"baseline"`       // Known calls check doesn't count (see check -baseline)
+	Cache          string   `yaml:"cache"`          // Cache of parsed entries for repeat collect runs
+
+	transformer.TemplateConfig `yaml:",inline"`
+
+	// Profiles are named variants of the settings above, selected with -profile
+	Profiles map[string]Profile `yaml:"profiles"`
+
+	// File is the path the configuration was loaded from
+	File string `yaml:"-"`
+}
+
+// Profile is a named set of settings layered over the top-level ones, so that
+// different parts of a repository (e.g. a server and a CLI) can be migrated to
+// different logging libraries. Empty settings are inherited, KeyRenames and
+// Overrides are added to the inherited ones, and LevelMap rules are checked
+// before the inherited rules.
+type Profile struct {
+	Path       string                     `yaml:"path"`
+	CSV        string                     `yaml:"csv"`
+	Pattern    string                     `yaml:"pattern"`
+	Style      string                     `yaml:"style"`
+	LoggerVar  string                     `yaml:"loggerVar"`
+	Template   string                     `yaml:"template"`
+	KeyStyle   string                     `yaml:"keyStyle"`
+	KeyRenames map[string]string          `yaml:"keyRenames"`
+	LevelMap   []transformer.LevelRule    `yaml:"levelMap"`
+	Overrides  []transformer.PathOverride `yaml:"overrides"`
+}
+
+// Find looks for a project configuration file in dir and its parents, stopping
+// at the first directory that contains go.mod or .git. It returns "" if none
+// is found.
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+
+	for {
+		for _, name := range FileNames {
+			candidate := filepath.Join(dir, name)
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate, nil
+			}
+		}
+
+		if isProjectRoot(dir) {
+			return "", nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// isProjectRoot reports whether dir holds a go.mod or .git
+func isProjectRoot(dir string) bool {
+	for _, marker := range []string{"go.mod", ".git"} {
+		if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Load reads a project configuration file. JSON files are accepted as well,
+// since JSON is valid YAML. The file is checked against the configuration
+// schema first, so unknown or misspelled keys are reported with their line
+// and column instead of being ignored.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := schema.Check(path, data); err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	cfg.File = path
+
+	// Paths in the file are relative to the file's directory
+	dir := filepath.Dir(path)
+	cfg.Path = resolvePath(dir, cfg.Path)
+	cfg.CSV = resolvePath(dir, cfg.CSV)
+	cfg.KeyConstants = resolvePath(dir, cfg.KeyConstants)
+	cfg.Matcher = resolvePath(dir, cfg.Matcher)
+	cfg.Plugin = resolvePath(dir, cfg.Plugin)
+	cfg.Baseline = resolvePath(dir, cfg.Baseline)
+	cfg.Cache = resolvePath(dir, cfg.Cache)
+	for i := range cfg.Overrides {
+		cfg.Overrides[i].Path = resolvePath(dir, cfg.Overrides[i].Path)
+	}
+	// An exec generator given as a relative path ("./tools/gen") is relative
+	// to the file too; bare names are looked up in PATH
+	if len(cfg.Command) > 0 && strings.ContainsRune(cfg.Command[0], filepath.Separator) {
+		cfg.Command[0] = resolvePath(dir, cfg.Command[0])
+	}
+	for name, p := range cfg.Profiles {
+		p.Path = resolvePath(dir, p.Path)
+		p.CSV = resolvePath(dir, p.CSV)
+		for i := range p.Overrides {
+			p.Overrides[i].Path = resolvePath(dir, p.Overrides[i].Path)
+		}
+		cfg.Profiles[name] = p
+	}
+
+	return &cfg, nil
+}
+
+// WithProfile returns a copy of the configuration with the named profile
+// applied. An empty name returns the configuration unchanged.
+func (c *Config) WithProfile(name string) (*Config, error) {
+	if name == "" {
+		return c, nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		if c.File == "" {
+			return nil, fmt.Errorf("profile %q requested but no project config was found", name)
+		}
+		names := make([]string, 0, len(c.Profiles))
+		for n := range c.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown profile %q in %s (available: %s)", name, c.File, strings.Join(names, ", "))
+	}
+
+	merged := *c
+	set := func(dst *string, value string) {
+		if value != "" {
+			*dst = value
+		}
+	}
+	set(&merged.Path, p.Path)
+	set(&merged.CSV, p.CSV)
+	set(&merged.Pattern, p.Pattern)
+	set(&merged.Style, p.Style)
+	set(&merged.LoggerVar, p.LoggerVar)
+	set(&merged.Template, p.Template)
+	set(&merged.KeyStyle, p.KeyStyle)
+
+	if len(p.KeyRenames) > 0 {
+		merged.KeyRenames = make(map[string]string, len(c.KeyRenames)+len(p.KeyRenames))
+		for k, v := range c.KeyRenames {
+			merged.KeyRenames[k] = v
+		}
+		for k, v := range p.KeyRenames {
+			merged.KeyRenames[k] = v
+		}
+	}
+	if len(p.LevelMap) > 0 {
+		merged.LevelMap = append(append([]transformer.LevelRule{}, p.LevelMap...), c.LevelMap...)
+	}
+	if len(p.Overrides) > 0 {
+		merged.Overrides = append(append([]transformer.PathOverride{}, p.Overrides...), c.Overrides...)
+	}
+
+	return &merged, nil
+}
+
+// Validate checks the settings the schema can't: the style of the top level
+// and of each profile must be a built-in or registered one, and the
+// template settings must be usable (see transformer.LoadTemplateConfig).
+func (c *Config) Validate() error {
+	if _, err := transformer.LoadTemplateConfig("", &c.TemplateConfig); err != nil {
+		return fmt.Errorf("%s: %w", c.File, err)
+	}
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		profile, err := c.WithProfile(name)
+		if err != nil {
+			return err
+		}
+		if _, err := transformer.LoadTemplateConfig("", &profile.TemplateConfig); err != nil {
+			return fmt.Errorf("%s: profile %s: %w", c.File, name, err)
+		}
+	}
+	return nil
+}
+
+// resolvePath joins a relative path onto dir
+func resolvePath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// Discover finds and loads the project configuration for dir. It returns an
+// empty Config if there is no configuration file.
+func Discover(dir string) (*Config, error) {
+	path, err := Find(dir)
+	if err != nil || path == "" {
+		return &Config{}, err
+	}
+	return Load(path)
+}
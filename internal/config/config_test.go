@@ -0,0 +1,48 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"logrefactor/pkg/transformer"
+)
+
+func TestValidate(t *testing.T) {
+	transformer.RegisterStyle("inhouse", transformer.GeneratorFunc(func(transformer.LogUpdate, *transformer.TemplateConfig, string, string, []transformer.FieldMapping) (string, error) {
+		return "", nil
+	}))
+	tests := []struct {
+		name string
+		file string
+		want string // Part of the error; empty for none
+	}{
+		{"built-in style", "style: zap\n", ""},
+		{"no style", "csv: logs.csv\n", ""},
+		{"registered style", "style: inhouse\n", ""},
+		{"misspelled style", "style: slgo\n", "unknown style: slgo"},
+		{"misspelled profile style", "style: slog\nprofiles:\n  cli:\n    style: zaap\n", "profile cli: unknown style: zaap"},
+		{"override style", "style: slog\noverrides:\n  - path: cmd\n    style: logruss\n", "unknown style for"},
+		{"exec without command", "style: exec\n", "style exec needs a command"},
+		{"invalid key style", "style: slog\nkeyStyle: pascal\n", "keyStyle"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), ".logrefactor.yaml")
+			if err := os.WriteFile(file, []byte(tt.file), 0644); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := Load(file)
+			if err == nil {
+				err = cfg.Validate()
+			}
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("got %v, want no error", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("got %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+// Package coverage measures how much of a code base already logs through the
+// target structured API, and keeps a history of the measurements.
+package coverage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"logrefactor/pkg/collector"
+)
+
+// stylePatterns match the calls each built-in style generates, as collect
+// names them ("log.Info", "log.Info().Str(...).Msg"). LV stands for the
+// logger variable.
+var stylePatterns = map[string]string{
+	"slog":        `^(LV|slog)\.(Debug|Info|Warn|Error|Log)(Context|Attrs)?$`,
+	"zap":         `^LV\.(Debug|Info|Warn|Error|DPanic|Panic|Fatal)$`,
+	"zap-sugared": `^LV\.(Debug|Info|Warn|Error|DPanic|Panic|Fatal)w$`,
+	"zerolog":     `^LV\..*\.(Msg|Msgf|Send)$`,
+	"logrus":      `^LV\.With(Fields?|Error)\(.*\)\.(Trace|Debug|Info|Warn|Warning|Error|Fatal|Panic)$`,
+	"klog":        `^LV(\.V\(.*\))?\.(InfoS|ErrorS)$`,
+	"hclog":       `^LV\.(Trace|Debug|Info|Warn|Error)$`,
+	"gokit":       `^level\.(Debug|Info|Warn|Error)\(.*\)\.Log$`,
+	"logr":        `^LV(\.V\(.*\))?\.(Info|Error)$`,
+	"apex":        `^LV(\.With(Error|Fields?)\(.*\))*\.(Debug|Info|Warn|Error|Fatal)$`,
+	"log15":       `^LV\.(Debug|Info|Warn|Error|Crit)$`,
+}
+
+// TargetPattern returns the pattern matching calls of a built-in style
+func TargetPattern(style, loggerVar string) (string, error) {
+	pattern, ok := stylePatterns[style]
+	if !ok {
+		return "", fmt.Errorf("no target pattern for style %q; set one explicitly", style)
+	}
+	return strings.ReplaceAll(pattern, "LV", regexp.QuoteMeta(loggerVar)), nil
+}
+
+// Options select what Measure scans
+type Options struct {
+	Path          string
+	Excludes      []string
+	LegacyPattern string // Calls still to migrate (the collect pattern)
+	TargetPattern string // Calls already using the structured API
+	Style         string // Recorded with the result
+}
+
+// Record is one coverage measurement
+type Record struct {
+	Time       time.Time `json:"time"`
+	Path       string    `json:"path"`
+	Style      string    `json:"style,omitempty"`
+	Total      int       `json:"total"`
+	Structured int       `json:"structured"`
+	Percent    float64   `json:"percent"`
+	Packages   []Package `json:"packages"`
+}
+
+// Package is the coverage of one package
+type Package struct {
+	Name       string  `json:"name"`
+	Total      int     `json:"total"`
+	Structured int     `json:"structured"`
+	Percent    float64 `json:"percent"`
+}
+
+// Measure scans the code and counts logging calls. A call matching
+// TargetPattern counts as structured, and the calls inside it (slog.Int,
+// the log.Info() of a zerolog chain) are not counted separately; a call
+// matching only LegacyPattern counts as still to migrate.
+func Measure(opts Options) (*Record, error) {
+	target, err := regexp.Compile(opts.TargetPattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target pattern: %w", err)
+	}
+	legacy, err := regexp.Compile(opts.LegacyPattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	type count struct{ total, structured int }
+	counts := make(map[string]*count)
+	err = collector.WalkGoFiles(opts.Path, opts.Excludes, func(path string) error {
+		node, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to parse %s: %v\n", path, err)
+			return nil
+		}
+		c, ok := counts[node.Name.Name]
+		if !ok {
+			c = &count{}
+			counts[node.Name.Name] = c
+		}
+		ast.Inspect(node, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			name := collector.CallName(call)
+			switch {
+			case name == "":
+			case target.MatchString(name):
+				c.total++
+				c.structured++
+				return false
+			case legacy.MatchString(name):
+				c.total++
+			}
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	record := &Record{Time: time.Now().UTC(), Path: opts.Path, Style: opts.Style, Packages: []Package{}}
+	for name, c := range counts {
+		if c.total == 0 {
+			continue
+		}
+		record.Total += c.total
+		record.Structured += c.structured
+		record.Packages = append(record.Packages, Package{
+			Name:       name,
+			Total:      c.total,
+			Structured: c.structured,
+			Percent:    percent(c.structured, c.total),
+		})
+	}
+	record.Percent = percent(record.Structured, record.Total)
+	sort.Slice(record.Packages, func(i, j int) bool {
+		if record.Packages[i].Percent != record.Packages[j].Percent {
+			return record.Packages[i].Percent < record.Packages[j].Percent
+		}
+		return record.Packages[i].Name < record.Packages[j].Name
+	})
+	return record, nil
+}
+
+// percent rounds to one decimal; no calls at all counts as fully covered
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(part*1000/total) / 10
+}
+
+// Text renders a record, least covered packages first
+func (r *Record) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Structured logging coverage: %.1f%% (%d of %d calls)\n", r.Percent, r.Structured, r.Total)
+	if len(r.Packages) > 0 {
+		fmt.Fprintf(&b, "\nBy package:\n")
+		for _, p := range r.Packages {
+			fmt.Fprintf(&b, "  %6.1f%%  %5d/%-5d %s\n", p.Percent, p.Structured, p.Total, p.Name)
+		}
+	}
+	return b.String()
+}
+
+// Append adds a record to a history file (JSON lines)
+func Append(historyFile string, record *Record) error {
+	f, err := os.OpenFile(historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(record); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// History reads a history file, oldest record first
+func History(historyFile string) ([]Record, error) {
+	f, err := os.Open(historyFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", historyFile, line, err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// Trend renders the history as one line per record with the change since
+// the previous one
+func Trend(records []Record) string {
+	var b strings.Builder
+	for i, r := range records {
+		delta := ""
+		if i > 0 {
+			delta = fmt.Sprintf("  %+.1f", r.Percent-records[i-1].Percent)
+		}
+		fmt.Fprintf(&b, "%s  %6.1f%%  %d/%d%s\n", r.Time.Local().Format("2006-01-02 15:04"), r.Percent, r.Structured, r.Total, delta)
+	}
+	return b.String()
+}
@@ -0,0 +1,190 @@
+// Package diff compares two collected CSVs, for example from two releases,
+// and reports the logging calls that were added, removed or changed.
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"logrefactor/internal/merge"
+	"logrefactor/internal/table"
+)
+
+// Entry is a logging call as recorded in a CSV
+type Entry struct {
+	ID        string `json:"id"`
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Call      string `json:"call"`
+	Level     string `json:"level"`
+	Message   string `json:"message"`
+	Arguments string `json:"arguments,omitempty"`
+}
+
+func (e *Entry) String() string {
+	return fmt.Sprintf("%s:%d %s(%s) [%s]", e.File, e.Line, e.Call, e.Message, e.Level)
+}
+
+// Change is a call found in both files with different content. Changes
+// lists what differs: "call", "level", "message" or "arguments".
+type Change struct {
+	Old     *Entry   `json:"old"`
+	New     *Entry   `json:"new"`
+	Changes []string `json:"changes"`
+}
+
+// Report is the difference between two CSVs
+type Report struct {
+	Added     []*Entry `json:"added"`
+	Removed   []*Entry `json:"removed"`
+	Changed   []Change `json:"changed"`
+	Unchanged int      `json:"unchanged"` // Matched calls that differ at most in line number
+}
+
+// Compare reads two CSVs and matches their entries by content. File paths
+// are compared as written, so collect both versions from the same relative
+// path. Matching tries, in order, with the nearest line winning:
+//
+//  1. same file, call, message and arguments
+//  2. same file, call and message (arguments changed)
+//  3. same file and message (call or level changed)
+//  4. same file, call and arguments (message changed)
+func Compare(oldFile, newFile string) (*Report, error) {
+	olds, err := load(oldFile)
+	if err != nil {
+		return nil, err
+	}
+	news, err := load(newFile)
+	if err != nil {
+		return nil, err
+	}
+
+	passes := []func(o, n *Entry) bool{
+		func(o, n *Entry) bool {
+			return o.File == n.File && o.Call == n.Call && o.Message == n.Message && o.Arguments == n.Arguments
+		},
+		func(o, n *Entry) bool { return o.File == n.File && o.Call == n.Call && o.Message == n.Message },
+		func(o, n *Entry) bool { return o.File == n.File && o.Message == n.Message },
+		func(o, n *Entry) bool {
+			return o.File == n.File && o.Call == n.Call && o.Arguments != "" && o.Arguments == n.Arguments
+		},
+	}
+
+	report := &Report{Added: []*Entry{}, Removed: []*Entry{}, Changed: []Change{}}
+	pairs := merge.Match(olds, news, func(e *Entry) int { return e.Line }, passes...)
+	matched := make(map[*Entry]bool, len(pairs))
+	for _, n := range news {
+		o, ok := pairs[n]
+		if !ok {
+			report.Added = append(report.Added, n)
+			continue
+		}
+		matched[o] = true
+		if changes := compare(o, n); len(changes) > 0 {
+			report.Changed = append(report.Changed, Change{Old: o, New: n, Changes: changes})
+		} else {
+			report.Unchanged++
+		}
+	}
+	for _, o := range olds {
+		if !matched[o] {
+			report.Removed = append(report.Removed, o)
+		}
+	}
+
+	sortEntries(report.Added)
+	sortEntries(report.Removed)
+	sort.SliceStable(report.Changed, func(i, j int) bool {
+		a, b := report.Changed[i].New, report.Changed[j].New
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return report, nil
+}
+
+// Text renders the report the way diff does: "+" for added calls, "-" for
+// removed ones and "~" for changed ones
+func (r *Report) Text() string {
+	var b strings.Builder
+	for _, e := range r.Added {
+		fmt.Fprintf(&b, "+ %s\n", e)
+	}
+	for _, e := range r.Removed {
+		fmt.Fprintf(&b, "- %s\n", e)
+	}
+	for _, c := range r.Changed {
+		var parts []string
+		for _, kind := range c.Changes {
+			switch kind {
+			case "call":
+				parts = append(parts, fmt.Sprintf("call %s -> %s", c.Old.Call, c.New.Call))
+			case "level":
+				parts = append(parts, fmt.Sprintf("level %s -> %s", c.Old.Level, c.New.Level))
+			case "message":
+				parts = append(parts, fmt.Sprintf("message %s -> %s", c.Old.Message, c.New.Message))
+			case "arguments":
+				parts = append(parts, fmt.Sprintf("arguments %s -> %s", c.Old.Arguments, c.New.Arguments))
+			}
+		}
+		fmt.Fprintf(&b, "~ %s:%d %s\n", c.New.File, c.New.Line, strings.Join(parts, "; "))
+	}
+	fmt.Fprintf(&b, "%d added, %d removed, %d changed, %d unchanged\n",
+		len(r.Added), len(r.Removed), len(r.Changed), r.Unchanged)
+	return b.String()
+}
+
+// compare lists what differs between two matched entries
+func compare(o, n *Entry) []string {
+	var changes []string
+	if o.Call != n.Call {
+		changes = append(changes, "call")
+	}
+	if o.Level != n.Level {
+		changes = append(changes, "level")
+	}
+	if o.Message != n.Message {
+		changes = append(changes, "message")
+	}
+	if o.Arguments != n.Arguments {
+		changes = append(changes, "arguments")
+	}
+	return changes
+}
+
+func load(file string) ([]*Entry, error) {
+	t, err := table.Read(file)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Require("FilePath", "Line", "OriginalCall", "LogLevel", "MessageTemplate"); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	entries := make([]*Entry, 0, len(t.Rows))
+	for _, row := range t.Rows {
+		line, _ := strconv.Atoi(t.Get(row, "Line"))
+		entries = append(entries, &Entry{
+			ID:        t.Get(row, "ID"),
+			File:      t.Get(row, "FilePath"),
+			Line:      line,
+			Call:      t.Get(row, "OriginalCall"),
+			Level:     t.Get(row, "LogLevel"),
+			Message:   t.Get(row, "MessageTemplate"),
+			Arguments: t.Get(row, "ArgumentDetails"),
+		})
+	}
+	return entries, nil
+}
+
+func sortEntries(entries []*Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].File != entries[j].File {
+			return entries[i].File < entries[j].File
+		}
+		return entries[i].Line < entries[j].Line
+	})
+}
@@ -0,0 +1,136 @@
+package diff
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	const header = "ID,FilePath,Line,OriginalCall,LogLevel,MessageTemplate,ArgumentDetails\n"
+	tests := []struct {
+		name      string
+		old, new  string // Rows after the header
+		added     string // Lines of the added entries
+		removed   string // Lines of the removed entries
+		changed   string // Old line, new line and changes of each changed entry
+		unchanged int
+	}{
+		{
+			name:      "moved only",
+			old:       "LOG-0001,a.go,10,log.Printf,INFO,\"\"\"hi\"\"\",\n",
+			new:       "LOG-0001,a.go,12,log.Printf,INFO,\"\"\"hi\"\"\",\n",
+			unchanged: 1,
+		},
+		{
+			name:    "added and removed",
+			old:     "LOG-0001,a.go,10,log.Printf,INFO,\"\"\"hi\"\"\",\n",
+			new:     "LOG-0001,b.go,10,log.Printf,INFO,\"\"\"hi\"\"\",\n",
+			added:   "[b.go:10]",
+			removed: "[a.go:10]",
+		},
+		{
+			name:    "arguments changed",
+			old:     "LOG-0001,a.go,10,log.Printf,INFO,\"\"\"hi %s\"\"\",name(unknown)=name[%s]\n",
+			new:     "LOG-0001,a.go,10,log.Printf,INFO,\"\"\"hi %s\"\"\",user(unknown)=user[%s]\n",
+			changed: "[10->10 [arguments]]",
+		},
+		{
+			name:    "call and level changed",
+			old:     "LOG-0001,a.go,10,log.Printf,INFO,\"\"\"failed\"\"\",\n",
+			new:     "LOG-0001,a.go,11,log.Fatalf,FATAL,\"\"\"failed\"\"\",\n",
+			changed: "[10->11 [call level]]",
+		},
+		{
+			name:    "message changed",
+			old:     "LOG-0001,a.go,10,log.Printf,INFO,\"\"\"hi %s\"\"\",name(unknown)=name[%s]\n",
+			new:     "LOG-0001,a.go,10,log.Printf,INFO,\"\"\"hello %s\"\"\",name(unknown)=name[%s]\n",
+			changed: "[10->10 [message]]",
+		},
+		{
+			name:      "nearest line wins",
+			old:       "LOG-0001,a.go,10,log.Printf,INFO,\"\"\"hi\"\"\",\nLOG-0002,a.go,50,log.Printf,INFO,\"\"\"hi\"\"\",\n",
+			new:       "LOG-0001,a.go,48,log.Printf,INFO,\"\"\"hi\"\"\",\n",
+			removed:   "[a.go:10]",
+			unchanged: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			oldFile, newFile := filepath.Join(dir, "old.csv"), filepath.Join(dir, "new.csv")
+			if err := os.WriteFile(oldFile, []byte(header+tt.old), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(newFile, []byte(header+tt.new), 0644); err != nil {
+				t.Fatal(err)
+			}
+			report, err := Compare(oldFile, newFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var changed []string
+			for _, c := range report.Changed {
+				changed = append(changed, fmt.Sprintf("%d->%d %v", c.Old.Line, c.New.Line, c.Changes))
+			}
+			if got := positions(report.Added); got != tt.added {
+				t.Errorf("added = %s, want %s", got, tt.added)
+			}
+			if got := positions(report.Removed); got != tt.removed {
+				t.Errorf("removed = %s, want %s", got, tt.removed)
+			}
+			if got := list(changed); got != tt.changed {
+				t.Errorf("changed = %s, want %s", got, tt.changed)
+			}
+			if report.Unchanged != tt.unchanged {
+				t.Errorf("unchanged = %d, want %d", report.Unchanged, tt.unchanged)
+			}
+		})
+	}
+}
+
+func TestCompareMissingColumn(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "old.csv")
+	if err := os.WriteFile(file, []byte("ID,FilePath,Line\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Compare(file, file)
+	if err == nil || !strings.Contains(err.Error(), "CSV has no OriginalCall column") {
+		t.Errorf("Compare = %v, want the OriginalCall column missing", err)
+	}
+}
+
+func TestText(t *testing.T) {
+	old := &Entry{File: "a.go", Line: 10, Call: "log.Printf", Level: "INFO", Message: `"hi"`}
+	report := &Report{
+		Added:     []*Entry{{File: "a.go", Line: 3, Call: "log.Print", Level: "INFO", Message: `"new"`}},
+		Changed:   []Change{{Old: old, New: &Entry{File: "a.go", Line: 11, Call: "log.Printf", Level: "ERROR", Message: `"hi"`}, Changes: []string{"level"}}},
+		Unchanged: 2,
+	}
+	want := "+ a.go:3 log.Print(\"new\") [INFO]\n" +
+		"~ a.go:11 level INFO -> ERROR\n" +
+		"1 added, 0 removed, 1 changed, 2 unchanged\n"
+	if got := report.Text(); got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+}
+
+// positions lists the file and line of each entry, or "" if there are none
+func positions(entries []*Entry) string {
+	var s []string
+	for _, e := range entries {
+		s = append(s, fmt.Sprintf("%s:%d", e.File, e.Line))
+	}
+	return list(s)
+}
+
+func list(s []string) string {
+	if len(s) == 0 {
+		return ""
+	}
+	return fmt.Sprint(s)
+}
@@ -0,0 +1,238 @@
+// Package git creates the branch and commits for a transform, split so that
+// a large mechanical change can be reviewed one package or file at a time.
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"logrefactor/pkg/transformer"
+)
+
+// Grouping of changes into commits
+const (
+	ByPackage = "package"
+	ByFile    = "file"
+)
+
+// Repo is a git working tree
+type Repo struct {
+	Root string // Top-level directory
+}
+
+// Open finds the repository containing dir
+func Open(dir string) (*Repo, error) {
+	out, err := run(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("%s is not in a git repository: %w", dir, err)
+	}
+	return &Repo{Root: strings.TrimSpace(out)}, nil
+}
+
+// run runs git in dir and returns its output; the error includes what git
+// printed on stderr
+func run(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
+
+// CreateBranch creates a branch at HEAD and switches to it
+func (r *Repo) CreateBranch(name string) error {
+	_, err := run(r.Root, "checkout", "-b", name)
+	return err
+}
+
+// Rel returns the path of an existing file relative to the repository
+// root, with forward slashes as git prints them
+func (r *Repo) Rel(file string) (string, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	rel, err := filepath.Rel(r.Root, abs)
+	if err != nil {
+		return "", err
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the repository %s", file, r.Root)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// Dirty returns the tracked files with uncommitted changes, relative to the
+// repository root
+func (r *Repo) Dirty() (map[string]bool, error) {
+	out, err := run(r.Root, "status", "--porcelain", "--untracked-files=no", "-z")
+	if err != nil {
+		return nil, err
+	}
+	dirty := make(map[string]bool)
+	for _, record := range strings.Split(out, "\x00") {
+		if len(record) > 3 {
+			dirty[record[3:]] = true
+		}
+	}
+	return dirty, nil
+}
+
+// Staged returns the files added, copied, modified or renamed in the index,
+// relative to the repository root
+func (r *Repo) Staged() ([]string, error) {
+	out, err := run(r.Root, "diff", "--cached", "--name-only", "--diff-filter=ACMR", "-z")
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, file := range strings.Split(out, "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// Commit stages files (relative to the root) and commits them, and only
+// them, with message
+func (r *Repo) Commit(message string, files []string) error {
+	args := append([]string{"add", "--"}, files...)
+	if _, err := run(r.Root, args...); err != nil {
+		return err
+	}
+	args = append([]string{"commit", "-q", "-m", message, "--"}, files...)
+	_, err := run(r.Root, args...)
+	return err
+}
+
+// Commit is one planned commit
+type Commit struct {
+	Title   string
+	Group   string   // Package directory or file
+	IDs     []string // Entries rewritten in the commit
+	Files   []string // Relative to the repository root
+	Message string
+}
+
+// Plan groups the changes of a transform into commits, by package directory
+// or by file, in path order. files lists every file written; those changed
+// without entries of their own (the key constants file) go into a first
+// commit, since the others use them.
+func (r *Repo) Plan(changes []transformer.Change, files []string, by string) ([]Commit, error) {
+	if by != ByPackage && by != ByFile {
+		return nil, fmt.Errorf("unknown commit grouping %q (use %s or %s)", by, ByPackage, ByFile)
+	}
+
+	ids := make(map[string][]string) // File -> entry IDs
+	for _, c := range changes {
+		file, err := r.Rel(c.File)
+		if err != nil {
+			return nil, err
+		}
+		ids[file] = append(ids[file], c.ID)
+	}
+
+	var support []string
+	groups := make(map[string]*Commit)
+	for _, f := range files {
+		file, err := r.Rel(f)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids[file]) == 0 {
+			support = append(support, file)
+			continue
+		}
+		group := file
+		if by == ByPackage {
+			group = path.Dir(file)
+		}
+		c, ok := groups[group]
+		if !ok {
+			c = &Commit{Group: group}
+			groups[group] = c
+		}
+		c.Files = append(c.Files, file)
+		c.IDs = append(c.IDs, ids[file]...)
+	}
+
+	var commits []Commit
+	if len(support) > 0 {
+		title := "logrefactor: add structured logging field keys"
+		commits = append(commits, Commit{Title: title, Files: support, Message: title + "\n"})
+	}
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c := groups[name]
+		sort.Strings(c.Files)
+		sort.Strings(c.IDs)
+		name := c.Group
+		if name == "." {
+			name = "the root package"
+		}
+		c.Title = fmt.Sprintf("logrefactor: migrate %s to structured logging", name)
+		c.Message = message(c)
+		commits = append(commits, *c)
+	}
+	return commits, nil
+}
+
+// message lists the entries and files of a commit below its title
+func message(c *Commit) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", c.Title)
+	fmt.Fprintf(&b, "Rewrites %d log calls generated from the reviewed CSV.\n\n", len(c.IDs))
+	fmt.Fprintf(&b, "Entries: %s\n", strings.Join(c.IDs, ", "))
+	if len(c.Files) > 1 {
+		b.WriteString("\nFiles:\n")
+		for _, f := range c.Files {
+			fmt.Fprintf(&b, "  %s\n", f)
+		}
+	}
+	return b.String()
+}
+
+// PullRequest renders a Markdown pull request description for the commits
+func PullRequest(branch string, commits []Commit) string {
+	total := 0
+	for _, c := range commits {
+		total += len(c.IDs)
+	}
+
+	var b strings.Builder
+	b.WriteString("## Migrate to structured logging\n\n")
+	fmt.Fprintf(&b, "Rewrites %d log calls with logrefactor", total)
+	if branch != "" {
+		fmt.Fprintf(&b, " on `%s`", branch)
+	}
+	fmt.Fprintf(&b, ", in %d commits that can be reviewed one at a time. The rewrites are mechanical; review the messages and field keys.\n\n", len(commits))
+	b.WriteString("| Commit | Calls | Entries |\n|---|---|---|\n")
+	for _, c := range commits {
+		if c.Group == "" {
+			fmt.Fprintf(&b, "| Field key constants | - | - |\n")
+			continue
+		}
+		fmt.Fprintf(&b, "| `%s` | %d | %s |\n", c.Group, len(c.IDs), strings.Join(c.IDs, ", "))
+	}
+	return b.String()
+}
@@ -0,0 +1,153 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"logrefactor/pkg/transformer"
+)
+
+func TestPlan(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Repo{Root: root}
+	abs := func(file string) string { return filepath.Join(root, filepath.FromSlash(file)) }
+	changes := []transformer.Change{
+		{ID: "LOG-0003", File: abs("api/server.go")},
+		{ID: "LOG-0001", File: abs("api/handler.go")},
+		{ID: "LOG-0002", File: abs("api/handler.go")},
+		{ID: "LOG-0004", File: abs("main.go")},
+	}
+	files := []string{abs("main.go"), abs("api/server.go"), abs("api/handler.go"), abs("logkeys/keys.go")}
+
+	tests := []struct {
+		by   string
+		want []string // Group, IDs and files of each commit
+	}{
+		{ByPackage, []string{
+			" [] [logkeys/keys.go]",
+			". [LOG-0004] [main.go]",
+			"api [LOG-0001 LOG-0002 LOG-0003] [api/handler.go api/server.go]",
+		}},
+		{ByFile, []string{
+			" [] [logkeys/keys.go]",
+			"api/handler.go [LOG-0001 LOG-0002] [api/handler.go]",
+			"api/server.go [LOG-0003] [api/server.go]",
+			"main.go [LOG-0004] [main.go]",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			commits, err := r.Plan(changes, files, tt.by)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, c := range commits {
+				got = append(got, fmt.Sprintf("%s %v %v", c.Group, c.IDs, c.Files))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Plan() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	commits, err := r.Plan(changes, files, ByPackage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "logrefactor: migrate the root package to structured logging"; commits[1].Title != want {
+		t.Errorf("title = %q, want %q", commits[1].Title, want)
+	}
+	if !strings.Contains(commits[2].Message, "Rewrites 3 log calls") || !strings.Contains(commits[2].Message, "\nFiles:\n  api/handler.go\n  api/server.go\n") {
+		t.Errorf("message = %q, want the calls and files listed", commits[2].Message)
+	}
+	if pr := PullRequest("logs", commits); !strings.Contains(pr, "Rewrites 4 log calls with logrefactor on `logs`, in 3 commits") ||
+		!strings.Contains(pr, "| Field key constants | - | - |\n") || !strings.Contains(pr, "| `api` | 3 | LOG-0001, LOG-0002, LOG-0003 |\n") {
+		t.Errorf("PullRequest() = %q", pr)
+	}
+
+	if _, err := r.Plan(changes, files, "module"); err == nil {
+		t.Error("Plan() with an unknown grouping succeeded")
+	}
+	if _, err := r.Plan(nil, []string{filepath.Join(root, "..", "outside.go")}, ByFile); err == nil || !strings.Contains(err.Error(), "is outside the repository") {
+		t.Errorf("Plan() of a file outside the repository = %v", err)
+	}
+}
+
+func TestCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.name", "test"},
+		{"config", "user.email", "test@example.com"},
+	} {
+		if _, err := run(dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"a.go", "b.go"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("package a\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Commit("first", []string{"a.go", "b.go"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.CreateBranch("logs"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.go", "b.go"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("package a\n\n// changed\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := run(dir, "add", "b.go"); err != nil {
+		t.Fatal(err)
+	}
+
+	dirty, err := r.Dirty()
+	if err != nil {
+		t.Fatal(err)
+	}
+	staged, err := r.Staged()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirty) != 2 || !dirty["a.go"] || !dirty["b.go"] || fmt.Sprint(staged) != "[b.go]" {
+		t.Errorf("dirty %v and staged %v, want a.go and b.go dirty and b.go staged", dirty, staged)
+	}
+
+	// Only the files given are committed, whatever else is staged
+	if err := r.Commit("second", []string{"a.go"}); err != nil {
+		t.Fatal(err)
+	}
+	out, err := run(dir, "show", "--name-only", "--format=%s", "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Fields(out)[0] != "second" || strings.Join(strings.Fields(out)[1:], " ") != "a.go" {
+		t.Errorf("HEAD = %q, want second with a.go only", out)
+	}
+	if branch, _ := run(dir, "branch", "--show-current"); strings.TrimSpace(branch) != "logs" {
+		t.Errorf("branch = %q, want logs", branch)
+	}
+
+	if _, err := Open(t.TempDir()); err == nil || !strings.Contains(err.Error(), "is not in a git repository") {
+		t.Errorf("Open() outside a repository = %v", err)
+	}
+}
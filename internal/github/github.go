@@ -0,0 +1,72 @@
+// Package github writes findings as GitHub Actions workflow commands
+// (::warning file=...,line=...::message), which the runner turns into
+// annotations shown inline on pull requests.
+package github
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"logrefactor/internal/sarif"
+)
+
+// Write writes one workflow command per finding. File paths are made
+// relative to root, which should be the repository root (the workspace the
+// workflow checked out).
+func Write(w io.Writer, findings []sarif.Finding, root string) error {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	for _, f := range findings {
+		abs, err := filepath.Abs(f.File)
+		if err != nil {
+			return err
+		}
+		file, err := filepath.Rel(absRoot, abs)
+		if err != nil {
+			return err
+		}
+
+		props := []string{
+			"file=" + property(filepath.ToSlash(file)),
+			fmt.Sprintf("line=%d", f.Line),
+			fmt.Sprintf("col=%d", f.Column),
+		}
+		if f.EndLine != 0 {
+			props = append(props, fmt.Sprintf("endLine=%d", f.EndLine), fmt.Sprintf("endColumn=%d", f.EndColumn))
+		}
+		title := f.Rule.ID + " " + f.Rule.Name
+		if f.ID != "" {
+			title += " (" + f.ID + ")"
+		}
+		props = append(props, "title="+property(title))
+		fmt.Fprintf(bw, "::%s %s::%s\n", command(f.Rule.Level), strings.Join(props, ","), data(f.Message))
+	}
+	return bw.Flush()
+}
+
+// command is the workflow command for a SARIF level
+func command(level string) string {
+	switch level {
+	case "error":
+		return "error"
+	case "note":
+		return "notice"
+	}
+	return "warning"
+}
+
+// data escapes a command's message
+func data(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// property escapes a command property value
+func property(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
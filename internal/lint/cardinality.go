@@ -0,0 +1,90 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"logrefactor/internal/naming"
+)
+
+// Kinds of high-cardinality values
+const (
+	ID   = "ID"
+	UUID = "UUID"
+	URL  = "URL"
+	Body = "HTTP body"
+)
+
+// identifier matches the identifiers of an expression
+var identifier = regexp.MustCompile(`[\pL_][\pL\pN_]*`)
+
+// Interpolated returns the issue for an argument a format verb puts into
+// the message text, if it looks like a value with many distinct values: an
+// ID, a UUID, a URL or an HTTP body. Every such value makes a new message
+// pattern, which log backends index and often bill by; as a field it
+// doesn't. IDs are recognized by the key, the last name of the expression
+// or a string literal in it (user.ID, orderID, r.Header.Get("X-Request-ID"));
+// UUIDs, URLs and bodies by any name in it (uuid.New(), r.URL.Path,
+// string(body)).
+func Interpolated(key, expression, typ string) (Issue, bool) {
+	switch typ {
+	case "bool", "nil", "error", "duration_ms", "time.Duration", "time.Time":
+		return Issue{}, false // IDs may be ints, but not these
+	}
+	if strings.HasPrefix(expression, "len(") || strings.HasPrefix(expression, "cap(") {
+		return Issue{}, false
+	}
+
+	issue := Issue{Key: key, Expression: expression, Severity: Low}
+	names := []string{key, lastName(expression)}
+	for _, m := range quoted.FindAllStringSubmatch(expression, -1) {
+		names = append(names, m[1])
+	}
+	for _, name := range names {
+		ws := naming.Words(name)
+		if len(ws) == 0 || slices.Contains(measures, ws[len(ws)-1]) {
+			continue
+		}
+		switch ws[len(ws)-1] {
+		case "id", "ids", "uid":
+			issue.Kind = ID
+			return issue, true
+		}
+	}
+	for _, name := range identifier.FindAllString(quoted.ReplaceAllString(expression, ""), -1) {
+		for _, w := range naming.Words(name) {
+			switch w {
+			case "uuid", "guid":
+				issue.Kind = UUID
+			case "url", "uri", "href":
+				issue.Kind = URL
+			case "body", "payload":
+				issue.Kind = Body
+			default:
+				continue
+			}
+			return issue, true
+		}
+	}
+	return Issue{}, false
+}
+
+// CardinalityNote describes the high-cardinality values of an entry for the
+// Notes column, e.g. "CARDINALITY: user.ID looks like an ID in the message;
+// move it to fields or drop it". It is empty if there are none.
+func CardinalityNote(issues []Issue) string {
+	if len(issues) == 0 {
+		return ""
+	}
+	var parts []string
+	for _, issue := range issues {
+		parts = append(parts, issue.String())
+	}
+	it := "it"
+	if len(issues) > 1 {
+		it = "them"
+	}
+	return fmt.Sprintf("CARDINALITY: %s in the message; move %s to fields or drop %s", strings.Join(parts, ", "), it, it)
+}
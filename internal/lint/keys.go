@@ -0,0 +1,89 @@
+package lint
+
+import (
+	"sort"
+	"strings"
+
+	"logrefactor/internal/naming"
+)
+
+// KeyUse is a field key as one entry logs it
+type KeyUse struct {
+	Key        string `json:"key"`
+	Type       string `json:"type,omitempty"`
+	Expression string `json:"expression"`
+	Package    string `json:"package"`
+	ID         string `json:"id"`
+	Location   string `json:"location"`
+}
+
+// Collision is one key logged under several names or with several types,
+// such as user_id as a string in one package and userID as an int in
+// another: the drift a structured logging migration is meant to remove
+type Collision struct {
+	Key   string   `json:"key"`   // The words of the key joined by "_", e.g. "user_id"
+	Names []string `json:"names"` // The names in use, most used first
+	Types []string `json:"types"` // The types logged, most used first
+	Uses  []KeyUse `json:"uses"`
+}
+
+// untyped are the recorded types that say nothing about a value, including
+// zap's and slog's Any and fields collect can't read (collector.ZapField,
+// collector.SlogAttr and collector.Unpaired)
+var untyped = map[string]bool{"": true, "unknown": true, "func_result": true, "nil": true, "any": true, "zap.Field": true, "slog.Attr": true, "unpaired": true}
+
+// Collisions groups uses by the words of their keys (see naming.Words;
+// user_id, userID and user.id are one key) and returns the keys used under
+// more than one name or with more than one type, sorted by key
+func Collisions(uses []KeyUse) []Collision {
+	groups := make(map[string][]KeyUse)
+	for _, use := range uses {
+		key := canonicalKey(use.Key)
+		if key == "" {
+			continue
+		}
+		groups[key] = append(groups[key], use)
+	}
+
+	var collisions []Collision
+	for key, group := range groups {
+		names, types := make(map[string]int), make(map[string]int)
+		for _, use := range group {
+			names[use.Key]++
+			if !untyped[use.Type] {
+				types[use.Type]++
+			}
+		}
+		if len(names) < 2 && len(types) < 2 {
+			continue
+		}
+		collisions = append(collisions, Collision{Key: key, Names: byUses(names), Types: byUses(types), Uses: group})
+	}
+	sort.Slice(collisions, func(i, j int) bool { return collisions[i].Key < collisions[j].Key })
+	return collisions
+}
+
+// canonicalKey returns the words of a key joined by "_"; dots separate
+// words as well, so a key in a group matches the flat one
+func canonicalKey(key string) string {
+	var words []string
+	for _, part := range strings.Split(key, ".") {
+		words = append(words, naming.Words(part)...)
+	}
+	return strings.Join(words, "_")
+}
+
+// byUses returns the keys of counts, most counted first, then by name
+func byUses(counts map[string]int) []string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
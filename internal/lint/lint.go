@@ -0,0 +1,225 @@
+// Package lint recognizes log fields that look like sensitive data, such as
+// passwords, API tokens or e-mail addresses, from the names of the
+// variables, fields and headers they log. A migration shouldn't carry a
+// credential leak over verbatim into nicely structured fields. It also
+// recognizes high-cardinality values, such as IDs and URLs, interpolated
+// into message text (see Interpolated), and messages that break the style
+// rules (see Message).
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"logrefactor/internal/naming"
+)
+
+// Severities of an Issue
+const (
+	High   = "high"   // Credentials: passwords, tokens, keys, auth headers
+	Medium = "medium" // Personal data: e-mail addresses, phone numbers, SSNs
+	Low    = "low"    // High-cardinality values in the message text, calls in loops, message style
+)
+
+// severities ranks the severities, lowest first
+var severities = []string{Low, Medium, High}
+
+// Kinds of sensitive data
+const (
+	Credential = "credential"
+	Personal   = "personal data"
+)
+
+// kinds are the kinds of issues with the article they take, if any
+var kinds = map[string]string{
+	Credential: "a",
+	Personal:   "",
+	ID:         "an",
+	UUID:       "a",
+	URL:        "a",
+	Body:       "an",
+}
+
+// Issue is a logged value that looks like sensitive data
+type Issue struct {
+	Key        string
+	Expression string
+	Kind       string
+	Severity   string
+}
+
+// words maps a word of a name to the kind of data it suggests. Compound
+// names ("api key", "credit card") are matched on adjacent words. A plain
+// "token" isn't listed: as often as not it is a lexer's.
+var words = map[string]string{
+	"password":        Credential,
+	"passwd":          Credential,
+	"passphrase":      Credential,
+	"secret":          Credential,
+	"access token":    Credential,
+	"auth token":      Credential,
+	"api token":       Credential,
+	"bearer token":    Credential,
+	"refresh token":   Credential,
+	"session token":   Credential,
+	"id token":        Credential,
+	"csrf token":      Credential,
+	"oauth token":     Credential,
+	"apikey":          Credential,
+	"api key":         Credential,
+	"access key":      Credential,
+	"private key":     Credential,
+	"privkey":         Credential,
+	"credential":      Credential,
+	"credentials":     Credential,
+	"creds":           Credential,
+	"authorization":   Credential,
+	"auth header":     Credential,
+	"bearer":          Credential,
+	"cookie":          Credential,
+	"session id":      Credential,
+	"jwt":             Credential,
+	"otp":             Credential,
+	"ssn":             Personal,
+	"social security": Personal,
+	"email":           Personal,
+	"e mail":          Personal,
+	"phone":           Personal,
+	"credit card":     Personal,
+	"card number":     Personal,
+	"cvv":             Personal,
+	"iban":            Personal,
+	"passport":        Personal,
+	"birthdate":       Personal,
+	"date of birth":   Personal,
+	"dob":             Personal,
+}
+
+// measures end names that describe a value rather than hold it, as in
+// tokenCount or passwordLength
+var measures = []string{"count", "len", "length", "size", "num", "total", "type", "kind", "expiry", "expires", "expiration", "ttl", "age", "valid", "set", "ok", "name", "enabled", "required"}
+
+// harmless are the recorded types that can't hold a secret
+var harmless = []string{"int", "float", "bool", "nil", "error", "time.Duration", "time.Time"}
+
+// quoted matches the string literals of an expression, such as the header
+// name in r.Header.Get("Authorization")
+var quoted = regexp.MustCompile(`"([^"\\]*)"`)
+
+// Field returns the issue for a logged field with the given key, expression
+// and recorded type (see collector.Argument), if its names suggest
+// sensitive data. The key, the last name of the expression (Password in
+// req.Password) and the string literals in it are looked at.
+func Field(key, expression, typ string) (Issue, bool) {
+	if slices.Contains(harmless, typ) {
+		return Issue{}, false
+	}
+
+	names := []string{key, lastName(expression)}
+	for _, m := range quoted.FindAllStringSubmatch(expression, -1) {
+		names = append(names, m[1])
+	}
+	for _, name := range names {
+		if kind := match(name); kind != "" {
+			issue := Issue{Key: key, Expression: expression, Kind: kind, Severity: Medium}
+			if kind == Credential {
+				issue.Severity = High
+			}
+			return issue, true
+		}
+	}
+	return Issue{}, false
+}
+
+// match returns the kind of sensitive data a name suggests, or ""
+func match(name string) string {
+	ws := naming.Words(name)
+	if len(ws) == 0 || slices.Contains(measures, ws[len(ws)-1]) {
+		return ""
+	}
+	for i := range ws {
+		if kind, ok := words[ws[i]]; ok {
+			return kind
+		}
+		if i+1 < len(ws) {
+			if kind, ok := words[ws[i]+" "+ws[i+1]]; ok {
+				return kind
+			}
+		}
+		if i+2 < len(ws) {
+			if kind, ok := words[ws[i]+" "+ws[i+1]+" "+ws[i+2]]; ok {
+				return kind
+			}
+		}
+	}
+	return ""
+}
+
+// lastName returns the last identifier of an expression: Password for
+// req.Password, GetToken for c.GetToken()
+func lastName(expression string) string {
+	expression = strings.TrimSuffix(expression, "()")
+	if i := strings.LastIndexAny(expression, ".*&"); i >= 0 {
+		expression = expression[i+1:]
+	}
+	if strings.ContainsAny(expression, "()[]\" ") {
+		return ""
+	}
+	return expression
+}
+
+// Severity returns the highest severity of the issues, or "" if there are
+// none
+func Severity(issues []Issue) string {
+	severity := ""
+	for _, issue := range issues {
+		if AtLeast(issue.Severity, severity) {
+			severity = issue.Severity
+		}
+	}
+	return severity
+}
+
+// AtLeast reports whether severity is min or higher. Every severity is at
+// least "".
+func AtLeast(severity, min string) bool {
+	return slices.Index(severities, severity) >= slices.Index(severities, min)
+}
+
+// Note describes the issues of an entry for the Notes column, e.g.
+// "SENSITIVE (high): req.Password looks like a credential; redact or drop
+// it before migrating". It is empty if there are none.
+func Note(issues []Issue) string {
+	if len(issues) == 0 {
+		return ""
+	}
+	var parts []string
+	for _, issue := range issues {
+		parts = append(parts, issue.String())
+	}
+	it := "it"
+	if len(issues) > 1 {
+		it = "them"
+	}
+	return fmt.Sprintf("SENSITIVE (%s): %s; redact or drop %s before migrating", Severity(issues), strings.Join(parts, ", "), it)
+}
+
+// String describes the issue, e.g. "req.Password looks like a credential"
+// or "log.Printf runs on every iteration of a range loop"
+func (i Issue) String() string {
+	switch i.Kind {
+	case Style:
+		return "message " + styles[i.Key]
+	case Loop:
+		return fmt.Sprintf("%s runs on every iteration of a %s loop", i.Expression, i.Key)
+	case HotPath:
+		return fmt.Sprintf("%s runs on a hot path", i.Expression)
+	}
+	kind := i.Kind
+	if article := kinds[kind]; article != "" {
+		kind = article + " " + kind
+	}
+	return fmt.Sprintf("%s looks like %s", i.Expression, kind)
+}
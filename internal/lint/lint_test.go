@@ -0,0 +1,147 @@
+package lint
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestField(t *testing.T) {
+	tests := []struct {
+		key, expression, typ string
+		want                 string // Kind and severity, or "" for no issue
+	}{
+		{"password", "req.Password", "string", "credential high"},
+		{"user", "r.Header.Get(\"Authorization\")", "string", "credential high"},
+		{"key", "cfg.APIKey", "string", "credential high"},
+		{"contact", "u.Email", "string", "personal data medium"},
+		{"card", "creditCardNumber", "string", "personal data medium"},
+		{"password_length", "len(req.Password)", "int", ""},
+		{"token_count", "tokenCount", "string", ""},
+		{"token", "lexer.Token", "string", ""},
+		{"secret", "secret", "bool", ""},
+		{"user", "u.Name", "string", ""},
+	}
+	for _, tt := range tests {
+		issue, ok := Field(tt.key, tt.expression, tt.typ)
+		got := ""
+		if ok {
+			got = issue.Kind + " " + issue.Severity
+		}
+		if got != tt.want {
+			t.Errorf("Field(%q, %q, %q) = %q, want %q", tt.key, tt.expression, tt.typ, got, tt.want)
+		}
+	}
+}
+
+func TestInterpolated(t *testing.T) {
+	tests := []struct {
+		key, expression, typ string
+		want                 string // Kind, or "" for no issue
+	}{
+		{"user_id", "user.ID", "int", ID},
+		{"order", "orderID", "string", ID},
+		{"request", "r.Header.Get(\"X-Request-ID\")", "string", ID},
+		{"id", "uuid.New()", "unknown", ID},
+		{"trace", "uuid.New().String()", "unknown", UUID},
+		{"path", "r.URL.Path", "string", URL},
+		{"data", "string(body)", "unknown", Body},
+		{"ids", "len(ids)", "int", ""},
+		{"id_count", "idCount", "int", ""},
+		{"user_id", "user.ID", "error", ""},
+		{"name", "user.Name", "string", ""},
+	}
+	for _, tt := range tests {
+		issue, ok := Interpolated(tt.key, tt.expression, tt.typ)
+		got := ""
+		if ok {
+			got = issue.Kind
+		}
+		if got != tt.want {
+			t.Errorf("Interpolated(%q, %q, %q) = %q, want %q", tt.key, tt.expression, tt.typ, got, tt.want)
+		}
+	}
+}
+
+func TestHot(t *testing.T) {
+	tests := []struct {
+		inLoop, level string
+		want          string // Issue, or "" for none
+	}{
+		{"range", "Info", "log.Printf runs on every iteration of a range loop"},
+		{"for", "ERROR", "log.Printf runs on every iteration of a for loop"},
+		{"hot", "Info", "log.Printf runs on a hot path"},
+		{"range", "debug", ""},
+		{"for", "Trace", ""},
+		{"", "Info", ""},
+	}
+	for _, tt := range tests {
+		issue, ok := Hot("log.Printf", tt.inLoop, tt.level)
+		got := ""
+		if ok {
+			got = issue.String()
+		}
+		if got != tt.want {
+			t.Errorf("Hot(%q, %q) = %q, want %q", tt.inLoop, tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestNotes(t *testing.T) {
+	password := Issue{Key: "password", Expression: "req.Password", Kind: Credential, Severity: High}
+	email := Issue{Key: "email", Expression: "u.Email", Kind: Personal, Severity: Medium}
+	id := Issue{Key: "id", Expression: "user.ID", Kind: ID, Severity: Low}
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"none", Note(nil), ""},
+		{"one", Note([]Issue{email}), "SENSITIVE (medium): u.Email looks like personal data; redact or drop it before migrating"},
+		{"highest severity", Note([]Issue{email, password}), "SENSITIVE (high): u.Email looks like personal data, req.Password looks like a credential; redact or drop them before migrating"},
+		{"cardinality", CardinalityNote([]Issue{id}), "CARDINALITY: user.ID looks like an ID in the message; move it to fields or drop it"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: note = %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestAtLeast(t *testing.T) {
+	tests := []struct {
+		severity, min string
+		want          bool
+	}{
+		{High, Medium, true},
+		{Medium, Medium, true},
+		{Low, Medium, false},
+		{Low, "", true},
+		{"", Low, false},
+	}
+	for _, tt := range tests {
+		if got := AtLeast(tt.severity, tt.min); got != tt.want {
+			t.Errorf("AtLeast(%q, %q) = %v, want %v", tt.severity, tt.min, got, tt.want)
+		}
+	}
+}
+
+func TestCollisions(t *testing.T) {
+	uses := []KeyUse{
+		{Key: "user_id", Type: "string", Package: "api"},
+		{Key: "userID", Type: "string", Package: "db"},
+		{Key: "user.id", Type: "int", Package: "auth"},
+		{Key: "user_id", Type: "string", Package: "api"},
+		{Key: "status", Type: "int"},
+		{Key: "status", Type: "unknown"},
+		{Key: "count", Type: "int"},
+		{Key: "count", Type: "float"},
+	}
+	var got []string
+	for _, c := range Collisions(uses) {
+		got = append(got, fmt.Sprintf("%s %v %v %d", c.Key, c.Names, c.Types, len(c.Uses)))
+	}
+	want := "[count [count] [float int] 2 user_id [user_id user.id userID] [string int] 4]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("Collisions() = %v, want %s", got, want)
+	}
+}
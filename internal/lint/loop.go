@@ -0,0 +1,25 @@
+package lint
+
+import "strings"
+
+// Kinds of hot log calls
+const (
+	Loop    = "loop"
+	HotPath = "hot path"
+)
+
+// Hot returns the issue for a log call collect found in the body of a loop
+// or in a function on its hot-path list, given the entry's InLoop column
+// ("for", "range" or "hot"). Each run of a loop logs again, so such calls
+// may need to move to Debug, be sampled or use an allocation-free API.
+// Calls already at Debug or Trace level have no issue.
+func Hot(call, inLoop, level string) (Issue, bool) {
+	if inLoop == "" || strings.EqualFold(level, "Debug") || strings.EqualFold(level, "Trace") {
+		return Issue{}, false
+	}
+	issue := Issue{Key: inLoop, Expression: call, Kind: Loop, Severity: Low}
+	if inLoop == "hot" {
+		issue.Kind = HotPath
+	}
+	return issue, true
+}
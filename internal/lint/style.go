@@ -0,0 +1,220 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Message style rules
+const (
+	Lowercase   = "lowercase"   // Start with a lower-case letter; acronyms such as HTTP are fine
+	Punctuation = "punctuation" // No trailing period, colon or newline
+	Prefix      = "prefix"      // No prefix built from values: "[%s] ", "%s: ", prefix + "..."
+	ASCII       = "ascii"       // ASCII characters only
+)
+
+// Rules are the message style rules, all of which are checked by default
+var Rules = []string{Lowercase, Punctuation, Prefix, ASCII}
+
+// Style is the kind of a message that breaks a style rule; the rule is the
+// issue's Key
+const Style = "style"
+
+// styles describe what breaks each rule
+var styles = map[string]string{
+	Lowercase:   "starts with a capital letter",
+	Punctuation: "ends with a period, colon or newline",
+	Prefix:      "starts with a prefix built from values",
+	ASCII:       "has non-ASCII characters",
+}
+
+// dynamicPrefix matches a prefix made of a format verb: "[%s] ", "(%d) ",
+// "%s: ", "%s - " or "%s | ". A verb followed by words alone, as in
+// "%d files copied", is part of the sentence.
+var dynamicPrefix = regexp.MustCompile(`^\s*(\[[^\]]*%[^\]]*\]|\([^)]*%[^)]*\)|<[^>]*%[^>]*>|%[-+# 0-9.*]*[a-zA-Z]\s*[:|>-])\s*`)
+
+// asciiReplacements are the ASCII forms of common typographic characters;
+// other non-ASCII characters are dropped by the fix
+var asciiReplacements = strings.NewReplacer(
+	"‘", "'", "’", "'", "“", `"`, "”", `"`,
+	"–", "-", "—", "-", "…", "...", " ", " ",
+	"→", "->", "×", "x",
+)
+
+// ParseRules returns the rules a list names, such as a -message-rules flag
+// split on commas: nil or "all" for every rule, "none" for none.
+func ParseRules(list []string) ([]string, error) {
+	if list == nil {
+		return Rules, nil
+	}
+	rules := []string{}
+	for _, name := range list {
+		switch name = strings.ToLower(strings.TrimSpace(name)); {
+		case name == "all":
+			return Rules, nil
+		case name == "none":
+		case slices.Contains(Rules, name):
+			if !slices.Contains(rules, name) {
+				rules = append(rules, name)
+			}
+		default:
+			return nil, fmt.Errorf("unknown message rule: %s (use %s, all or none)", name, strings.Join(Rules, ", "))
+		}
+	}
+	return rules, nil
+}
+
+// Message returns the issues of a message, as it will be logged, with the
+// rules it breaks
+func Message(message string, rules []string) []Issue {
+	var issues []Issue
+	add := func(rule string) {
+		issues = append(issues, Issue{Key: rule, Expression: message, Kind: Style, Severity: Low})
+	}
+	for _, rule := range rules {
+		switch rule {
+		case Lowercase:
+			if lowerFirst(strings.TrimSpace(message)) != strings.TrimSpace(message) {
+				add(rule)
+			}
+		case Punctuation:
+			if strings.TrimRight(message, ".:\n\r\t ") != strings.TrimRight(message, "\t ") {
+				add(rule)
+			}
+		case Prefix:
+			if dynamicPrefix.MatchString(message) {
+				add(rule)
+			}
+		case ASCII:
+			if strings.ContainsFunc(message, func(r rune) bool { return r > unicode.MaxASCII }) {
+				add(rule)
+			}
+		}
+	}
+	return issues
+}
+
+// Fix returns a message with the rules applied: typographic characters
+// replaced by their ASCII forms and others dropped, a prefix built from
+// values removed, trailing periods, colons and newlines trimmed and the
+// first word lower-cased unless it is an acronym
+func Fix(message string, rules []string) string {
+	if slices.Contains(rules, ASCII) {
+		message = asciiReplacements.Replace(message)
+		message = strings.Map(func(r rune) rune {
+			if r > unicode.MaxASCII {
+				return -1
+			}
+			return r
+		}, message)
+		message = strings.Join(strings.Fields(message), " ")
+	}
+	if slices.Contains(rules, Prefix) {
+		message = dynamicPrefix.ReplaceAllString(message, "")
+	}
+	if slices.Contains(rules, Punctuation) {
+		message = strings.TrimRight(message, ".:\n\r\t ")
+	}
+	if slices.Contains(rules, Lowercase) {
+		message = lowerFirst(strings.TrimSpace(message))
+	}
+	return message
+}
+
+// Template returns the issues of a collected MessageTemplate and the
+// message with them fixed (see Fix), escaped as in a string literal the
+// way NewMessage is. A string literal is checked as it reads; a
+// concatenation that puts a value in front of a literal, such as prefix +
+// "failed", breaks the Prefix rule and is fixed to the literal text. Other
+// templates, such as a variable, aren't checked.
+func Template(template string, rules []string) ([]Issue, string) {
+	if text, err := strconv.Unquote(template); err == nil {
+		issues := Message(text, rules)
+		if len(issues) == 0 {
+			return nil, ""
+		}
+		return issues, escape(Fix(text, rules))
+	}
+
+	parts := strings.Split(template, " + ")
+	if !slices.Contains(rules, Prefix) || len(parts) < 2 || isQuoted(parts[0]) {
+		return nil, ""
+	}
+	issues := []Issue{{Key: Prefix, Expression: template, Kind: Style, Severity: Low}}
+	var text strings.Builder
+	for _, part := range parts[1:] {
+		s, err := strconv.Unquote(part)
+		if err != nil {
+			return issues, "" // A value follows as well: there is no text to keep
+		}
+		text.WriteString(s)
+	}
+	for _, issue := range Message(text.String(), rules) {
+		if issue.Key != Prefix {
+			issues = append(issues, issue)
+		}
+	}
+	return issues, escape(Fix(text.String(), rules))
+}
+
+// escape returns a message as the inside of a string literal, so quotes
+// and newlines can be written back into code
+func escape(message string) string {
+	quoted := strconv.Quote(message)
+	return quoted[1 : len(quoted)-1]
+}
+
+// StyleNote names the rules an entry's message breaks for the Notes
+// column, e.g. "STYLE: message breaks the lowercase and punctuation rules;
+// see SuggestedMessage". It is empty if there are none.
+func StyleNote(issues []Issue, fix string) string {
+	if len(issues) == 0 {
+		return ""
+	}
+	var rules []string
+	for _, issue := range issues {
+		rules = append(rules, issue.Key)
+	}
+	note := "STYLE: message breaks the " + rules[len(rules)-1] + " rule"
+	if len(rules) > 1 {
+		note = "STYLE: message breaks the " + strings.Join(rules[:len(rules)-1], ", ") + " and " + rules[len(rules)-1] + " rules"
+	}
+	if fix != "" {
+		note += "; see SuggestedMessage"
+	}
+	return note
+}
+
+// Unescape returns the text of a message written as the inside of a
+// string literal, as NewMessage is, or the message itself if it isn't one
+func Unescape(message string) string {
+	if text, err := strconv.Unquote(`"` + message + `"`); err == nil {
+		return text
+	}
+	return message
+}
+
+// isQuoted reports whether an expression is a string literal
+func isQuoted(expression string) bool {
+	_, err := strconv.Unquote(expression)
+	return err == nil
+}
+
+// lowerFirst lower-cases a capitalized first word ("Failed" -> "failed")
+// but keeps acronyms and mixed-case identifiers ("HTTP", "gRPC", "UserID")
+func lowerFirst(message string) string {
+	first, size := utf8.DecodeRuneInString(message)
+	if !unicode.IsUpper(first) {
+		return message
+	}
+	word, _, _ := strings.Cut(message[size:], " ")
+	if strings.ContainsFunc(word, unicode.IsUpper) {
+		return message
+	}
+	return string(unicode.ToLower(first)) + message[size:]
+}
@@ -0,0 +1,109 @@
+package lint
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMessage(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string // Keys of the issues
+	}{
+		{"user logged in", "[]"},
+		{"HTTP server started", "[]"},
+		{"Failed to connect.", "[lowercase punctuation]"},
+		{"[%s] request done", "[prefix]"},
+		{"%s: request done", "[prefix]"},
+		{"%d files copied", "[]"},
+		{"retrying…\n", "[punctuation ascii]"},
+	}
+	for _, tt := range tests {
+		var keys []string
+		for _, issue := range Message(tt.message, Rules) {
+			keys = append(keys, issue.Key)
+		}
+		if got := fmt.Sprint(keys); got != tt.want {
+			t.Errorf("Message(%q) = %s, want %s", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestFix(t *testing.T) {
+	tests := []struct {
+		message string
+		rules   []string
+		want    string
+	}{
+		{"Failed to connect.", Rules, "failed to connect"},
+		{"HTTP server started:", Rules, "HTTP server started"},
+		{"[%s] Request done", Rules, "request done"},
+		{"“quoted” — done…", Rules, `"quoted" - done`},
+		{"Failed to connect.", []string{Punctuation}, "Failed to connect"},
+		{"Failed to connect.", nil, "Failed to connect."},
+	}
+	for _, tt := range tests {
+		if got := Fix(tt.message, tt.rules); got != tt.want {
+			t.Errorf("Fix(%q, %v) = %q, want %q", tt.message, tt.rules, got, tt.want)
+		}
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		issues   string // Keys of the issues
+		fix      string
+	}{
+		{`"user logged in"`, "[]", ""},
+		{`"Saved \"%s\".\n"`, "[lowercase punctuation]", `saved \"%s\"`},
+		{`prefix + "Failed."`, "[prefix lowercase punctuation]", "failed"},
+		{`prefix + ": " + err.Error()`, "[prefix]", ""},
+		{`"a" + b`, "[]", ""},
+		{"msg", "[]", ""},
+	}
+	for _, tt := range tests {
+		issues, fix := Template(tt.template, Rules)
+		var keys []string
+		for _, issue := range issues {
+			keys = append(keys, issue.Key)
+		}
+		if got := fmt.Sprint(keys); got != tt.issues || fix != tt.fix {
+			t.Errorf("Template(%q) = %s, %q, want %s, %q", tt.template, got, fix, tt.issues, tt.fix)
+		}
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		list []string
+		want string
+	}{
+		{nil, "[lowercase punctuation prefix ascii]"},
+		{[]string{"all"}, "[lowercase punctuation prefix ascii]"},
+		{[]string{"none"}, "[]"},
+		{[]string{" Prefix", "ascii", "prefix"}, "[prefix ascii]"},
+		{[]string{"caps"}, "unknown message rule: caps (use lowercase, punctuation, prefix, ascii, all or none)"},
+	}
+	for _, tt := range tests {
+		rules, err := ParseRules(tt.list)
+		got := fmt.Sprint(rules)
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.want {
+			t.Errorf("ParseRules(%q) = %s, want %s", tt.list, got, tt.want)
+		}
+	}
+}
+
+func TestStyleNote(t *testing.T) {
+	issues, fix := Template(`"Done."`, Rules)
+	if got, want := StyleNote(issues, fix), "STYLE: message breaks the lowercase and punctuation rules; see SuggestedMessage"; got != want {
+		t.Errorf("StyleNote() = %q, want %q", got, want)
+	}
+	issues, fix = Template(`prefix + ": " + err.Error()`, Rules)
+	if got, want := StyleNote(issues, fix), "STYLE: message breaks the prefix rule"; got != want {
+		t.Errorf("StyleNote() = %q, want %q", got, want)
+	}
+}
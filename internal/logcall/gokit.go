@@ -0,0 +1,55 @@
+package logcall
+
+import "go/ast"
+
+// gokitLevels are the functions of go-kit's level package wrapping a
+// logger to log at their level, such as level.Info(logger), by level
+var gokitLevels = map[string]string{"Debug": "Debug", "Info": "Info", "Warn": "Warn", "Error": "Error"}
+
+// gokitWiths are go-kit's functions returning a logger that adds pairs to
+// every call, such as log.With(logger, "user", id)
+var gokitWiths = map[string]bool{"With": true, "WithPrefix": true, "WithSuffix": true}
+
+// GokitLogger returns the logger a go-kit Log call is made on, such as
+// logger in level.Error(log.With(logger, "user", id)).Log("msg", "failed"),
+// the level a level function wraps it in, if any, and the With calls in
+// between, in the order they add their pairs. ok is false for calls other
+// than Log.
+func GokitLogger(call *ast.CallExpr) (logger ast.Expr, level string, withs []*ast.CallExpr, ok bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Log" {
+		return nil, "", nil, false
+	}
+	logger = sel.X
+	for {
+		inner, ok := logger.(*ast.CallExpr)
+		if !ok || len(inner.Args) == 0 {
+			break
+		}
+		fun, ok := inner.Fun.(*ast.SelectorExpr)
+		if !ok {
+			break
+		}
+		if _, ok := fun.X.(*ast.Ident); !ok {
+			break
+		}
+		switch name := fun.Sel.Name; {
+		case gokitLevels[name] != "" && len(inner.Args) == 1:
+			if level == "" {
+				level = gokitLevels[name]
+			}
+		case gokitWiths[name]:
+			withs = append([]*ast.CallExpr{inner}, withs...)
+		default:
+			return logger, level, withs, true
+		}
+		logger = inner.Args[0]
+	}
+	return logger, level, withs, true
+}
+
+// GokitWith reports whether a call is to one of gokitWiths
+func GokitWith(call *ast.CallExpr) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	return ok && gokitWiths[sel.Sel.Name] && len(call.Args) > 0
+}
@@ -0,0 +1,22 @@
+// Package logcall reads the calls of logging libraries from the AST: the
+// fields of zap, slog and zerolog calls, the event of a zerolog call, the
+// logger of a go-kit call and the error a log call returns right after.
+// collect records entries with it, and transform reads the calls it
+// rewrites the same way.
+package logcall
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+)
+
+// StringLit returns the value of a string literal
+func StringLit(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
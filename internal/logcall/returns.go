@@ -0,0 +1,82 @@
+package logcall
+
+import (
+	"go/ast"
+	"strings"
+)
+
+// LogAndReturn returns the statement after a log call that returns the
+// error the call logs, and the error's name, as for
+//
+//	log.Printf("open %s: %v", path, err)
+//	return nil, err
+//
+// The caller will likely log the error again, so it is reported twice. A
+// result counts when it is the error or a call passed it, such as
+// fmt.Errorf("open: %w", err). stmt is the statement of the call and
+// parent the block or case clause holding it. It returns nil, "" if there
+// is no such statement.
+func LogAndReturn(call *ast.CallExpr, stmt, parent ast.Node) (*ast.ReturnStmt, string) {
+	expr, ok := stmt.(*ast.ExprStmt)
+	if !ok || expr.X != call {
+		return nil, ""
+	}
+	var list []ast.Stmt
+	switch p := parent.(type) {
+	case *ast.BlockStmt:
+		list = p.List
+	case *ast.CaseClause:
+		list = p.Body
+	case *ast.CommClause:
+		list = p.Body
+	}
+	var next ast.Stmt
+	for i, s := range list {
+		if s == expr && i+1 < len(list) {
+			next = list[i+1]
+		}
+	}
+	ret, ok := next.(*ast.ReturnStmt)
+	if !ok {
+		return nil, ""
+	}
+
+	for _, arg := range call.Args {
+		ident, ok := arg.(*ast.Ident)
+		if !ok || !errorName(ident.Name) {
+			continue
+		}
+		for _, result := range ret.Results {
+			if mentions(result, ident.Name) {
+				return ret, ident.Name
+			}
+		}
+	}
+	return nil, ""
+}
+
+// mentions reports whether a returned expression is the identifier name
+// or a call passed it
+func mentions(result ast.Expr, name string) bool {
+	switch r := result.(type) {
+	case *ast.Ident:
+		return r.Name == name
+	case *ast.CallExpr:
+		for _, arg := range r.Args {
+			if ident, ok := arg.(*ast.Ident); ok && ident.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// errorName reports whether a variable's name says it holds an error, as
+// collect infers types from names: err, or a name ending in Error other
+// than a bool's, such as hasError
+func errorName(name string) bool {
+	if strings.HasPrefix(name, "is") || strings.HasPrefix(name, "has") {
+		return false
+	}
+	return name == "err" || strings.HasSuffix(name, "Error")
+}
@@ -0,0 +1,47 @@
+package logcall
+
+import "go/ast"
+
+// slogTypes are the slog attribute constructors that take a key and a
+// value, by the type of the value as collect records it
+var slogTypes = map[string]string{
+	"String": "string", "Int": "int", "Int64": "int64", "Uint64": "uint64",
+	"Float64": "float64", "Bool": "bool", "Duration": "time.Duration", "Time": "time.Time",
+	"Any": "any",
+}
+
+// SlogAttr returns the key, value and value type of a slog attribute
+// constructor call, such as slog.String("user", name). ok is false for
+// other expressions, for keys that aren't string literals and for groups.
+func SlogAttr(expr ast.Expr) (key string, value ast.Expr, typ string, ok bool) {
+	name, call := SlogConstructor(expr)
+	if call == nil || slogTypes[name] == "" || len(call.Args) != 2 {
+		return "", nil, "", false
+	}
+	key, ok = StringLit(call.Args[0])
+	if !ok {
+		return "", nil, "", false
+	}
+	return key, call.Args[1], slogTypes[name], true
+}
+
+// SlogConstructor returns the name and call of a call to a slog attribute
+// constructor, such as "String" for slog.String("user", name), "Group" or
+// "GroupAttrs"
+func SlogConstructor(expr ast.Expr) (string, *ast.CallExpr) {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return "", nil
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", nil
+	}
+	if pkg, ok := sel.X.(*ast.Ident); !ok || pkg.Name != "slog" {
+		return "", nil
+	}
+	if slogTypes[sel.Sel.Name] == "" && sel.Sel.Name != "Group" && sel.Sel.Name != "GroupAttrs" {
+		return "", nil
+	}
+	return sel.Sel.Name, call
+}
@@ -0,0 +1,55 @@
+package logcall
+
+import "go/ast"
+
+// zapTypes are the zap field constructors that take a key and a value, by
+// the type of the value as collect records it. "any" stands for values zap
+// logs however they come, as slog.Any does.
+var zapTypes = map[string]string{
+	"String": "string", "Strings": "[]string", "ByteString": "any", "Stringer": "any",
+	"Int": "int", "Int8": "int", "Int16": "int", "Int32": "int", "Int64": "int64",
+	"Uint": "uint", "Uint8": "uint", "Uint16": "uint", "Uint32": "uint", "Uint64": "uint64", "Uintptr": "any",
+	"Float32": "float32", "Float64": "float64", "Bool": "bool",
+	"Duration": "time.Duration", "Time": "time.Time",
+	"NamedError": "error", "Any": "any", "Reflect": "any", "Binary": "any",
+	"Ints": "any", "Int64s": "any", "Float64s": "any", "Bools": "any", "Durations": "any", "Times": "any", "Errors": "any",
+}
+
+// ZapField returns the key, value and value type of a zap field
+// constructor call, such as zap.String("user", name) or zap.Error(err),
+// whose key is "error". ok is false for other expressions, for keys that
+// aren't string literals and for fields without a single value, such as
+// zap.Namespace or zap.Object.
+func ZapField(expr ast.Expr) (key string, value ast.Expr, typ string, ok bool) {
+	name, call := ZapConstructor(expr)
+	switch {
+	case call == nil:
+		return "", nil, "", false
+	case name == "Error" && len(call.Args) == 1:
+		return "error", call.Args[0], "error", true
+	case zapTypes[name] == "" || len(call.Args) != 2:
+		return "", nil, "", false
+	}
+	key, ok = StringLit(call.Args[0])
+	if !ok {
+		return "", nil, "", false
+	}
+	return key, call.Args[1], zapTypes[name], true
+}
+
+// ZapConstructor returns the name and call of a call to a function of the
+// zap package, such as "String" for zap.String("user", name)
+func ZapConstructor(expr ast.Expr) (string, *ast.CallExpr) {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return "", nil
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", nil
+	}
+	if pkg, ok := sel.X.(*ast.Ident); !ok || pkg.Name != "zap" {
+		return "", nil
+	}
+	return sel.Sel.Name, call
+}
@@ -0,0 +1,139 @@
+package logcall
+
+import (
+	"go/ast"
+	"strings"
+)
+
+// zerologSends are the zerolog Event methods writing the event, by the
+// number of arguments they take: -1 for Msgf's format and values
+var zerologSends = map[string]int{"Msg": 1, "Msgf": -1, "Send": 0}
+
+// zerologLevels are the zerolog Logger methods starting an event, by its
+// level. Log's event has none, nor has WithLevel's unless its level is a
+// constant such as zerolog.ErrorLevel. Err, which starts an event at Error
+// or, for a nil error, Info, is also an Event method adding the error, so
+// it is told apart by its receiver (see ZerologEvent).
+var zerologLevels = map[string]string{
+	"Trace": "Trace", "Debug": "Debug", "Info": "Info", "Warn": "Warn", "Error": "Error",
+	"Fatal": "Fatal", "Panic": "Panic", "Log": "Unknown", "WithLevel": "Unknown",
+}
+
+// zerologTypes are the zerolog Event methods adding a field with a key and
+// a value, by the type of the value as collect records it
+var zerologTypes = map[string]string{
+	"Str": "string", "Strs": "[]string", "Stringer": "any", "Stringers": "any",
+	"Bytes": "any", "Hex": "any", "RawJSON": "any",
+	"Int": "int", "Int8": "int", "Int16": "int", "Int32": "int", "Int64": "int64",
+	"Uint": "uint", "Uint8": "uint", "Uint16": "uint", "Uint32": "uint", "Uint64": "uint64",
+	"Float32": "float32", "Float64": "float64", "Bool": "bool",
+	"Dur": "time.Duration", "Time": "time.Time", "AnErr": "error",
+	"Interface": "any", "Any": "any", "IPAddr": "any", "IPPrefix": "any", "MACAddr": "any",
+	"Ints": "any", "Int64s": "any", "Uints": "any", "Float64s": "any", "Bools": "any",
+	"Durs": "any", "Times": "any", "Errs": "any",
+}
+
+// zerologSilent are the zerolog Event methods adding no field written in
+// the call, such as Timestamp or Caller, which the target library's
+// handler adds if it is set up to
+var zerologSilent = map[string]bool{"Timestamp": true, "Caller": true, "CallerSkipFrame": true, "Stack": true}
+
+// ZerologSilent reports whether a call building a zerolog event is one
+// adding no field written in the call, such as Timestamp() or Caller()
+func ZerologSilent(link *ast.CallExpr) bool {
+	sel, ok := link.Fun.(*ast.SelectorExpr)
+	return ok && zerologSilent[sel.Sel.Name]
+}
+
+// ZerologEvent returns the call starting the zerolog event a call writes,
+// such as log.Error() in
+// log.Error().Err(err).Str("id", id).Msg("sync failed"), the event's
+// level and the calls building the event after the one starting it, in
+// source order (Err(err) and Str("id", id)). When the event starts with
+// Err, as in log.Err(err).Msg("sync failed"), that call is the first of
+// them, since it adds the error too. ok is false for other calls.
+func ZerologEvent(call *ast.CallExpr) (start *ast.CallExpr, level string, links []*ast.CallExpr, ok bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return nil, "", nil, false
+	}
+	if n, send := zerologSends[sel.Sel.Name]; !send || n >= 0 && len(call.Args) != n || n < 0 && len(call.Args) == 0 {
+		return nil, "", nil, false
+	}
+
+	// Walking outwards in, up to the call starting the event
+	for x := sel.X; ; {
+		inner, isCall := x.(*ast.CallExpr)
+		if !isCall {
+			return nil, "", nil, false
+		}
+		fun, isSel := inner.Fun.(*ast.SelectorExpr)
+		if !isSel {
+			return nil, "", nil, false
+		}
+		name := fun.Sel.Name
+		switch {
+		case name == "Err" && len(inner.Args) == 1 && !zerologEventCall(fun.X):
+			return inner, "Error", append([]*ast.CallExpr{inner}, links...), true
+		case name == "WithLevel" && len(inner.Args) == 1:
+			return inner, zerologConstLevel(inner.Args[0]), links, true
+		case zerologLevels[name] != "" && name != "WithLevel" && len(inner.Args) == 0:
+			return inner, zerologLevels[name], links, true
+		}
+		links = append([]*ast.CallExpr{inner}, links...)
+		x = fun.X
+	}
+}
+
+// zerologEventCall reports whether expr is a call of a zerolog method
+// returning an event, such as log.Error() or Str("id", id)
+func zerologEventCall(expr ast.Expr) bool {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	name := sel.Sel.Name
+	return zerologLevels[name] != "" || zerologTypes[name] != "" || zerologSilent[name] ||
+		name == "Err" || name == "Fields" || name == "Dict"
+}
+
+// zerologConstLevel returns the level of a zerolog.Level constant such as
+// zerolog.ErrorLevel, or "Unknown"
+func zerologConstLevel(expr ast.Expr) string {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return "Unknown"
+	}
+	name, ok := strings.CutSuffix(sel.Sel.Name, "Level")
+	if level := zerologLevels[name]; ok && level != "" && name != "WithLevel" {
+		return level
+	}
+	return "Unknown"
+}
+
+// ZerologField returns the key, value and value type of a call adding a
+// field to a zerolog event, such as Str("id", id) or Err(err), whose key
+// is "error". ok is false for other calls, for keys that aren't string
+// literals and for fields without a single value, such as Dict or Fields.
+func ZerologField(call *ast.CallExpr) (key string, value ast.Expr, typ string, ok bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", nil, "", false
+	}
+	name := sel.Sel.Name
+	switch {
+	case name == "Err" && len(call.Args) == 1:
+		return "error", call.Args[0], "error", true
+	case zerologTypes[name] == "" || len(call.Args) != 2:
+		return "", nil, "", false
+	}
+	key, ok = StringLit(call.Args[0])
+	if !ok {
+		return "", nil, "", false
+	}
+	return key, call.Args[1], zerologTypes[name], true
+}
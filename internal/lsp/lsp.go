@@ -0,0 +1,253 @@
+// Package lsp is a small language server offering a "Convert to structured
+// logging" code action on log calls, so calls can be migrated one at a time
+// from the editor. It runs next to gopls; editors send code action requests
+// to both and merge the results.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"logrefactor/internal/config"
+	"logrefactor/internal/naming"
+	"logrefactor/internal/sarif"
+	"logrefactor/pkg/collector"
+	"logrefactor/pkg/transformer"
+)
+
+// Server answers LSP requests. Settings empty here come from the project
+// configuration found above each file (.logrefactor.yaml).
+type Server struct {
+	Pattern       string // Regex matching the calls to offer the action on (default: collector.UnstructuredPattern)
+	Config        string // Template configuration file (JSON)
+	ProjectConfig string // Project configuration file
+	Profile       string // Named profile from the project configuration
+
+	pattern  *regexp.Regexp
+	docs     map[string]string   // Open documents by URI
+	projects map[string]*project // Directory -> settings
+	mu       sync.Mutex
+}
+
+// project holds the settings that apply to the files in a directory
+type project struct {
+	keyStyle string
+	template *transformer.TemplateConfig
+}
+
+// Serve reads requests from r and writes responses to w until the client
+// sends exit or closes r
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	pattern := s.Pattern
+	if pattern == "" {
+		pattern = collector.UnstructuredPattern
+	}
+	var err error
+	if s.pattern, err = regexp.Compile(pattern); err != nil {
+		return fmt.Errorf("invalid pattern: %w", err)
+	}
+	s.docs = make(map[string]string)
+	s.projects = make(map[string]*project)
+
+	in := bufio.NewReader(r)
+	out := bufio.NewWriter(w)
+	for {
+		body, err := read(in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var m message
+		if err := json.Unmarshal(body, &m); err != nil {
+			write(out, message{ID: &null, Error: &responseError{codeParseError, err.Error()}})
+			continue
+		}
+		if m.Method == "exit" {
+			return nil
+		}
+		result, rpcErr := s.handle(m)
+		if m.ID == nil {
+			continue // Notifications have no response
+		}
+		if result == nil && rpcErr == nil {
+			result = null // A successful response always has a result
+		}
+		if err := write(out, message{ID: m.ID, Result: result, Error: rpcErr}); err != nil {
+			return err
+		}
+	}
+}
+
+// handle runs one request or notification
+func (s *Server) handle(m message) (interface{}, *responseError) {
+	switch m.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":   1, // Full documents on every change
+				"codeActionProvider": map[string]interface{}{"codeActionKinds": []string{"refactor.rewrite"}},
+			},
+			"serverInfo": map[string]string{"name": "logrefactor"},
+		}, nil
+	case "shutdown":
+		return nil, nil
+	case "textDocument/didOpen":
+		var p didOpenParams
+		if err := json.Unmarshal(m.Params, &p); err != nil {
+			return nil, &responseError{codeInvalidParams, err.Error()}
+		}
+		s.docs[p.TextDocument.URI] = p.TextDocument.Text
+	case "textDocument/didChange":
+		var p didChangeParams
+		if err := json.Unmarshal(m.Params, &p); err != nil {
+			return nil, &responseError{codeInvalidParams, err.Error()}
+		}
+		for _, c := range p.ContentChanges {
+			text := s.docs[p.TextDocument.URI]
+			if c.Range != nil {
+				text = text[:offset(text, c.Range.Start)] + c.Text + text[offset(text, c.Range.End):]
+			} else {
+				text = c.Text
+			}
+			s.docs[p.TextDocument.URI] = text
+		}
+	case "textDocument/didClose":
+		var p didCloseParams
+		if err := json.Unmarshal(m.Params, &p); err != nil {
+			return nil, &responseError{codeInvalidParams, err.Error()}
+		}
+		delete(s.docs, p.TextDocument.URI)
+	case "textDocument/codeAction":
+		var p codeActionParams
+		if err := json.Unmarshal(m.Params, &p); err != nil {
+			return nil, &responseError{codeInvalidParams, err.Error()}
+		}
+		actions, err := s.codeActions(p)
+		if err != nil {
+			return nil, &responseError{codeInternalError, err.Error()}
+		}
+		return actions, nil
+	default:
+		if m.ID != nil {
+			return nil, &responseError{codeMethodNotFound, "method not supported: " + m.Method}
+		}
+	}
+	return nil, nil
+}
+
+// codeActions offers to convert the log calls overlapping the requested
+// range. A file that doesn't parse (mid-edit) gets no actions.
+func (s *Server) codeActions(p codeActionParams) ([]codeAction, error) {
+	actions := []codeAction{}
+	text, ok := s.docs[p.TextDocument.URI]
+	if !ok {
+		return actions, nil
+	}
+	path, err := filePath(p.TextDocument.URI)
+	if err != nil {
+		return actions, nil
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, text, 0)
+	if err != nil {
+		return actions, nil
+	}
+	proj, err := s.project(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := offset(text, p.Range.Start), offset(text, p.Range.End)
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		callStart, callEnd := fset.Position(call.Pos()).Offset, fset.Position(call.End()).Offset
+		if callEnd < start || callStart > end {
+			return false // Nothing inside it overlaps either
+		}
+		if name := collector.CallName(call); name == "" || !s.pattern.MatchString(name) {
+			return true
+		}
+
+		update := sarif.Update(collector.Entry(call, fset, file.Name.Name, proj.keyStyle))
+		fix := sarif.Fix(update, proj.template)
+		if fix == "" {
+			return true
+		}
+		actions = append(actions, codeAction{
+			Title:       "Convert to structured logging",
+			Kind:        "refactor.rewrite",
+			IsPreferred: true,
+			Edit: workspaceEdit{Changes: map[string][]textEdit{
+				p.TextDocument.URI: {{
+					Range:   rangeType{Start: positionAt(text, callStart), End: positionAt(text, callEnd)},
+					NewText: fix,
+				}},
+			}},
+		})
+		// Calls nested in the arguments would be replaced with it
+		return false
+	})
+	return actions, nil
+}
+
+// project loads the settings for the files in dir, once per directory
+func (s *Server) project(dir string) (*project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.projects[dir]; ok {
+		return p, nil
+	}
+
+	var cfg *config.Config
+	var err error
+	if s.ProjectConfig != "" {
+		cfg, err = config.Load(s.ProjectConfig)
+	} else {
+		cfg, err = config.Discover(dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load project config: %w", err)
+	}
+	if cfg, err = cfg.WithProfile(s.Profile); err != nil {
+		return nil, err
+	}
+	template, err := transformer.LoadTemplateConfig(s.Config, &cfg.TemplateConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load template config: %w", err)
+	}
+
+	p := &project{keyStyle: cfg.KeyStyle, template: template}
+	if p.keyStyle == "" {
+		p.keyStyle = naming.SnakeCase
+	}
+	s.projects[dir] = p
+	return p, nil
+}
+
+// filePath turns a file:// URI into a path
+func filePath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("not a file URI: %s", uri)
+	}
+	return filepath.FromSlash(u.Path), nil
+}
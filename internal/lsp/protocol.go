@@ -0,0 +1,164 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The subset of JSON-RPC 2.0 and the LSP types the server uses
+
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  interface{}      `json:"result,omitempty"`
+	Error   *responseError   `json:"error,omitempty"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// null is the JSON null, for results and IDs that must be present
+var null = json.RawMessage("null")
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"` // In UTF-16 code units
+}
+
+type rangeType struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type textEdit struct {
+	Range   rangeType `json:"range"`
+	NewText string    `json:"newText"`
+}
+
+type workspaceEdit struct {
+	Changes map[string][]textEdit `json:"changes"`
+}
+
+type codeAction struct {
+	Title       string        `json:"title"`
+	Kind        string        `json:"kind"`
+	IsPreferred bool          `json:"isPreferred,omitempty"`
+	Edit        workspaceEdit `json:"edit"`
+}
+
+type didOpenParams struct {
+	TextDocument struct {
+		URI  string `json:"uri"`
+		Text string `json:"text"`
+	} `json:"textDocument"`
+}
+
+type didChangeParams struct {
+	TextDocument   textDocumentIdentifier `json:"textDocument"`
+	ContentChanges []struct {
+		Range *rangeType `json:"range"`
+		Text  string     `json:"text"`
+	} `json:"contentChanges"`
+}
+
+type didCloseParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+type codeActionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Range        rangeType              `json:"range"`
+}
+
+// read reads one message: headers, a blank line, then Content-Length bytes
+func read(r *bufio.Reader) ([]byte, error) {
+	headers, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length %q", headers.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// write writes one message with its Content-Length header
+func write(w *bufio.Writer, m message) error {
+	m.JSONRPC = "2.0"
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body))
+	w.Write(body)
+	return w.Flush()
+}
+
+// offset converts an LSP position to a byte offset in text. Positions past
+// the end of a line or of the text are clamped.
+func offset(text string, p position) int {
+	i := 0
+	for line := 0; line < p.Line; line++ {
+		next := strings.IndexByte(text[i:], '\n')
+		if next < 0 {
+			return len(text)
+		}
+		i += next + 1
+	}
+	for units := 0; i < len(text) && text[i] != '\n' && units < p.Character; {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		units += utf16Len(r)
+		i += size
+	}
+	return i
+}
+
+// positionAt converts a byte offset in text to an LSP position
+func positionAt(text string, off int) position {
+	var p position
+	lineStart := 0
+	for i := 0; i < off && i < len(text); i++ {
+		if text[i] == '\n' {
+			p.Line++
+			lineStart = i + 1
+		}
+	}
+	for _, r := range text[lineStart:min(off, len(text))] {
+		p.Character += utf16Len(r)
+	}
+	return p
+}
+
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
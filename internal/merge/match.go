@@ -0,0 +1,41 @@
+package merge
+
+// Match pairs old and new entries of two collections by content, trying
+// passes in order from the strictest. Within a pass, each new entry still
+// unpaired, in order, is paired with the unpaired old entry the pass
+// accepts that is nearest to its line. It returns the old entry paired
+// with each new one that found one.
+func Match[E comparable](olds, news []E, line func(E) int, passes ...func(o, n E) bool) map[E]E {
+	pairs := make(map[E]E) // new -> old
+	taken := make(map[E]bool)
+	for _, same := range passes {
+		for _, n := range news {
+			if _, ok := pairs[n]; ok {
+				continue
+			}
+			var best E
+			found := false
+			for _, o := range olds {
+				if taken[o] || !same(o, n) {
+					continue
+				}
+				if !found || distance(line(o), line(n)) < distance(line(best), line(n)) {
+					best, found = o, true
+				}
+			}
+			if found {
+				pairs[n] = best
+				taken[best] = true
+			}
+		}
+	}
+	return pairs
+}
+
+// distance returns how many lines apart a and b are
+func distance(a, b int) int {
+	if a < b {
+		return b - a
+	}
+	return a - b
+}
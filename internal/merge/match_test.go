@@ -0,0 +1,78 @@
+package merge
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	type call struct {
+		name string
+		line int
+	}
+	sameName := func(o, n *call) bool { return o.name == n.name }
+	anyCall := func(o, n *call) bool { return true }
+	tests := []struct {
+		name       string
+		olds, news []call
+		passes     []func(o, n *call) bool
+		want       string // Old line of each new entry, 0 if unpaired
+	}{
+		{
+			name:   "nearest line wins",
+			olds:   []call{{"a", 10}, {"a", 40}},
+			news:   []call{{"a", 38}},
+			passes: []func(o, n *call) bool{sameName},
+			want:   "[40]",
+		},
+		{
+			name:   "each old entry pairs once",
+			olds:   []call{{"a", 10}},
+			news:   []call{{"a", 11}, {"a", 12}},
+			passes: []func(o, n *call) bool{sameName},
+			want:   "[10 0]",
+		},
+		{
+			name:   "stricter pass first",
+			olds:   []call{{"a", 10}, {"b", 11}},
+			news:   []call{{"b", 10}, {"c", 30}},
+			passes: []func(o, n *call) bool{sameName, anyCall},
+			want:   "[11 10]",
+		},
+		{
+			name:   "ties go to the first old entry",
+			olds:   []call{{"a", 8}, {"a", 12}},
+			news:   []call{{"a", 10}},
+			passes: []func(o, n *call) bool{sameName},
+			want:   "[8]",
+		},
+		{
+			name:   "nothing to pair",
+			news:   []call{{"a", 1}},
+			passes: []func(o, n *call) bool{sameName},
+			want:   "[0]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			olds := make([]*call, len(tt.olds))
+			for i := range tt.olds {
+				olds[i] = &tt.olds[i]
+			}
+			news := make([]*call, len(tt.news))
+			for i := range tt.news {
+				news[i] = &tt.news[i]
+			}
+			pairs := Match(olds, news, func(c *call) int { return c.line }, tt.passes...)
+			got := make([]int, len(news))
+			for i, n := range news {
+				if o, ok := pairs[n]; ok {
+					got[i] = o.line
+				}
+			}
+			if fmt.Sprint(got) != tt.want {
+				t.Errorf("paired lines = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+// Package merge reconciles an edited CSV with a fresh collection of the same
+// code, so a long migration can re-collect without losing the edits made so
+// far.
+package merge
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"logrefactor/internal/table"
+)
+
+// EditColumns are the columns people fill in; they are carried over from the
+// old CSV to matched entries
+var EditColumns = []string{"NewCall", "NewMessage", "StructuredFields", "Notes"}
+
+// Change describes what happened to one entry
+type Change struct {
+	ID      string
+	OldLine int
+	NewLine int
+	File    string
+	Call    string
+	Message string
+}
+
+// Result summarizes a merge
+type Result struct {
+	Matched   int      // Entries found in both files
+	Carried   int      // Matched entries whose edits were carried over
+	Moved     []Change // Matched entries whose line changed
+	Stale     []Change // Edited entries whose arguments changed since the edit
+	Removed   []Change // Old entries no longer found
+	Added     []Change // New entries, given fresh IDs
+	Unchanged int
+}
+
+// Options control a merge
+type Options struct {
+	DropRemoved bool // Leave removed entries out instead of keeping them, marked, at the end
+}
+
+// entry is a row with the values used to match it
+type entry struct {
+	row     []string
+	id      string
+	file    string
+	line    int
+	call    string
+	message string
+	args    string
+	pkg     string
+}
+
+// Merge matches the entries of oldFile (edited) and newFile (re-collected)
+// and writes newFile's entries to outputFile with the old IDs and edits of
+// matched entries. Entries are matched by content, not ID, because collect
+// numbers entries by position:
+//
+//  1. same file, call, message template and arguments
+//  2. same file, call and message template
+//  3. same package, call, message template and arguments (the file was renamed)
+//
+// Within a pass the candidate with the nearest line wins. Unmatched new
+// entries get IDs after the highest old one; unmatched old entries are kept
+// at the end with Line and Column 0, so transform ignores them, and a note.
+func Merge(oldFile, newFile, outputFile string, opts Options) (*Result, error) {
+	oldTable, err := table.Read(oldFile)
+	if err != nil {
+		return nil, err
+	}
+	newTable, err := table.Read(newFile)
+	if err != nil {
+		return nil, err
+	}
+	required := []string{"ID", "FilePath", "Line", "OriginalCall", "MessageTemplate"}
+	if err := oldTable.Require(required...); err != nil {
+		return nil, fmt.Errorf("%s: %w", oldFile, err)
+	}
+	if err := newTable.Require(required...); err != nil {
+		return nil, fmt.Errorf("%s: %w", newFile, err)
+	}
+
+	olds := entries(oldTable)
+	news := entries(newTable)
+
+	passes := []func(o, n *entry) bool{
+		func(o, n *entry) bool {
+			return o.file == n.file && o.call == n.call && o.message == n.message && o.args == n.args
+		},
+		func(o, n *entry) bool { return o.file == n.file && o.call == n.call && o.message == n.message },
+		func(o, n *entry) bool {
+			return o.pkg == n.pkg && o.call == n.call && o.message == n.message && o.args == n.args
+		},
+	}
+	pairs := Match(olds, news, func(e *entry) int { return e.line }, passes...) // new -> old
+	matched := make(map[*entry]bool, len(pairs))
+	for _, o := range pairs {
+		matched[o] = true
+	}
+
+	// Output columns: the new file's, plus any extra columns the old one had
+	header := append([]string(nil), newTable.Header...)
+	for _, name := range oldTable.Header {
+		if !newTable.Has(name) {
+			header = append(header, name)
+		}
+	}
+	out := table.New(header)
+
+	next := maxID(olds) + 1
+	result := &Result{}
+	for _, n := range news {
+		row := make([]string, 0, len(out.Header))
+		for _, name := range out.Header {
+			row = append(row, newTable.Get(n.row, name))
+		}
+
+		o, ok := pairs[n]
+		if !ok {
+			id := fmt.Sprintf("LOG-%04d", next)
+			next++
+			row = out.Set(row, "ID", id)
+			result.Added = append(result.Added, change(id, 0, n))
+			out.Rows = append(out.Rows, row)
+			continue
+		}
+
+		result.Matched++
+		row = out.Set(row, "ID", o.id)
+		carried := false
+		for _, name := range EditColumns {
+			if value := oldTable.Get(o.row, name); value != "" {
+				row = out.Set(row, name, value)
+				carried = carried || name != "Notes"
+			}
+		}
+		for _, name := range oldTable.Header {
+			if !newTable.Has(name) {
+				row = out.Set(row, name, oldTable.Get(o.row, name))
+			}
+		}
+		if carried {
+			result.Carried++
+			if o.args != n.args {
+				result.Stale = append(result.Stale, change(o.id, o.line, n))
+			}
+		}
+		if o.line != n.line || o.file != n.file {
+			result.Moved = append(result.Moved, change(o.id, o.line, n))
+		} else {
+			result.Unchanged++
+		}
+		out.Rows = append(out.Rows, row)
+	}
+
+	for _, o := range olds {
+		if matched[o] {
+			continue
+		}
+		result.Removed = append(result.Removed, Change{ID: o.id, OldLine: o.line, File: o.file, Call: o.call, Message: o.message})
+		if opts.DropRemoved {
+			continue
+		}
+		row := make([]string, 0, len(out.Header))
+		for _, name := range out.Header {
+			row = append(row, oldTable.Get(o.row, name))
+		}
+		row = out.Set(row, "Line", "0")
+		if out.Has("Column") {
+			row = out.Set(row, "Column", "0")
+		}
+		row = out.Set(row, "Notes", joinNote(fmt.Sprintf("REMOVED: not found when re-collected (was line %d)", o.line), oldTable.Get(o.row, "Notes")))
+		out.Rows = append(out.Rows, row)
+	}
+
+	if err := out.Write(outputFile); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func entries(t *table.Table) []*entry {
+	list := make([]*entry, 0, len(t.Rows))
+	for _, row := range t.Rows {
+		line, _ := strconv.Atoi(t.Get(row, "Line"))
+		file := t.Get(row, "FilePath")
+		if absFile, err := filepath.Abs(file); err == nil {
+			file = absFile
+		}
+		list = append(list, &entry{
+			row:     row,
+			id:      t.Get(row, "ID"),
+			file:    file,
+			line:    line,
+			call:    t.Get(row, "OriginalCall"),
+			message: t.Get(row, "MessageTemplate"),
+			args:    t.Get(row, "ArgumentDetails"),
+			pkg:     t.Get(row, "Package"),
+		})
+	}
+	return list
+}
+
+func change(id string, oldLine int, n *entry) Change {
+	return Change{ID: id, OldLine: oldLine, NewLine: n.line, File: n.file, Call: n.call, Message: n.message}
+}
+
+var idNumber = regexp.MustCompile(`(\d+)$`)
+
+// maxID returns the highest number used in an entry ID
+func maxID(list []*entry) int {
+	max := 0
+	for _, e := range list {
+		if m := idNumber.FindStringSubmatch(e.id); m != nil {
+			if n, err := strconv.Atoi(m[1]); err == nil && n > max {
+				max = n
+			}
+		}
+	}
+	return max
+}
+
+func joinNote(note, existing string) string {
+	if existing == "" {
+		return note
+	}
+	return note + "; " + existing
+}
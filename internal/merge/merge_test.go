@@ -0,0 +1,97 @@
+package merge
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"logrefactor/internal/table"
+)
+
+func TestMerge(t *testing.T) {
+	const header = "ID,FilePath,Line,Column,OriginalCall,MessageTemplate,ArgumentDetails,Package,NewMessage,Notes\n"
+	tests := []struct {
+		name     string
+		old, new string // Rows after the header
+		opts     Options
+		want     string // ID, Line and NewMessage of each output row
+		moved    int
+		stale    int
+		removed  int
+	}{
+		{
+			name: "unchanged",
+			old:  "LOG-0001,a.go,10,2,log.Printf,\"\"\"hi\"\"\",,main,hello,\n",
+			new:  "LOG-0001,a.go,10,2,log.Printf,\"\"\"hi\"\"\",,main,,\n",
+			want: "LOG-0001 10 hello",
+		},
+		{
+			name:  "moved, keeping its ID and edit",
+			old:   "LOG-0007,a.go,10,2,log.Printf,\"\"\"hi\"\"\",,main,hello,\n",
+			new:   "LOG-0001,a.go,4,2,log.Print,\"\"\"new\"\"\",,main,,\nLOG-0002,a.go,14,2,log.Printf,\"\"\"hi\"\"\",,main,,\n",
+			want:  "LOG-0008 4 |LOG-0007 14 hello",
+			moved: 1,
+		},
+		{
+			name:  "arguments changed since the edit",
+			old:   "LOG-0001,a.go,10,2,log.Printf,\"\"\"hi %s\"\"\",name(unknown)=name[%s],main,hello,\n",
+			new:   "LOG-0001,a.go,10,2,log.Printf,\"\"\"hi %s\"\"\",user(unknown)=user[%s],main,,\n",
+			want:  "LOG-0001 10 hello",
+			stale: 1,
+		},
+		{
+			name:  "file renamed within the package",
+			old:   "LOG-0001,a.go,10,2,log.Printf,\"\"\"hi\"\"\",,main,hello,\n",
+			new:   "LOG-0001,b.go,10,2,log.Printf,\"\"\"hi\"\"\",,main,,\n",
+			want:  "LOG-0001 10 hello",
+			moved: 1,
+		},
+		{
+			name:    "removed entries are kept at line 0",
+			old:     "LOG-0001,a.go,10,2,log.Printf,\"\"\"hi\"\"\",,main,hello,\n",
+			new:     "LOG-0001,a.go,10,2,log.Printf,\"\"\"bye\"\"\",,main,,\n",
+			want:    "LOG-0002 10 |LOG-0001 0 hello",
+			removed: 1,
+		},
+		{
+			name:    "removed entries dropped",
+			old:     "LOG-0001,a.go,10,2,log.Printf,\"\"\"hi\"\"\",,main,hello,\n",
+			new:     "LOG-0001,a.go,10,2,log.Printf,\"\"\"bye\"\"\",,main,,\n",
+			opts:    Options{DropRemoved: true},
+			want:    "LOG-0002 10 ",
+			removed: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			oldFile, newFile, outFile := filepath.Join(dir, "old.csv"), filepath.Join(dir, "new.csv"), filepath.Join(dir, "out.csv")
+			if err := os.WriteFile(oldFile, []byte(header+tt.old), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(newFile, []byte(header+tt.new), 0644); err != nil {
+				t.Fatal(err)
+			}
+			result, err := Merge(oldFile, newFile, outFile, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := table.Read(outFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var rows []string
+			for _, row := range out.Rows {
+				rows = append(rows, out.Get(row, "ID")+" "+out.Get(row, "Line")+" "+out.Get(row, "NewMessage"))
+			}
+			if got := strings.Join(rows, "|"); got != tt.want {
+				t.Errorf("rows = %q, want %q", got, tt.want)
+			}
+			if len(result.Moved) != tt.moved || len(result.Stale) != tt.stale || len(result.Removed) != tt.removed {
+				t.Errorf("moved, stale, removed = %d, %d, %d; want %d, %d, %d",
+					len(result.Moved), len(result.Stale), len(result.Removed), tt.moved, tt.stale, tt.removed)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package naming
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Supported key styles
+const (
+	SnakeCase = "snake_case"
+	CamelCase = "camelCase"
+	KebabCase = "kebab-case"
+	Screaming = "SCREAMING"
+)
+
+// DefaultRenames are the key renames applied to suggested keys when nothing
+// else is configured
+var DefaultRenames = map[string]string{
+	"err":   "error",
+	"msg":   "message",
+	"state": "status",
+}
+
+// Normalize maps a key style name (including short aliases like "snake" or
+// "camel") to one of the supported styles. It returns "" for unknown styles.
+func Normalize(style string) string {
+	switch strings.ToLower(strings.TrimSpace(style)) {
+	case "snake_case", "snake":
+		return SnakeCase
+	case "camelcase", "camel":
+		return CamelCase
+	case "kebab-case", "kebab":
+		return KebabCase
+	case "screaming", "screaming_snake_case", "upper":
+		return Screaming
+	}
+	return ""
+}
+
+// Convert rewrites a key in the given style. Dotted keys ("http.statusCode")
+// are converted segment by segment. An empty or unknown style leaves the key
+// unchanged.
+func Convert(key, style string) string {
+	style = Normalize(style)
+	if style == "" || key == "" {
+		return key
+	}
+
+	segments := strings.Split(key, ".")
+	for i, segment := range segments {
+		segments[i] = convertWords(Words(segment), style)
+	}
+	return strings.Join(segments, ".")
+}
+
+// Words splits an identifier into lowercase words. Acronyms stay together, so
+// "HTTPServer" is ["http", "server"] and "requestID" is ["request", "id"].
+// Underscores, hyphens and spaces also separate words.
+func Words(s string) []string {
+	var words []string
+	var current []rune
+
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, strings.ToLower(string(current)))
+			current = current[:0]
+		}
+	}
+
+	runes := []rune(s)
+	for i, r := range runes {
+		if r == '_' || r == '-' || r == ' ' {
+			flush()
+			continue
+		}
+
+		if i > 0 && unicode.IsUpper(r) && len(current) > 0 {
+			prev := runes[i-1]
+			// lower -> Upper: "userName" splits before "N"
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) {
+				flush()
+			} else if unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1]) && !isPluralSuffix(runes, i+1) {
+				// end of an acronym: "HTTPServer" splits before "S"
+				flush()
+			}
+		}
+
+		current = append(current, r)
+	}
+	flush()
+
+	return words
+}
+
+// isPluralSuffix reports whether the lowercase rune at i is a lone "s" closing
+// an acronym, as in "userIDs"
+func isPluralSuffix(runes []rune, i int) bool {
+	return runes[i] == 's' && (i+1 == len(runes) || !unicode.IsLetter(runes[i+1]) || unicode.IsUpper(runes[i+1]))
+}
+
+// convertWords joins lowercase words in the given (normalized) style
+func convertWords(words []string, style string) string {
+	switch style {
+	case CamelCase:
+		for i := 1; i < len(words); i++ {
+			words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+		}
+		return strings.Join(words, "")
+	case KebabCase:
+		return strings.Join(words, "-")
+	case Screaming:
+		return strings.ToUpper(strings.Join(words, "_"))
+	default:
+		return strings.Join(words, "_")
+	}
+}
+
+// initialisms are words written in all caps in Go identifiers
+var initialisms = map[string]bool{
+	"api": true, "dns": true, "grpc": true, "http": true, "https": true,
+	"id": true, "ids": true, "ip": true, "json": true, "sql": true,
+	"ssh": true, "tcp": true, "tls": true, "ttl": true, "udp": true,
+	"ui": true, "uid": true, "uri": true, "url": true, "uuid": true,
+	"xml": true,
+}
+
+// GoName converts a key to an exported Go identifier, writing common
+// initialisms in caps: "user_id" becomes "UserID", "http.method" "HTTPMethod"
+func GoName(key string) string {
+	var b strings.Builder
+	for _, segment := range strings.Split(key, ".") {
+		for _, word := range Words(segment) {
+			if initialisms[word] {
+				if word == "ids" {
+					b.WriteString("IDs")
+				} else {
+					b.WriteString(strings.ToUpper(word))
+				}
+				continue
+			}
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return b.String()
+}
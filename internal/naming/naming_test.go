@@ -0,0 +1,76 @@
+package naming
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string // Words joined with spaces
+	}{
+		{"userName", "user name"},
+		{"HTTPServer", "http server"},
+		{"requestID", "request id"},
+		{"userIDs", "user ids"},
+		{"user_id", "user id"},
+		{"content-type", "content type"},
+		{"retry2Count", "retry2 count"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(Words(tt.in), " "); got != tt.want {
+			t.Errorf("Words(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		key, style string
+		want       string
+	}{
+		{"userName", SnakeCase, "user_name"},
+		{"user_name", CamelCase, "userName"},
+		{"HTTPStatus", "kebab", "http-status"},
+		{"requestID", "upper", "REQUEST_ID"},
+		{"http.statusCode", "snake", "http.status_code"},
+		{"userName", "", "userName"},
+		{"userName", "pascal", "userName"},
+	}
+	for _, tt := range tests {
+		if got := Convert(tt.key, tt.style); got != tt.want {
+			t.Errorf("Convert(%q, %q) = %q, want %q", tt.key, tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"snake":                SnakeCase,
+		" CamelCase ":          CamelCase,
+		"kebab-case":           KebabCase,
+		"screaming_snake_case": Screaming,
+		"pascal":               "",
+	}
+	for style, want := range tests {
+		if got := Normalize(style); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", style, got, want)
+		}
+	}
+}
+
+func TestGoName(t *testing.T) {
+	tests := map[string]string{
+		"user_id":     "UserID",
+		"http.method": "HTTPMethod",
+		"userIDs":     "UserIDs",
+		"retry_count": "RetryCount",
+	}
+	for key, want := range tests {
+		if got := GoName(key); got != want {
+			t.Errorf("GoName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
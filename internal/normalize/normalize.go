@@ -0,0 +1,193 @@
+// Package normalize suggests structured-logging messages for collected
+// entries, so reviewers start from a draft instead of a blank NewMessage.
+package normalize
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"logrefactor/internal/table"
+)
+
+// verbPattern matches a printf verb
+var verbPattern = regexp.MustCompile(`%[-+# 0]*(\*|[0-9]+)?(\.(\*|[0-9]+))?[a-zA-Z]`)
+
+// keyedVerb matches "key=%v" and "key: %v" style tokens, keeping the key
+var keyedVerb = regexp.MustCompile(`^([\pL_][\pL\pN_.-]*)[=:]` + verbPattern.String() + `[,;]?$`)
+
+// danglingWords are dropped from the end of a message once the values that
+// followed them are gone ("completed in %dms" -> "completed")
+var danglingWords = map[string]bool{
+	"a": true, "an": true, "as": true, "at": true, "by": true, "for": true, "from": true,
+	"in": true, "into": true, "is": true, "of": true, "on": true, "the": true, "to": true,
+	"was": true, "with": true, "=": true,
+}
+
+// Message turns a printf-style message into a constant structured-logging
+// message: format verbs and the values glued to them are removed ("%dms",
+// "'%s'", "user=%s" keeps "user"), words left dangling at the end are
+// dropped, trailing punctuation is trimmed and a capitalized first word is
+// lower-cased (acronyms such as HTTP are kept). The template may be a quoted
+// Go string literal, as collect records it.
+func Message(template string) string {
+	text := template
+	if unquoted, err := strconv.Unquote(template); err == nil {
+		text = unquoted
+	}
+	text = strings.ReplaceAll(text, "%%", "\x00")
+
+	var words []string
+	for _, word := range strings.Fields(text) {
+		if !verbPattern.MatchString(word) {
+			words = append(words, word)
+			continue
+		}
+		if m := keyedVerb.FindStringSubmatch(word); m != nil {
+			words = append(words, m[1])
+		}
+	}
+
+	for len(words) > 0 {
+		last := strings.TrimRight(words[len(words)-1], " :;,.-!?=")
+		if last == "" || danglingWords[strings.ToLower(last)] {
+			words = words[:len(words)-1]
+			continue
+		}
+		words[len(words)-1] = last
+		break
+	}
+	if len(words) == 0 {
+		return ""
+	}
+	words[0] = lowerFirst(words[0])
+
+	return strings.ReplaceAll(strings.Join(words, " "), "\x00", "%")
+}
+
+// ArgCount returns the number of arguments a printf format reads: one
+// per verb, plus one per * width or precision. It returns -1 for a format
+// ending in a bare %, and false for formats with argument indexes.
+func ArgCount(format string) (int, bool) {
+	n := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		if i < len(format) && format[i] == '%' {
+			continue
+		}
+		for i < len(format) && strings.IndexByte("+-# 0", format[i]) >= 0 {
+			i++
+		}
+		// Width, then precision
+		for part := 0; part < 2; part++ {
+			if part == 1 {
+				if i >= len(format) || format[i] != '.' {
+					break
+				}
+				i++
+			}
+			if i < len(format) && format[i] == '[' {
+				return 0, false
+			}
+			if i < len(format) && format[i] == '*' {
+				n++
+				i++
+				continue
+			}
+			for i < len(format) && format[i] >= '0' && format[i] <= '9' {
+				i++
+			}
+		}
+		if i < len(format) && format[i] == '[' {
+			return 0, false
+		}
+		if i >= len(format) {
+			return -1, true
+		}
+		// The verb; a multi-byte one is skipped a byte at a time, which
+		// can't contain a '%'
+		n++
+	}
+	return n, true
+}
+
+// lowerFirst lower-cases a capitalized word ("Failed" -> "failed") but keeps
+// acronyms and mixed-case identifiers ("HTTP", "gRPC", "UserID")
+func lowerFirst(word string) string {
+	first, size := utf8.DecodeRuneInString(word)
+	if !unicode.IsUpper(first) {
+		return word
+	}
+	for _, r := range word[size:] {
+		if unicode.IsUpper(r) {
+			return word
+		}
+	}
+	return string(unicode.ToLower(first)) + word[size:]
+}
+
+// Suggestion is a NewMessage proposed for an entry
+type Suggestion struct {
+	ID       string
+	Original string
+	Message  string
+}
+
+// Result summarizes a normalize run
+type Result struct {
+	Suggestions []Suggestion
+	Kept        int // Entries that already had a NewMessage
+	Skipped     int // Entries without a literal message to start from
+}
+
+// CSV fills in NewMessage for the entries of csvFile and writes the result
+// to outputFile. Entries that already have a NewMessage are left alone
+// unless force is set. Messages that aren't string literals (a variable, a
+// concatenation) are skipped. With outputFile empty nothing is written.
+func CSV(csvFile, outputFile string, force bool) (*Result, error) {
+	t, err := table.Read(csvFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Require("ID", "MessageTemplate", "NewMessage"); err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	for i, row := range t.Rows {
+		if t.Get(row, "NewMessage") != "" && !force {
+			result.Kept++
+			continue
+		}
+		template := t.Get(row, "MessageTemplate")
+		if !isLiteral(template) {
+			result.Skipped++
+			continue
+		}
+		message := Message(template)
+		if message == "" {
+			result.Skipped++
+			continue
+		}
+		t.Rows[i] = t.Set(row, "NewMessage", message)
+		result.Suggestions = append(result.Suggestions, Suggestion{ID: t.Get(row, "ID"), Original: template, Message: message})
+	}
+
+	if outputFile != "" {
+		if err := t.Write(outputFile); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// isLiteral reports whether a collected message is a single string literal
+func isLiteral(template string) bool {
+	_, err := strconv.Unquote(template)
+	return err == nil
+}
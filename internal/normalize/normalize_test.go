@@ -0,0 +1,100 @@
+package normalize
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"logrefactor/internal/table"
+)
+
+func TestMessage(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{`"Failed to connect to %s"`, "failed to connect"},
+		{`"request completed in %dms"`, "request completed"},
+		{`"user=%s logged in"`, "user logged"},
+		{`"loading '%s'"`, "loading"},
+		{`"HTTP server started on port %d."`, "HTTP server started on port"},
+		{`"UserID missing"`, "UserID missing"},
+		{`"100%% done: %v"`, "100% done"},
+		{`"%v"`, ""},
+		{"not quoted %s", "not quoted"},
+	}
+	for _, tt := range tests {
+		if got := Message(tt.template); got != tt.want {
+			t.Errorf("Message(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestArgCount(t *testing.T) {
+	tests := []struct {
+		format string
+		want   int
+		ok     bool
+	}{
+		{"no verbs", 0, true},
+		{"%s and %d", 2, true},
+		{"100%% done", 0, true},
+		{"%-10s|%+.2f", 2, true},
+		{"%*d", 2, true},
+		{"%.*f", 2, true},
+		{"%*.*f", 3, true},
+		{"ends in %", -1, true},
+		{"%[1]s", 0, false},
+		{"%*[2]d", 0, false},
+		{"%é", 1, true},
+	}
+	for _, tt := range tests {
+		got, ok := ArgCount(tt.format)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ArgCount(%q) = %d, %v, want %d, %v", tt.format, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestCSV(t *testing.T) {
+	const input = "ID,MessageTemplate,NewMessage\n" +
+		"LOG-0001,\"\"\"Saved %d rows\"\"\",\n" +
+		"LOG-0002,\"\"\"hi %s\"\"\",hello\n" +
+		"LOG-0003,msg,\n" +
+		"LOG-0004,\"\"\"%v\"\"\",\n"
+	tests := []struct {
+		name    string
+		force   bool
+		want    []string // NewMessage of each row
+		kept    int
+		skipped int
+	}{
+		{"keeps edits", false, []string{"saved rows", "hello", "", ""}, 1, 2},
+		{"force", true, []string{"saved rows", "hi", "", ""}, 0, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			in, out := filepath.Join(dir, "in.csv"), filepath.Join(dir, "out.csv")
+			if err := os.WriteFile(in, []byte(input), 0644); err != nil {
+				t.Fatal(err)
+			}
+			result, err := CSV(in, out, tt.force)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Kept != tt.kept || result.Skipped != tt.skipped {
+				t.Errorf("kept %d and skipped %d, want %d and %d", result.Kept, result.Skipped, tt.kept, tt.skipped)
+			}
+			written, err := table.Read(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, row := range written.Rows {
+				if got := written.Get(row, "NewMessage"); got != tt.want[i] {
+					t.Errorf("row %d NewMessage = %q, want %q", i+1, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
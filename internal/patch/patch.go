@@ -0,0 +1,256 @@
+// Package patch writes file changes as a unified diff that git apply (or
+// patch -p1) accepts, so transform results can go through code review
+// before they touch a working tree.
+package patch
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// context is the number of unchanged lines around each hunk
+const context = 3
+
+// File is the content of a file before and after a change. Old is nil for
+// a new file.
+type File struct {
+	Path string
+	Old  []byte
+	New  []byte
+}
+
+// Write writes a unified diff of files to w, sorted by path. Paths are made
+// relative to base (usually the repository root, see RepoRoot) and use
+// forward slashes.
+func Write(w io.Writer, files []File, base string) error {
+	sorted := append([]File(nil), files...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+
+	bw := bufio.NewWriter(w)
+	for _, f := range sorted {
+		name, err := relative(base, f.Path)
+		if err != nil {
+			return err
+		}
+		writeFile(bw, name, f.Old, f.New)
+	}
+	return bw.Flush()
+}
+
+// RepoRoot returns the closest directory at or above dir that contains a
+// .git entry, or dir itself if there is none
+func RepoRoot(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return dir
+	}
+	for d := abs; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return d
+		}
+		if filepath.Dir(d) == d {
+			return abs
+		}
+	}
+}
+
+func relative(base, path string) (string, error) {
+	absBase, err := filepath.Abs(base)
+	if err != nil {
+		return "", err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(absBase, absPath)
+	if err != nil {
+		return "", err
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside %s", path, base)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// op is one line of a diff: ' ' kept, '-' removed or '+' added
+type op struct {
+	kind byte
+	line string // Including its newline, if it has one
+}
+
+func writeFile(w *bufio.Writer, name string, old, new []byte) {
+	ops := diff(lines(old), lines(new))
+
+	fmt.Fprintf(w, "diff --git a/%s b/%s\n", name, name)
+	if old == nil {
+		fmt.Fprintf(w, "new file mode 100644\n--- /dev/null\n")
+	} else {
+		fmt.Fprintf(w, "--- a/%s\n", name)
+	}
+	fmt.Fprintf(w, "+++ b/%s\n", name)
+
+	// oldLine and newLine count the lines before ops[i]
+	oldLine, newLine := 0, 0
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			oldLine++
+			newLine++
+			i++
+			continue
+		}
+
+		// A hunk starts context lines before the change and runs until
+		// more than 2*context unchanged lines separate it from the next one
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*context {
+				end += min(run-end, context)
+				break
+			}
+			end = run
+		}
+
+		hunkOld, hunkNew := oldLine-(i-start), newLine-(i-start)
+		oldCount, newCount := 0, 0
+		for _, o := range ops[start:end] {
+			if o.kind != '+' {
+				oldCount++
+			}
+			if o.kind != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(w, "@@ -%s +%s @@\n", span(hunkOld, oldCount), span(hunkNew, newCount))
+		for _, o := range ops[start:end] {
+			w.WriteByte(o.kind)
+			w.WriteString(o.line)
+			if !strings.HasSuffix(o.line, "\n") {
+				w.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		for _, o := range ops[i:end] {
+			if o.kind != '+' {
+				oldLine++
+			}
+			if o.kind != '-' {
+				newLine++
+			}
+		}
+		i = end
+	}
+}
+
+// span formats the start and length of a hunk side; an empty side names the
+// line before it
+func span(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// lines splits content after each newline
+func lines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	result := strings.SplitAfter(string(content), "\n")
+	if result[len(result)-1] == "" {
+		result = result[:len(result)-1]
+	}
+	return result
+}
+
+// diff returns the shortest edit script turning a into b (Myers' algorithm)
+func diff(a, b []string) []op {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, d)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack walks the saved states back from the end to recover the edits.
+// trace[d] holds v (for k in -d-1..d+1) as it was before step d.
+func backtrack(a, b []string, trace [][]int, steps int) []op {
+	var ops []op
+	x, y := len(a), len(b)
+	for d := steps; d > 0; d-- {
+		prev := func(k int) int { return trace[d][k+d+1] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && prev(k-1) < prev(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := prev(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			ops = append(ops, op{' ', a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			ops = append(ops, op{'+', b[y-1]})
+			y--
+		} else {
+			ops = append(ops, op{'-', a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		ops = append(ops, op{' ', a[x-1]})
+		x--
+		y--
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
@@ -0,0 +1,97 @@
+// Package plugin runs generator and matcher plugins compiled to WebAssembly.
+//
+// A plugin is a WASI command module (for example built with
+// GOOS=wasip1 GOARCH=wasm, or TinyGo's wasip1 target). For every call it is
+// started fresh with a JSON request on stdin and its answer is read from
+// stdout. Plugins have no access to the file system, network, environment
+// or clock beyond what WASI stdio provides, so they can be shared without
+// trusting their authors.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// Timeout bounds a single plugin call
+const Timeout = 10 * time.Second
+
+// Plugin is a compiled WASM module that can be called repeatedly, until it
+// is closed
+type Plugin struct {
+	path    string
+	runtime wazero.Runtime
+	module  wazero.CompiledModule
+	mu      sync.Mutex
+}
+
+// Load compiles the module at path, for the calls of one run. Close frees
+// it once the run is over.
+func Load(ctx context.Context, path string) (*Plugin, error) {
+	wasm, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin: %w", err)
+	}
+
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		runtime.Close(ctx)
+		return nil, err
+	}
+	module, err := runtime.CompileModule(ctx, wasm)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to compile plugin %s: %w", path, err)
+	}
+
+	return &Plugin{path: path, runtime: runtime, module: module}, nil
+}
+
+// Close frees the runtime of the plugin; it can't be called after
+func (p *Plugin) Close(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.runtime.Close(ctx)
+}
+
+// Call runs the plugin with input on stdin and returns what it wrote to
+// stdout. A non-zero exit status is returned as an error carrying stderr.
+// The call is stopped if ctx is cancelled or it runs longer than Timeout.
+func (p *Plugin) Call(ctx context.Context, input []byte) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	config := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs(p.path).
+		WithStdin(bytes.NewReader(input)).
+		WithStdout(&stdout).
+		WithStderr(&stderr)
+
+	mod, err := p.runtime.InstantiateModule(ctx, p.module, config)
+	if mod != nil {
+		mod.Close(ctx)
+	}
+	if err != nil {
+		if exitErr, ok := err.(*sys.ExitError); !ok || exitErr.ExitCode() != 0 {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return nil, fmt.Errorf("%s: %w: %s", p.path, err, msg)
+			}
+			return nil, fmt.Errorf("%s: %w", p.path, err)
+		}
+	}
+	return stdout.Bytes(), nil
+}
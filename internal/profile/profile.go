@@ -0,0 +1,91 @@
+// Package profile adds -cpuprofile, -memprofile and -trace flags to a
+// command, so slow runs on large trees can be diagnosed with go tool pprof
+// and go tool trace, and reported with the data attached.
+package profile
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// Flags are the profiling flags of a command
+type Flags struct {
+	cpu   *string
+	mem   *string
+	trace *string
+}
+
+// Register adds the profiling flags to fs
+func Register(fs *flag.FlagSet) *Flags {
+	return &Flags{
+		cpu:   fs.String("cpuprofile", "", "Write a CPU profile to this file"),
+		mem:   fs.String("memprofile", "", "Write a heap profile to this file when the command finishes"),
+		trace: fs.String("trace", "", "Write an execution trace to this file"),
+	}
+}
+
+// Start starts the profiles that were asked for. The returned function
+// stops them and writes the files; it must run before the program exits.
+func (f *Flags) Start() (stop func(), err error) {
+	var stops []func()
+	stop = func() {
+		for i := len(stops) - 1; i >= 0; i-- {
+			stops[i]()
+		}
+		stops = nil
+	}
+
+	if *f.cpu != "" {
+		file, err := os.Create(*f.cpu)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create CPU profile: %w", err)
+		}
+		if err := pprof.StartCPUProfile(file); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to start CPU profile: %w", err)
+		}
+		stops = append(stops, func() {
+			pprof.StopCPUProfile()
+			file.Close()
+		})
+	}
+
+	if *f.trace != "" {
+		file, err := os.Create(*f.trace)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("failed to create trace: %w", err)
+		}
+		if err := trace.Start(file); err != nil {
+			file.Close()
+			stop()
+			return nil, fmt.Errorf("failed to start trace: %w", err)
+		}
+		stops = append(stops, func() {
+			trace.Stop()
+			file.Close()
+		})
+	}
+
+	if *f.mem != "" {
+		path := *f.mem
+		stops = append(stops, func() {
+			file, err := os.Create(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to create heap profile: %v\n", err)
+				return
+			}
+			defer file.Close()
+			runtime.GC() // Up-to-date statistics
+			if err := pprof.WriteHeapProfile(file); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to write heap profile: %v\n", err)
+			}
+		})
+	}
+
+	return stop, nil
+}
@@ -0,0 +1,186 @@
+package report
+
+import (
+	"go/scanner"
+	"go/token"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"logrefactor/pkg/transformer"
+)
+
+// previewContext is the number of unchanged lines shown around a change
+const previewContext = 3
+
+// Preview is the data behind a side-by-side preview of transform changes
+type Preview struct {
+	Title     string
+	Generated time.Time
+	Changes   int
+	Files     []PreviewFile
+}
+
+// PreviewFile holds the changes to one file, in line order
+type PreviewFile struct {
+	Name  string
+	Hunks []Hunk
+}
+
+// Hunk is one change with the lines around it. Before and After are the
+// unchanged lines; Prefix and Suffix are the rest of the lines the call
+// starts and ends on.
+type Hunk struct {
+	transformer.Change
+	Before, After  string
+	Prefix, Suffix string
+}
+
+// BuildPreview groups changes by file and reads the unchanged lines around
+// them. It must run before the changes are written (i.e. after a dry run).
+func BuildPreview(changes []transformer.Change, title string) *Preview {
+	p := &Preview{Title: title, Generated: time.Now(), Changes: len(changes)}
+
+	byFile := make(map[string][]transformer.Change)
+	for _, c := range changes {
+		byFile[c.File] = append(byFile[c.File], c)
+	}
+	names := make([]string, 0, len(byFile))
+	for name := range byFile {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fileChanges := byFile[name]
+		sort.Slice(fileChanges, func(i, j int) bool { return fileChanges[i].Line < fileChanges[j].Line })
+		content, _ := os.ReadFile(name)
+		lines := strings.SplitAfter(string(content), "\n")
+
+		file := PreviewFile{Name: name}
+		for _, c := range fileChanges {
+			file.Hunks = append(file.Hunks, hunk(c, lines))
+		}
+		p.Files = append(p.Files, file)
+	}
+	return p
+}
+
+// hunk cuts the context of a change out of the file's lines. Without the
+// file (or if it no longer matches) only the call itself is shown.
+func hunk(c transformer.Change, lines []string) Hunk {
+	h := Hunk{Change: c}
+	first := c.Line - 1
+	last := first + strings.Count(c.Old, "\n")
+	if first < 0 || last >= len(lines) {
+		return h
+	}
+	text := strings.Join(lines[first:last+1], "")
+	start := c.Column - 1
+	if start > len(text) || !strings.HasPrefix(text[start:], c.Old) {
+		return h
+	}
+	h.Prefix = text[:start]
+	h.Suffix = strings.TrimSuffix(text[start+len(c.Old):], "\n")
+
+	from := first - previewContext
+	if from < 0 {
+		from = 0
+	}
+	to := last + 1 + previewContext
+	if to > len(lines) {
+		to = len(lines)
+	}
+	h.Before = strings.Join(lines[from:first], "")
+	if last+1 < to {
+		h.After = "\n" + strings.TrimSuffix(strings.Join(lines[last+1:to], ""), "\n")
+	}
+	return h
+}
+
+// HTML writes the preview as a standalone page
+func (p *Preview) HTML(w io.Writer) error {
+	return previewTemplate.Execute(w, p)
+}
+
+// highlight marks up Go keywords, literals and comments. The code doesn't
+// need to be a complete file.
+func highlight(src string) htmltemplate.HTML {
+	var s scanner.Scanner
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	s.Init(file, []byte(src), nil, scanner.ScanComments)
+
+	var b strings.Builder
+	done := 0
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		class := ""
+		switch {
+		case tok.IsKeyword():
+			class, lit = "kw", tok.String()
+		case tok == token.STRING || tok == token.CHAR:
+			class = "str"
+		case tok == token.INT || tok == token.FLOAT || tok == token.IMAG:
+			class = "num"
+		case tok == token.COMMENT:
+			class = "com"
+		}
+		offset := file.Offset(pos)
+		if class == "" || offset < done || offset+len(lit) > len(src) {
+			continue
+		}
+		b.WriteString(htmltemplate.HTMLEscapeString(src[done:offset]))
+		b.WriteString(`<span class="` + class + `">`)
+		b.WriteString(htmltemplate.HTMLEscapeString(src[offset : offset+len(lit)]))
+		b.WriteString(`</span>`)
+		done = offset + len(lit)
+	}
+	b.WriteString(htmltemplate.HTMLEscapeString(src[done:]))
+	return htmltemplate.HTML(b.String())
+}
+
+var previewTemplate = htmltemplate.Must(htmltemplate.New("preview").Funcs(htmltemplate.FuncMap{
+	"go": highlight,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", sans-serif; margin: 2em; color: #222; }
+nav ul { columns: 2; }
+h2 { font-size: 1.1em; border-bottom: 1px solid #ccc; padding-bottom: 0.2em; margin-top: 2em; }
+table { border-collapse: collapse; width: 100%; table-layout: fixed; margin: 0.5em 0 1.5em; }
+th { text-align: left; font-weight: normal; color: #555; padding: 0.2em 0.6em; }
+td { vertical-align: top; border: 1px solid #ddd; padding: 0; }
+pre { margin: 0; padding: 0.5em 0.6em; overflow-x: auto; font-size: 12px; line-height: 1.45; }
+.del { background: #ffeef0; display: inline; }
+.add { background: #e6ffed; display: inline; }
+.kw { color: #d73a49; } .str { color: #032f62; } .num { color: #005cc5; } .com { color: #6a737d; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p><em>{{.Changes}} changes in {{len .Files}} files, generated {{.Generated.Format "2006-01-02 15:04"}}</em></p>
+<nav><ul>
+{{range $i, $f := .Files}}<li><a href="#file-{{$i}}"><code>{{$f.Name}}</code></a> ({{len $f.Hunks}})</li>
+{{end}}</ul></nav>
+{{range $i, $f := .Files}}
+<h2 id="file-{{$i}}"><code>{{$f.Name}}</code></h2>
+{{range $f.Hunks}}<table>
+<tr><th>{{.ID}}, line {{.Line}}: before</th><th>after</th></tr>
+<tr>
+<td><pre>{{go .Before}}{{go .Prefix}}<span class="del">{{go .Old}}</span>{{go .Suffix}}{{go .After}}</pre></td>
+<td><pre>{{go .Before}}{{go .Prefix}}<span class="add">{{go .New}}</span>{{go .Suffix}}{{go .After}}</pre></td>
+</tr>
+</table>
+{{end}}{{end}}</body>
+</html>
+`))
@@ -0,0 +1,491 @@
+// Package report renders a migration summary as Markdown or HTML: progress
+// per package, before/after examples, the field keys in use and the keys
+// logged under several names or types, the packages whose levels stand out
+// from the project's, the packages mixing logging libraries, the calls that
+// log an error they return and the calls still to migrate.
+package report
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"logrefactor/internal/lint"
+	"logrefactor/internal/stats"
+	"logrefactor/internal/table"
+	"logrefactor/pkg/transformer"
+)
+
+// Options control what goes into a report
+type Options struct {
+	Title     string
+	Journal   string // Transform journal; entries in it count as migrated
+	Examples  int    // Before/after examples to show
+	Remaining int    // Remaining calls to list (0 = all)
+}
+
+// Report is the data behind a rendered report
+type Report struct {
+	Title      string
+	Source     string
+	Generated  time.Time
+	HasJournal bool
+
+	Total    int
+	Edited   int // Entries with NewMessage or NewCall filled in
+	Migrated int // Entries in the journal
+
+	Packages      []Package
+	Examples      []Example
+	Keys          []Key
+	Collisions    []lint.Collision         // Keys logged under several names or types
+	Levels        []stats.LevelAnomaly     // Packages whose levels stand out from the project's
+	Mixed         []stats.PackageLibraries // Packages using two or more logging libraries, in the order to unify them
+	Returned      []Returned               // Calls logging an error their function returns, not yet migrated
+	Remaining     []Call
+	RemainingMore int // Remaining calls left out of the list
+}
+
+// Package is the progress of one package
+type Package struct {
+	Name     string
+	Total    int
+	Edited   int
+	Migrated int
+}
+
+// Percent is the share of calls migrated, or edited without a journal
+func (p Package) Percent(hasJournal bool) int {
+	return percent(p.Migrated, p.Edited, p.Total, hasJournal)
+}
+
+// Percent is the overall share of calls migrated, or edited without a journal
+func (r *Report) Percent() int {
+	return percent(r.Migrated, r.Edited, r.Total, r.HasJournal)
+}
+
+func percent(migrated, edited, total int, hasJournal bool) int {
+	if total == 0 {
+		return 0
+	}
+	done := edited
+	if hasJournal {
+		done = migrated
+	}
+	return done * 100 / total
+}
+
+// Example is a call before and after migration. After is the code transform
+// wrote, or without a journal the edited message.
+type Example struct {
+	ID       string
+	Location string
+	Before   string
+	After    string
+}
+
+// Key is a field key used by edited entries
+type Key struct {
+	Name    string
+	Uses    int
+	Types   []string
+	Example string // An expression logged under the key
+}
+
+// Call is a call still to migrate
+type Call struct {
+	ID       string
+	Location string
+	Call     string
+	Message  string
+}
+
+// Returned is a call that logs an error its function then returns, so the
+// caller likely logs it again
+type Returned struct {
+	ID       string
+	Location string
+	Call     string
+	Message  string
+	Error    string
+}
+
+// Build reads a CSV (and the journal, if it exists) and assembles a report
+func Build(csvFile string, opts Options) (*Report, error) {
+	t, err := table.Read(csvFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Require("ID", "FilePath", "Line", "Package", "OriginalCall", "MessageTemplate"); err != nil {
+		return nil, err
+	}
+
+	r := &Report{Title: opts.Title, Source: csvFile, Generated: time.Now()}
+	if r.Title == "" {
+		r.Title = "Logging migration report"
+	}
+
+	applied := make(map[string]transformer.JournalEntry)
+	if opts.Journal != "" {
+		entries, err := transformer.LoadJournal(opts.Journal)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to load journal: %w", err)
+		}
+		r.HasJournal = err == nil
+		for _, entry := range entries {
+			applied[entry.ID+"\x00"+entry.File] = entry
+		}
+	}
+
+	packages := make(map[string]*Package)
+	keys := make(map[string]*Key)
+	keyTypes := make(map[string]map[string]bool)
+	var uses []lint.KeyUse
+	levels := make(map[string]map[string]int)
+	libraries := make(map[string]map[string]int)
+	loggers := make(map[string]map[string]int)
+	for i, row := range t.Rows {
+		update, err := transformer.ParseUpdate(t, row)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping malformed row %d: %v\n", i+2, err)
+			continue
+		}
+		location := fmt.Sprintf("%s:%d", update.FilePath, update.Line)
+
+		pkg, ok := packages[update.Package]
+		if !ok {
+			pkg = &Package{Name: update.Package}
+			packages[update.Package] = pkg
+		}
+		pkg.Total++
+		r.Total++
+		if levels[update.Package] == nil {
+			levels[update.Package] = make(map[string]int)
+		}
+		levels[update.Package][update.LogLevel]++
+		if update.SourceLibrary != "" {
+			if libraries[update.Package] == nil {
+				libraries[update.Package] = make(map[string]int)
+				loggers[update.Package] = make(map[string]int)
+			}
+			libraries[update.Package][update.SourceLibrary]++
+			if logger := stats.LoggerName(update.OriginalCall, update.Receiver, update.SourceLibrary); logger != "" {
+				loggers[update.Package][logger]++
+			}
+		}
+		uses = append(uses, update.KeyUses(true)...)
+
+		edited := update.NewMessage != "" || update.NewCall != ""
+		if edited {
+			pkg.Edited++
+			r.Edited++
+			for _, field := range flatten(update.Fields(true)) {
+				k, ok := keys[field.Key]
+				if !ok {
+					k = &Key{Name: field.Key, Example: field.Expression}
+					keys[field.Key] = k
+					keyTypes[field.Key] = make(map[string]bool)
+				}
+				k.Uses++
+				if field.Type != "" && field.Type != "unknown" && !keyTypes[field.Key][field.Type] {
+					keyTypes[field.Key][field.Type] = true
+					k.Types = append(k.Types, field.Type)
+				}
+			}
+		}
+
+		absFile, _ := filepath.Abs(update.FilePath)
+		entry, migrated := applied[update.ID+"\x00"+absFile]
+		if migrated {
+			pkg.Migrated++
+			r.Migrated++
+		}
+
+		if update.Returns != "" && !migrated {
+			r.Returned = append(r.Returned, Returned{ID: update.ID, Location: location, Call: update.OriginalCall, Message: update.MessageTemplate, Error: update.Returns})
+		}
+
+		switch {
+		case migrated:
+			r.Examples = append(r.Examples, Example{ID: update.ID, Location: location, Before: entry.Original, After: entry.Replacement})
+		case edited && !r.HasJournal:
+			after := update.NewCall
+			if update.NewMessage != "" {
+				after = strconv.Quote(strings.Trim(update.NewMessage, `"'`+"`"))
+			}
+			r.Examples = append(r.Examples, Example{ID: update.ID, Location: location,
+				Before: update.OriginalCall + "(" + update.MessageTemplate + ", ...)", After: after})
+		default:
+			r.Remaining = append(r.Remaining, Call{ID: update.ID, Location: location, Call: update.OriginalCall, Message: update.MessageTemplate})
+		}
+	}
+
+	for _, pkg := range packages {
+		r.Packages = append(r.Packages, *pkg)
+	}
+	sort.Slice(r.Packages, func(i, j int) bool { return r.Packages[i].Name < r.Packages[j].Name })
+
+	for _, k := range keys {
+		sort.Strings(k.Types)
+		r.Keys = append(r.Keys, *k)
+	}
+	sort.Slice(r.Keys, func(i, j int) bool {
+		if r.Keys[i].Uses != r.Keys[j].Uses {
+			return r.Keys[i].Uses > r.Keys[j].Uses
+		}
+		return r.Keys[i].Name < r.Keys[j].Name
+	})
+	r.Collisions = lint.Collisions(uses)
+	r.Levels = stats.LevelAnomalies(levels)
+	for _, p := range stats.UnificationPlan(libraries, loggers) {
+		if p.Mixed() {
+			r.Mixed = append(r.Mixed, p)
+		}
+	}
+
+	if opts.Examples >= 0 && len(r.Examples) > opts.Examples {
+		r.Examples = r.Examples[:opts.Examples]
+	}
+	if opts.Remaining > 0 && len(r.Remaining) > opts.Remaining {
+		r.RemainingMore = len(r.Remaining) - opts.Remaining
+		r.Remaining = r.Remaining[:opts.Remaining]
+	}
+	return r, nil
+}
+
+// flatten returns the leaf fields, descending into groups
+func flatten(fields []transformer.FieldMapping) []transformer.FieldMapping {
+	var flat []transformer.FieldMapping
+	for _, field := range fields {
+		if len(field.Fields) > 0 {
+			flat = append(flat, flatten(field.Fields)...)
+			continue
+		}
+		flat = append(flat, field)
+	}
+	return flat
+}
+
+// Markdown writes the report as GitHub-flavored Markdown, ready to paste
+// into an issue
+func (r *Report) Markdown(w io.Writer) error {
+	return markdownTemplate.Execute(w, r)
+}
+
+// HTML writes the report as a standalone HTML page
+func (r *Report) HTML(w io.Writer) error {
+	return htmlTemplate.Execute(w, r)
+}
+
+// cell escapes text for a Markdown table cell
+func cell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+// code wraps text in a Markdown code span, using a longer fence if the text
+// contains backticks
+func code(s string) string {
+	s = cell(s)
+	fence := "`"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		s = " " + s + " "
+	}
+	return fence + s + fence
+}
+
+// diffLines prefixes every line of code for a diff block
+func diffLines(prefix, code string) string {
+	lines := strings.Split(code, "\n")
+	for i, line := range lines {
+		lines[i] = prefix + " " + line
+	}
+	return strings.Join(lines, "\n")
+}
+
+var markdownTemplate = template.Must(template.New("markdown").Funcs(template.FuncMap{
+	"cell":      cell,
+	"code":      code,
+	"diffLines": diffLines,
+	"join":      strings.Join,
+}).Parse(`# {{.Title}}
+
+_Generated {{.Generated.Format "2006-01-02"}} from ` + "`{{.Source}}`" + `_
+
+## Progress
+
+{{if .HasJournal -}}
+**{{.Migrated}} of {{.Total}} calls migrated ({{.Percent}}%)**; {{.Edited}} edited in the CSV.
+{{- else -}}
+**{{.Edited}} of {{.Total}} calls edited ({{.Percent}}%)**; no transform journal found.
+{{- end}}
+
+| Package | Calls | Edited |{{if .HasJournal}} Migrated |{{end}} Progress |
+|---|---:|---:|{{if .HasJournal}}---:|{{end}}---:|
+{{range .Packages}}| {{code .Name}} | {{.Total}} | {{.Edited}} |{{if $.HasJournal}} {{.Migrated}} |{{end}} {{.Percent $.HasJournal}}% |
+{{end}}
+{{- if .Examples}}
+## Before and after
+{{range .Examples}}
+**{{.ID}}** {{code .Location}}
+
+` + "```diff" + `
+{{diffLines "-" .Before}}
+{{diffLines "+" .After}}
+` + "```" + `
+{{end}}{{end}}
+{{- if .Keys}}
+## Field keys
+
+| Key | Uses | Types | Example |
+|---|---:|---|---|
+{{range .Keys}}| {{code .Name}} | {{.Uses}} | {{cell (join .Types ", ")}} | {{code .Example}} |
+{{end}}{{end}}
+{{- if .Collisions}}
+## Key collisions
+
+These keys are logged under more than one name or with more than one type.
+Pick one name and type for each, so queries find every entry.
+
+| Key | Names | Types | Uses |
+|---|---|---|---:|
+{{range .Collisions}}| {{code .Key}} | {{code (join .Names ", ")}} | {{cell (join .Types ", ")}} | {{len .Uses}} |
+{{end}}{{end}}
+{{- if .Levels}}
+## Level anomalies
+
+These packages log at a level far more, or not at all, compared with the
+project as a whole. Check their levels first when choosing new ones.
+
+| Package | Calls | Levels |
+|---|---:|---|
+{{range .Levels}}| {{code .Package}} | {{.Entries}} | {{cell .String}} |
+{{end}}{{end}}
+{{- if .Mixed}}
+## Mixed libraries
+
+These packages log with more than one library. Move each to one library
+and logger, starting from the top.
+
+| Package | Calls | Libraries and loggers |
+|---|---:|---|
+{{range .Mixed}}| {{code .Package}} | {{.Entries}} | {{cell .String}} |
+{{end}}{{end}}
+{{- if .Returned}}
+## Logged and returned errors
+
+These calls log an error their function then returns, so it is likely logged
+again up the stack. ` + "`transform -log-and-return wrap`" + ` drops the call and
+wraps the returned error instead.
+
+| ID | Location | Call | Error |
+|---|---|---|---|
+{{range .Returned}}| {{.ID}} | {{code .Location}} | {{code (printf "%s(%s)" .Call .Message)}} | {{code .Error}} |
+{{end}}{{end}}
+{{- if .Remaining}}
+## Remaining calls
+
+| ID | Location | Call |
+|---|---|---|
+{{range .Remaining}}| {{.ID}} | {{code .Location}} | {{code (printf "%s(%s)" .Call .Message)}} |
+{{end}}
+{{- if .RemainingMore}}
+_…and {{.RemainingMore}} more._
+{{end}}{{end}}`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(htmltemplate.FuncMap{
+	"join": strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", sans-serif; max-width: 60em; margin: 2em auto; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+td.num { text-align: right; }
+pre { background: #f6f8fa; padding: 0.6em; overflow-x: auto; }
+.del { color: #b31d28; } .add { color: #22863a; }
+progress { width: 8em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p><em>Generated {{.Generated.Format "2006-01-02"}} from <code>{{.Source}}</code></em></p>
+
+<h2>Progress</h2>
+{{if .HasJournal -}}
+<p><strong>{{.Migrated}} of {{.Total}} calls migrated ({{.Percent}}%)</strong>; {{.Edited}} edited in the CSV.</p>
+{{- else -}}
+<p><strong>{{.Edited}} of {{.Total}} calls edited ({{.Percent}}%)</strong>; no transform journal found.</p>
+{{- end}}
+<table>
+<tr><th>Package</th><th>Calls</th><th>Edited</th>{{if .HasJournal}}<th>Migrated</th>{{end}}<th>Progress</th></tr>
+{{range .Packages}}<tr><td><code>{{.Name}}</code></td><td class="num">{{.Total}}</td><td class="num">{{.Edited}}</td>{{if $.HasJournal}}<td class="num">{{.Migrated}}</td>{{end}}<td><progress max="100" value="{{.Percent $.HasJournal}}"></progress> {{.Percent $.HasJournal}}%</td></tr>
+{{end}}</table>
+{{if .Examples}}
+<h2>Before and after</h2>
+{{range .Examples}}<p><strong>{{.ID}}</strong> <code>{{.Location}}</code></p>
+<pre><span class="del">{{.Before}}</span>
+<span class="add">{{.After}}</span></pre>
+{{end}}{{end}}
+{{- if .Keys}}
+<h2>Field keys</h2>
+<table>
+<tr><th>Key</th><th>Uses</th><th>Types</th><th>Example</th></tr>
+{{range .Keys}}<tr><td><code>{{.Name}}</code></td><td class="num">{{.Uses}}</td><td>{{join .Types ", "}}</td><td><code>{{.Example}}</code></td></tr>
+{{end}}</table>
+{{end}}
+{{- if .Collisions}}
+<h2>Key collisions</h2>
+<p>These keys are logged under more than one name or with more than one type. Pick one name and type for each, so queries find every entry.</p>
+<table>
+<tr><th>Key</th><th>Names</th><th>Types</th><th>Uses</th></tr>
+{{range .Collisions}}<tr><td><code>{{.Key}}</code></td><td><code>{{join .Names ", "}}</code></td><td>{{join .Types ", "}}</td><td class="num">{{len .Uses}}</td></tr>
+{{end}}</table>
+{{end}}
+{{- if .Levels}}
+<h2>Level anomalies</h2>
+<p>These packages log at a level far more, or not at all, compared with the project as a whole. Check their levels first when choosing new ones.</p>
+<table>
+<tr><th>Package</th><th>Calls</th><th>Levels</th></tr>
+{{range .Levels}}<tr><td><code>{{.Package}}</code></td><td class="num">{{.Entries}}</td><td>{{.String}}</td></tr>
+{{end}}</table>
+{{end}}
+{{- if .Mixed}}
+<h2>Mixed libraries</h2>
+<p>These packages log with more than one library. Move each to one library and logger, starting from the top.</p>
+<table>
+<tr><th>Package</th><th>Calls</th><th>Libraries and loggers</th></tr>
+{{range .Mixed}}<tr><td><code>{{.Package}}</code></td><td class="num">{{.Entries}}</td><td>{{.String}}</td></tr>
+{{end}}</table>
+{{end}}
+{{- if .Returned}}
+<h2>Logged and returned errors</h2>
+<p>These calls log an error their function then returns, so it is likely logged again up the stack. <code>transform -log-and-return wrap</code> drops the call and wraps the returned error instead.</p>
+<table>
+<tr><th>ID</th><th>Location</th><th>Call</th><th>Error</th></tr>
+{{range .Returned}}<tr><td>{{.ID}}</td><td><code>{{.Location}}</code></td><td><code>{{.Call}}({{.Message}})</code></td><td><code>{{.Error}}</code></td></tr>
+{{end}}</table>
+{{end}}
+{{- if .Remaining}}
+<h2>Remaining calls</h2>
+<table>
+<tr><th>ID</th><th>Location</th><th>Call</th></tr>
+{{range .Remaining}}<tr><td>{{.ID}}</td><td><code>{{.Location}}</code></td><td><code>{{.Call}}({{.Message}})</code></td></tr>
+{{end}}</table>
+{{if .RemainingMore}}<p><em>…and {{.RemainingMore}} more.</em></p>{{end}}
+{{end}}</body>
+</html>
+`))
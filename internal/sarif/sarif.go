@@ -0,0 +1,496 @@
+// Package sarif writes findings as SARIF 2.1.0, the format GitHub code
+// scanning and other CI systems read, so unstructured log calls show up
+// inline on pull requests with the structured replacement as a fix.
+package sarif
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"logrefactor/internal/lint"
+	"logrefactor/internal/normalize"
+	"logrefactor/internal/table"
+	"logrefactor/pkg/collector"
+	"logrefactor/pkg/transformer"
+)
+
+// Rule describes a kind of finding
+type Rule struct {
+	ID          string
+	Name        string
+	Description string
+	Help        string
+	Level       string // error, warning or note
+}
+
+// UnstructuredCall is the rule for a log call still using a format string
+var UnstructuredCall = Rule{
+	ID:          "LR001",
+	Name:        "UnstructuredLogCall",
+	Description: "Log call with an unstructured format string",
+	Help:        "Log a constant message and pass the values as structured fields. The fix is generated by logrefactor from the collected entry; review the message and keys before applying it.",
+	Level:       "warning",
+}
+
+// LoggedCredential is the rule for a log call passing what looks like a
+// credential, such as a password, an API token or an Authorization header
+var LoggedCredential = Rule{
+	ID:          "LR002",
+	Name:        "LoggedCredential",
+	Description: "Log call passing what looks like a credential",
+	Help:        "Don't log secrets: drop the value or log a redacted form. Transform holds such entries back until the field is removed from StructuredFields.",
+	Level:       "error",
+}
+
+// LoggedPersonalData is the rule for a log call passing what looks like
+// personal data, such as an e-mail address or a phone number
+var LoggedPersonalData = Rule{
+	ID:          "LR003",
+	Name:        "LoggedPersonalData",
+	Description: "Log call passing what looks like personal data",
+	Help:        "Personal data in logs is subject to retention and access rules; drop the value, hash it or log a redacted form.",
+	Level:       "warning",
+}
+
+// HighCardinalityMessage is the rule for a log call whose format puts an
+// ID, a URL or the like into the message text, making every message unique
+var HighCardinalityMessage = Rule{
+	ID:          "LR004",
+	Name:        "HighCardinalityMessage",
+	Description: "Log message interpolating a high-cardinality value",
+	Help:        "Every distinct message is a new pattern for the log backend to index, and often to bill. Keep the message constant and pass the ID or URL as a field, or drop it.",
+	Level:       "note",
+}
+
+// LogInLoop is the rule for a log call in a loop body or on a hot path
+var LogInLoop = Rule{
+	ID:          "LR005",
+	Name:        "LogInLoop",
+	Description: "Log call in a loop or on a hot path",
+	Help:        "The call logs on every iteration. Consider the Debug level, sampling, logging once after the loop, or an allocation-free logger API.",
+	Level:       "note",
+}
+
+// MessageStyle is the rule for a log message that breaks a message style
+// rule, such as starting with a capital letter or ending with a period
+var MessageStyle = Rule{
+	ID:          "LR006",
+	Name:        "MessageStyle",
+	Description: "Log message breaking the message style rules",
+	Help:        "Messages are easier to search and group when they read alike: lower-case, no trailing punctuation, no prefix built from values and ASCII only. The SuggestedMessage column of the collected CSV has a fix.",
+	Level:       "note",
+}
+
+// Finding is one result at a source location. Fix, if set, replaces the
+// region with new code.
+type Finding struct {
+	Rule      Rule
+	ID        string // Entry ID, if the finding comes from a CSV
+	File      string
+	Line      int
+	Column    int
+	EndLine   int
+	EndColumn int
+	Message   string
+	Fix       string
+	Key       string // Stable identity across runs, hashed into the fingerprint
+}
+
+// FromCSV makes a finding for every entry of a collected CSV (see
+// FromUpdates), and one for every issue of an entry, such as a field that
+// looks like sensitive data, with rules as the message style rules (see
+// FromIssues)
+func FromCSV(csvFile string, config *transformer.TemplateConfig, rules []string) ([]Finding, error) {
+	updates, err := ReadCSV(csvFile)
+	if err != nil {
+		return nil, err
+	}
+	return append(FromUpdates(updates, config), FromIssues(updates, true, rules)...), nil
+}
+
+// ReadCSV reads the entries of a collected CSV, skipping malformed rows
+// with a warning
+func ReadCSV(csvFile string) ([]transformer.LogUpdate, error) {
+	t, err := table.Read(csvFile)
+	if err != nil {
+		return nil, err
+	}
+	var updates []transformer.LogUpdate
+	for _, row := range t.Rows {
+		update, err := transformer.ParseUpdate(t, row)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping entry: %v\n", err)
+			continue
+		}
+		updates = append(updates, update)
+	}
+	return updates, nil
+}
+
+// FromEntries makes a finding for every scanned entry (see FromUpdates)
+func FromEntries(entries []collector.LogEntry, config *transformer.TemplateConfig) []Finding {
+	updates := make([]transformer.LogUpdate, 0, len(entries))
+	for _, e := range entries {
+		updates = append(updates, Update(e))
+	}
+	return FromUpdates(updates, config)
+}
+
+// Update is the CSV row collect writes for a scanned entry
+func Update(e collector.LogEntry) transformer.LogUpdate {
+	return transformer.LogUpdate{
+		ID:               e.ID,
+		FilePath:         e.FilePath,
+		Line:             e.Line,
+		Column:           e.Column,
+		OriginalCall:     e.OriginalCall,
+		SourceLibrary:    e.SourceLibrary,
+		Receiver:         e.Receiver,
+		InLoop:           e.InLoop,
+		Returns:          e.Returns,
+		Helper:           e.Helper,
+		Package:          e.Package,
+		LogLevel:         e.LogLevel,
+		SuggestedLevel:   e.SuggestedLevel,
+		LevelConfidence:  e.LevelConfidence,
+		MessageTemplate:  e.MessageTemplate,
+		SuggestedMessage: e.SuggestedMessage,
+		ClusterID:        e.ClusterID,
+		ArgumentDetails:  collector.FormatArgumentDetails(e.Arguments),
+		NewCall:          e.NewCall,
+		NewMessage:       e.NewMessage,
+		StructuredFields: e.StructuredFields,
+		Status:           e.Status,
+	}
+}
+
+// FromUpdates makes an UnstructuredCall finding for every entry, with the
+// fix from Fix. Entries whose call can't be found in the source any more
+// are skipped.
+func FromUpdates(updates []transformer.LogUpdate, config *transformer.TemplateConfig) []Finding {
+	var findings []Finding
+	files := make(map[string]map[string]token.Position) // File -> call start -> call end
+	for _, update := range updates {
+		ends, ok := files[update.FilePath]
+		if !ok {
+			ends = callEnds(update.FilePath)
+			files[update.FilePath] = ends
+		}
+		end, ok := ends[fmt.Sprintf("%d:%d", update.Line, update.Column)]
+		if !ok {
+			continue
+		}
+
+		f := Finding{
+			Rule:      UnstructuredCall,
+			ID:        update.ID,
+			File:      update.FilePath,
+			Line:      update.Line,
+			Column:    update.Column,
+			EndLine:   end.Line,
+			EndColumn: end.Column,
+			Message:   Message(update),
+			Key:       update.OriginalCall + "\x00" + update.MessageTemplate,
+		}
+		if fix := Fix(update, config); fix != "" {
+			f.Fix = fix
+			f.Message += "; use " + fix
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// FromIssues makes a LoggedCredential or LoggedPersonalData finding for
+// every field of an entry that looks like sensitive data (see
+// LogUpdate.Issues), with autoMap as transform -auto-map, and a
+// HighCardinalityMessage finding for every high-cardinality value put into
+// a message (see LogUpdate.Interpolated), a MessageStyle finding for every
+// message style rule of rules broken (see LogUpdate.Style) and a LogInLoop
+// finding for calls in loops (see LogUpdate.Lint). Like FromUpdates, it
+// skips entries whose call can't be found in the source.
+func FromIssues(updates []transformer.LogUpdate, autoMap bool, rules []string) []Finding {
+	var findings []Finding
+	files := make(map[string]map[string]token.Position)
+	for _, update := range updates {
+		issues := update.Lint(autoMap, rules)
+		if len(issues) == 0 {
+			continue
+		}
+		ends, ok := files[update.FilePath]
+		if !ok {
+			ends = callEnds(update.FilePath)
+			files[update.FilePath] = ends
+		}
+		end, ok := ends[fmt.Sprintf("%d:%d", update.Line, update.Column)]
+		if !ok {
+			continue
+		}
+
+		for _, issue := range issues {
+			rule := HighCardinalityMessage
+			key := update.OriginalCall + "\x00" + update.MessageTemplate + "\x00" + issue.Expression
+			switch issue.Kind {
+			case lint.Credential:
+				rule = LoggedCredential
+			case lint.Personal:
+				rule = LoggedPersonalData
+			case lint.Loop, lint.HotPath:
+				rule = LogInLoop
+			case lint.Style:
+				rule = MessageStyle
+				key += "\x00" + issue.Key // One message may break several rules
+			}
+			findings = append(findings, Finding{
+				Rule:      rule,
+				ID:        update.ID,
+				File:      update.FilePath,
+				Line:      update.Line,
+				Column:    update.Column,
+				EndLine:   end.Line,
+				EndColumn: end.Column,
+				Message:   fmt.Sprintf("%s(%s): %s (%s)", update.OriginalCall, update.MessageTemplate, issue, issue.Severity),
+				Key:       key,
+			})
+		}
+	}
+	return findings
+}
+
+// Message describes the finding for an entry
+func Message(update transformer.LogUpdate) string {
+	return fmt.Sprintf("%s(%s) logs an unstructured message", update.OriginalCall, update.MessageTemplate)
+}
+
+// Fix returns the call transform would generate for an entry: from
+// NewMessage when it is filled in, otherwise from the message normalize
+// would suggest. It is empty if there is no message to use or the call
+// can't be generated.
+func Fix(update transformer.LogUpdate, config *transformer.TemplateConfig) string {
+	if update.NewMessage == "" && update.NewCall == "" {
+		update.NewMessage = normalize.Message(update.MessageTemplate)
+	}
+	if update.NewMessage == "" && update.NewCall == "" {
+		return ""
+	}
+	fix, err := transformer.Generate(update, config, true)
+	if err != nil {
+		return ""
+	}
+	return fix
+}
+
+// callEnds maps the start of every call in a file to its end
+func callEnds(path string) map[string]token.Position {
+	ends := make(map[string]token.Position)
+	fset := token.NewFileSet()
+	node, err := parser.ParseFile(fset, path, nil, 0)
+	if err != nil {
+		return ends
+	}
+	ast.Inspect(node, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			pos := fset.Position(call.Pos())
+			key := fmt.Sprintf("%d:%d", pos.Line, pos.Column)
+			if _, ok := ends[key]; !ok {
+				ends[key] = fset.Position(call.End())
+			}
+		}
+		return true
+	})
+	return ends
+}
+
+// Write writes the findings as a SARIF log. File paths are made relative to
+// root, which should be the repository root so code scanning can place
+// them.
+func Write(w io.Writer, findings []Finding, root string) error {
+	rules := []rule{}
+	index := make(map[string]int)
+	results := []result{}
+	occurrences := make(map[string]int)
+
+	for _, f := range findings {
+		if _, ok := index[f.Rule.ID]; !ok {
+			index[f.Rule.ID] = len(rules)
+			rules = append(rules, rule{
+				ID:               f.Rule.ID,
+				Name:             f.Rule.Name,
+				ShortDescription: text{f.Rule.Description},
+				Help:             text{f.Rule.Help},
+				DefaultConfig:    configuration{f.Rule.Level},
+			})
+		}
+
+		uri, err := relative(root, f.File)
+		if err != nil {
+			return err
+		}
+		loc := artifact{URI: uri, URIBaseID: "%SRCROOT%"}
+		reg := region{StartLine: f.Line, StartColumn: f.Column, EndLine: f.EndLine, EndColumn: f.EndColumn}
+
+		// The fingerprint ignores line numbers, so a finding keeps its
+		// identity when code above it moves
+		key := f.Rule.ID + "\x00" + uri + "\x00" + f.Key
+		occurrences[key]++
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d", key, occurrences[key])))
+
+		r := result{
+			RuleID:       f.Rule.ID,
+			RuleIndex:    index[f.Rule.ID],
+			Level:        f.Rule.Level,
+			Message:      text{f.Message},
+			Locations:    []location{{Physical: physical{Artifact: loc, Region: reg}}},
+			Fingerprints: map[string]string{"logrefactor/v1": hex.EncodeToString(sum[:16])},
+		}
+		if f.ID != "" {
+			r.Properties = map[string]string{"entryId": f.ID}
+		}
+		if f.Fix != "" {
+			r.Fixes = []fix{{
+				Description: text{"Use structured logging"},
+				Changes: []change{{
+					Artifact:     loc,
+					Replacements: []replacement{{Deleted: reg, Inserted: &content{f.Fix}}},
+				}},
+			}}
+		}
+		results = append(results, r)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i].Locations[0].Physical, results[j].Locations[0].Physical
+		if a.Artifact.URI != b.Artifact.URI {
+			return a.Artifact.URI < b.Artifact.URI
+		}
+		return a.Region.StartLine < b.Region.StartLine
+	})
+
+	doc := log{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []run{{
+			Tool:    tool{Driver: driver{Name: "logrefactor", InformationURI: "https://github.com/mallardduck/logrefactor", Rules: rules}},
+			Results: results,
+		}},
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+func relative(root, file string) (string, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(absRoot, absFile)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// The subset of the SARIF object model logrefactor writes
+
+type log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []run  `json:"runs"`
+}
+
+type run struct {
+	Tool    tool     `json:"tool"`
+	Results []result `json:"results"`
+}
+
+type tool struct {
+	Driver driver `json:"driver"`
+}
+
+type driver struct {
+	Name           string `json:"name"`
+	InformationURI string `json:"informationUri"`
+	Rules          []rule `json:"rules"`
+}
+
+type rule struct {
+	ID               string        `json:"id"`
+	Name             string        `json:"name"`
+	ShortDescription text          `json:"shortDescription"`
+	Help             text          `json:"help"`
+	DefaultConfig    configuration `json:"defaultConfiguration"`
+}
+
+type configuration struct {
+	Level string `json:"level"`
+}
+
+type text struct {
+	Text string `json:"text"`
+}
+
+type result struct {
+	RuleID       string            `json:"ruleId"`
+	RuleIndex    int               `json:"ruleIndex"`
+	Level        string            `json:"level"`
+	Message      text              `json:"message"`
+	Locations    []location        `json:"locations"`
+	Fingerprints map[string]string `json:"partialFingerprints"`
+	Fixes        []fix             `json:"fixes,omitempty"`
+	Properties   map[string]string `json:"properties,omitempty"`
+}
+
+type location struct {
+	Physical physical `json:"physicalLocation"`
+}
+
+type physical struct {
+	Artifact artifact `json:"artifactLocation"`
+	Region   region   `json:"region"`
+}
+
+type artifact struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId"`
+}
+
+type region struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+type fix struct {
+	Description text     `json:"description"`
+	Changes     []change `json:"artifactChanges"`
+}
+
+type change struct {
+	Artifact     artifact      `json:"artifactLocation"`
+	Replacements []replacement `json:"replacements"`
+}
+
+type replacement struct {
+	Deleted  region   `json:"deletedRegion"`
+	Inserted *content `json:"insertedContent,omitempty"`
+}
+
+type content struct {
+	Text string `json:"text"`
+}
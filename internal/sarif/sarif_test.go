@@ -0,0 +1,138 @@
+package sarif
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"logrefactor/pkg/transformer"
+)
+
+const source = `package main
+
+import "log"
+
+func main() {
+	log.Printf("Saved %d rows", n)
+	log.Printf("login %s", req.Password)
+	log.Printf("gone")
+}
+`
+
+// updates returns the entries collect records for source in file, and one
+// whose call is gone
+func updates(file string) []transformer.LogUpdate {
+	return []transformer.LogUpdate{
+		{ID: "LOG-0001", FilePath: file, Line: 6, Column: 2, OriginalCall: "log.Printf", LogLevel: "INFO", MessageTemplate: `"Saved %d rows"`, ArgumentDetails: "n(int)=n[%d]"},
+		{ID: "LOG-0002", FilePath: file, Line: 7, Column: 2, OriginalCall: "log.Printf", LogLevel: "INFO", MessageTemplate: `"login %s"`, ArgumentDetails: "password(string)=req.Password[%s]"},
+		{ID: "LOG-0003", FilePath: file, Line: 30, Column: 2, OriginalCall: "log.Printf", LogLevel: "INFO", MessageTemplate: `"gone"`},
+	}
+}
+
+func TestFindings(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(file, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := &transformer.TemplateConfig{Style: "slog", LoggerVar: "logger"}
+
+	tests := []struct {
+		name     string
+		findings []Finding
+		want     []string // Rule, entry, region and fix of each finding
+	}{
+		{
+			name:     "unstructured calls",
+			findings: FromUpdates(updates(file), config),
+			want: []string{
+				`LR001 LOG-0001 6:2-6:32 logger.Info("saved rows", slog.Int("n", n))`,
+				`LR001 LOG-0002 7:2-7:38 logger.Info("login", slog.String("password", req.Password))`,
+			},
+		},
+		{
+			name:     "issues",
+			findings: FromIssues(updates(file), true, nil),
+			want: []string{
+				"LR002 LOG-0002 7:2-7:38 ",
+			},
+		},
+		{
+			name:     "message style",
+			findings: FromIssues(updates(file)[:1], true, []string{"lowercase"}),
+			want: []string{
+				"LR006 LOG-0001 6:2-6:32 ",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, f := range tt.findings {
+				got = append(got, fmt.Sprintf("%s %s %d:%d-%d:%d %s", f.Rule.ID, f.ID, f.Line, f.Column, f.EndLine, f.EndColumn, f.Fix))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("findings = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	root := t.TempDir()
+	file := filepath.Join(root, "cmd", "main.go")
+	findings := []Finding{
+		{Rule: LoggedCredential, ID: "LOG-0002", File: file, Line: 9, Column: 2, EndLine: 9, EndColumn: 40, Message: "second", Key: "k2"},
+		{Rule: UnstructuredCall, ID: "LOG-0001", File: file, Line: 6, Column: 2, EndLine: 6, EndColumn: 37, Message: "first", Fix: `logger.Info("saved")`, Key: "k1"},
+		{Rule: UnstructuredCall, File: file, Line: 8, Column: 2, EndLine: 8, EndColumn: 37, Message: "again", Key: "k1"},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, findings, root); err != nil {
+		t.Fatal(err)
+	}
+	var doc log
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	r := doc.Runs[0]
+	if len(r.Tool.Driver.Rules) != 2 || r.Tool.Driver.Rules[0].ID != LoggedCredential.ID || r.Tool.Driver.Rules[1].ID != UnstructuredCall.ID {
+		t.Errorf("rules = %+v, want each rule once in the order first used", r.Tool.Driver.Rules)
+	}
+	var got []string
+	for _, res := range r.Results {
+		loc := res.Locations[0].Physical
+		got = append(got, fmt.Sprintf("%s %d %s:%d %s %d", res.RuleID, res.RuleIndex, loc.Artifact.URI, loc.Region.StartLine, res.Properties["entryId"], len(res.Fixes)))
+	}
+	want := []string{
+		"LR001 1 cmd/main.go:6 LOG-0001 1",
+		"LR001 1 cmd/main.go:8  0",
+		"LR002 0 cmd/main.go:9 LOG-0002 0",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("results = %q, want %q", got, want)
+	}
+	if fix := r.Results[0].Fixes[0].Changes[0].Replacements[0]; fix.Inserted.Text != `logger.Info("saved")` || fix.Deleted.EndColumn != 37 {
+		t.Errorf("fix = %+v", fix)
+	}
+
+	// Fingerprints ignore lines, and tell repeated findings apart
+	fingerprint := func(res result) string { return res.Fingerprints["logrefactor/v1"] }
+	if fingerprint(r.Results[0]) == fingerprint(r.Results[1]) {
+		t.Error("repeated findings share a fingerprint")
+	}
+	findings[1].Line, findings[1].EndLine = 16, 16
+	buf.Reset()
+	if err := Write(&buf, findings[1:2], root); err != nil {
+		t.Fatal(err)
+	}
+	var moved log
+	if err := json.Unmarshal(buf.Bytes(), &moved); err != nil {
+		t.Fatal(err)
+	}
+	if fingerprint(moved.Runs[0].Results[0]) != fingerprint(r.Results[0]) {
+		t.Error("a moved finding's fingerprint changed")
+	}
+}
@@ -0,0 +1,229 @@
+package scaffold
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// knownLibraries maps logging import paths to a short library name and the
+// style logrefactor uses for it
+var knownLibraries = map[string]struct{ Name, Style string }{
+	"log":                              {"log", ""},
+	"log/slog":                         {"slog", "slog"},
+	"github.com/sirupsen/logrus":       {"logrus", "logrus"},
+	"go.uber.org/zap":                  {"zap", "zap"},
+	"github.com/rs/zerolog":            {"zerolog", "zerolog"},
+	"github.com/rs/zerolog/log":        {"zerolog", "zerolog"},
+	"k8s.io/klog/v2":                   {"klog", "klog"},
+	"k8s.io/klog":                      {"klog", "klog"},
+	"github.com/golang/glog":           {"glog", "klog"},
+	"github.com/hashicorp/go-hclog":    {"hclog", "hclog"},
+	"github.com/go-kit/log":            {"go-kit", "gokit"},
+	"github.com/go-kit/kit/log":        {"go-kit", "gokit"},
+	"github.com/go-kit/log/level":      {"go-kit", "gokit"},
+	"github.com/go-kit/kit/log/level":  {"go-kit", "gokit"},
+	"github.com/go-logr/logr":          {"logr", "logr"},
+	"github.com/apex/log":              {"apex/log", "apex"},
+	"github.com/inconshreveable/log15": {"log15", "log15"},
+}
+
+// LibraryName returns the short name of a logging library import path
+// ("go.uber.org/zap" -> "zap"), and false for other imports
+func LibraryName(importPath string) (string, bool) {
+	lib, ok := knownLibraries[importPath]
+	return lib.Name, ok
+}
+
+// logMethods are method names that identify a call as a logging call
+var logMethods = map[string]bool{
+	"Print": true, "Printf": true, "Println": true,
+	"Fatal": true, "Fatalf": true, "Fatalln": true,
+	"Panic": true, "Panicf": true, "Panicln": true,
+	"Trace": true, "Tracef": true, "Debug": true, "Debugf": true,
+	"Info": true, "Infof": true, "InfoS": true, "Infow": true,
+	"Warn": true, "Warnf": true, "Warning": true, "Warningf": true, "Warnw": true,
+	"Error": true, "Errorf": true, "ErrorS": true, "Errorw": true,
+}
+
+// ignoredReceivers share method names with loggers but are not loggers
+var ignoredReceivers = map[string]bool{"fmt": true, "t": true, "b": true, "tb": true}
+
+// defaultExcludes are always suggested
+var defaultExcludes = []string{"vendor", "testdata"}
+
+// Report is what Inspect found in a project
+type Report struct {
+	Files      int
+	Libraries  map[string]int // library name -> number of files importing it
+	LoggerVars map[string]int // receiver expression -> number of log calls
+	Style      string         // suggested target style
+}
+
+// Inspect scans the Go files under root for logging imports and the
+// receivers of logging calls
+func Inspect(root string) (*Report, error) {
+	report := &Report{
+		Libraries:  make(map[string]int),
+		LoggerVars: make(map[string]int),
+	}
+	styles := make(map[string]int)
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			name := info.Name()
+			if path != root && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+
+		fset := token.NewFileSet()
+		node, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to parse %s: %v\n", path, err)
+			return nil
+		}
+		report.Files++
+
+		// A file importing two packages of a library, such as go-kit's
+		// log and level, counts once
+		seen := make(map[string]bool)
+		for _, imp := range node.Imports {
+			importPath, _ := strconv.Unquote(imp.Path.Value)
+			if lib, ok := knownLibraries[importPath]; ok && !seen[lib.Name] {
+				seen[lib.Name] = true
+				report.Libraries[lib.Name]++
+				if lib.Style != "" {
+					styles[lib.Style]++
+				}
+			}
+		}
+
+		ast.Inspect(node, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || !logMethods[sel.Sel.Name] {
+				return true
+			}
+			if receiver := receiverName(sel.X); receiver != "" && !ignoredReceivers[receiver] {
+				report.LoggerVars[receiver]++
+			}
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report.Style = "slog"
+	if best := topKeys(styles, 1); len(best) > 0 {
+		report.Style = best[0]
+	}
+	return report, nil
+}
+
+// receiverName renders identifiers and selector chains ("log", "s.logger");
+// other receivers (calls, index expressions) return ""
+func receiverName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.SelectorExpr:
+		if x := receiverName(e.X); x != "" {
+			return x + "." + e.Sel.Name
+		}
+	}
+	return ""
+}
+
+// Pattern returns a collect pattern matching the most common logger receivers
+func (r *Report) Pattern() string {
+	vars := topKeys(r.LoggerVars, 5)
+	if len(vars) == 0 {
+		return `log\.|logrus\.|logger\.`
+	}
+	parts := make([]string, len(vars))
+	for i, v := range vars {
+		parts[i] = regexp.QuoteMeta(v + ".")
+	}
+	return strings.Join(parts, "|")
+}
+
+// LoggerVar returns the most common logger receiver, defaulting to "log"
+func (r *Report) LoggerVar() string {
+	if vars := topKeys(r.LoggerVars, 1); len(vars) > 0 {
+		return vars[0]
+	}
+	return "log"
+}
+
+// YAML renders a starter .logrefactor.yaml for the report
+func (r *Report) YAML() string {
+	var b strings.Builder
+
+	b.WriteString("# logrefactor project configuration, generated by `logrefactor init`.\n")
+	b.WriteString("# Review the suggestions below; command-line flags override these settings.\n")
+	fmt.Fprintf(&b, "#\n# Scanned %d Go files.\n", r.Files)
+	if len(r.Libraries) > 0 {
+		b.WriteString("# Logging libraries in use (files importing them):\n")
+		for _, name := range topKeys(r.Libraries, len(r.Libraries)) {
+			fmt.Fprintf(&b, "#   %-10s %d\n", name, r.Libraries[name])
+		}
+	}
+	if len(r.LoggerVars) > 0 {
+		b.WriteString("# Most common logger variables (log calls):\n")
+		for _, name := range topKeys(r.LoggerVars, 5) {
+			fmt.Fprintf(&b, "#   %-10s %d\n", name, r.LoggerVars[name])
+		}
+	}
+
+	b.WriteString("\npath: .\n")
+	b.WriteString("csv: log_entries.csv\n")
+	fmt.Fprintf(&b, "pattern: %s\n", strconv.Quote(r.Pattern()))
+	b.WriteString("exclude:\n")
+	for _, e := range defaultExcludes {
+		fmt.Fprintf(&b, "  - %s\n", e)
+	}
+	b.WriteString("keyStyle: snake_case\n")
+	b.WriteString("\n# Target logging library\n")
+	fmt.Fprintf(&b, "style: %s\n", r.Style)
+	fmt.Fprintf(&b, "loggerVar: %s\n", strconv.Quote(r.LoggerVar()))
+
+	return b.String()
+}
+
+// topKeys returns up to n keys with the highest counts, ties broken by name
+func topKeys(counts map[string]int, n int) []string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
@@ -0,0 +1,300 @@
+package schema
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configSchema is the JSON Schema for project configuration and template
+// files. It is the published form of the rules Check enforces.
+//
+//go:embed config.schema.json
+var configSchema []byte
+
+// JSON returns the configuration JSON Schema
+func JSON() []byte {
+	return configSchema
+}
+
+// Schema is the subset of JSON Schema (draft-07) used by config.schema.json
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Enum                 []string           `json:"enum"`
+	Properties           map[string]*Schema `json:"properties"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	Required             []string           `json:"required"`
+	Minimum              *float64           `json:"minimum"`
+	Definitions          map[string]*Schema `json:"definitions"`
+}
+
+// Problem is a single schema violation
+type Problem struct {
+	Line    int
+	Column  int
+	Field   string // Dotted path of the offending value, e.g. levelMap[0].to
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%d:%d: %s", p.Line, p.Column, p.Message)
+}
+
+// Error reports every schema violation found in a file
+type Error struct {
+	File     string
+	Problems []Problem
+}
+
+func (e *Error) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		lines[i] = fmt.Sprintf("%s:%s", e.File, p)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Check validates a YAML or JSON configuration file against the schema. It
+// returns an *Error listing every problem, or an error if the file can't be
+// parsed at all.
+func Check(file string, data []byte) error {
+	problems, err := Validate(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	if len(problems) > 0 {
+		return &Error{File: file, Problems: problems}
+	}
+	return nil
+}
+
+// Validate checks a YAML or JSON document against the configuration schema
+// and returns the problems found, in document order
+func Validate(data []byte) ([]Problem, error) {
+	root, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil // Empty file
+	}
+
+	v := &validator{root: root}
+	v.check(root, doc.Content[0], "")
+	return v.problems, nil
+}
+
+// load parses the embedded schema
+func load() (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(configSchema, &s); err != nil {
+		return nil, fmt.Errorf("invalid embedded schema: %w", err)
+	}
+	return &s, nil
+}
+
+type validator struct {
+	root     *Schema
+	problems []Problem
+}
+
+func (v *validator) report(node *yaml.Node, field, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if field != "" {
+		msg = field + ": " + msg
+	}
+	v.problems = append(v.problems, Problem{Line: node.Line, Column: node.Column, Field: field, Message: msg})
+}
+
+// resolve follows a "#/definitions/name" reference
+func (v *validator) resolve(s *Schema) *Schema {
+	for s != nil && s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/definitions/")
+		s = v.root.Definitions[name]
+	}
+	return s
+}
+
+func (v *validator) check(s *Schema, node *yaml.Node, field string) {
+	s = v.resolve(s)
+	if s == nil {
+		return
+	}
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	// A null value leaves the setting unset
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		if node.Kind != yaml.MappingNode {
+			v.report(node, field, "expected an object, got %s", describe(node))
+			return
+		}
+		v.checkObject(s, node, field)
+	case "array":
+		if node.Kind != yaml.SequenceNode {
+			v.report(node, field, "expected a list, got %s", describe(node))
+			return
+		}
+		for i, item := range node.Content {
+			v.check(s.Items, item, fmt.Sprintf("%s[%d]", field, i))
+		}
+	case "string":
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!str" {
+			v.report(node, field, "expected a string, got %s", describe(node))
+			return
+		}
+		if len(s.Enum) > 0 && !contains(s.Enum, node.Value) {
+			v.report(node, field, "invalid value %q (must be one of: %s)", node.Value, strings.Join(s.Enum, ", "))
+		}
+	case "integer":
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+			v.report(node, field, "expected an integer, got %s", describe(node))
+			return
+		}
+		if s.Minimum != nil {
+			var n float64
+			if err := node.Decode(&n); err == nil && n < *s.Minimum {
+				v.report(node, field, "must be at least %v", *s.Minimum)
+			}
+		}
+	case "boolean":
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			v.report(node, field, "expected true or false, got %s", describe(node))
+		}
+	}
+}
+
+func (v *validator) checkObject(s *Schema, node *yaml.Node, field string) {
+	var extra *Schema
+	allowExtra := true
+	if len(s.AdditionalProperties) > 0 {
+		if err := json.Unmarshal(s.AdditionalProperties, &allowExtra); err != nil {
+			allowExtra = true
+			extra = &Schema{}
+			json.Unmarshal(s.AdditionalProperties, extra)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		seen[key.Value] = true
+		path := join(field, key.Value)
+
+		if prop, ok := s.Properties[key.Value]; ok {
+			v.check(prop, value, path)
+			continue
+		}
+		if extra != nil {
+			v.check(extra, value, path)
+			continue
+		}
+		if !allowExtra {
+			msg := fmt.Sprintf("unknown field %q", key.Value)
+			if suggestion := closest(key.Value, s.Properties); suggestion != "" {
+				msg += fmt.Sprintf(" (did you mean %q?)", suggestion)
+			}
+			v.report(key, field, "%s", msg)
+		}
+	}
+
+	for _, name := range s.Required {
+		if !seen[name] {
+			v.report(node, field, "missing required field %q", name)
+		}
+	}
+}
+
+// join appends a key to a dotted field path
+func join(field, key string) string {
+	if field == "" {
+		return key
+	}
+	return field + "." + key
+}
+
+// describe names a node's kind for error messages
+func describe(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "an object"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+	switch node.Tag {
+	case "!!str":
+		return fmt.Sprintf("string %q", node.Value)
+	case "!!int", "!!float":
+		return "number " + node.Value
+	case "!!bool":
+		return node.Value
+	}
+	return node.Value
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// closest returns the known property name nearest to key, or "" if none is
+// close enough to be a likely typo
+func closest(key string, properties map[string]*Schema) string {
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	best, bestDist := "", 3
+	for _, name := range names {
+		if strings.EqualFold(name, key) {
+			return name
+		}
+		if d := distance(strings.ToLower(key), strings.ToLower(name)); d < bestDist {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// distance is the Levenshtein edit distance between a and b
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
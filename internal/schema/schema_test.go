@@ -0,0 +1,137 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want []string // Problems, as Problem.String formats them
+	}{
+		{
+			name: "valid",
+			doc:  "$schema: ./config.schema.json\nstyle: slog\nkeyStyle: snake_case\nmaxLineLength: 100\nverbosity:\n  debug: 2\n",
+		},
+		{
+			name: "empty",
+			doc:  "",
+		},
+		{
+			name: "null leaves a setting unset",
+			doc:  "style: ~\n",
+		},
+		{
+			name: "unknown field with a suggestion",
+			doc:  "styel: slog\n",
+			want: []string{`1:1: unknown field "styel" (did you mean "style"?)`},
+		},
+		{
+			name: "unknown field differing in case",
+			doc:  "KeyStyle: snake_case\n",
+			want: []string{`1:1: unknown field "KeyStyle" (did you mean "keyStyle"?)`},
+		},
+		{
+			name: "unknown field without a suggestion",
+			doc:  "colour: blue\n",
+			want: []string{`1:1: unknown field "colour"`},
+		},
+		{
+			name: "value not in the enum",
+			doc:  "keyStyle: pascal\n",
+			want: []string{`1:11: keyStyle: invalid value "pascal" (must be one of: snake_case, snake, camelCase, camelcase, camel, kebab-case, kebab, SCREAMING, screaming, screaming_snake_case, upper)`},
+		},
+		{
+			name: "below the minimum",
+			doc:  "maxLineLength: -1\n",
+			want: []string{"1:16: maxLineLength: must be at least 0"},
+		},
+		{
+			name: "wrong types",
+			doc:  "maxLineLength: long\nskipTests: 1\nverbosity:\n  debug: two\n",
+			want: []string{
+				`1:16: maxLineLength: expected an integer, got string "long"`,
+				"2:12: skipTests: expected true or false, got number 1",
+				`4:10: verbosity.debug: expected an integer, got string "two"`,
+			},
+		},
+		{
+			name: "nested levelMap rules",
+			doc:  "levelMap:\n  - from: Printf\n  - from: Fatalf\n    to: [error]\n    pkg: main\n",
+			want: []string{
+				`2:5: levelMap[0]: missing required field "to"`,
+				"4:9: levelMap[1].to: expected a string, got a list",
+				`5:5: levelMap[1]: unknown field "pkg"`,
+			},
+		},
+		{
+			name: "list where an object goes",
+			doc:  "- style: slog\n",
+			want: []string{"1:1: expected an object, got a list"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems, err := Validate([]byte(tt.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, p := range problems {
+				got = append(got, p.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Validate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	err := Check("a.yaml", []byte("styel: slog\n"))
+	var schemaErr *Error
+	if !errors.As(err, &schemaErr) || len(schemaErr.Problems) != 1 || schemaErr.Problems[0].Field != "" {
+		t.Fatalf("Check() = %v, want one problem at the top level", err)
+	}
+	if want := `a.yaml:1:1: unknown field "styel" (did you mean "style"?)`; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+
+	if err := Check("a.yaml", []byte("style: [\n")); err == nil || !strings.HasPrefix(err.Error(), "failed to parse a.yaml: ") {
+		t.Errorf("Check() of invalid YAML = %v, want a parse error", err)
+	}
+	if err := Check("a.json", []byte(`{"style": "zap", "skipTests": true}`)); err != nil {
+		t.Errorf("Check() of valid JSON = %v", err)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var root Schema
+	if err := json.Unmarshal(JSON(), &root); err != nil {
+		t.Fatal(err)
+	}
+	// Every reference names a definition
+	var walk func(s *Schema, field string)
+	walk = func(s *Schema, field string) {
+		if s == nil {
+			return
+		}
+		if s.Ref != "" {
+			if _, ok := root.Definitions[strings.TrimPrefix(s.Ref, "#/definitions/")]; !ok {
+				t.Errorf("%s: unresolved reference %s", field, s.Ref)
+			}
+		}
+		for name, p := range s.Properties {
+			walk(p, join(field, name))
+		}
+		walk(s.Items, field+"[]")
+	}
+	walk(&root, "")
+	for name, d := range root.Definitions {
+		walk(d, "#/definitions/"+name)
+	}
+}
@@ -0,0 +1,498 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"logrefactor/internal/config"
+	"logrefactor/internal/table"
+	"logrefactor/pkg/collector"
+	"logrefactor/pkg/transformer"
+)
+
+// Job states
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled" // By DELETE /jobs/{id} or the job's timeout
+)
+
+// Job is a collect or transform run started through the API
+type Job struct {
+	ID       string      `json:"id"`
+	Kind     string      `json:"kind"` // collect, transform or dry-run
+	Status   string      `json:"status"`
+	Request  interface{} `json:"request"`
+	Started  time.Time   `json:"started"`
+	Finished *time.Time  `json:"finished,omitempty"`
+	Error    string      `json:"error,omitempty"`
+	Result   interface{} `json:"result,omitempty"`
+
+	done   chan struct{}
+	cancel context.CancelFunc
+}
+
+// CollectRequest is the body of POST /collect. Settings left empty come from
+// the project configuration of Path, then the collect defaults.
+type CollectRequest struct {
+	Path          string   `json:"path"`
+	Output        string   `json:"output"` // Default: log_entries.csv in Path
+	Pattern       string   `json:"pattern"`
+	Exclude       []string `json:"exclude"`
+	KeyStyle      string   `json:"keyStyle"`
+	Matcher       string   `json:"matcher"`
+	Imports       []string `json:"imports"`
+	ProjectConfig string   `json:"projectConfig"`
+	Profile       string   `json:"profile"`
+}
+
+// TransformRequest is the body of POST /transform and POST /dry-run.
+// Settings left empty come from the project configuration of Path.
+type TransformRequest struct {
+	CSV           string   `json:"csv"` // Default: log_entries.csv in Path
+	Path          string   `json:"path"`
+	Config        string   `json:"config"` // Template configuration file
+	Style         string   `json:"style"`
+	LoggerVar     string   `json:"loggerVar"`
+	KeyStyle      string   `json:"keyStyle"`
+	AutoMap       *bool    `json:"autoMap"`
+	KeyConstants  string   `json:"keyConstants"`
+	Journal       *string  `json:"journal"` // Default: the default journal next to CSV; "" disables it
+	OnlyApproved  *bool    `json:"onlyApproved"`
+	MinConfidence *float64 `json:"minConfidence"` // Hold auto-mapped entries scoring below this
+	IDs           []string `json:"ids"`
+	ProjectConfig string   `json:"projectConfig"`
+	Profile       string   `json:"profile"`
+}
+
+// API runs collect and transform for other programs, such as a dashboard
+// driving the migration of many repositories. Runs are jobs: the POST
+// returns at once with the job, which is polled at /jobs/{id} (or add
+// ?wait=true to block until it is done). Jobs are kept in memory until the
+// server stops.
+type API struct {
+	Token   string        // If set, requests must send "Authorization: Bearer <Token>"
+	Root    string        // If set, every path in a request must be inside Root
+	Timeout time.Duration // If set, jobs running longer are cancelled; ?timeout= on a POST overrides it
+
+	mu     sync.Mutex
+	jobs   map[string]*Job
+	nextID int
+	write  sync.Mutex // Serializes transforms, which may touch the same files
+}
+
+// Handler returns the API:
+//
+//	POST /collect              start a collect job (CollectRequest)
+//	GET  /entries?csv={file}   the entries of a CSV
+//	POST /transform            start a transform job (TransformRequest)
+//	POST /dry-run              like /transform, without writing anything
+//	GET  /jobs                 all jobs, newest first
+//	GET  /jobs/{id}            one job; transform and dry-run jobs list their changes
+//	DELETE /jobs/{id}          cancel a running job
+//
+// Without a Token, requests must be addressed to localhost, and POST
+// requests must send the X-Logrefactor header (see Server.Handler).
+func (a *API) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/collect", a.handleCollect)
+	mux.HandleFunc("/entries", a.handleEntries)
+	mux.HandleFunc("/transform", a.handleTransform)
+	mux.HandleFunc("/dry-run", a.handleTransform)
+	mux.HandleFunc("/jobs", a.handleJobs)
+	mux.HandleFunc("/jobs/", a.handleJob)
+	if a.Token == "" {
+		return guard(mux)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+a.Token)) != 1 {
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("missing or invalid token"))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (a *API) handleCollect(w http.ResponseWriter, r *http.Request) {
+	var req CollectRequest
+	if !decodePost(w, r, &req) {
+		return
+	}
+	if err := a.allowed(req.ProjectConfig, req.Path); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+	cfg, err := projectConfig(req.ProjectConfig, req.Path, req.Profile)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	req.Path = first(req.Path, cfg.Path, ".")
+	req.Output = first(req.Output, cfg.CSV, filepath.Join(req.Path, "log_entries.csv"))
+	req.Pattern = first(req.Pattern, cfg.Pattern, collector.DefaultPattern)
+	req.KeyStyle = first(req.KeyStyle, cfg.KeyStyle, "snake_case")
+	req.Matcher = first(req.Matcher, cfg.Matcher)
+	if req.Exclude == nil {
+		req.Exclude = cfg.Exclude
+	}
+	if req.Imports == nil {
+		req.Imports = cfg.Imports
+	}
+	if err := a.allowed(cfg.File, req.Path, req.Output, req.Matcher); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+
+	a.start(w, r, "collect", req, func(ctx context.Context) (interface{}, error) {
+		opts := collector.Options{Root: req.Path, Pattern: req.Pattern, KeyStyle: req.KeyStyle, Excludes: req.Exclude, SkipTests: cfg.SkipTests != nil && *cfg.SkipTests, Matcher: req.Matcher, Imports: req.Imports, HotPaths: cfg.HotPaths, MessageRules: cfg.MessageRules, Verbosity: cfg.Verbosity}
+		if err := collector.Collect(ctx, req.Output, opts); err != nil {
+			return nil, err
+		}
+		t, err := table.Read(req.Output)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"csv": req.Output, "entries": len(t.Rows)}, nil
+	})
+}
+
+func (a *API) handleTransform(w http.ResponseWriter, r *http.Request) {
+	var req TransformRequest
+	if !decodePost(w, r, &req) {
+		return
+	}
+	dryRun := r.URL.Path == "/dry-run"
+	if err := a.allowed(req.ProjectConfig, req.Path, req.Config); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+	cfg, err := projectConfig(req.ProjectConfig, req.Path, req.Profile)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	req.Path = first(req.Path, cfg.Path, ".")
+	req.CSV = first(req.CSV, cfg.CSV, filepath.Join(req.Path, "log_entries.csv"))
+	req.KeyConstants = first(req.KeyConstants, cfg.KeyConstants)
+	if req.AutoMap == nil {
+		autoMap := cfg.AutoMap == nil || *cfg.AutoMap
+		req.AutoMap = &autoMap
+	}
+	if req.OnlyApproved == nil {
+		onlyApproved := cfg.OnlyApproved != nil && *cfg.OnlyApproved
+		req.OnlyApproved = &onlyApproved
+	}
+	if req.MinConfidence == nil {
+		minConfidence := 0.0
+		if cfg.MinConfidence != nil {
+			minConfidence = *cfg.MinConfidence
+		}
+		req.MinConfidence = &minConfidence
+	}
+	if req.Journal == nil {
+		journal := transformer.JournalFor(req.CSV)
+		req.Journal = &journal
+	}
+	if err := a.allowed(cfg.File, req.Path, req.CSV, req.Config, req.KeyConstants, *req.Journal); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+
+	// Precedence: project config < template file < request, as for the
+	// transform command
+	templateConfig, err := transformer.LoadTemplateConfig(req.Config, &cfg.TemplateConfig)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("failed to load template config: %w", err))
+		return
+	}
+	if req.Style != "" {
+		templateConfig.Style = req.Style
+	}
+	if req.LoggerVar != "" {
+		templateConfig.LoggerVar = req.LoggerVar
+	}
+	if req.KeyStyle != "" {
+		templateConfig.KeyStyle = req.KeyStyle
+	}
+	// The generators the configuration runs must be inside Root too
+	if err := a.allowed(templateConfig.Plugin, commandPath(templateConfig.Command)); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+
+	kind := "transform"
+	if dryRun {
+		kind = "dry-run"
+	}
+	a.start(w, r, kind, req, func(ctx context.Context) (interface{}, error) {
+		changes := []transformer.Change{}
+		templateConfig.OnChange(func(c transformer.Change) {
+			changes = append(changes, c)
+		})
+		a.write.Lock()
+		defer a.write.Unlock()
+		err := transformer.Transform(ctx, req.CSV, transformer.Options{
+			Config:         templateConfig,
+			DryRun:         dryRun,
+			AutoMap:        *req.AutoMap,
+			KeyConstants:   req.KeyConstants,
+			Journal:        *req.Journal,
+			OnlyApproved:   *req.OnlyApproved,
+			TestLogs:       cfg.TestLogs != nil && *cfg.TestLogs,
+			MinConfidence:  *req.MinConfidence,
+			AllowSensitive: cfg.AllowSensitive != nil && *cfg.AllowSensitive,
+			LogAndReturn:   cfg.LogAndReturn,
+			RewriteHelpers: cfg.RewriteHelpers != nil && *cfg.RewriteHelpers,
+			MessageRules:   cfg.MessageRules,
+			IDs:            req.IDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"changes": changes}, nil
+	})
+}
+
+func (a *API) handleEntries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	csvFile := r.URL.Query().Get("csv")
+	if csvFile == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("missing csv parameter"))
+		return
+	}
+	if err := a.allowed(csvFile); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+	t, err := table.Read(csvFile)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	entries := make([]map[string]string, 0, len(t.Rows))
+	for _, row := range t.Rows {
+		entry := make(map[string]string, len(t.Header))
+		for _, name := range t.Header {
+			entry[name] = t.Get(row, name)
+		}
+		entries = append(entries, entry)
+	}
+	writeJSON(w, map[string]interface{}{"csv": csvFile, "columns": t.Header, "entries": entries})
+}
+
+func (a *API) handleJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	a.mu.Lock()
+	jobs := make([]Job, 0, len(a.jobs))
+	for _, job := range a.jobs {
+		jobs = append(jobs, *job)
+	}
+	a.mu.Unlock()
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Started.After(jobs[j].Started) })
+	writeJSON(w, map[string]interface{}{"jobs": jobs})
+}
+
+func (a *API) handleJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/jobs/")
+	a.mu.Lock()
+	job, ok := a.jobs[id]
+	a.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no job %s", id))
+		return
+	}
+	if r.Method == http.MethodDelete {
+		// The job stops after the file it is on, so wait for it to report
+		// what it got done
+		job.cancel()
+		<-job.done
+	} else if r.URL.Query().Get("wait") == "true" {
+		select {
+		case <-job.done:
+		case <-r.Context().Done():
+			return
+		}
+	}
+	a.mu.Lock()
+	snapshot := *job
+	a.mu.Unlock()
+	writeJSON(w, snapshot)
+}
+
+// start runs fn as a new job and answers with the job: 202 Accepted while
+// it runs, or the finished job with ?wait=true
+func (a *API) start(w http.ResponseWriter, r *http.Request, kind string, req interface{}, fn func(ctx context.Context) (interface{}, error)) {
+	timeout := a.Timeout
+	if value := r.URL.Query().Get("timeout"); value != "" {
+		var err error
+		if timeout, err = time.ParseDuration(value); err != nil || timeout <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid timeout %q", value))
+			return
+		}
+	}
+	// Jobs outlive the request that started them
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	a.mu.Lock()
+	if a.jobs == nil {
+		a.jobs = make(map[string]*Job)
+	}
+	a.nextID++
+	job := &Job{
+		ID:      "job-" + strconv.Itoa(a.nextID),
+		Kind:    kind,
+		Status:  JobRunning,
+		Request: req,
+		Started: time.Now().UTC(),
+		done:    make(chan struct{}),
+		cancel:  cancel,
+	}
+	a.jobs[job.ID] = job
+	a.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		result, err := fn(ctx)
+		finished := time.Now().UTC()
+		a.mu.Lock()
+		job.Finished = &finished
+		if err != nil {
+			job.Status = JobFailed
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				job.Status = JobCancelled
+			}
+			job.Error = err.Error()
+		} else {
+			job.Status = JobSucceeded
+			job.Result = result
+		}
+		a.mu.Unlock()
+		close(job.done)
+	}()
+
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	status := http.StatusAccepted
+	if r.URL.Query().Get("wait") == "true" {
+		<-job.done
+		status = http.StatusOK
+	}
+	a.mu.Lock()
+	snapshot := *job
+	a.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(snapshot)
+}
+
+// allowed checks that every non-empty path is inside Root
+func (a *API) allowed(paths ...string) error {
+	if a.Root == "" {
+		return nil
+	}
+	root, err := filepath.Abs(a.Root)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		if abs != root && !strings.HasPrefix(abs, root+string(filepath.Separator)) {
+			return fmt.Errorf("%s is outside %s", path, a.Root)
+		}
+	}
+	return nil
+}
+
+// commandPath returns the program an exec generator's command runs: the
+// path it is given as, such as "./tools/gen", or where a bare name is found
+// in PATH. A name that isn't found is returned as it is, and so checked as
+// a path in the working directory.
+func commandPath(command []string) string {
+	if len(command) == 0 {
+		return ""
+	}
+	if strings.ContainsRune(command[0], filepath.Separator) {
+		return command[0]
+	}
+	if path, err := exec.LookPath(command[0]); err == nil {
+		return path
+	}
+	return command[0]
+}
+
+// decodePost decodes the JSON body of a POST request, answering the request
+// itself if that fails
+func decodePost(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return false
+	}
+	return true
+}
+
+// projectConfig loads the project configuration as the commands do: the
+// given file, or the one discovered from path, with profile applied
+func projectConfig(file, path, profile string) (*config.Config, error) {
+	if path == "" {
+		path = "."
+	}
+	var cfg *config.Config
+	var err error
+	if file != "" {
+		cfg, err = config.Load(file)
+	} else {
+		cfg, err = config.Discover(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load project config: %w", err)
+	}
+	return cfg.WithProfile(profile)
+}
+
+// first returns the first non-empty value
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
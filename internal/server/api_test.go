@@ -0,0 +1,81 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAPIRootChecksConfigs(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	write := func(path, content string) string {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	outsideConfig := write(filepath.Join(outside, ".logrefactor.yaml"), "style: exec\ncommand: [\"./gen\"]\n")
+	write(filepath.Join(root, "go.mod"), "module example\n")
+	insideConfig := write(filepath.Join(root, ".logrefactor.yaml"), "style: exec\ncommand: [\""+filepath.Join(outside, "gen")+"\"]\n")
+	template := write(filepath.Join(root, "wasm.json"), `{"style": "wasm", "plugin": "`+filepath.Join(outside, "gen.wasm")+`"}`)
+	pathConfig := write(filepath.Join(root, "path.yaml"), "style: exec\ncommand: [\"logrefactor-gen\"]\n")
+	write(filepath.Join(outside, "logrefactor-gen"), "#!/bin/sh\n")
+	if err := os.Chmod(filepath.Join(outside, "logrefactor-gen"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", outside)
+
+	api := &API{Root: root}
+	handler := api.Handler()
+	tests := []struct {
+		name, path, body string
+	}{
+		{"collect with an outside project config", "/collect", `{"path": "` + root + `", "projectConfig": "` + outsideConfig + `"}`},
+		{"transform with an outside project config", "/dry-run", `{"path": "` + root + `", "projectConfig": "` + outsideConfig + `"}`},
+		{"exec generator outside root", "/dry-run", `{"path": "` + root + `", "projectConfig": "` + insideConfig + `"}`},
+		{"wasm plugin outside root", "/dry-run", `{"path": "` + root + `", "projectConfig": "` + insideConfig + `", "config": "` + template + `"}`},
+		{"exec generator found in PATH outside root", "/dry-run", `{"path": "` + root + `", "projectConfig": "` + pathConfig + `"}`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+		req.Host = "localhost:8090"
+		req.Header.Set("X-Logrefactor", "1")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "outside") {
+			t.Errorf("%s: %d %s, want 403 outside root", tt.name, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestGuardHost(t *testing.T) {
+	handler := guard(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	tests := []struct {
+		host string
+		want int
+	}{
+		{"localhost:8080", http.StatusOK},
+		{"LOCALHOST", http.StatusOK},
+		{"127.0.0.1:8080", http.StatusOK},
+		{"[::1]:8080", http.StatusOK},
+		{"::1", http.StatusOK},
+		{"rebound.example.com:8080", http.StatusForbidden},
+		{"localhost.example.com", http.StatusForbidden},
+		{"192.168.1.10:8080", http.StatusForbidden},
+		{"", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/entries", nil)
+		req.Host = tt.host
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("Host %q: %d, want %d", tt.host, rec.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+// Package server serves a local web UI for reviewing and editing collected
+// entries, previewing the generated calls and applying them.
+package server
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"logrefactor/internal/table"
+	"logrefactor/pkg/transformer"
+)
+
+//go:embed ui
+var ui embed.FS
+
+// EditableColumns are the columns the UI may change
+var EditableColumns = []string{"NewCall", "NewMessage", "StructuredFields", "Notes", "Status", "Approved"}
+
+// sourceContext is the number of lines shown around a call
+const sourceContext = 6
+
+// Server holds the CSV being reviewed and the settings used to preview and
+// apply it. The CSV is re-read on every request, so edits made elsewhere
+// (a spreadsheet, another command) show up on reload.
+type Server struct {
+	CSV          string
+	Path         string
+	Config       *transformer.TemplateConfig
+	AutoMap      bool
+	KeyConstants string // Passed to transform when applying
+	Journal      string // Passed to transform when applying
+
+	mu sync.Mutex // Serializes CSV reads and writes
+}
+
+// Handler returns the UI and its JSON API:
+//
+//	GET  /api/entries            all entries, with a preview of each edited one
+//	PUT  /api/entries/{id}       update the editable columns of an entry
+//	GET  /api/source?id={id}     source lines around an entry's call
+//	POST /api/apply              transform the given IDs ({"ids": [...], "dryRun": bool})
+//
+// Requests that change anything must send the X-Logrefactor header, which a
+// page from another origin can't do without a CORS preflight the server
+// doesn't answer, and every request must be addressed to localhost or a
+// loopback address (see guard).
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	static, _ := fs.Sub(ui, "ui")
+	mux.Handle("/", http.FileServer(http.FS(static)))
+	mux.HandleFunc("/api/entries", s.handleEntries)
+	mux.HandleFunc("/api/entries/", s.handleEntry)
+	mux.HandleFunc("/api/source", s.handleSource)
+	mux.HandleFunc("/api/apply", s.handleApply)
+	return guard(mux)
+}
+
+// guard rejects requests for a Host other than localhost or a loopback
+// address, such as those a page sends once it has rebound its own domain to
+// 127.0.0.1, and state-changing requests without the X-Logrefactor header
+func guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !loopbackHost(r.Host) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("host %s not allowed", r.Host))
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Header.Get("X-Logrefactor") == "" {
+			writeError(w, http.StatusForbidden, fmt.Errorf("missing X-Logrefactor header"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// loopbackHost reports whether host, with or without a port, is localhost
+// or a loopback address
+func loopbackHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// entryJSON is an entry as the API returns it
+type entryJSON struct {
+	Values       map[string]string `json:"values"`
+	Preview      string            `json:"preview,omitempty"`
+	PreviewError string            `json:"previewError,omitempty"`
+}
+
+func (s *Server) entry(t *table.Table, row []string) entryJSON {
+	e := entryJSON{Values: make(map[string]string, len(t.Header))}
+	for _, name := range t.Header {
+		e.Values[name] = t.Get(row, name)
+	}
+	update, err := transformer.ParseUpdate(t, row)
+	if err != nil {
+		e.PreviewError = err.Error()
+		return e
+	}
+	if update.NewMessage == "" && update.NewCall == "" {
+		return e
+	}
+	if e.Preview, err = transformer.Generate(update, s.Config, s.AutoMap); err != nil {
+		e.PreviewError = err.Error()
+	}
+	return e
+}
+
+func (s *Server) handleEntries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	s.mu.Lock()
+	t, err := table.Read(s.CSV)
+	s.mu.Unlock()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	entries := make([]entryJSON, 0, len(t.Rows))
+	for _, row := range t.Rows {
+		entries = append(entries, s.entry(t, row))
+	}
+	writeJSON(w, map[string]interface{}{
+		"csv":      s.CSV,
+		"columns":  t.Header,
+		"editable": EditableColumns,
+		"entries":  entries,
+	})
+}
+
+func (s *Server) handleEntry(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/entries/")
+	if r.Method != http.MethodPut {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	var changes map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&changes); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+	for name := range changes {
+		if !editable(name) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("column %s can't be edited", name))
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, err := table.Read(s.CSV)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	index := -1
+	for i, row := range t.Rows {
+		if t.Get(row, "ID") == id {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no entry %s", id))
+		return
+	}
+
+	for name, value := range changes {
+		t.Rows[index] = t.Set(t.Rows[index], name, value)
+	}
+	if err := t.Write(s.CSV); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, s.entry(t, t.Rows[index]))
+}
+
+func (s *Server) handleSource(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	s.mu.Lock()
+	t, err := table.Read(s.CSV)
+	s.mu.Unlock()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	for _, row := range t.Rows {
+		if t.Get(row, "ID") != id {
+			continue
+		}
+		file := t.Get(row, "FilePath")
+		line, _ := strconv.Atoi(t.Get(row, "Line"))
+		content, err := os.ReadFile(file)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		lines := strings.Split(string(content), "\n")
+		start := line - sourceContext
+		if start < 1 {
+			start = 1
+		}
+		end := line + sourceContext
+		if end > len(lines) {
+			end = len(lines)
+		}
+		if start > end {
+			start = end
+		}
+		writeJSON(w, map[string]interface{}{
+			"file":  file,
+			"line":  line,
+			"start": start,
+			"lines": lines[start-1 : end],
+		})
+		return
+	}
+	writeError(w, http.StatusNotFound, fmt.Errorf("no entry %s", id))
+}
+
+func (s *Server) handleApply(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	var req struct {
+		IDs          []string `json:"ids"`
+		DryRun       bool     `json:"dryRun"`
+		OnlyApproved bool     `json:"onlyApproved"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+	if len(req.IDs) == 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("no entries selected"))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	config := *s.Config
+	journal := s.Journal
+	if req.DryRun {
+		journal = ""
+	}
+	changes := []transformer.Change{}
+	config.OnChange(func(c transformer.Change) {
+		changes = append(changes, c)
+	})
+	err := transformer.Transform(r.Context(), s.CSV, transformer.Options{
+		Config:       &config,
+		DryRun:       req.DryRun,
+		AutoMap:      s.AutoMap,
+		KeyConstants: s.KeyConstants,
+		Journal:      journal,
+		OnlyApproved: req.OnlyApproved,
+		IDs:          req.IDs,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, map[string]interface{}{"changes": changes, "dryRun": req.DryRun})
+}
+
+func editable(name string) bool {
+	for _, column := range EditableColumns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
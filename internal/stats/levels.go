@@ -0,0 +1,88 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Thresholds of LevelAnomalies
+const (
+	// MinAnomalyEntries is how many entries a package needs to be compared
+	// with the project: fewer say little about its levels
+	MinAnomalyEntries = 10
+	// AnomalyMargin is how far above the project's share a level's share
+	// of a package must be to stand out, e.g. 0.4 for a package logging 70%
+	// at Error in a project logging 30% at Error
+	AnomalyMargin = 0.4
+	// missingExpected is how many entries at a level a package must be
+	// expected to have, going by the project's share, for having none of
+	// them to stand out
+	missingExpected = 3
+)
+
+// LevelAnomaly is a level a package logs at far more, or not at all, than
+// the project does, such as a package logging 95% at Error or with no
+// Debug. These are the packages to look at first when choosing new levels.
+type LevelAnomaly struct {
+	Package string  `json:"package"`
+	Entries int     `json:"entries"` // Entries of the package
+	Level   string  `json:"level"`
+	Count   int     `json:"count"` // Entries of the package at Level; 0 when the level is missing
+	Share   float64 `json:"share"` // Count as a share of Entries
+	Norm    float64 `json:"norm"`  // Share of the project's entries at Level
+}
+
+// String describes the anomaly, e.g. "95% at Error (project: 20%)" or "no
+// Debug (project: 30%)"
+func (a LevelAnomaly) String() string {
+	if a.Count == 0 {
+		return fmt.Sprintf("no %s (project: %.0f%%)", a.Level, a.Norm*100)
+	}
+	return fmt.Sprintf("%.0f%% at %s (project: %.0f%%)", a.Share*100, a.Level, a.Norm*100)
+}
+
+// LevelAnomalies compares the level counts of each package (package ->
+// LogLevel -> entries) with the project's, the counts of all packages
+// together. A package with at least MinAnomalyEntries entries stands out
+// at a level whose share of its entries exceeds the project's by
+// AnomalyMargin, or at a level it has none of although the project's share
+// would give it several. Entries without a level ("Unknown") aren't
+// compared. The anomalies are sorted by package, then level.
+func LevelAnomalies(byPackage map[string]map[string]int) []LevelAnomaly {
+	project := make(map[string]int)
+	total := 0
+	for _, levels := range byPackage {
+		for level, n := range levels {
+			project[level] += n
+			total += n
+		}
+	}
+
+	var anomalies []LevelAnomaly
+	for pkg, levels := range byPackage {
+		entries := 0
+		for _, n := range levels {
+			entries += n
+		}
+		if entries < MinAnomalyEntries || entries == total {
+			continue
+		}
+		for level, n := range project {
+			if level == "" || level == "Unknown" {
+				continue
+			}
+			norm := float64(n) / float64(total)
+			share := float64(levels[level]) / float64(entries)
+			if share-norm >= AnomalyMargin || levels[level] == 0 && norm*float64(entries) >= missingExpected {
+				anomalies = append(anomalies, LevelAnomaly{Package: pkg, Entries: entries, Level: level, Count: levels[level], Share: share, Norm: norm})
+			}
+		}
+	}
+	sort.Slice(anomalies, func(i, j int) bool {
+		if anomalies[i].Package != anomalies[j].Package {
+			return anomalies[i].Package < anomalies[j].Package
+		}
+		return anomalies[i].Level < anomalies[j].Level
+	})
+	return anomalies
+}
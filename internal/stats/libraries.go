@@ -0,0 +1,134 @@
+package stats
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/types"
+	"sort"
+	"strings"
+
+	"logrefactor/internal/logcall"
+)
+
+// notLibraries are SourceLibrary values that don't make a package mixed:
+// calls whose library isn't known, and testing's t.Log and its like, which
+// stay as they are
+var notLibraries = map[string]bool{"unknown": true, "testing": true}
+
+// PackageLibraries are the logging libraries a package's entries use and
+// the loggers they log to. A package using two or more libraries at once
+// is the place to start unifying on one.
+type PackageLibraries struct {
+	Package   string         `json:"package"`
+	Entries   int            `json:"entries"`
+	Libraries map[string]int `json:"libraries"` // Library -> entries
+	Loggers   map[string]int `json:"loggers"`   // Logger variable, package or field logged to, e.g. "log" or "s.logger" -> entries
+}
+
+// Mixed reports whether the package uses two or more libraries, leaving out
+// notLibraries
+func (p PackageLibraries) Mixed() bool {
+	n := 0
+	for lib := range p.Libraries {
+		if !notLibraries[lib] {
+			n++
+		}
+	}
+	return n > 1
+}
+
+// String lists the libraries and loggers, largest first, e.g. "logrus 30,
+// log 12 (loggers: s.logger 30, log 12)"
+func (p PackageLibraries) String() string {
+	return fmt.Sprintf("%s (loggers: %s)", joinCounts(p.Libraries), joinCounts(p.Loggers))
+}
+
+// UnificationPlan orders the packages of the libraries and loggers their
+// entries use (package -> library or logger -> entries) in the order to
+// unify them: packages mixing libraries first, those with the most
+// libraries before the others, then by entries and name.
+func UnificationPlan(libraries, loggers map[string]map[string]int) []PackageLibraries {
+	plan := make([]PackageLibraries, 0, len(libraries))
+	for pkg, libs := range libraries {
+		entries := 0
+		for _, n := range libs {
+			entries += n
+		}
+		plan = append(plan, PackageLibraries{Package: pkg, Entries: entries, Libraries: libs, Loggers: loggers[pkg]})
+	}
+	sort.Slice(plan, func(i, j int) bool {
+		a, b := plan[i], plan[j]
+		if a.Mixed() != b.Mixed() {
+			return a.Mixed()
+		}
+		if a.Mixed() && len(a.Libraries) != len(b.Libraries) {
+			return len(a.Libraries) > len(b.Libraries)
+		}
+		if a.Entries != b.Entries {
+			return a.Entries > b.Entries
+		}
+		return a.Package < b.Package
+	})
+	return plan
+}
+
+// LoggerName returns what an entry logs to: its Receiver if it has one, or
+// else the variable or package its OriginalCall starts from past any calls
+// adding fields or levels, such as "logger" for
+// logger.WithField("user", id).Info or level.Info(logger).Log and "log" for
+// log.Printf. It returns "" when OriginalCall can't be parsed.
+func LoggerName(originalCall, receiver, library string) string {
+	if receiver != "" {
+		return receiver
+	}
+	expr, err := parser.ParseExpr(originalCall + "()")
+	if err != nil {
+		return ""
+	}
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return ""
+	}
+	if library == "go-kit" {
+		if logger, _, _, ok := logcall.GokitLogger(call); ok {
+			return types.ExprString(logger)
+		}
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	x := sel.X
+	for {
+		inner, ok := x.(*ast.CallExpr)
+		if !ok {
+			break
+		}
+		fun, ok := inner.Fun.(*ast.SelectorExpr)
+		if !ok {
+			break
+		}
+		x = fun.X
+	}
+	return types.ExprString(x)
+}
+
+// joinCounts renders counts as "name n" pairs, largest first
+func joinCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s %d", k, counts[k])
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,218 @@
+package stats
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"logrefactor/internal/scaffold"
+	"logrefactor/internal/table"
+)
+
+// Report summarizes a collected CSV
+type Report struct {
+	Total                int                `json:"total"`
+	WithNewMessage       int                `json:"withNewMessage"`       // Entries with NewMessage filled in
+	WithStructuredFields int                `json:"withStructuredFields"` // Entries with StructuredFields filled in
+	Ready                int                `json:"ready"`                // Entries transform would change (NewMessage or NewCall set)
+	ByLevel              map[string]int     `json:"byLevel"`
+	ByPackage            map[string]int     `json:"byPackage"`
+	ByFile               map[string]int     `json:"byFile"`
+	ByLibrary            map[string]int     `json:"byLibrary"`                // SourceLibrary, or for older CSVs a guess from the file's imports
+	LevelAnomalies       []LevelAnomaly     `json:"levelAnomalies,omitempty"` // Packages whose levels stand out from the project's
+	Unification          []PackageLibraries `json:"unification"`              // Packages in the order to unify their libraries, mixed ones first
+}
+
+// FromCSV reads a collected (and possibly edited) CSV and counts its entries.
+// Columns are looked up by header name.
+func FromCSV(csvFile string) (*Report, error) {
+	t, err := table.Read(csvFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Require("FilePath", "Package", "OriginalCall", "LogLevel"); err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		ByLevel:   make(map[string]int),
+		ByPackage: make(map[string]int),
+		ByFile:    make(map[string]int),
+		ByLibrary: make(map[string]int),
+	}
+	imports := make(map[string]map[string]string)
+	levels := make(map[string]map[string]int)
+	libraries := make(map[string]map[string]int)
+	loggers := make(map[string]map[string]int)
+
+	for _, record := range t.Rows {
+		get := func(name string) string {
+			return t.Get(record, name)
+		}
+
+		filePath := get("FilePath")
+		report.Total++
+		report.ByLevel[get("LogLevel")]++
+		pkg := get("Package")
+		report.ByPackage[pkg]++
+		if levels[pkg] == nil {
+			levels[pkg] = make(map[string]int)
+		}
+		levels[pkg][get("LogLevel")]++
+		report.ByFile[filePath]++
+
+		lib := get("SourceLibrary")
+		if lib == "" {
+			libs, ok := imports[filePath]
+			if !ok {
+				libs = fileLibraries(filePath)
+				imports[filePath] = libs
+			}
+			lib = library(get("OriginalCall"), libs)
+		}
+		report.ByLibrary[lib]++
+		if libraries[pkg] == nil {
+			libraries[pkg] = make(map[string]int)
+			loggers[pkg] = make(map[string]int)
+		}
+		libraries[pkg][lib]++
+		if logger := LoggerName(get("OriginalCall"), get("Receiver"), lib); logger != "" {
+			loggers[pkg][logger]++
+		}
+
+		newMessage, newCall := get("NewMessage"), get("NewCall")
+		if newMessage != "" {
+			report.WithNewMessage++
+		}
+		if get("StructuredFields") != "" {
+			report.WithStructuredFields++
+		}
+		if newMessage != "" || newCall != "" {
+			report.Ready++
+		}
+	}
+	report.LevelAnomalies = LevelAnomalies(levels)
+	report.Unification = UnificationPlan(libraries, loggers)
+
+	return report, nil
+}
+
+// fileLibraries maps the local names of a file's logging imports to their
+// library names. It returns an empty map if the file can't be read.
+func fileLibraries(filePath string) map[string]string {
+	libs := make(map[string]string)
+	node, err := parser.ParseFile(token.NewFileSet(), filePath, nil, parser.ImportsOnly)
+	if err != nil {
+		return libs
+	}
+	for _, imp := range node.Imports {
+		importPath, _ := strconv.Unquote(imp.Path.Value)
+		name, ok := scaffold.LibraryName(importPath)
+		if !ok {
+			continue
+		}
+		local := path.Base(importPath)
+		if strings.HasPrefix(local, "v") && strings.Trim(local[1:], "0123456789") == "" {
+			local = path.Base(path.Dir(importPath)) // k8s.io/klog/v2
+		}
+		if imp.Name != nil {
+			local = imp.Name.Name
+		}
+		libs[local] = name
+	}
+	return libs
+}
+
+// library attributes a call to a library: the import its receiver names, or
+// the file's only logging import (for calls on logger variables)
+func library(originalCall string, libs map[string]string) string {
+	receiver := originalCall
+	if dot := strings.Index(receiver, "."); dot != -1 {
+		receiver = receiver[:dot]
+	}
+	if name, ok := libs[receiver]; ok {
+		return name
+	}
+	if len(libs) == 1 {
+		for _, name := range libs {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// Text renders the report as plain text. top limits the package, file and
+// unification plan lists (0 = no limit).
+func (r *Report) Text(top int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Entries: %d\n", r.Total)
+	fmt.Fprintf(&b, "  NewMessage filled:       %s\n", ratio(r.WithNewMessage, r.Total))
+	fmt.Fprintf(&b, "  StructuredFields filled: %s\n", ratio(r.WithStructuredFields, r.Total))
+	fmt.Fprintf(&b, "  Ready to transform:      %s\n", ratio(r.Ready, r.Total))
+
+	writeCounts(&b, "By library", r.ByLibrary, 0)
+	writeCounts(&b, "By level", r.ByLevel, 0)
+	writeCounts(&b, "By package", r.ByPackage, top)
+	writeCounts(&b, "By file", r.ByFile, top)
+
+	if len(r.LevelAnomalies) > 0 {
+		fmt.Fprintf(&b, "\nLevel anomalies:\n")
+		for _, a := range r.LevelAnomalies {
+			fmt.Fprintf(&b, "  %-40s %s\n", a.Package, a)
+		}
+	}
+
+	if len(r.Unification) > 0 {
+		fmt.Fprintf(&b, "\nUnification plan:\n")
+		for i, p := range r.Unification {
+			if top > 0 && i == top {
+				fmt.Fprintf(&b, "  ... %d more\n", len(r.Unification)-top)
+				break
+			}
+			mixed := ""
+			if p.Mixed() {
+				mixed = "mixed: "
+			}
+			fmt.Fprintf(&b, "  %-40s %s%s\n", p.Package, mixed, p)
+		}
+	}
+	return b.String()
+}
+
+func ratio(n, total int) string {
+	if total == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%d (%.0f%%)", n, float64(n)*100/float64(total))
+}
+
+// writeCounts writes a titled list of counts, largest first
+func writeCounts(b *strings.Builder, title string, counts map[string]int, top int) {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	fmt.Fprintf(b, "\n%s:\n", title)
+	for i, k := range keys {
+		if top > 0 && i == top {
+			fmt.Fprintf(b, "  ... %d more\n", len(keys)-top)
+			break
+		}
+		label := k
+		if label == "" {
+			label = "(none)"
+		}
+		fmt.Fprintf(b, "  %-40s %d\n", label, counts[k])
+	}
+}
@@ -0,0 +1,222 @@
+// Package suggest turns transform changes into code review suggestions in
+// the shape the GitHub and GitLab APIs take, so a bot can post each rewrite
+// as an inline suggestion on an open pull or merge request.
+package suggest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"logrefactor/pkg/transformer"
+)
+
+// Formats
+const (
+	GitHub = "github"
+	GitLab = "gitlab"
+)
+
+// Suggestion replaces whole lines of a file
+type Suggestion struct {
+	IDs         []string
+	Path        string // Relative to the repository root, with forward slashes
+	StartLine   int
+	EndLine     int
+	Replacement string // The new lines, without the final newline
+}
+
+// Build turns changes into suggestions. A suggestion replaces the lines a
+// call spans; calls sharing a line become one suggestion. The files must
+// still hold the original code (i.e. after a dry run). Paths are made
+// relative to root.
+func Build(changes []transformer.Change, root string) ([]Suggestion, error) {
+	byFile := make(map[string][]transformer.Change)
+	for _, c := range changes {
+		byFile[c.File] = append(byFile[c.File], c)
+	}
+	files := make([]string, 0, len(byFile))
+	for file := range byFile {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	var suggestions []Suggestion
+	for _, file := range files {
+		rel, err := relative(root, file)
+		if err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		fileSuggestions, err := build(byFile[file], rel, string(content))
+		if err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, fileSuggestions...)
+	}
+	return suggestions, nil
+}
+
+// build makes the suggestions for one file
+func build(changes []transformer.Change, path, content string) ([]Suggestion, error) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Line != changes[j].Line {
+			return changes[i].Line < changes[j].Line
+		}
+		return changes[i].Column < changes[j].Column
+	})
+
+	// starts[i] is the offset of line i+1
+	starts := []int{0}
+	for i, r := range content {
+		if r == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	lineEnd := func(line int) int {
+		if line < len(starts) {
+			return starts[line] - 1
+		}
+		return len(content)
+	}
+
+	var suggestions []Suggestion
+	for i := 0; i < len(changes); {
+		// Group the changes whose lines overlap
+		first := changes[i].Line
+		last := first + strings.Count(changes[i].Old, "\n")
+		j := i + 1
+		for ; j < len(changes) && changes[j].Line <= last; j++ {
+			if end := changes[j].Line + strings.Count(changes[j].Old, "\n"); end > last {
+				last = end
+			}
+		}
+		if first < 1 || last > len(starts) {
+			return nil, fmt.Errorf("%s: line %d is past the end of the file", path, last)
+		}
+
+		blockStart := starts[first-1]
+		block := content[blockStart:lineEnd(last)]
+		s := Suggestion{Path: path, StartLine: first, EndLine: last}
+		for k := j - 1; k >= i; k-- {
+			c := changes[k]
+			offset := starts[c.Line-1] + c.Column - 1 - blockStart
+			if offset < 0 || !strings.HasPrefix(block[offset:], c.Old) {
+				return nil, fmt.Errorf("%s:%d: %s no longer matches the file", path, c.Line, c.ID)
+			}
+			block = block[:offset] + c.New + block[offset+len(c.Old):]
+			s.IDs = append([]string{c.ID}, s.IDs...)
+		}
+		s.Replacement = block
+		suggestions = append(suggestions, s)
+		i = j
+	}
+	return suggestions, nil
+}
+
+func relative(root, file string) (string, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(absRoot, absFile)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// body is the comment text: the entries and a suggestion block. GitLab
+// needs the number of lines below the commented one in the fence.
+func body(s Suggestion, format string) string {
+	fence := "```"
+	for strings.Contains(s.Replacement, fence) {
+		fence += "`"
+	}
+	info := "suggestion"
+	if format == GitLab {
+		info = fmt.Sprintf("suggestion:-0+%d", s.EndLine-s.StartLine)
+	}
+	return fmt.Sprintf("Structured logging rewrite for %s (logrefactor).\n\n%s%s\n%s\n%s\n",
+		strings.Join(s.IDs, ", "), fence, info, s.Replacement, fence)
+}
+
+// githubComment is a review comment as the GitHub pull request review API
+// takes it
+type githubComment struct {
+	Path      string `json:"path"`
+	Line      int    `json:"line"`
+	Side      string `json:"side"`
+	StartLine int    `json:"start_line,omitempty"`
+	StartSide string `json:"start_side,omitempty"`
+	Body      string `json:"body"`
+}
+
+// gitlabDiscussion is a merge request discussion as the GitLab API takes
+// it. The SHAs come from the merge request's diff_refs.
+type gitlabDiscussion struct {
+	Body     string `json:"body"`
+	Position struct {
+		PositionType string `json:"position_type"`
+		BaseSHA      string `json:"base_sha"`
+		StartSHA     string `json:"start_sha"`
+		HeadSHA      string `json:"head_sha"`
+		OldPath      string `json:"old_path"`
+		NewPath      string `json:"new_path"`
+		NewLine      int    `json:"new_line"`
+	} `json:"position"`
+}
+
+// Write writes the suggestions as JSON. For GitHub that is the body of a
+// "create a review" request (POST /repos/{owner}/{repo}/pulls/{number}/reviews);
+// for GitLab a list of bodies for "create a merge request thread"
+// (POST /projects/{id}/merge_requests/{iid}/discussions), whose position
+// SHAs the poster fills in from the merge request's diff_refs.
+func Write(w io.Writer, suggestions []Suggestion, format string) error {
+	var v interface{}
+	switch format {
+	case GitHub:
+		review := struct {
+			Event    string          `json:"event"`
+			Body     string          `json:"body"`
+			Comments []githubComment `json:"comments"`
+		}{Event: "COMMENT", Comments: []githubComment{}}
+		review.Body = fmt.Sprintf("logrefactor suggests %d structured logging rewrites.", len(suggestions))
+		for _, s := range suggestions {
+			c := githubComment{Path: s.Path, Line: s.EndLine, Side: "RIGHT", Body: body(s, format)}
+			if s.StartLine != s.EndLine {
+				c.StartLine, c.StartSide = s.StartLine, "RIGHT"
+			}
+			review.Comments = append(review.Comments, c)
+		}
+		v = review
+	case GitLab:
+		discussions := []gitlabDiscussion{}
+		for _, s := range suggestions {
+			d := gitlabDiscussion{Body: body(s, format)}
+			d.Position.PositionType = "text"
+			d.Position.OldPath, d.Position.NewPath = s.Path, s.Path
+			d.Position.NewLine = s.StartLine
+			discussions = append(discussions, d)
+		}
+		v = discussions
+	default:
+		return fmt.Errorf("unknown suggestion format %q (use %s or %s)", format, GitHub, GitLab)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,272 @@
+package table
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	_ "modernc.org/sqlite" // Registers the "sqlite" driver
+)
+
+// Entries can be kept in an SQLite database instead of a CSV file, for
+// migrations too large to pass a CSV around: collect, serve, transform and
+// every other command read and write the same file, and transform records
+// which entries it applied. A database is used when the path has one of
+// these extensions. The rows are held in an "entries" table with a TEXT
+// column per CSV column, in collect order.
+var databaseExtensions = []string{".db", ".sqlite", ".sqlite3"}
+
+// IsDatabase reports whether path names an SQLite database rather than a CSV
+// file
+func IsDatabase(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range databaseExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// openDatabase opens an SQLite database, waiting for other commands (e.g.
+// serve) that are writing it
+func openDatabase(path string) (*sql.DB, error) {
+	return sql.Open("sqlite", "file:"+filepath.ToSlash(path)+"?_pragma=busy_timeout(10000)")
+}
+
+// openDatabaseReader starts reading the entries of a database, in collect
+// order
+func openDatabaseReader(path string) (*Reader, error) {
+	// Opening a database that doesn't exist would create it
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	db, err := openDatabase(path)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query("SELECT * FROM entries ORDER BY rowid")
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	header, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		db.Close()
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	r := &Reader{
+		Table:  New(header),
+		path:   path,
+		db:     db,
+		rows:   rows,
+		values: make([]sql.NullString, len(header)),
+		dest:   make([]interface{}, len(header)),
+	}
+	for i := range r.values {
+		r.dest[i] = &r.values[i]
+	}
+	return r, nil
+}
+
+// writeDatabase replaces the entries in a database with the table's, in one
+// transaction
+func writeDatabase(path string, t *Table) error {
+	w, err := Create(path, t.Header)
+	if err != nil {
+		return err
+	}
+	if err := w.Append(t.Rows); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// Mark sets column to value in the rows of a database whose ID is one of
+// ids, adding the column if needed. Unlike Read and Write it works in
+// place, without loading the table.
+func Mark(path, column, value string, ids []string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	db, err := openDatabase(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := mark(tx, column, value, ids); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to update %s: %w", path, err)
+	}
+	return tx.Commit()
+}
+
+func mark(tx *sql.Tx, column, value string, ids []string) error {
+	rows, err := tx.Query("SELECT * FROM entries LIMIT 0")
+	if err != nil {
+		return err
+	}
+	header, err := rows.Columns()
+	rows.Close()
+	if err != nil {
+		return err
+	}
+	if !New(header).Has(column) {
+		if _, err := tx.Exec("ALTER TABLE entries ADD COLUMN " + quote(column) + " TEXT"); err != nil {
+			return err
+		}
+	}
+
+	// The IDs go in a temporary table, so the update is a single pass
+	// rather than a scan per ID
+	if _, err := tx.Exec("CREATE TEMP TABLE marked (id TEXT PRIMARY KEY)"); err != nil {
+		return err
+	}
+	defer tx.Exec("DROP TABLE temp.marked")
+	stmt, err := tx.Prepare("INSERT OR IGNORE INTO marked VALUES (?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, id := range ids {
+		if _, err := stmt.Exec(id); err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec("UPDATE entries SET "+quote(column)+" = ? WHERE \"ID\" IN (SELECT id FROM marked)", value)
+	return err
+}
+
+// Writer writes a table as its rows become available, so a long run keeps
+// memory flat and leaves the rows written so far if it is interrupted
+type Writer struct {
+	header []string
+
+	// CSV file
+	file *os.File
+	csv  *csv.Writer
+
+	// Database
+	db     *sql.DB
+	insert string
+}
+
+// Create starts a CSV file or database (see IsDatabase) with the given
+// columns. An existing file or table is replaced.
+func Create(path string, header []string) (*Writer, error) {
+	w := &Writer{header: append([]string(nil), header...)}
+	if !IsDatabase(path) {
+		file, err := os.Create(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create CSV file: %w", err)
+		}
+		w.file, w.csv = file, csv.NewWriter(file)
+		if err := w.csv.Write(header); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to write CSV header: %w", err)
+		}
+		return w, nil
+	}
+
+	db, err := openDatabase(path)
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]string, len(header))
+	params := make([]string, len(header))
+	for i, name := range header {
+		columns[i] = quote(name) + " TEXT"
+		params[i] = "?"
+	}
+	tx, err := db.Begin()
+	if err == nil {
+		if _, err = tx.Exec("DROP TABLE IF EXISTS entries"); err == nil {
+			_, err = tx.Exec("CREATE TABLE entries (" + strings.Join(columns, ", ") + ")")
+		}
+		if err == nil {
+			err = tx.Commit()
+		} else {
+			tx.Rollback()
+		}
+	}
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	w.db = db
+	w.insert = "INSERT INTO entries VALUES (" + strings.Join(params, ", ") + ")"
+	return w, nil
+}
+
+// Append writes rows, padded or cut to the header width, and makes them
+// durable before returning
+func (w *Writer) Append(rows [][]string) error {
+	if w.csv != nil {
+		for _, row := range rows {
+			for len(row) < len(w.header) {
+				row = append(row, "")
+			}
+			if err := w.csv.Write(row); err != nil {
+				return fmt.Errorf("failed to write CSV row: %w", err)
+			}
+		}
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+		return nil
+	}
+
+	tx, err := w.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(w.insert)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	args := make([]interface{}, len(w.header))
+	for _, row := range rows {
+		for i := range args {
+			args[i] = ""
+			if i < len(row) {
+				args[i] = row[i]
+			}
+		}
+		if _, err := stmt.Exec(args...); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to write row: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Close finishes the file
+func (w *Writer) Close() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			w.file.Close()
+			return err
+		}
+		return w.file.Close()
+	}
+	return w.db.Close()
+}
+
+// quote quotes an SQL identifier
+func quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,206 @@
+// Package table reads and writes entry CSV files by column name, so commands
+// that don't transform code keep working when users add, drop or reorder
+// columns.
+package table
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Table is a CSV file held in memory
+type Table struct {
+	Header  []string
+	Rows    [][]string
+	columns map[string]int
+}
+
+// New returns an empty table with the given columns
+func New(header []string) *Table {
+	t := &Table{Header: append([]string(nil), header...)}
+	t.index()
+	return t
+}
+
+// Read loads a CSV file. The first record is the header; rows may be
+// shorter or longer than it. A database (see IsDatabase) is read the same
+// way.
+func Read(path string) (*Table, error) {
+	r, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	t := New(r.Header)
+	for {
+		row, err := r.Next()
+		if err == io.EOF {
+			return t, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		t.Rows = append(t.Rows, row)
+	}
+}
+
+// Reader reads a CSV file or database a row at a time, for files too large
+// to hold in memory. The embedded Table has the header, for looking values
+// up by name; its Rows stay empty.
+type Reader struct {
+	*Table
+	path string
+
+	// CSV file
+	file *os.File
+	csv  *csv.Reader
+
+	// Database
+	db     *sql.DB
+	rows   *sql.Rows
+	values []sql.NullString
+	dest   []interface{}
+}
+
+// Open starts reading a CSV file or database (see IsDatabase)
+func Open(path string) (*Reader, error) {
+	if IsDatabase(path) {
+		return openDatabaseReader(path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		file.Close()
+		return nil, fmt.Errorf("%s is empty", path)
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return &Reader{Table: New(header), path: path, file: file, csv: reader}, nil
+}
+
+// Next returns the next row, or io.EOF after the last one. Rows are not
+// reused, so callers may keep them.
+func (r *Reader) Next() ([]string, error) {
+	if r.csv != nil {
+		row, err := r.csv.Read()
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read %s: %w", r.path, err)
+		}
+		return row, err
+	}
+
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", r.path, err)
+		}
+		return nil, io.EOF
+	}
+	if err := r.rows.Scan(r.dest...); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", r.path, err)
+	}
+	row := make([]string, len(r.values))
+	for i, v := range r.values {
+		row[i] = v.String
+	}
+	return row, nil
+}
+
+// Close releases the file
+func (r *Reader) Close() error {
+	if r.csv != nil {
+		return r.file.Close()
+	}
+	r.rows.Close()
+	return r.db.Close()
+}
+
+func (t *Table) index() {
+	t.columns = make(map[string]int, len(t.Header))
+	for i, name := range t.Header {
+		if _, ok := t.columns[name]; !ok {
+			t.columns[name] = i
+		}
+	}
+}
+
+// Has reports whether the table has a column
+func (t *Table) Has(name string) bool {
+	_, ok := t.columns[name]
+	return ok
+}
+
+// Require returns an error naming the first missing column
+func (t *Table) Require(names ...string) error {
+	for _, name := range names {
+		if !t.Has(name) {
+			return fmt.Errorf("CSV has no %s column", name)
+		}
+	}
+	return nil
+}
+
+// Get returns a row's value in the named column, or "" if the table or row
+// doesn't have it
+func (t *Table) Get(row []string, name string) string {
+	if i, ok := t.columns[name]; ok && i < len(row) {
+		return row[i]
+	}
+	return ""
+}
+
+// Set stores a value in the named column of a row, adding the column to
+// the table if needed, and returns the (possibly extended) row
+func (t *Table) Set(row []string, name, value string) []string {
+	i, ok := t.columns[name]
+	if !ok {
+		t.Header = append(t.Header, name)
+		i = len(t.Header) - 1
+		t.columns[name] = i
+	}
+	for len(row) <= i {
+		row = append(row, "")
+	}
+	row[i] = value
+	return row
+}
+
+// Write saves the table, padding short rows to the header width
+func (t *Table) Write(path string) error {
+	if IsDatabase(path) {
+		return writeDatabase(path, t)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create CSV file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write(t.Header); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	for _, row := range t.Rows {
+		for len(row) < len(t.Header) {
+			row = append(row, "")
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,101 @@
+package table
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetSet(t *testing.T) {
+	tab := New([]string{"ID", "Line"})
+	row := []string{"LOG-0001"}
+	if got := tab.Get(row, "Line"); got != "" {
+		t.Errorf("Get of a short row = %q, want \"\"", got)
+	}
+	row = tab.Set(row, "Notes", "kept")
+	if !tab.Has("Notes") || fmt.Sprint(tab.Header) != "[ID Line Notes]" || fmt.Sprint(row) != "[LOG-0001  kept]" {
+		t.Errorf("after Set: header %v, row %q", tab.Header, row)
+	}
+	if err := tab.Require("ID", "Status"); err == nil || err.Error() != "CSV has no Status column" {
+		t.Errorf("Require = %v, want the Status column missing", err)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, name := range []string{"entries.csv", "entries.db"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			w, err := Create(path, []string{"ID", "Line", "Notes"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Rows are padded or cut to the header
+			if err := w.Append([][]string{{"LOG-0001", "3"}, {"LOG-0002", "5", "a, \"quoted\" note"}}); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			tab, err := Read(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(tab.Header) != "[ID Line Notes]" || len(tab.Rows) != 2 {
+				t.Fatalf("read header %v and %d rows, want 3 columns and 2 rows", tab.Header, len(tab.Rows))
+			}
+			if got := tab.Get(tab.Rows[1], "Notes"); got != `a, "quoted" note` {
+				t.Errorf("Notes = %q", got)
+			}
+
+			// Rows are streamed in the order they were written
+			r, err := Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			var ids []string
+			for {
+				row, err := r.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				ids = append(ids, r.Get(row, "ID"))
+			}
+			if fmt.Sprint(ids) != "[LOG-0001 LOG-0002]" {
+				t.Errorf("IDs = %v", ids)
+			}
+		})
+	}
+}
+
+func TestMark(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "entries.sqlite")
+	tab := New([]string{"ID"})
+	tab.Rows = [][]string{{"LOG-0001"}, {"LOG-0002"}, {"LOG-0003"}}
+	if err := tab.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := Mark(path, "Applied", "yes", []string{"LOG-0001", "LOG-0003", "LOG-0003"}); err != nil {
+		t.Fatal(err)
+	}
+	tab, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var applied []string
+	for _, row := range tab.Rows {
+		applied = append(applied, tab.Get(row, "Applied"))
+	}
+	if fmt.Sprint(applied) != "[yes  yes]" {
+		t.Errorf("Applied = %q, want LOG-0001 and LOG-0003 marked", applied)
+	}
+
+	if err := Mark(filepath.Join(t.TempDir(), "missing.db"), "Applied", "yes", nil); err == nil {
+		t.Error("Mark of a missing database succeeded, want an error")
+	}
+}
@@ -1,61 +1,1922 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
 	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
 
-	"logrefactor/internal/collector"
-	"logrefactor/internal/transformer"
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"logrefactor/analyzer"
+	"logrefactor/internal/baseline"
+	"logrefactor/internal/cluster"
+	"logrefactor/internal/config"
+	"logrefactor/internal/coverage"
+	"logrefactor/internal/diff"
+	"logrefactor/internal/git"
+	"logrefactor/internal/github"
+	"logrefactor/internal/lint"
+	"logrefactor/internal/lsp"
+	"logrefactor/internal/merge"
+	"logrefactor/internal/normalize"
+	"logrefactor/internal/patch"
+	"logrefactor/internal/profile"
+	"logrefactor/internal/report"
+	"logrefactor/internal/sarif"
+	"logrefactor/internal/scaffold"
+	"logrefactor/internal/schema"
+	"logrefactor/internal/server"
+	"logrefactor/internal/stats"
+	"logrefactor/internal/suggest"
+	"logrefactor/pkg/collector"
+	"logrefactor/pkg/transformer"
 )
 
 func main() {
-	// Subcommands
+	if len(os.Args) < 2 {
+		fmt.Println("Usage:")
+		fmt.Println("  logrefactor collect [options]   - Collect and index log entries")
+		fmt.Println("  logrefactor transform [options] - Apply transformations from CSV")
+		fmt.Println("  logrefactor validate [options]  - Check an edited CSV before transform")
+		fmt.Println("  logrefactor stats [options]     - Summarize a CSV by level, package, file and library")
+		fmt.Println("  logrefactor verify [options]    - Confirm every edited entry was replaced")
+		fmt.Println("  logrefactor check [options]     - Fail when unstructured log calls are found (for CI)")
+		fmt.Println("  logrefactor lint [options]      - Flag log calls passing credentials or personal data")
+		fmt.Println("  logrefactor keys [options]      - Find field keys logged under several names or types")
+		fmt.Println("  logrefactor vet [flags] pkgs    - Run the analyzer on type-checked packages (-fix applies fixes)")
+		fmt.Println("  logrefactor revert [options]    - Restore the original code of transformed entries")
+		fmt.Println("  logrefactor merge [options]     - Carry edits over to a re-collected CSV")
+		fmt.Println("  logrefactor diff a.csv b.csv    - Compare the log entries of two CSVs")
+		fmt.Println("  logrefactor annotate [options]  - Insert TODO comments above collected calls")
+		fmt.Println("  logrefactor report [options]    - Write a Markdown or HTML migration report")
+		fmt.Println("  logrefactor coverage [options]  - Measure and record structured logging coverage")
+		fmt.Println("  logrefactor normalize [options] - Pre-fill NewMessage from the original messages")
+		fmt.Println("  logrefactor cluster [options]   - List entries with alike messages and unify their NewMessage")
+		fmt.Println("  logrefactor serve [options]     - Review and edit entries in a local web UI")
+		fmt.Println("  logrefactor api [options]       - Serve collect and transform as a JSON API")
+		fmt.Println("  logrefactor lsp [options]       - Language server offering to convert log calls in the editor")
+		fmt.Println("  logrefactor init [options]      - Write a starter .logrefactor.yaml")
+		fmt.Println("  logrefactor config validate     - Check config and template files against the schema")
+		fmt.Println("  logrefactor config schema       - Print the configuration JSON Schema")
+		fmt.Println("  logrefactor templates dump      - Print a built-in style as a custom template")
+		fmt.Println("\nExamples:")
+		fmt.Println("  logrefactor collect -path ./mypackage -output logs.csv")
+		fmt.Println("  logrefactor transform -input logs.csv -path ./mypackage")
+		fmt.Println("\nSettings are also read from .logrefactor.yaml in the project root;")
+		fmt.Println("command-line flags take precedence.")
+		os.Exit(1)
+	}
+
+	switch os.Args[1] {
+	case "collect":
+		runCollect(os.Args[2:])
+	case "transform":
+		runTransform(os.Args[2:])
+	case "validate":
+		runValidate(os.Args[2:])
+	case "stats":
+		runStats(os.Args[2:])
+	case "verify":
+		runVerify(os.Args[2:])
+	case "check":
+		runCheck(os.Args[2:])
+	case "lint":
+		runLint(os.Args[2:])
+	case "vet":
+		runVet(os.Args[2:])
+	case "revert":
+		runRevert(os.Args[2:])
+	case "merge":
+		runMerge(os.Args[2:])
+	case "diff":
+		runDiff(os.Args[2:])
+	case "annotate":
+		runAnnotate(os.Args[2:])
+	case "report":
+		runReport(os.Args[2:])
+	case "coverage":
+		runCoverage(os.Args[2:])
+	case "normalize":
+		runNormalize(os.Args[2:])
+	case "cluster":
+		runCluster(os.Args[2:])
+	case "keys":
+		runKeys(os.Args[2:])
+	case "serve":
+		runServe(os.Args[2:])
+	case "api":
+		runAPI(os.Args[2:])
+	case "lsp":
+		runLSP(os.Args[2:])
+	case "init":
+		runInit(os.Args[2:])
+	case "config":
+		runConfig(os.Args[2:])
+	case "templates":
+		runTemplates(os.Args[2:])
+	default:
+		// go vet -vettool runs the binary with flags or a .cfg file
+		if strings.HasPrefix(os.Args[1], "-") || strings.HasSuffix(os.Args[1], ".cfg") {
+			runVet(os.Args[1:])
+		}
+		fmt.Printf("Unknown command: %s\n", os.Args[1])
+		os.Exit(1)
+	}
+}
+
+func runCollect(args []string) {
 	collectCmd := flag.NewFlagSet("collect", flag.ExitOnError)
 	collectPath := collectCmd.String("path", ".", "Path to the Go project or package")
 	collectOutput := collectCmd.String("output", "log_entries.csv", "Output CSV file")
-	collectPattern := collectCmd.String("pattern", "log\\.|logrus\\.|logger\\.", "Regex pattern to match logging calls")
+	collectPattern := collectCmd.String("pattern", collector.DefaultPattern, "Regex pattern to match logging calls")
+	collectExclude := collectCmd.String("exclude", "", "Comma-separated paths or globs to skip (e.g. vendor,testdata)")
+	collectKeyStyle := collectCmd.String("key-style", "snake_case", "Suggested field key style: snake_case, camelCase, kebab-case or SCREAMING")
+	collectProjectConfig := collectCmd.String("project-config", "", "Project configuration file (default: .logrefactor.yaml in the project root)")
+	collectProfile := collectCmd.String("profile", "", "Named profile from the project configuration")
+	collectMatcher := collectCmd.String("matcher", "", "WASM plugin that decides which matched calls are log statements")
+	collectImports := collectCmd.String("imports", "", "Comma-separated import paths matched calls must belong to (\"default\" for the supported logging libraries)")
+	collectHelpers := collectCmd.Bool("helpers", false, "Also record calls to the project's logging helpers, small functions wrapping a single log call")
+	collectHotPaths := collectCmd.String("hot-paths", "", "Comma-separated globs of functions whose calls are hot, as Func or Type.Method (e.g. *.ServeHTTP); their entries get InLoop hot")
+	collectMessageRules := collectCmd.String("message-rules", "all", "Comma-separated message style rules: lowercase, punctuation, prefix, ascii, all or none (messages breaking one get a note and SuggestedMessage)")
+	collectSARIF := collectCmd.String("sarif", "", "Also write the entries as SARIF findings to this file, with the structured call as the fix")
+	collectConfig := collectCmd.String("config", "", "Template configuration file (JSON) used for the SARIF and annotation fixes")
+	collectFormat := collectCmd.String("format", "text", "Console output: text, or github to also print each entry as an Actions annotation")
+	collectSkipTests := collectCmd.Bool("skip-tests", false, "Skip _test.go files")
+	collectStaged := collectCmd.Bool("staged", false, "Only scan the Go files staged in git (for pre-commit hooks)")
+	collectJobs := collectCmd.Int("jobs", 0, "Number of files to parse in parallel (default: GOMAXPROCS; 1 parses serially)")
+	collectCache := collectCmd.String("cache", "", "Cache file of parsed entries, so files unchanged since the last run aren't parsed again")
+	collectProfiling := profile.Register(collectCmd)
+	collectCmd.Parse(args)
+	startProfiles(collectProfiling)
+	defer stopProfiles()
+
+	cfg := loadProjectConfig(*collectProjectConfig, *collectPath, *collectProfile)
+	set := setFlags(collectCmd)
+	override(set, "path", collectPath, cfg.Path)
+	override(set, "output", collectOutput, cfg.CSV)
+	override(set, "pattern", collectPattern, cfg.Pattern)
+	override(set, "key-style", collectKeyStyle, cfg.KeyStyle)
+	override(set, "matcher", collectMatcher, cfg.Matcher)
+	override(set, "cache", collectCache, cfg.Cache)
+
+	excludes := cfg.Exclude
+	if set["exclude"] {
+		excludes = splitList(*collectExclude)
+	}
+	imports := cfg.Imports
+	if set["imports"] {
+		imports = splitList(*collectImports)
+	}
+	if !set["helpers"] && cfg.Helpers != nil {
+		*collectHelpers = *cfg.Helpers
+	}
+	hotPaths := cfg.HotPaths
+	if set["hot-paths"] {
+		hotPaths = splitList(*collectHotPaths)
+	}
+	messageRules := cfg.MessageRules
+	if set["message-rules"] {
+		messageRules = splitList(*collectMessageRules)
+	}
+	if !set["skip-tests"] && cfg.SkipTests != nil {
+		*collectSkipTests = *cfg.SkipTests
+	}
+
+	if *collectFormat != "text" && *collectFormat != "github" {
+		fmt.Fprintf(os.Stderr, "Unknown format: %s (use text or github)\n", *collectFormat)
+		exit(1)
+	}
 
+	opts := collector.Options{
+		Root:         *collectPath,
+		Pattern:      *collectPattern,
+		KeyStyle:     *collectKeyStyle,
+		Excludes:     excludes,
+		SkipTests:    *collectSkipTests,
+		Matcher:      *collectMatcher,
+		Imports:      imports,
+		Helpers:      *collectHelpers,
+		HotPaths:     hotPaths,
+		MessageRules: messageRules,
+		Verbosity:    cfg.Verbosity,
+		Jobs:         *collectJobs,
+		Cache:        *collectCache,
+	}
+	ctx := interruptible()
+	var err error
+	if *collectStaged {
+		opts.Files, err = stagedFiles(*collectPath)
+	}
+	if err == nil {
+		err = collector.Collect(ctx, *collectOutput, opts)
+	}
+	if errors.Is(err, context.Canceled) {
+		fmt.Fprintf(os.Stderr, "Interrupted: %s has the entries of the files collected so far\n", *collectOutput)
+		exit(130)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error collecting log entries: %v\n", err)
+		exit(1)
+	}
+	fmt.Printf("Successfully collected log entries to %s\n", *collectOutput)
+
+	if *collectSARIF == "" && *collectFormat != "github" {
+		return
+	}
+	templateConfig, err := transformer.LoadTemplateConfig(*collectConfig, &cfg.TemplateConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading template config: %v\n", err)
+		exit(1)
+	}
+	findings, err := sarif.FromCSV(*collectOutput, templateConfig, messageRules)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", *collectOutput, err)
+		exit(1)
+	}
+	if *collectFormat == "github" {
+		if err := writeAnnotations(findings); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing annotations: %v\n", err)
+			exit(1)
+		}
+	}
+	if *collectSARIF != "" {
+		if err := writeSARIF(*collectSARIF, findings); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing SARIF: %v\n", err)
+			exit(1)
+		}
+		fmt.Printf("Wrote %d findings to %s\n", len(findings), *collectSARIF)
+	}
+}
+
+// writeAnnotations prints findings as GitHub Actions workflow commands,
+// with paths relative to the repository root
+func writeAnnotations(findings []sarif.Finding) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	return github.Write(os.Stdout, findings, patch.RepoRoot(cwd))
+}
+
+// stagedFiles returns the files staged in the git repository containing
+// path
+func stagedFiles(path string) ([]string, error) {
+	repo, err := git.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	staged, err := repo.Staged()
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(staged))
+	for _, file := range staged {
+		files = append(files, filepath.Join(repo.Root, filepath.FromSlash(file)))
+	}
+	return files, nil
+}
+
+// writeSARIF writes findings as SARIF, with paths relative to the
+// repository root
+func writeSARIF(file string, findings []sarif.Finding) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := sarif.Write(f, findings, patch.RepoRoot(cwd)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func runTransform(args []string) {
 	transformCmd := flag.NewFlagSet("transform", flag.ExitOnError)
 	transformInput := transformCmd.String("input", "log_entries.csv", "Input CSV file with updated entries")
 	transformPath := transformCmd.String("path", ".", "Path to the Go project or package")
 	transformDryRun := transformCmd.Bool("dry-run", false, "Show changes without applying them")
 	transformConfig := transformCmd.String("config", "", "Template configuration file (JSON)")
+	transformStyle := transformCmd.String("style", "", "Output style (overrides the template configuration)")
+	transformLoggerVar := transformCmd.String("logger-var", "", "Logger variable name (overrides the template configuration)")
+	transformKeyStyle := transformCmd.String("key-style", "", "Field key style (overrides the template configuration)")
 	transformAutoMap := transformCmd.Bool("auto-map", true, "Auto-generate field mappings from ArgumentDetails when StructuredFields is empty")
+	transformKeyConstants := transformCmd.String("key-constants", "", "Go file for shared field key constants (e.g. logkeys/keys.go); generated calls reference them")
+	transformOnlyApproved := transformCmd.Bool("only-approved", false, "Apply only entries whose Status is approved or whose Approved column is filled in")
+	transformMinConfidence := transformCmd.Float64("min-confidence", 0, "Hold entries whose auto-mapped fields have a FieldConfidence below this (0 to 1), for review")
+	transformTestLogs := transformCmd.Bool("test-logs", false, "Also apply entries of test output (SourceLibrary testing, e.g. t.Logf), which are skipped by default")
+	transformLogAndReturn := transformCmd.String("log-and-return", transformer.LogAndReturnKeep, "Entries logging an error their function then returns (Returns column): keep migrates the call, wrap drops it and returns the error wrapped with fmt.Errorf")
+	transformRewriteHelpers := transformCmd.Bool("rewrite-helpers", false, "Rewrite logging helpers (Helper column) to take a message and key/value pairs, and their calls to pass them, instead of migrating the calls")
+	transformMessageRules := transformCmd.String("message-rules", "all", "Comma-separated message style rules: lowercase, punctuation, prefix, ascii, all or none (entries whose NewMessage breaks one are held)")
+	transformAllowSensitive := transformCmd.Bool("allow-sensitive", false, "Also apply entries whose fields look like credentials (passwords, tokens, ...), which are held by default")
+	transformIDs := transformCmd.String("ids", "", "Comma-separated entry IDs to apply (default: all)")
+	transformIDFile := transformCmd.String("id-file", "", "File listing entry IDs to apply, one per line")
+	transformBranch := transformCmd.String("branch", "", "Create and switch to this git branch before transforming")
+	transformCommit := transformCmd.String("commit", "", "Commit the changes, one commit per package or file")
+	transformPRBody := transformCmd.String("pr-body", "", "With -commit, write a Markdown pull request description to this file")
+	transformSuggestions := transformCmd.String("suggestions", "", "Write the changes as review suggestions (JSON) to this file instead of editing files")
+	transformSuggestionFormat := transformCmd.String("suggestion-format", "github", "Format of -suggestions: github or gitlab")
+	transformPatch := transformCmd.String("patch", "", "Write the changes as a unified diff to this file instead of editing files")
+	transformOutDir := transformCmd.String("out-dir", "", "Write the changed files under this directory, at their paths relative to -path, leaving the originals untouched")
+	transformFormat := transformCmd.String("format", "text", "Output format of the changes: text or json (json requires -dry-run)")
+	transformList := transformCmd.Bool("l", false, "List the files that would change instead of changing them; exit 1 if there are any")
+	transformHTML := transformCmd.String("html", "", "With -dry-run, write a side-by-side HTML preview of the changes to this file")
+	transformJournal := transformCmd.String("journal", "", "File recording applied edits for revert (default: "+transformer.DefaultJournal+" next to -input; empty to disable)")
+	transformProjectConfig := transformCmd.String("project-config", "", "Project configuration file (default: .logrefactor.yaml in the project root)")
+	transformProfile := transformCmd.String("profile", "", "Named profile from the project configuration")
+	transformJobs := transformCmd.Int("jobs", 0, "Number of files to transform in parallel (default: GOMAXPROCS; 1 transforms serially)")
+	transformProfiling := profile.Register(transformCmd)
+	transformCmd.Parse(args)
+	startProfiles(transformProfiling)
+	defer stopProfiles()
 
-	if len(os.Args) < 2 {
-		fmt.Println("Usage:")
-		fmt.Println("  logrefactor collect [options]   - Collect and index log entries")
-		fmt.Println("  logrefactor transform [options] - Apply transformations from CSV")
-		fmt.Println("\nExamples:")
-		fmt.Println("  logrefactor collect -path ./mypackage -output logs.csv")
-		fmt.Println("  logrefactor transform -input logs.csv -path ./mypackage")
+	cfg := loadProjectConfig(*transformProjectConfig, *transformPath, *transformProfile)
+	set := setFlags(transformCmd)
+	override(set, "input", transformInput, cfg.CSV)
+	override(set, "path", transformPath, cfg.Path)
+	if !set["journal"] {
+		*transformJournal = transformer.JournalFor(*transformInput)
+	}
+	override(set, "key-constants", transformKeyConstants, cfg.KeyConstants)
+	if !set["auto-map"] && cfg.AutoMap != nil {
+		*transformAutoMap = *cfg.AutoMap
+	}
+	if !set["only-approved"] && cfg.OnlyApproved != nil {
+		*transformOnlyApproved = *cfg.OnlyApproved
+	}
+	if !set["test-logs"] && cfg.TestLogs != nil {
+		*transformTestLogs = *cfg.TestLogs
+	}
+	if !set["min-confidence"] && cfg.MinConfidence != nil {
+		*transformMinConfidence = *cfg.MinConfidence
+	}
+	override(set, "log-and-return", transformLogAndReturn, cfg.LogAndReturn)
+	if !set["rewrite-helpers"] && cfg.RewriteHelpers != nil {
+		*transformRewriteHelpers = *cfg.RewriteHelpers
+	}
+	if !set["allow-sensitive"] && cfg.AllowSensitive != nil {
+		*transformAllowSensitive = *cfg.AllowSensitive
+	}
+	messageRules := cfg.MessageRules
+	if set["message-rules"] {
+		messageRules = splitList(*transformMessageRules)
+	}
+
+	// Precedence: project config < template file (-config) < flags
+	templateConfig, err := transformer.LoadTemplateConfig(*transformConfig, &cfg.TemplateConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading template config: %v\n", err)
+		exit(1)
+	}
+	if set["style"] {
+		templateConfig.Style = *transformStyle
+	}
+	if set["logger-var"] {
+		templateConfig.LoggerVar = *transformLoggerVar
+	}
+	if set["key-style"] {
+		templateConfig.KeyStyle = *transformKeyStyle
+	}
+	templateConfig.SetJobs(*transformJobs)
+
+	ids := splitList(*transformIDs)
+	if *transformIDFile != "" {
+		fileIDs, err := readIDFile(*transformIDFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading ID file: %v\n", err)
+			exit(1)
+		}
+		ids = append(ids, fileIDs...)
+	}
+
+	// Every file transform changes, or would change in a dry run
+	var files []patch.File
+	templateConfig.OnFile(func(path string, old, new []byte) {
+		files = append(files, patch.File{Path: path, Old: old, New: new})
+	})
+	// -patch writes a diff instead of the files, and -l lists them
+	if *transformPatch != "" {
+		*transformDryRun = true
+	}
+	if *transformList {
+		if *transformPatch != "" || *transformSuggestions != "" || *transformHTML != "" {
+			fmt.Fprintf(os.Stderr, "Error: -l can't be used with -patch, -suggestions or -html\n")
+			exit(1)
+		}
+		*transformDryRun = true
+		templateConfig.SetOutput(io.Discard)
+	}
+	if *transformSuggestions != "" {
+		if *transformSuggestionFormat != suggest.GitHub && *transformSuggestionFormat != suggest.GitLab {
+			fmt.Fprintf(os.Stderr, "Error: -suggestion-format must be %s or %s\n", suggest.GitHub, suggest.GitLab)
+			exit(1)
+		}
+		*transformDryRun = true
+	}
+	// With -format json, stdout is only the JSON document
+	status := os.Stdout
+	switch *transformFormat {
+	case "text":
+	case "json":
+		if !*transformDryRun || *transformList {
+			fmt.Fprintf(os.Stderr, "Error: -format json requires -dry-run and can't be used with -l\n")
+			exit(1)
+		}
+		templateConfig.SetOutput(io.Discard)
+		status = os.Stderr
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown format: %s (use text or json)\n", *transformFormat)
+		exit(1)
+	}
+	if *transformHTML != "" && !*transformDryRun {
+		fmt.Fprintf(os.Stderr, "Error: -html requires -dry-run\n")
+		exit(1)
+	}
+	var changes []transformer.Change
+	templateConfig.OnChange(func(c transformer.Change) {
+		changes = append(changes, c)
+	})
+
+	// -branch and -commit work on the repository containing -path
+	var repo *git.Repo
+	var dirty map[string]bool
+	if *transformBranch != "" || *transformCommit != "" {
+		if *transformDryRun {
+			fmt.Fprintf(os.Stderr, "Error: -branch and -commit can't be used with -dry-run or -patch\n")
+			exit(1)
+		}
+		if repo, err = git.Open(*transformPath); err == nil {
+			dirty, err = repo.Dirty()
+		}
+		if err == nil && *transformBranch != "" {
+			err = repo.CreateBranch(*transformBranch)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+	}
+	if *transformCommit != "" && *transformCommit != git.ByPackage && *transformCommit != git.ByFile {
+		fmt.Fprintf(os.Stderr, "Error: -commit must be %s or %s\n", git.ByPackage, git.ByFile)
+		exit(1)
+	}
+	if *transformPRBody != "" && *transformCommit == "" {
+		fmt.Fprintf(os.Stderr, "Error: -pr-body requires -commit\n")
+		exit(1)
+	}
+	// -out-dir writes a changed copy of the files, with nothing to revert
+	var outFS transformer.WriteFS
+	if *transformOutDir != "" {
+		if repo != nil {
+			fmt.Fprintf(os.Stderr, "Error: -out-dir can't be used with -branch or -commit\n")
+			exit(1)
+		}
+		root, err := filepath.Abs(*transformPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		outFS = copyFS{root: root, dir: *transformOutDir}
+		*transformJournal = ""
+	}
+
+	ctx := interruptible()
+	err = transformer.Transform(ctx, *transformInput, transformer.Options{
+		Config:         templateConfig,
+		DryRun:         *transformDryRun,
+		AutoMap:        *transformAutoMap,
+		KeyConstants:   *transformKeyConstants,
+		Journal:        *transformJournal,
+		OnlyApproved:   *transformOnlyApproved,
+		TestLogs:       *transformTestLogs,
+		MinConfidence:  *transformMinConfidence,
+		AllowSensitive: *transformAllowSensitive,
+		LogAndReturn:   *transformLogAndReturn,
+		RewriteHelpers: *transformRewriteHelpers,
+		MessageRules:   messageRules,
+		IDs:            ids,
+		FS:             outFS,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error transforming log entries: %v\n", err)
+		if errors.Is(err, context.Canceled) {
+			// The files already started were finished and journaled
+			fmt.Fprintln(os.Stderr, "Interrupted: only the files listed above were transformed")
+			exit(130)
+		}
+		exit(1)
+	}
+	if *transformCommit != "" {
+		if err := commitChanges(repo, changes, files, dirty, *transformCommit, *transformBranch, *transformPRBody); err != nil {
+			fmt.Fprintf(os.Stderr, "Error committing: %v\n", err)
+			exit(1)
+		}
+	}
+	if *transformSuggestions != "" {
+		count, err := writeSuggestions(*transformSuggestions, changes, *transformSuggestionFormat)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing suggestions: %v\n", err)
+			exit(1)
+		}
+		fmt.Fprintf(status, "Wrote %d suggestions to %s\n", count, *transformSuggestions)
+	}
+	if *transformPatch != "" {
+		if err := writePatch(*transformPatch, files); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing patch: %v\n", err)
+			exit(1)
+		}
+		fmt.Fprintf(status, "Wrote patch for %d files to %s (apply with git apply)\n", len(files), *transformPatch)
+	}
+	if *transformHTML != "" {
+		if err := writePreview(*transformHTML, changes); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing preview: %v\n", err)
+			exit(1)
+		}
+		fmt.Fprintf(status, "Wrote preview of %d changes to %s\n", len(changes), *transformHTML)
+	}
+	if *transformList {
+		paths := make([]string, 0, len(files))
+		for _, f := range files {
+			paths = append(paths, f.Path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			fmt.Println(path)
+		}
+		if len(paths) > 0 {
+			exit(1)
+		}
+		return
+	}
+	if *transformFormat == "json" {
+		out := struct {
+			Changes []transformer.Change `json:"changes"`
+			DryRun  bool                 `json:"dryRun"`
+		}{Changes: changes, DryRun: true}
+		if out.Changes == nil {
+			out.Changes = []transformer.Change{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		enc.Encode(out)
+		return
+	}
+	if *transformDryRun {
+		fmt.Println("Dry run completed - no files were modified")
+	} else {
+		fmt.Println("Successfully transformed log entries")
+	}
+}
+
+// commitChanges commits the files transform wrote, grouped by package or
+// file. Files that already had uncommitted changes are left out, so the
+// commits only contain logrefactor's edits.
+func commitChanges(repo *git.Repo, changes []transformer.Change, written []patch.File, dirty map[string]bool, by, branch, prBody string) error {
+	var files []string
+	for _, file := range written {
+		rel, err := repo.Rel(file.Path)
+		if err != nil {
+			return err
+		}
+		if dirty[rel] {
+			fmt.Fprintf(os.Stderr, "Warning: %s had uncommitted changes; leaving it out of the commits\n", rel)
+			continue
+		}
+		files = append(files, file.Path)
+	}
+
+	commits, err := repo.Plan(changes, files, by)
+	if err != nil {
+		return err
+	}
+	for _, c := range commits {
+		if err := repo.Commit(c.Message, c.Files); err != nil {
+			return err
+		}
+		if len(c.IDs) == 0 {
+			fmt.Printf("Committed: %s\n", c.Title)
+			continue
+		}
+		fmt.Printf("Committed: %s (%d entries)\n", c.Title, len(c.IDs))
+	}
+	if prBody != "" {
+		if err := os.WriteFile(prBody, []byte(git.PullRequest(branch, commits)), 0644); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote pull request description to %s\n", prBody)
+	}
+	return nil
+}
+
+// writePatch writes a unified diff of files, with paths relative to the
+// repository root
+func writePatch(file string, files []patch.File) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := patch.Write(f, files, patch.RepoRoot(cwd)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// copyFS reads source files where they are and writes them under dir, at
+// their path relative to root, for transform -out-dir. Names are OS paths,
+// as in the FilePath column.
+type copyFS struct {
+	root, dir string
+}
+
+func (c copyFS) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+func (c copyFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(c.root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%s is outside %s", name, c.root)
+	}
+	return transformer.DirFS(c.dir).WriteFile(filepath.ToSlash(rel), data, perm)
+}
+
+// writeSuggestions writes review suggestions for the changes of a dry run,
+// with paths relative to the repository root, and returns how many
+func writeSuggestions(file string, changes []transformer.Change, format string) (int, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return 0, err
+	}
+	suggestions, err := suggest.Build(changes, patch.RepoRoot(cwd))
+	if err != nil {
+		return 0, err
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return 0, err
+	}
+	if err := suggest.Write(f, suggestions, format); err != nil {
+		f.Close()
+		return 0, err
+	}
+	return len(suggestions), f.Close()
+}
+
+// writePreview writes the HTML preview of a dry run
+func writePreview(file string, changes []transformer.Change) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := report.BuildPreview(changes, "Transform preview").HTML(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func runValidate(args []string) {
+	validateCmd := flag.NewFlagSet("validate", flag.ExitOnError)
+	validateInput := validateCmd.String("input", "log_entries.csv", "Edited CSV file to check")
+	validatePath := validateCmd.String("path", ".", "Where to look for .logrefactor.yaml")
+	validateProjectConfig := validateCmd.String("project-config", "", "Project configuration file (default: .logrefactor.yaml in the project root)")
+	validateCmd.Parse(args)
+
+	cfg := loadProjectConfig(*validateProjectConfig, *validatePath, "")
+	override(setFlags(validateCmd), "input", validateInput, cfg.CSV)
+
+	issues, err := transformer.ValidateCSV(*validateInput)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error validating %s: %v\n", *validateInput, err)
 		os.Exit(1)
 	}
 
-	switch os.Args[1] {
-	case "collect":
-		collectCmd.Parse(os.Args[2:])
-		if err := collector.Collect(*collectPath, *collectOutput, *collectPattern); err != nil {
-			fmt.Fprintf(os.Stderr, "Error collecting log entries: %v\n", err)
+	errors := 0
+	for _, issue := range issues {
+		fmt.Printf("%s:%s\n", *validateInput, issue)
+		if !issue.Warning {
+			errors++
+		}
+	}
+	fmt.Printf("%d errors, %d warnings\n", errors, len(issues)-errors)
+	if errors > 0 {
+		os.Exit(1)
+	}
+}
+
+func runStats(args []string) {
+	statsCmd := flag.NewFlagSet("stats", flag.ExitOnError)
+	statsInput := statsCmd.String("input", "log_entries.csv", "Collected CSV file")
+	statsPath := statsCmd.String("path", ".", "Path to the Go project or package (scanned with -rescan)")
+	statsRescan := statsCmd.Bool("rescan", false, "Scan -path again instead of reading -input")
+	statsFormat := statsCmd.String("format", "text", "Output format: text or json")
+	statsTop := statsCmd.Int("top", 10, "Number of packages, files and unification plan entries to list (0 = all)")
+	statsProjectConfig := statsCmd.String("project-config", "", "Project configuration file (default: .logrefactor.yaml in the project root)")
+	statsProfile := statsCmd.String("profile", "", "Named profile from the project configuration")
+	statsCmd.Parse(args)
+
+	cfg := loadProjectConfig(*statsProjectConfig, *statsPath, *statsProfile)
+	set := setFlags(statsCmd)
+	override(set, "input", statsInput, cfg.CSV)
+	override(set, "path", statsPath, cfg.Path)
+
+	input := *statsInput
+	if *statsRescan {
+		tmp, err := os.CreateTemp("", "logrefactor-stats-*.csv")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
-		fmt.Printf("Successfully collected log entries to %s\n", *collectOutput)
+		tmp.Close()
+		defer os.Remove(tmp.Name())
 
-	case "transform":
-		transformCmd.Parse(os.Args[2:])
-		if err := transformer.Transform(*transformInput, *transformPath, *transformDryRun, *transformConfig, *transformAutoMap); err != nil {
-			fmt.Fprintf(os.Stderr, "Error transforming log entries: %v\n", err)
+		opts := collector.Options{Root: *statsPath, Pattern: cfg.Pattern, KeyStyle: cfg.KeyStyle, Excludes: cfg.Exclude, SkipTests: cfg.SkipTests != nil && *cfg.SkipTests, Matcher: cfg.Matcher, Imports: cfg.Imports, HotPaths: cfg.HotPaths, MessageRules: cfg.MessageRules, Verbosity: cfg.Verbosity}
+		if err := collector.Collect(context.Background(), tmp.Name(), opts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error collecting log entries: %v\n", err)
 			os.Exit(1)
 		}
-		if *transformDryRun {
-			fmt.Println("Dry run completed - no files were modified")
+		input = tmp.Name()
+	}
+
+	report, err := stats.FromCSV(input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", input, err)
+		os.Exit(1)
+	}
+
+	switch *statsFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	case "text":
+		fmt.Print(report.Text(*statsTop))
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q\n", *statsFormat)
+		os.Exit(1)
+	}
+}
+
+func runVerify(args []string) {
+	verifyCmd := flag.NewFlagSet("verify", flag.ExitOnError)
+	verifyInput := verifyCmd.String("input", "log_entries.csv", "CSV used for the transform")
+	verifyPath := verifyCmd.String("path", ".", "Path to the Go project or package")
+	verifyPattern := verifyCmd.String("pattern", collector.DefaultPattern, "Regex pattern to match logging calls")
+	verifyExclude := verifyCmd.String("exclude", "", "Comma-separated paths or globs to skip (e.g. vendor,testdata)")
+	verifyBuild := verifyCmd.Bool("build", false, "Also run go build ./... in -path")
+	verifyVerbose := verifyCmd.Bool("v", false, "List the calls that still use the original functions")
+	verifyStrict := verifyCmd.Bool("strict", false, "Fail if calls using the original functions remain")
+	verifyProjectConfig := verifyCmd.String("project-config", "", "Project configuration file (default: .logrefactor.yaml in the project root)")
+	verifyProfile := verifyCmd.String("profile", "", "Named profile from the project configuration")
+	verifyCmd.Parse(args)
+
+	cfg := loadProjectConfig(*verifyProjectConfig, *verifyPath, *verifyProfile)
+	set := setFlags(verifyCmd)
+	override(set, "input", verifyInput, cfg.CSV)
+	override(set, "path", verifyPath, cfg.Path)
+	override(set, "pattern", verifyPattern, cfg.Pattern)
+	excludes := cfg.Exclude
+	if set["exclude"] {
+		excludes = splitList(*verifyExclude)
+	}
+
+	opts := collector.Options{Root: *verifyPath, Pattern: *verifyPattern, KeyStyle: cfg.KeyStyle, Excludes: excludes, SkipTests: cfg.SkipTests != nil && *cfg.SkipTests, Matcher: cfg.Matcher, Imports: cfg.Imports, HotPaths: cfg.HotPaths, MessageRules: cfg.MessageRules, Verbosity: cfg.Verbosity}
+	scanned, err := collector.Run(interruptible(), opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error scanning %s: %v\n", *verifyPath, err)
+		os.Exit(1)
+	}
+	result, err := transformer.Verify(*verifyInput, scanned)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error verifying: %v\n", err)
+		os.Exit(1)
+	}
+
+	for _, update := range result.NotReplaced {
+		fmt.Printf("NOT REPLACED %s %s:%d: %s\n", update.ID, update.FilePath, update.Line, update.OriginalCall)
+	}
+	for _, entry := range result.Remaining {
+		if !*verifyVerbose && !*verifyStrict {
+			break
+		}
+		fmt.Printf("REMAINING    %s:%d: %s(%s)\n", entry.FilePath, entry.Line, entry.OriginalCall, entry.MessageTemplate)
+	}
+	fmt.Printf("%d edited entries checked: %d replaced, %d not replaced; %d calls still use the original functions\n",
+		result.Checked, result.Checked-len(result.NotReplaced), len(result.NotReplaced), len(result.Remaining))
+
+	failed := !result.OK() || (*verifyStrict && len(result.Remaining) > 0)
+	if *verifyBuild {
+		cmd := exec.Command("go", "build", "./...")
+		cmd.Dir = *verifyPath
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "go build failed: %v\n", err)
+			failed = true
 		} else {
-			fmt.Println("Successfully transformed log entries")
+			fmt.Println("go build ./... succeeded")
 		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
 
+// runCheck exits 0 when the unstructured calls are within -max, 1 when
+// there are more, and 2 when the check itself fails
+func runCheck(args []string) {
+	checkCmd := flag.NewFlagSet("check", flag.ExitOnError)
+	checkPath := checkCmd.String("path", ".", "Path to the Go project or package")
+	checkPattern := checkCmd.String("pattern", collector.UnstructuredPattern, "Regex pattern to match unstructured logging calls")
+	checkExclude := checkCmd.String("exclude", "", "Comma-separated paths or globs to skip (e.g. vendor,testdata)")
+	checkMax := checkCmd.Int("max", 0, "Number of unstructured calls allowed before the check fails")
+	checkFormat := checkCmd.String("format", "text", "Output format: text, json, sarif or github (Actions annotations)")
+	checkConfig := checkCmd.String("config", "", "Template configuration file (JSON) used for the SARIF and annotation fixes")
+	checkBaseline := checkCmd.String("baseline", "", "Baseline file of known calls that don't count against -max")
+	checkWriteBaseline := checkCmd.Bool("write-baseline", false, "Write the current calls to the -baseline file and exit")
+	checkStaged := checkCmd.Bool("staged", false, "Only check the Go files staged in git (for pre-commit hooks)")
+	checkImports := checkCmd.String("imports", "", "Comma-separated import paths matched calls must belong to (\"default\" for the supported logging libraries)")
+	checkProjectConfig := checkCmd.String("project-config", "", "Project configuration file (default: .logrefactor.yaml in the project root)")
+	checkProfile := checkCmd.String("profile", "", "Named profile from the project configuration")
+	checkCmd.Parse(args)
+
+	cfg := loadProjectConfig(*checkProjectConfig, *checkPath, *checkProfile)
+	set := setFlags(checkCmd)
+	override(set, "path", checkPath, cfg.Path)
+	override(set, "baseline", checkBaseline, cfg.Baseline)
+	excludes := cfg.Exclude
+	if set["exclude"] {
+		excludes = splitList(*checkExclude)
+	}
+	imports := cfg.Imports
+	if set["imports"] {
+		imports = splitList(*checkImports)
+	}
+	if *checkWriteBaseline && (*checkBaseline == "" || *checkStaged) {
+		fmt.Fprintln(os.Stderr, "Error: -write-baseline requires -baseline and can't be used with -staged")
+		os.Exit(2)
+	}
+
+	opts := collector.Options{Root: *checkPath, Pattern: *checkPattern, KeyStyle: cfg.KeyStyle, Excludes: excludes, SkipTests: cfg.SkipTests != nil && *cfg.SkipTests, Matcher: cfg.Matcher, Imports: imports, HotPaths: cfg.HotPaths, MessageRules: cfg.MessageRules, Verbosity: cfg.Verbosity}
+	var entries []collector.LogEntry
+	var err error
+	if *checkStaged {
+		opts.Files, err = stagedFiles(*checkPath)
+	}
+	if err == nil {
+		entries, err = collector.Run(interruptible(), opts)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error scanning %s: %v\n", *checkPath, err)
+		os.Exit(2)
+	}
+
+	// Calls in the baseline were there before the gate was enabled; only
+	// the others count
+	known := 0
+	if *checkBaseline != "" {
+		current, err := baseline.Entries(entries, patch.RepoRoot(*checkPath))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading baseline: %v\n", err)
+			os.Exit(2)
+		}
+		if *checkWriteBaseline {
+			if err := baseline.Write(*checkBaseline, current); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing baseline: %v\n", err)
+				os.Exit(2)
+			}
+			fmt.Printf("Wrote %d calls to %s\n", len(current), *checkBaseline)
+			return
+		}
+		saved, err := baseline.Load(*checkBaseline)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading baseline: %v\n", err)
+			os.Exit(2)
+		}
+		fresh, stale := baseline.Compare(saved, current)
+		var unknown []collector.LogEntry
+		for i, e := range entries {
+			if fresh[i] {
+				unknown = append(unknown, e)
+			}
+		}
+		known = len(entries) - len(unknown)
+		entries = unknown
+		// Only some files were scanned, so calls missing from the others
+		// aren't gone
+		if len(stale) > 0 && !*checkStaged {
+			fmt.Fprintf(os.Stderr, "%d baseline entries are no longer found; run with -write-baseline to drop them\n", len(stale))
+		}
+	}
+	failed := len(entries) > *checkMax
+
+	switch *checkFormat {
+	case "text", "github":
+		if *checkFormat == "github" {
+			templateConfig, err := transformer.LoadTemplateConfig(*checkConfig, &cfg.TemplateConfig)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading template config: %v\n", err)
+				os.Exit(2)
+			}
+			if err := writeAnnotations(sarif.FromEntries(entries, templateConfig)); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing annotations: %v\n", err)
+				os.Exit(2)
+			}
+		} else {
+			for _, e := range entries {
+				fmt.Printf("%s:%d:%d: %s(%s)\n", e.FilePath, e.Line, e.Column, e.OriginalCall, e.MessageTemplate)
+			}
+		}
+		status := "ok"
+		if failed {
+			status = "FAIL"
+		}
+		if *checkBaseline != "" {
+			fmt.Printf("%s: %d new unstructured log calls (%d allowed, %d in the baseline)\n", status, len(entries), *checkMax, known)
+		} else {
+			fmt.Printf("%s: %d unstructured log calls (%d allowed)\n", status, len(entries), *checkMax)
+		}
+	case "json":
+		type location struct {
+			File    string `json:"file"`
+			Line    int    `json:"line"`
+			Column  int    `json:"column"`
+			Call    string `json:"call"`
+			Message string `json:"message"`
+		}
+		out := struct {
+			Calls    []location `json:"calls"`
+			Allowed  int        `json:"allowed"`
+			Baseline int        `json:"baseline"`
+			Failed   bool       `json:"failed"`
+		}{Calls: []location{}, Allowed: *checkMax, Baseline: known, Failed: failed}
+		for _, e := range entries {
+			out.Calls = append(out.Calls, location{e.FilePath, e.Line, e.Column, e.OriginalCall, e.MessageTemplate})
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(out)
+	case "sarif":
+		templateConfig, err := transformer.LoadTemplateConfig(*checkConfig, &cfg.TemplateConfig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading template config: %v\n", err)
+			os.Exit(2)
+		}
+		cwd, err := os.Getwd()
+		if err == nil {
+			err = sarif.Write(os.Stdout, sarif.FromEntries(entries, templateConfig), patch.RepoRoot(cwd))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing SARIF: %v\n", err)
+			os.Exit(2)
+		}
 	default:
-		fmt.Printf("Unknown command: %s\n", os.Args[1])
+		fmt.Fprintf(os.Stderr, "Unknown format: %s (use text, json, sarif or github)\n", *checkFormat)
+		os.Exit(2)
+	}
+
+	if failed {
 		os.Exit(1)
 	}
 }
+
+// runVet runs the analyzer with the go/analysis driver, which loads and
+// type-checks the packages named in args. -fix applies the suggested fixes
+// and -diff prints them.
+func runLint(args []string) {
+	lintCmd := flag.NewFlagSet("lint", flag.ExitOnError)
+	lintInput := lintCmd.String("input", "", "Collected CSV to check, as reviewed (default: scan -path)")
+	lintPath := lintCmd.String("path", ".", "Path to the Go project or package")
+	lintPattern := lintCmd.String("pattern", collector.DefaultPattern, "Regex pattern to match logging calls")
+	lintExclude := lintCmd.String("exclude", "", "Comma-separated paths or globs to skip (e.g. vendor,testdata)")
+	lintImports := lintCmd.String("imports", "", "Comma-separated import paths matched calls must belong to (\"default\" for the supported logging libraries)")
+	lintAutoMap := lintCmd.Bool("auto-map", true, "Check the fields auto-mapped from ArgumentDetails when StructuredFields is empty, as transform would write them")
+	lintMessageRules := lintCmd.String("message-rules", "all", "Comma-separated message style rules: lowercase, punctuation, prefix, ascii, all or none")
+	lintFail := lintCmd.String("fail", lint.High, "Lowest severity that fails the check: high, medium, low or none")
+	lintFormat := lintCmd.String("format", "text", "Output format: text, json, sarif or github (Actions annotations)")
+	lintProjectConfig := lintCmd.String("project-config", "", "Project configuration file (default: .logrefactor.yaml in the project root)")
+	lintProfile := lintCmd.String("profile", "", "Named profile from the project configuration")
+	lintCmd.Parse(args)
+
+	cfg := loadProjectConfig(*lintProjectConfig, *lintPath, *lintProfile)
+	set := setFlags(lintCmd)
+	override(set, "path", lintPath, cfg.Path)
+	override(set, "pattern", lintPattern, cfg.Pattern)
+	if !set["auto-map"] && cfg.AutoMap != nil {
+		*lintAutoMap = *cfg.AutoMap
+	}
+	excludes := cfg.Exclude
+	if set["exclude"] {
+		excludes = splitList(*lintExclude)
+	}
+	imports := cfg.Imports
+	if set["imports"] {
+		imports = splitList(*lintImports)
+	}
+	messageRules := cfg.MessageRules
+	if set["message-rules"] {
+		messageRules = splitList(*lintMessageRules)
+	}
+	rules, err := lint.ParseRules(messageRules)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	switch *lintFail {
+	case lint.High, lint.Medium, lint.Low, "none":
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown severity: %s (use high, medium, low or none)\n", *lintFail)
+		os.Exit(2)
+	}
+
+	var updates []transformer.LogUpdate
+	if *lintInput != "" {
+		var err error
+		updates, err = sarif.ReadCSV(*lintInput)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", *lintInput, err)
+			os.Exit(2)
+		}
+	} else {
+		opts := collector.Options{Root: *lintPath, Pattern: *lintPattern, KeyStyle: cfg.KeyStyle, Excludes: excludes, SkipTests: cfg.SkipTests != nil && *cfg.SkipTests, Matcher: cfg.Matcher, Imports: imports, HotPaths: cfg.HotPaths, MessageRules: messageRules, Verbosity: cfg.Verbosity}
+		entries, err := collector.Run(interruptible(), opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error scanning %s: %v\n", *lintPath, err)
+			os.Exit(2)
+		}
+		for _, e := range entries {
+			updates = append(updates, sarif.Update(e))
+		}
+	}
+
+	type issue struct {
+		File       string `json:"file"`
+		Line       int    `json:"line"`
+		Column     int    `json:"column"`
+		ID         string `json:"id,omitempty"`
+		Key        string `json:"key"`
+		Expression string `json:"expression"`
+		Kind       string `json:"kind"`
+		Severity   string `json:"severity"`
+
+		description string
+	}
+	issues := []issue{}
+	counts := make(map[string]int) // By severity, but for calls in loops and message style
+	loops, styles := 0, 0
+	failed := false
+	for _, update := range updates {
+		for _, i := range update.Lint(*lintAutoMap, rules) {
+			issues = append(issues, issue{update.FilePath, update.Line, update.Column, update.ID, i.Key, i.Expression, i.Kind, i.Severity, i.String()})
+			switch i.Kind {
+			case lint.Loop, lint.HotPath:
+				loops++
+			case lint.Style:
+				styles++
+			default:
+				counts[i.Severity]++
+			}
+			failed = failed || *lintFail != "none" && lint.AtLeast(i.Severity, *lintFail)
+		}
+	}
+
+	switch *lintFormat {
+	case "text", "github":
+		if *lintFormat == "github" {
+			if err := writeAnnotations(sarif.FromIssues(updates, *lintAutoMap, rules)); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing annotations: %v\n", err)
+				os.Exit(2)
+			}
+		} else {
+			for _, i := range issues {
+				id := ""
+				if i.ID != "" {
+					id = " " + i.ID
+				}
+				fmt.Printf("%s:%d:%d:%s %s: %s\n", i.File, i.Line, i.Column, id, i.Severity, i.description)
+			}
+		}
+		status := "ok"
+		if failed {
+			status = "FAIL"
+		}
+		fmt.Printf("%s: %d fields look like credentials, %d like personal data; %d high-cardinality values in messages, %d calls in loops or on hot paths; %d message style issues\n",
+			status, counts[lint.High], counts[lint.Medium], counts[lint.Low], loops, styles)
+	case "json":
+		out := struct {
+			Issues []issue `json:"issues"`
+			Failed bool    `json:"failed"`
+		}{issues, failed}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(out)
+	case "sarif":
+		cwd, err := os.Getwd()
+		if err == nil {
+			err = sarif.Write(os.Stdout, sarif.FromIssues(updates, *lintAutoMap, rules), patch.RepoRoot(cwd))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing SARIF: %v\n", err)
+			os.Exit(2)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown format: %s (use text, json, sarif or github)\n", *lintFormat)
+		os.Exit(2)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func runKeys(args []string) {
+	keysCmd := flag.NewFlagSet("keys", flag.ExitOnError)
+	keysInput := keysCmd.String("input", "log_entries.csv", "Collected (and edited) CSV file")
+	keysAutoMap := keysCmd.Bool("auto-map", true, "Include the fields auto-mapped from ArgumentDetails when StructuredFields is empty, as transform would write them")
+	keysFormat := keysCmd.String("format", "text", "Output format: text or json")
+	keysProjectConfig := keysCmd.String("project-config", "", "Project configuration file (default: .logrefactor.yaml in the project root)")
+	keysProfile := keysCmd.String("profile", "", "Named profile from the project configuration")
+	keysCmd.Parse(args)
+
+	cfg := loadProjectConfig(*keysProjectConfig, ".", *keysProfile)
+	set := setFlags(keysCmd)
+	override(set, "input", keysInput, cfg.CSV)
+	if !set["auto-map"] && cfg.AutoMap != nil {
+		*keysAutoMap = *cfg.AutoMap
+	}
+
+	updates, err := sarif.ReadCSV(*keysInput)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", *keysInput, err)
+		os.Exit(2)
+	}
+	var uses []lint.KeyUse
+	for _, update := range updates {
+		uses = append(uses, update.KeyUses(*keysAutoMap)...)
+	}
+	collisions := lint.Collisions(uses)
+
+	switch *keysFormat {
+	case "text":
+		for _, c := range collisions {
+			line := fmt.Sprintf("%s: logged as %s", c.Key, strings.Join(c.Names, ", "))
+			if len(c.Types) > 0 {
+				line += " with types " + strings.Join(c.Types, ", ")
+			}
+			fmt.Println(line)
+			for _, use := range c.Uses {
+				typ := ""
+				if use.Type != "" && use.Type != "unknown" {
+					typ = " (" + use.Type + ")"
+				}
+				fmt.Printf("  %s %s %s = %s%s\n", use.ID, use.Location, use.Key, use.Expression, typ)
+			}
+		}
+		fmt.Printf("%d keys logged under several names or types\n", len(collisions))
+	case "json":
+		if collisions == nil {
+			collisions = []lint.Collision{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(collisions)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown format: %s (use text or json)\n", *keysFormat)
+		os.Exit(2)
+	}
+	if len(collisions) > 0 {
+		os.Exit(1)
+	}
+}
+
+func runVet(args []string) {
+	os.Args = append([]string{"logrefactor vet"}, args...)
+	singlechecker.Main(analyzer.Analyzer)
+}
+
+func runRevert(args []string) {
+	revertCmd := flag.NewFlagSet("revert", flag.ExitOnError)
+	revertInput := revertCmd.String("input", "log_entries.csv", "CSV transform read; its journal is used unless -journal is given")
+	revertJournal := revertCmd.String("journal", "", "Journal written by transform (default: "+transformer.DefaultJournal+" next to -input)")
+	revertProjectConfig := revertCmd.String("project-config", "", "Project configuration file (default: .logrefactor.yaml in the project root)")
+	revertProfile := revertCmd.String("profile", "", "Named profile from the project configuration")
+	revertIDs := revertCmd.String("ids", "", "Comma-separated entry IDs to revert")
+	revertFiles := revertCmd.String("file", "", "Comma-separated files whose edits are all reverted")
+	revertAll := revertCmd.Bool("all", false, "Revert every edit in the journal")
+	revertDryRun := revertCmd.Bool("dry-run", false, "Show what would be reverted without changing files")
+	revertCmd.Parse(args)
+
+	cfg := loadProjectConfig(*revertProjectConfig, ".", *revertProfile)
+	set := setFlags(revertCmd)
+	override(set, "input", revertInput, cfg.CSV)
+	if !set["journal"] {
+		*revertJournal = transformer.JournalFor(*revertInput)
+	}
+
+	opts := transformer.RevertOptions{
+		IDs:    splitList(*revertIDs),
+		Files:  splitList(*revertFiles),
+		All:    *revertAll,
+		DryRun: *revertDryRun,
+	}
+	if !opts.All && len(opts.IDs) == 0 && len(opts.Files) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: specify -ids, -file or -all")
+		os.Exit(1)
+	}
+
+	if err := transformer.Revert(*revertJournal, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error reverting: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runMerge(args []string) {
+	mergeCmd := flag.NewFlagSet("merge", flag.ExitOnError)
+	mergeOld := mergeCmd.String("old", "", "Edited CSV")
+	mergeNew := mergeCmd.String("new", "", "CSV from a fresh collect")
+	mergeOutput := mergeCmd.String("output", "", "Merged CSV to write (default: overwrite -new)")
+	mergeDropRemoved := mergeCmd.Bool("drop-removed", false, "Leave out entries no longer found instead of keeping them marked")
+	mergeVerbose := mergeCmd.Bool("v", false, "List moved, added and removed entries")
+	mergeCmd.Parse(args)
+
+	if *mergeOld == "" || *mergeNew == "" {
+		fmt.Fprintln(os.Stderr, "Error: -old and -new are required")
+		os.Exit(1)
+	}
+	if *mergeOutput == "" {
+		*mergeOutput = *mergeNew
+	}
+
+	result, err := merge.Merge(*mergeOld, *mergeNew, *mergeOutput, merge.Options{DropRemoved: *mergeDropRemoved})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error merging: %v\n", err)
+		os.Exit(1)
+	}
+
+	for _, c := range result.Stale {
+		fmt.Printf("STALE   %s %s:%d: arguments changed since it was edited; review StructuredFields\n", c.ID, c.File, c.NewLine)
+	}
+	if *mergeVerbose {
+		for _, c := range result.Moved {
+			fmt.Printf("MOVED   %s %s:%d -> %d\n", c.ID, c.File, c.OldLine, c.NewLine)
+		}
+		for _, c := range result.Added {
+			fmt.Printf("ADDED   %s %s:%d: %s(%s)\n", c.ID, c.File, c.NewLine, c.Call, c.Message)
+		}
+		for _, c := range result.Removed {
+			fmt.Printf("REMOVED %s %s:%d: %s(%s)\n", c.ID, c.File, c.OldLine, c.Call, c.Message)
+		}
+	}
+	fmt.Printf("%d matched (%d with edits, %d moved), %d added, %d removed, %d stale\n",
+		result.Matched, result.Carried, len(result.Moved), len(result.Added), len(result.Removed), len(result.Stale))
+	fmt.Printf("Merged entries written to %s\n", *mergeOutput)
+}
+
+func runDiff(args []string) {
+	diffCmd := flag.NewFlagSet("diff", flag.ExitOnError)
+	diffFormat := diffCmd.String("format", "text", "Output format: text or json")
+	diffCmd.Parse(args)
+
+	if diffCmd.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "Usage: logrefactor diff [-format text|json] old.csv new.csv")
+		os.Exit(1)
+	}
+
+	report, err := diff.Compare(diffCmd.Arg(0), diffCmd.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error comparing: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch *diffFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		enc.Encode(report)
+	case "text":
+		fmt.Print(report.Text())
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q\n", *diffFormat)
+		os.Exit(1)
+	}
+}
+
+func runAnnotate(args []string) {
+	annotateCmd := flag.NewFlagSet("annotate", flag.ExitOnError)
+	annotateInput := annotateCmd.String("input", "log_entries.csv", "Collected CSV file")
+	annotateIDs := annotateCmd.String("ids", "", "Comma-separated entry IDs to annotate (default: all)")
+	annotateLevel := annotateCmd.String("level", "", "Comma-separated log levels to annotate (e.g. Error,Fatal)")
+	annotatePackage := annotateCmd.String("package", "", "Comma-separated packages to annotate")
+	annotateText := annotateCmd.String("text", "convert to structured logging", "Comment text after the entry ID")
+	annotateRemove := annotateCmd.Bool("remove", false, "Remove the TODO comments of the selected entries")
+	annotateDryRun := annotateCmd.Bool("dry-run", false, "Show changes without applying them")
+	annotateProjectConfig := annotateCmd.String("project-config", "", "Project configuration file (default: .logrefactor.yaml in the project root)")
+	annotateProfile := annotateCmd.String("profile", "", "Named profile from the project configuration")
+	annotateCmd.Parse(args)
+
+	cfg := loadProjectConfig(*annotateProjectConfig, ".", *annotateProfile)
+	set := setFlags(annotateCmd)
+	override(set, "input", annotateInput, cfg.CSV)
+
+	opts := transformer.AnnotateOptions{
+		IDs:      splitList(*annotateIDs),
+		Levels:   splitList(*annotateLevel),
+		Packages: splitList(*annotatePackage),
+		Text:     *annotateText,
+		Remove:   *annotateRemove,
+		DryRun:   *annotateDryRun,
+	}
+	if err := transformer.Annotate(*annotateInput, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error annotating: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runReport(args []string) {
+	reportCmd := flag.NewFlagSet("report", flag.ExitOnError)
+	reportInput := reportCmd.String("input", "log_entries.csv", "Collected (and edited) CSV file")
+	reportJournal := reportCmd.String("journal", "", "Transform journal; entries in it count as migrated (default: "+transformer.DefaultJournal+" next to -input)")
+	reportFormat := reportCmd.String("format", "markdown", "Output format: markdown or html")
+	reportOutput := reportCmd.String("output", "", "File to write (default: stdout)")
+	reportTitle := reportCmd.String("title", "", "Report title")
+	reportExamples := reportCmd.Int("examples", 5, "Before/after examples to include")
+	reportRemaining := reportCmd.Int("remaining", 50, "Remaining calls to list (0 = all)")
+	reportProjectConfig := reportCmd.String("project-config", "", "Project configuration file (default: .logrefactor.yaml in the project root)")
+	reportProfile := reportCmd.String("profile", "", "Named profile from the project configuration")
+	reportCmd.Parse(args)
+
+	cfg := loadProjectConfig(*reportProjectConfig, ".", *reportProfile)
+	set := setFlags(reportCmd)
+	override(set, "input", reportInput, cfg.CSV)
+	if !set["journal"] {
+		*reportJournal = transformer.JournalFor(*reportInput)
+	}
+
+	r, err := report.Build(*reportInput, report.Options{
+		Title:     *reportTitle,
+		Journal:   *reportJournal,
+		Examples:  *reportExamples,
+		Remaining: *reportRemaining,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", *reportInput, err)
+		os.Exit(1)
+	}
+
+	out := os.Stdout
+	if *reportOutput != "" {
+		f, err := os.Create(*reportOutput)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	switch *reportFormat {
+	case "markdown", "md":
+		err = r.Markdown(out)
+	case "html":
+		err = r.HTML(out)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q\n", *reportFormat)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runCoverage(args []string) {
+	coverageCmd := flag.NewFlagSet("coverage", flag.ExitOnError)
+	coveragePath := coverageCmd.String("path", ".", "Path to the Go project or package")
+	coveragePattern := coverageCmd.String("pattern", collector.DefaultPattern, "Regex pattern matching logging calls still to migrate")
+	coverageExclude := coverageCmd.String("exclude", "", "Comma-separated paths or globs to skip (e.g. vendor,testdata)")
+	coverageStyle := coverageCmd.String("style", "", "Target style (default: from the project config, or slog)")
+	coverageLoggerVar := coverageCmd.String("logger-var", "", "Target logger variable (default: from the project config, or log)")
+	coverageTarget := coverageCmd.String("target-pattern", "", "Regex pattern matching structured calls (default: derived from -style and -logger-var)")
+	coverageHistory := coverageCmd.String("history", "logrefactor-coverage.jsonl", "History file the measurement is appended to (empty to not record)")
+	coverageTrend := coverageCmd.Bool("trend", false, "Print the recorded history after measuring")
+	coverageFormat := coverageCmd.String("format", "text", "Output format: text or json")
+	coverageProjectConfig := coverageCmd.String("project-config", "", "Project configuration file (default: .logrefactor.yaml in the project root)")
+	coverageProfile := coverageCmd.String("profile", "", "Named profile from the project configuration")
+	coverageCmd.Parse(args)
+
+	cfg := loadProjectConfig(*coverageProjectConfig, *coveragePath, *coverageProfile)
+	set := setFlags(coverageCmd)
+	override(set, "path", coveragePath, cfg.Path)
+	override(set, "pattern", coveragePattern, cfg.Pattern)
+	override(set, "style", coverageStyle, cfg.Style)
+	override(set, "logger-var", coverageLoggerVar, cfg.LoggerVar)
+	excludes := cfg.Exclude
+	if set["exclude"] {
+		excludes = splitList(*coverageExclude)
+	}
+	if *coverageStyle == "" {
+		*coverageStyle = "slog"
+	}
+	if *coverageLoggerVar == "" {
+		*coverageLoggerVar = "log"
+	}
+
+	target := *coverageTarget
+	if target == "" {
+		var err error
+		if target, err = coverage.TargetPattern(*coverageStyle, *coverageLoggerVar); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	record, err := coverage.Measure(coverage.Options{
+		Path:          *coveragePath,
+		Excludes:      excludes,
+		LegacyPattern: *coveragePattern,
+		TargetPattern: target,
+		Style:         *coverageStyle,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error measuring coverage: %v\n", err)
+		os.Exit(1)
+	}
+	if *coverageHistory != "" {
+		if err := coverage.Append(*coverageHistory, record); err != nil {
+			fmt.Fprintf(os.Stderr, "Error recording coverage: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	switch *coverageFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(record)
+	case "text":
+		fmt.Print(record.Text())
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q\n", *coverageFormat)
+		os.Exit(1)
+	}
+
+	if *coverageTrend && *coverageHistory != "" {
+		records, err := coverage.History(*coverageHistory)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading history: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\nTrend (%s):\n%s", *coverageHistory, coverage.Trend(records))
+	}
+}
+
+func runNormalize(args []string) {
+	normalizeCmd := flag.NewFlagSet("normalize", flag.ExitOnError)
+	normalizeInput := normalizeCmd.String("input", "log_entries.csv", "Collected CSV file")
+	normalizeOutput := normalizeCmd.String("output", "", "CSV file to write (default: overwrite -input)")
+	normalizeForce := normalizeCmd.Bool("force", false, "Replace NewMessage values that are already filled in")
+	normalizeDryRun := normalizeCmd.Bool("dry-run", false, "Print the suggestions without writing the CSV")
+	normalizeProjectConfig := normalizeCmd.String("project-config", "", "Project configuration file (default: .logrefactor.yaml in the project root)")
+	normalizeProfile := normalizeCmd.String("profile", "", "Named profile from the project configuration")
+	normalizeCmd.Parse(args)
+
+	cfg := loadProjectConfig(*normalizeProjectConfig, ".", *normalizeProfile)
+	set := setFlags(normalizeCmd)
+	override(set, "input", normalizeInput, cfg.CSV)
+
+	output := *normalizeOutput
+	if output == "" {
+		output = *normalizeInput
+	}
+	if *normalizeDryRun {
+		output = ""
+	}
+
+	result, err := normalize.CSV(*normalizeInput, output, *normalizeForce)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error normalizing %s: %v\n", *normalizeInput, err)
+		os.Exit(1)
+	}
+
+	for _, s := range result.Suggestions {
+		fmt.Printf("%s: %s -> %q\n", s.ID, s.Original, s.Message)
+	}
+	fmt.Printf("%d messages suggested, %d already filled in, %d without a literal message\n",
+		len(result.Suggestions), result.Kept, result.Skipped)
+	if output != "" {
+		fmt.Printf("Updated: %s\n", output)
+	}
+}
+
+func runCluster(args []string) {
+	clusterCmd := flag.NewFlagSet("cluster", flag.ExitOnError)
+	clusterInput := clusterCmd.String("input", "log_entries.csv", "Collected CSV file")
+	clusterMinSize := clusterCmd.Int("min-size", 2, "List clusters with at least this many entries")
+	clusterFormat := clusterCmd.String("format", "text", "Output format: text or json")
+	clusterID := clusterCmd.String("id", "", "Cluster to fill in NewMessage for, e.g. MSG-0003 (with -message)")
+	clusterMessage := clusterCmd.String("message", "", "Canonical message to fill in as the NewMessage of every entry of -id")
+	clusterForce := clusterCmd.Bool("force", false, "With -message, replace NewMessage values that are already filled in")
+	clusterOutput := clusterCmd.String("output", "", "CSV file to write with -message (default: overwrite -input)")
+	clusterProjectConfig := clusterCmd.String("project-config", "", "Project configuration file (default: .logrefactor.yaml in the project root)")
+	clusterProfile := clusterCmd.String("profile", "", "Named profile from the project configuration")
+	clusterCmd.Parse(args)
+
+	cfg := loadProjectConfig(*clusterProjectConfig, ".", *clusterProfile)
+	set := setFlags(clusterCmd)
+	override(set, "input", clusterInput, cfg.CSV)
+
+	if *clusterID != "" || *clusterMessage != "" {
+		if *clusterID == "" || *clusterMessage == "" {
+			fmt.Fprintln(os.Stderr, "Error: -id and -message go together")
+			os.Exit(1)
+		}
+		output := *clusterOutput
+		if output == "" {
+			output = *clusterInput
+		}
+		ids, err := cluster.SetMessage(*clusterInput, output, *clusterID, *clusterMessage, *clusterForce)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Set NewMessage of %d entries of %s: %s\n", len(ids), *clusterID, strings.Join(ids, ", "))
+		fmt.Printf("Updated: %s\n", output)
+		return
+	}
+
+	clusters, err := cluster.Read(*clusterInput, *clusterMinSize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", *clusterInput, err)
+		os.Exit(1)
+	}
+	switch *clusterFormat {
+	case "text":
+		entries := 0
+		for _, c := range clusters {
+			fmt.Printf("%s (%d entries)\n", c.ID, len(c.Members))
+			for _, m := range c.Members {
+				line := fmt.Sprintf("  %s %s %s", m.ID, m.Location, m.Message)
+				if m.NewMessage != "" {
+					line += fmt.Sprintf(" -> %q", m.NewMessage)
+				}
+				fmt.Println(line)
+			}
+			entries += len(c.Members)
+		}
+		fmt.Printf("%d clusters of alike messages, %d entries\n", len(clusters), entries)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if clusters == nil {
+			clusters = []cluster.Cluster{}
+		}
+		enc.Encode(clusters)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown format: %s (use text or json)\n", *clusterFormat)
+		os.Exit(1)
+	}
+}
+
+func runServe(args []string) {
+	serveCmd := flag.NewFlagSet("serve", flag.ExitOnError)
+	serveInput := serveCmd.String("input", "log_entries.csv", "CSV file to review; edits are saved back to it")
+	servePath := serveCmd.String("path", ".", "Path to the Go project or package")
+	serveAddr := serveCmd.String("addr", "localhost:8080", "Address to listen on")
+	serveConfig := serveCmd.String("config", "", "Template configuration file (JSON)")
+	serveStyle := serveCmd.String("style", "", "Output style (overrides the template configuration)")
+	serveLoggerVar := serveCmd.String("logger-var", "", "Logger variable name (overrides the template configuration)")
+	serveKeyStyle := serveCmd.String("key-style", "", "Field key style (overrides the template configuration)")
+	serveAutoMap := serveCmd.Bool("auto-map", true, "Auto-generate field mappings from ArgumentDetails when StructuredFields is empty")
+	serveKeyConstants := serveCmd.String("key-constants", "", "Go file for shared field key constants, used when applying")
+	serveJournal := serveCmd.String("journal", "", "File recording applied edits for revert (default: "+transformer.DefaultJournal+" next to -input; empty to disable)")
+	serveProjectConfig := serveCmd.String("project-config", "", "Project configuration file (default: .logrefactor.yaml in the project root)")
+	serveProfile := serveCmd.String("profile", "", "Named profile from the project configuration")
+	serveCmd.Parse(args)
+
+	cfg := loadProjectConfig(*serveProjectConfig, *servePath, *serveProfile)
+	set := setFlags(serveCmd)
+	override(set, "input", serveInput, cfg.CSV)
+	override(set, "path", servePath, cfg.Path)
+	if !set["journal"] {
+		*serveJournal = transformer.JournalFor(*serveInput)
+	}
+	override(set, "key-constants", serveKeyConstants, cfg.KeyConstants)
+	if !set["auto-map"] && cfg.AutoMap != nil {
+		*serveAutoMap = *cfg.AutoMap
+	}
+
+	templateConfig, err := transformer.LoadTemplateConfig(*serveConfig, &cfg.TemplateConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading template config: %v\n", err)
+		os.Exit(1)
+	}
+	if set["style"] {
+		templateConfig.Style = *serveStyle
+	}
+	if set["logger-var"] {
+		templateConfig.LoggerVar = *serveLoggerVar
+	}
+	if set["key-style"] {
+		templateConfig.KeyStyle = *serveKeyStyle
+	}
+
+	if _, err := os.Stat(*serveInput); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	srv := &server.Server{
+		CSV:          *serveInput,
+		Path:         *servePath,
+		Config:       templateConfig,
+		AutoMap:      *serveAutoMap,
+		KeyConstants: *serveKeyConstants,
+		Journal:      *serveJournal,
+	}
+	fmt.Printf("Serving %s on http://%s (Ctrl+C to stop)\n", *serveInput, *serveAddr)
+	if err := http.ListenAndServe(*serveAddr, srv.Handler()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error serving: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runAPI(args []string) {
+	apiCmd := flag.NewFlagSet("api", flag.ExitOnError)
+	apiAddr := apiCmd.String("addr", "localhost:8090", "Address to listen on")
+	apiToken := apiCmd.String("token", os.Getenv("LOGREFACTOR_API_TOKEN"), "Require this bearer token on every request (default: $LOGREFACTOR_API_TOKEN)")
+	apiRoot := apiCmd.String("root", "", "Only allow paths inside this directory")
+	apiTimeout := apiCmd.Duration("job-timeout", 0, "Cancel jobs that run longer than this (0 for no limit)")
+	apiCmd.Parse(args)
+
+	api := &server.API{Token: *apiToken, Root: *apiRoot, Timeout: *apiTimeout}
+	fmt.Printf("Serving the API on http://%s (Ctrl+C to stop)\n", *apiAddr)
+	if err := http.ListenAndServe(*apiAddr, api.Handler()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error serving: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runLSP serves the language server on stdin and stdout, as editors start
+// it
+func runLSP(args []string) {
+	lspCmd := flag.NewFlagSet("lsp", flag.ExitOnError)
+	lspPattern := lspCmd.String("pattern", collector.UnstructuredPattern, "Regex matching the logging calls to offer conversion for")
+	lspConfig := lspCmd.String("config", "", "Template configuration file (JSON)")
+	lspProjectConfig := lspCmd.String("project-config", "", "Project configuration file (default: .logrefactor.yaml above each file)")
+	lspProfile := lspCmd.String("profile", "", "Named profile from the project configuration")
+	lspCmd.Parse(args)
+
+	s := &lsp.Server{
+		Pattern:       *lspPattern,
+		Config:        *lspConfig,
+		ProjectConfig: *lspProjectConfig,
+		Profile:       *lspProfile,
+	}
+	if err := s.Serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runInit(args []string) {
+	initCmd := flag.NewFlagSet("init", flag.ExitOnError)
+	initPath := initCmd.String("path", ".", "Project root to inspect")
+	initOutput := initCmd.String("output", "", "Config file to write (default: .logrefactor.yaml in -path)")
+	initForce := initCmd.Bool("force", false, "Overwrite an existing config file")
+	initCmd.Parse(args)
+
+	output := *initOutput
+	if output == "" {
+		output = filepath.Join(*initPath, config.FileNames[0])
+	}
+	if _, err := os.Stat(output); err == nil && !*initForce {
+		fmt.Fprintf(os.Stderr, "Error: %s already exists (use -force to overwrite)\n", output)
+		os.Exit(1)
+	}
+
+	report, err := scaffold.Inspect(*initPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error inspecting project: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(output, []byte(report.YAML()), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing config: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s (style %s, logger %s)\n", output, report.Style, report.LoggerVar())
+}
+
+func runConfig(args []string) {
+	if len(args) < 1 {
+		fmt.Println("Usage:")
+		fmt.Println("  logrefactor config validate [-path dir] [file ...]")
+		fmt.Println("  logrefactor config schema")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "validate":
+		runConfigValidate(args[1:])
+	case "schema":
+		os.Stdout.Write(schema.JSON())
+	default:
+		fmt.Printf("Unknown config command: %s\n", args[0])
+		os.Exit(1)
+	}
+}
+
+func runConfigValidate(args []string) {
+	validateCmd := flag.NewFlagSet("config validate", flag.ExitOnError)
+	validatePath := validateCmd.String("path", ".", "Where to look for .logrefactor.yaml when no files are given")
+	validateCmd.Parse(args)
+
+	files := validateCmd.Args()
+	if len(files) == 0 {
+		file, err := config.Find(*validatePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error finding project config: %v\n", err)
+			os.Exit(1)
+		}
+		if file == "" {
+			fmt.Fprintf(os.Stderr, "Error: no project config found from %s\n", *validatePath)
+			os.Exit(1)
+		}
+		files = []string{file}
+	}
+
+	failed := false
+	for _, file := range files {
+		cfg, err := config.Load(file)
+		if err == nil {
+			err = cfg.Validate()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s: OK\n", file)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func runTemplates(args []string) {
+	if len(args) < 1 || args[0] != "dump" {
+		fmt.Println("Usage:")
+		fmt.Println("  logrefactor templates dump -style zap [-logger-var logger] [-raw]")
+		os.Exit(1)
+	}
+
+	dumpCmd := flag.NewFlagSet("templates dump", flag.ExitOnError)
+	dumpStyle := dumpCmd.String("style", "slog", "Built-in style to dump")
+	dumpLoggerVar := dumpCmd.String("logger-var", "log", "Logger variable for the generated template config")
+	dumpRaw := dumpCmd.Bool("raw", false, "Print only the template text instead of a template config")
+	dumpCmd.Parse(args[1:])
+
+	tmpl, err := transformer.BuiltinTemplate(*dumpStyle)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *dumpRaw {
+		fmt.Println(tmpl)
+		return
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	enc.Encode(struct {
+		Style     string `json:"style"`
+		LoggerVar string `json:"loggerVar"`
+		Template  string `json:"template"`
+	}{"custom", *dumpLoggerVar, tmpl})
+}
+
+// stopProfiles stops the profiles started with startProfiles
+var stopProfiles = func() {}
+
+// startProfiles starts the profiles asked for with the profiling flags
+func startProfiles(flags *profile.Flags) {
+	stop, err := flags.Start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error starting profiling: %v\n", err)
+		os.Exit(1)
+	}
+	stopProfiles = stop
+}
+
+// exit stops the running profiles, which os.Exit would lose, and exits
+func exit(code int) {
+	stopProfiles()
+	os.Exit(code)
+}
+
+// interruptible returns a context cancelled by the first Ctrl-C (or
+// SIGTERM), so a long command can stop cleanly. A second Ctrl-C kills the
+// program as usual.
+func interruptible() context.Context {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx
+}
+
+// loadProjectConfig loads the file given with -project-config, or discovers
+// one starting from path, and applies the -profile. It exits on errors.
+func loadProjectConfig(file, path, profile string) *config.Config {
+	var cfg *config.Config
+	var err error
+	if file != "" {
+		cfg, err = config.Load(file)
+	} else {
+		cfg, err = config.Discover(path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading project config: %v\n", err)
+		os.Exit(1)
+	}
+	cfg, err = cfg.WithProfile(profile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return cfg
+}
+
+// setFlags returns the names of the flags given explicitly on the command line
+func setFlags(fs *flag.FlagSet) map[string]bool {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return set
+}
+
+// override replaces a flag's value with the project config value, unless the
+// flag was given explicitly or the config leaves it empty
+func override(set map[string]bool, name string, value *string, configValue string) {
+	if !set[name] && configValue != "" {
+		*value = configValue
+	}
+}
+
+// readIDFile reads entry IDs from a file: one or more per line, separated by
+// commas or spaces; text after # is a comment
+func readIDFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if i := strings.Index(line, "#"); i != -1 {
+			line = line[:i]
+		}
+		ids = append(ids, strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\r'
+		})...)
+	}
+	return ids, nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
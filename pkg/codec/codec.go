@@ -0,0 +1,230 @@
+// Package codec converts entry tables to and from bytes, so entries can be
+// written to any io.Writer and updates read from any io.Reader. A table is
+// what collect writes: a header row naming the columns, then a row of
+// strings per entry. Columns are matched by name, so a codec only has to
+// keep the header and rows together; CSV and JSON Lines are provided, and
+// other formats can be added by implementing Codec.
+package codec
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Codec creates the encoders and decoders of a format
+type Codec interface {
+	NewEncoder(w io.Writer) Encoder
+	NewDecoder(r io.Reader) Decoder
+}
+
+// Encoder writes rows. The first row written is the header.
+type Encoder interface {
+	Write(row []string) error
+	// Flush writes any buffered rows to the underlying writer
+	Flush() error
+}
+
+// Decoder reads rows. The first row read is the header; Read returns
+// io.EOF after the last row. Rows may be shorter or longer than the header,
+// and are not reused, so callers may keep them.
+type Decoder interface {
+	Read() ([]string, error)
+}
+
+// Codecs by name, for choosing one from a flag or request
+var codecs = map[string]Codec{
+	"csv":  CSV,
+	"json": JSON,
+}
+
+// Lookup returns the codec registered under name ("csv" or "json", or one
+// added with Register)
+func Lookup(name string) (Codec, bool) {
+	c, ok := codecs[name]
+	return c, ok
+}
+
+// Register makes a codec available to Lookup. It is meant to be called
+// from init functions, and replaces any codec with the same name.
+func Register(name string, c Codec) {
+	codecs[name] = c
+}
+
+// CSV is the format collect writes by default
+var CSV Codec = csvCodec{}
+
+type csvCodec struct{}
+
+func (csvCodec) NewEncoder(w io.Writer) Encoder {
+	return &csvEncoder{w: csv.NewWriter(w)}
+}
+
+func (csvCodec) NewDecoder(r io.Reader) Decoder {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	return reader
+}
+
+type csvEncoder struct {
+	w     *csv.Writer
+	width int
+}
+
+// Write pads rows to the header width, which spreadsheets expect
+func (e *csvEncoder) Write(row []string) error {
+	if e.width == 0 {
+		e.width = len(row)
+	}
+	for len(row) < e.width {
+		row = append(row, "")
+	}
+	return e.w.Write(row)
+}
+
+func (e *csvEncoder) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// JSON writes each row as a JSON object on its own line, keyed by column
+// in header order. Reading, the header is taken from the keys of the first object; keys
+// that first object doesn't have are ignored in the others. Values may be
+// strings, numbers, booleans or null (read as "").
+var JSON Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) NewEncoder(w io.Writer) Encoder {
+	return &jsonEncoder{w: w}
+}
+
+func (jsonCodec) NewDecoder(r io.Reader) Decoder {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	return &jsonDecoder{dec: dec}
+}
+
+type jsonEncoder struct {
+	w      io.Writer
+	header []string
+	buf    bytes.Buffer
+}
+
+func (e *jsonEncoder) Write(row []string) error {
+	if e.header == nil {
+		e.header = append([]string(nil), row...)
+		return nil
+	}
+	e.buf.WriteByte('{')
+	for i, name := range e.header {
+		value := ""
+		if i < len(row) {
+			value = row[i]
+		}
+		if i > 0 {
+			e.buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		val, _ := json.Marshal(value)
+		e.buf.Write(key)
+		e.buf.WriteByte(':')
+		e.buf.Write(val)
+	}
+	e.buf.WriteString("}\n")
+	if e.buf.Len() >= 64*1024 {
+		return e.Flush()
+	}
+	return nil
+}
+
+func (e *jsonEncoder) Flush() error {
+	_, err := e.w.Write(e.buf.Bytes())
+	e.buf.Reset()
+	return err
+}
+
+type jsonDecoder struct {
+	dec     *json.Decoder
+	header  []string
+	columns map[string]int
+	pending []string // The first row, read along with the header
+}
+
+func (d *jsonDecoder) Read() ([]string, error) {
+	if d.pending != nil {
+		row := d.pending
+		d.pending = nil
+		return row, nil
+	}
+	keys, values, err := d.object()
+	if err != nil {
+		return nil, err
+	}
+	if d.header == nil {
+		d.header = keys
+		d.columns = make(map[string]int, len(keys))
+		for i, key := range keys {
+			if _, ok := d.columns[key]; !ok {
+				d.columns[key] = i
+			}
+		}
+		d.pending = values
+		return append([]string(nil), keys...), nil
+	}
+	row := make([]string, len(d.header))
+	for i, key := range keys {
+		if j, ok := d.columns[key]; ok {
+			row[j] = values[i]
+		}
+	}
+	return row, nil
+}
+
+// object reads the next object, keeping its keys in order
+func (d *jsonDecoder) object() (keys, values []string, err error) {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return nil, nil, err // io.EOF at the end
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, nil, fmt.Errorf("expected a JSON object, found %v", tok)
+	}
+	for d.dec.More() {
+		tok, err := d.dec.Token()
+		if err != nil {
+			return nil, nil, unexpected(err)
+		}
+		key := tok.(string) // Object keys are always strings
+		var value interface{}
+		if err := d.dec.Decode(&value); err != nil {
+			return nil, nil, unexpected(err)
+		}
+		var s string
+		switch v := value.(type) {
+		case nil:
+		case string:
+			s = v
+		case json.Number, bool:
+			s = fmt.Sprint(v)
+		default:
+			return nil, nil, fmt.Errorf("value of %q is not a string", key)
+		}
+		keys = append(keys, key)
+		values = append(values, s)
+	}
+	if _, err := d.dec.Token(); err != nil { // The closing brace
+		return nil, nil, unexpected(err)
+	}
+	return keys, values, nil
+}
+
+// unexpected turns the end of input inside an object into an error
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,174 @@
+package collector
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// cacheVersion changes whenever the entries extracted from a file would,
+// which invalidates every cache written before
+const cacheVersion = 22
+
+// cache remembers the entries found in each file, so a repeat collect only
+// parses the files that changed. A file is unchanged if its size and
+// modification time match, or failing that its content hash. The whole
+// cache is dropped when the scan settings differ from those it was built
+// with.
+type cache struct {
+	path     string
+	settings string
+
+	mu    sync.Mutex
+	files map[string]cacheRecord // Absolute path -> record
+}
+
+// cacheRecord is what the cache holds for one file. The entries have no IDs
+// and are numbered again when they are emitted.
+type cacheRecord struct {
+	Size    int64      `json:"size"`
+	ModTime int64      `json:"modTime"` // Unix nanoseconds
+	Hash    string     `json:"hash"`
+	Entries []LogEntry `json:"entries"`
+}
+
+type cacheFile struct {
+	Version  int                    `json:"version"`
+	Settings string                 `json:"settings"`
+	Files    map[string]cacheRecord `json:"files"`
+}
+
+// loadCache reads the cache at path. A missing, unreadable or outdated
+// cache starts out empty rather than failing the scan.
+func loadCache(path, pattern, keyStyle, matcherPlugin string, imports, hotPaths, messageRules []string, verbosity map[string]int) (*cache, error) {
+	settings := pattern + "\x00" + keyStyle
+	if len(imports) > 0 {
+		settings += "\x00" + strings.Join(imports, ",")
+	}
+	if len(hotPaths) > 0 {
+		settings += "\x00hot:" + strings.Join(hotPaths, ",")
+	}
+	settings += "\x00style:" + strings.Join(messageRules, ",")
+	if len(verbosity) > 0 {
+		settings += fmt.Sprintf("\x00v:%d,%d", verbosity["Debug"], verbosity["Trace"])
+	}
+	if matcherPlugin != "" {
+		data, err := os.ReadFile(matcherPlugin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read matcher: %w", err)
+		}
+		settings += "\x00" + hash(data)
+	}
+	sum := sha256.Sum256([]byte(settings))
+
+	c := &cache{path: path, settings: hex.EncodeToString(sum[:]), files: make(map[string]cacheRecord)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, nil
+	}
+	var f cacheFile
+	if err := json.Unmarshal(data, &f); err != nil || f.Version != cacheVersion || f.Settings != c.settings {
+		return c, nil
+	}
+	if f.Files != nil {
+		c.files = f.Files
+	}
+	return c, nil
+}
+
+// lookup returns the cached entries of a file if it hasn't changed. content
+// is the file, once it had to be read to compare hashes; the caller can
+// parse it instead of reading the file again.
+func (c *cache) lookup(path string) (entries []LogEntry, content []byte, ok bool) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, nil, false
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, false
+	}
+	c.mu.Lock()
+	record, found := c.files[abs]
+	c.mu.Unlock()
+	if !found {
+		return nil, nil, false
+	}
+
+	if record.Size == info.Size() && record.ModTime == info.ModTime().UnixNano() {
+		return withPath(record.Entries, path), nil, true
+	}
+	content, err = os.ReadFile(path)
+	if err != nil || hash(content) != record.Hash {
+		return nil, content, false
+	}
+	// Touched but not changed: remember the new time so the next run
+	// doesn't have to read it
+	record.Size, record.ModTime = info.Size(), info.ModTime().UnixNano()
+	c.store(abs, record)
+	return withPath(record.Entries, path), content, true
+}
+
+// add records the entries parsed from content, the current file at path
+func (c *cache) add(path string, content []byte, entries []LogEntry) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	c.store(abs, cacheRecord{
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+		Hash:    hash(content),
+		Entries: append([]LogEntry(nil), entries...),
+	})
+}
+
+func (c *cache) store(abs string, record cacheRecord) {
+	c.mu.Lock()
+	c.files[abs] = record
+	c.mu.Unlock()
+}
+
+// save writes the cache, leaving out files that no longer exist. The file
+// is replaced in one step, so an interrupted run leaves the old cache.
+func (c *cache) save() error {
+	for abs := range c.files {
+		if _, err := os.Stat(abs); err != nil {
+			delete(c.files, abs)
+		}
+	}
+	data, err := json.Marshal(cacheFile{Version: cacheVersion, Settings: c.settings, Files: c.files})
+	if err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// withPath returns a copy of entries with FilePath set to path, which may
+// be spelled differently from the run that cached them
+func withPath(entries []LogEntry, path string) []LogEntry {
+	result := make([]LogEntry, len(entries))
+	for i, e := range entries {
+		e.FilePath = path
+		result[i] = e
+	}
+	return result
+}
+
+func hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
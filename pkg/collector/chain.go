@@ -0,0 +1,157 @@
+package collector
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+
+	"logrefactor/internal/logcall"
+)
+
+// chainedArguments returns the fields a call sets on its logger in the call
+// itself before logging, in source order and with their keys as written:
+// with WithField, WithFields and WithError for logrus and apex/log, as in
+//
+//	logrus.WithFields(logrus.Fields{"user": id}).WithError(err).Errorf("sync %s: %v", name, err)
+//
+// with With for zap and slog, WithValues for logr, the calls building a
+// zerolog event (see logcall.ZerologEvent) and go-kit's With around the
+// logger of a call of library "go-kit" (see logcall.GokitLogger).
+// WithError's key is "error". unread are the calls setting fields collect
+// can't read, such as WithFields(fields) or a WithField whose key isn't a
+// string literal.
+func chainedArguments(call *ast.CallExpr, library string) (args []Argument, unread []string) {
+	if _, _, links, ok := logcall.ZerologEvent(call); ok {
+		return zerologArguments(links)
+	}
+	if library == "go-kit" {
+		return gokitArguments(call)
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	for ok {
+		inner, isCall := sel.X.(*ast.CallExpr)
+		if !isCall {
+			break
+		}
+		sel, ok = inner.Fun.(*ast.SelectorExpr)
+		if !ok {
+			break
+		}
+
+		// Walking outwards in, so each call's fields go before the ones
+		// found so far
+		var set []Argument
+		read := true
+		switch sel.Sel.Name {
+		case "WithField":
+			if len(inner.Args) != 2 {
+				read = false
+				break
+			}
+			key, isKey := logcall.StringLit(inner.Args[0])
+			if !isKey {
+				read = false
+				break
+			}
+			set = append(set, chainedArgument(key, inner.Args[1], inferType(inner.Args[1])))
+		case "WithFields":
+			set, read = literalFields(onlyArg(inner))
+		case "WithError":
+			if len(inner.Args) != 1 {
+				read = false
+				break
+			}
+			set = append(set, chainedArgument("error", inner.Args[0], "error"))
+		case "With":
+			if set, read = zapWith(inner); !read {
+				set, read = slogWith(inner)
+			}
+		case "WithValues":
+			set, read = slogWith(inner)
+		default:
+			continue
+		}
+		if !read {
+			unread = append([]string{linkText(inner)}, unread...)
+			continue
+		}
+		args = append(set, args...)
+	}
+	return args, unread
+}
+
+// chainLink reports whether the call at the end of path is the receiver of
+// a method call, as logrus.WithField("user", id) is in
+// logrus.WithField("user", id).Info("login"). path runs from the file down
+// to the call.
+func chainLink(path []ast.Node) bool {
+	if len(path) < 3 {
+		return false
+	}
+	sel, ok := path[len(path)-2].(*ast.SelectorExpr)
+	if !ok || sel.X != path[len(path)-1] {
+		return false
+	}
+	outer, ok := path[len(path)-3].(*ast.CallExpr)
+	return ok && outer.Fun == sel
+}
+
+// chainNote names the calls setting fields collect can't read for the
+// Notes column, e.g. "CHAIN: WithFields(fields) sets fields collect can't
+// read; list them in StructuredFields". It is empty if there are none.
+func chainNote(unread []string) string {
+	if len(unread) == 0 {
+		return ""
+	}
+	verb := "set"
+	if len(unread) == 1 {
+		verb = "sets"
+	}
+	return fmt.Sprintf("CHAIN: %s %s fields collect can't read; list them in StructuredFields", strings.Join(unread, ", "), verb)
+}
+
+// chainedArgument returns the Argument of a field set on the logger, which
+// isn't one of the call's: its Index is -1
+func chainedArgument(key string, value ast.Expr, typ string) Argument {
+	expr := formatExpr(value)
+	return Argument{Index: -1, Expression: expr, VarName: extractVarName(expr), Type: typ, SuggestedKey: key}
+}
+
+// literalFields returns the fields of a map literal with string keys, such
+// as logrus.Fields{"user": id}. ok is false for other expressions.
+func literalFields(expr ast.Expr) (args []Argument, ok bool) {
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok {
+		return nil, false
+	}
+	for _, elt := range lit.Elts {
+		kv, isKV := elt.(*ast.KeyValueExpr)
+		if !isKV {
+			return nil, false
+		}
+		key, isKey := logcall.StringLit(kv.Key)
+		if !isKey {
+			return nil, false
+		}
+		args = append(args, chainedArgument(key, kv.Value, inferType(kv.Value)))
+	}
+	return args, true
+}
+
+// linkText writes a call of a chain as the Notes column names it, e.g.
+// "WithFields(fields)"
+func linkText(call *ast.CallExpr) string {
+	exprs := make([]string, len(call.Args))
+	for i, arg := range call.Args {
+		exprs[i] = formatExpr(arg)
+	}
+	return call.Fun.(*ast.SelectorExpr).Sel.Name + "(" + strings.Join(exprs, ", ") + ")"
+}
+
+// onlyArg returns the argument of a call with one, or nil
+func onlyArg(call *ast.CallExpr) ast.Expr {
+	if len(call.Args) != 1 {
+		return nil
+	}
+	return call.Args[0]
+}
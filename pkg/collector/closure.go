@@ -0,0 +1,28 @@
+package collector
+
+import "go/ast"
+
+// Closure values of a LogEntry
+const (
+	ClosureDefer     = "defer"
+	ClosureGoroutine = "goroutine"
+)
+
+// closure returns ClosureDefer if the call is deferred or in a function
+// literal a defer statement runs (defer func() { ... }()), ClosureGoroutine
+// likewise for go statements, or "" otherwise. The innermost statement
+// decides. path runs from the file down to the call; the search stops at
+// the enclosing function declaration.
+func closure(path []ast.Node) string {
+	for i := len(path) - 2; i >= 0; i-- {
+		switch path[i].(type) {
+		case *ast.FuncDecl:
+			return ""
+		case *ast.DeferStmt:
+			return ClosureDefer
+		case *ast.GoStmt:
+			return ClosureGoroutine
+		}
+	}
+	return ""
+}
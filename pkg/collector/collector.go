@@ -0,0 +1,1453 @@
+// Package collector finds the log calls in Go source and describes each
+// one, with its arguments and suggested field keys, as a LogEntry. Run
+// returns the entries of a tree, and Scan iterates over them; Collect
+// writes them to a CSV file (or database) for review, which
+// transformer.Transform then applies, and CollectTo to any io.Writer in a
+// codec's format.
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/fs"
+	"iter"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"logrefactor/internal/cluster"
+	"logrefactor/internal/lint"
+	"logrefactor/internal/logcall"
+	"logrefactor/internal/naming"
+	"logrefactor/internal/normalize"
+	"logrefactor/internal/plugin"
+	"logrefactor/internal/table"
+	"logrefactor/pkg/codec"
+)
+
+// LogEntry represents a single log statement with all its arguments for structured logging migration
+type LogEntry struct {
+	ID               string
+	FilePath         string
+	Line             int
+	Column           int
+	Package          string
+	OriginalCall     string // e.g., "log.Printf"
+	SourceLibrary    string // Logging library the call belongs to, e.g. "logrus" (see Library)
+	Receiver         string // Struct field or accessor the call logs to, e.g. "s.logger"; empty for package functions and plain variables
+	Closure          string // ClosureDefer or ClosureGoroutine when the call runs in a defer or go statement
+	InLoop           string // LoopFor or LoopRange when the call is in a loop body, LoopHot in a function on Options.HotPaths
+	Returns          string // Error the function returns right after logging it, e.g. "err" (see LogAndReturn)
+	Helper           string // Logging helper the call is to, or that the call is inside, when the helper takes its message first (see Options.Helpers)
+	LogLevel         string // e.g., "Info", "Error", "Debug" (extracted if possible)
+	SuggestedLevel   string // Level inferred from the call site when LogLevel is Unknown or Info (see suggestLevel)
+	LevelConfidence  string // How sure SuggestedLevel is: high, medium or low
+	MessageTemplate  string // The format string or message
+	SuggestedMessage string // MessageTemplate with the style rules applied when it breaks them (see Options.MessageRules)
+	ClusterID        string // Cluster of entries with alike messages, e.g. "MSG-0003" (see cluster.Clusters)
+	Arguments        []Argument
+	NewCall          string // To be filled: new logging function call
+	NewMessage       string // To be filled: improved message
+	StructuredFields string // To be filled: JSON or comma-separated field mappings
+	Notes            string
+	Status           string  // Review status to start from: ManualReview for a message that isn't a constant, otherwise empty
+	FieldConfidence  float64 // How likely the fields -auto-map derives from Arguments are right, 0 to 1 (see fieldConfidence)
+}
+
+// Argument represents a single argument passed to the log function
+type Argument struct {
+	Index       int    // Position in argument list (0-based)
+	Expression  string // The actual Go expression (e.g., "user.Name", "err")
+	VarName     string // Simplified variable name for field key
+	Type        string // Inferred type if possible
+	FormatVerb  string // Associated format verb (%s, %v, %d, etc.) if applicable
+	SuggestedKey string // Suggested field name for structured logging
+}
+
+// UnstructuredPattern matches printf-style calls on the usual logger names
+// (log.Printf, logger.Infof, klog.V(2).Infof, ...) but not the structured
+// calls that replace them, nor fmt.Errorf. It is the default for check and
+// the analyzer; the collect pattern is usually broader.
+const UnstructuredPattern = `(^|\.)(log|logger|logrus|klog|glog)(\.V\(.*\))?\.((Print|Fatal|Panic)(f|ln)?|(Trace|Debug|Info|Warn|Warning|Error)f)$`
+
+// DefaultPattern is the pattern collect matches calls with unless told
+// otherwise: anything on log, logrus or logger
+const DefaultPattern = `log\.|logrus\.|logger\.`
+
+// Options configure Run and Collect. The zero value scans the current
+// directory with DefaultPattern.
+type Options struct {
+	// Root is the directory to scan (default: the current directory)
+	Root string
+	// Files, if not nil, limits the scan to these files, such as those
+	// staged in git. Files that aren't Go files, aren't under Root or match
+	// an exclude are skipped, as are files that no longer exist. An empty
+	// list scans nothing.
+	Files []string
+	// Pattern is a regex matched against the function of each call, e.g.
+	// "log.Printf" (default: DefaultPattern)
+	Pattern string
+	// KeyStyle controls the suggested field keys (see the naming package;
+	// default: snake_case)
+	KeyStyle string
+	// Excludes are paths to skip (see isExcluded)
+	Excludes []string
+	// SkipTests skips _test.go files, as if "*_test.go" were excluded
+	SkipTests bool
+	// Matcher is a WASM plugin that calls matching Pattern are also passed
+	// to, which decides whether they are log statements
+	Matcher string
+	// Matchers, if set, decide which calls are log statements instead of
+	// Pattern: every call is offered to them in turn and the first that
+	// matches records it. PatternMatcher brings the pattern back as one of
+	// them. The Matcher plugin is still asked about the calls they match.
+	// They can't be used with Cache.
+	Matchers []CallMatcher
+	// Imports, if set, limits the calls matched to those whose function or
+	// method belongs to one of these packages (see Call.Target and
+	// ImportMatch), e.g. "log" or "github.com/sirupsen/logrus", so a local
+	// variable named log or dialog.Printf isn't taken for a logger. Calls
+	// the file alone can't resolve, such as on a logger declared in another
+	// file, are kept.
+	Imports []string
+	// Helpers records calls to the project's logging helpers as entries:
+	// functions of a few statements around a single matched call that
+	// pass it a parameter, such as
+	// func logError(msg string, err error) { log.Printf("%s: %v", msg, err) }.
+	// Calls in the same package and to exported helpers of imported
+	// packages are recorded, with the helper's level and the arguments
+	// from its message parameter on. The call inside the helper is kept,
+	// with a note. It can't be used with Cache, since a file's entries
+	// then depend on other files.
+	Helpers bool
+	// HotPaths are functions whose log calls are hot even outside a loop,
+	// such as request handlers, as globs on "Func" or "Type.Method",
+	// optionally qualified with the package name (e.g. "*.ServeHTTP").
+	// Their entries have InLoop set to LoopHot.
+	HotPaths []string
+	// MessageRules are the message style rules messages are checked
+	// against (see lint.ParseRules; nil checks all of them). A message that
+	// breaks one gets a note and SuggestedMessage.
+	MessageRules []string
+	// Verbosity are the V(n) levels Debug and Trace start at, e.g.
+	// {"Debug": 4, "Trace": 5} (default: KlogVerbosity, or LogrVerbosity
+	// for logr), which set the level of Info calls made on V(n), such as
+	// klog.V(4).Infof, or inside an if checking it, such as
+	// if klog.V(4).Enabled() { ... }
+	Verbosity map[string]int
+	// Jobs is the number of goroutines parsing files (default: GOMAXPROCS).
+	// The entries and their IDs are the same for any number.
+	Jobs int
+	// Cache, if set, is a file caching the entries of every file, so files
+	// that haven't changed since the last run aren't parsed again. It
+	// can't be used with FS.
+	Cache string
+	// FS, if set, is the file system to scan instead of the OS, such as an
+	// archive or an fstest.MapFS. Root and Files are then slash-separated
+	// names in it, as are the FilePath of the entries.
+	FS fs.FS
+
+	// Hooks for progress and diagnostics. They are called one at a time, in
+	// the order of the output: OnFileStart for each file scanned, then
+	// OnEntryFound for each of its entries, with its ID. OnWarning gets the
+	// problems that don't stop the scan, such as files that don't parse;
+	// without it they are printed to standard error.
+	OnFileStart  func(path string)
+	OnEntryFound func(entry LogEntry)
+	OnWarning    func(err error)
+}
+
+// scanner returns the scanner and walker for opts
+func (opts Options) scanner() (*scanner, walker, error) {
+	if opts.Root == "" {
+		opts.Root = "."
+	}
+	if opts.Pattern == "" {
+		opts.Pattern = DefaultPattern
+	}
+	if opts.SkipTests {
+		opts.Excludes = append(slices.Clone(opts.Excludes), "*_test.go")
+	}
+	if opts.FS != nil && opts.Cache != "" {
+		return nil, nil, fmt.Errorf("the cache can't be used with an FS")
+	}
+	if len(opts.Matchers) > 0 && opts.Cache != "" {
+		return nil, nil, fmt.Errorf("the cache can't be used with matchers")
+	}
+	if opts.Helpers && opts.Cache != "" {
+		return nil, nil, fmt.Errorf("the cache can't be used with helpers")
+	}
+	s, err := newScanner(opts.Pattern, opts.KeyStyle, opts.Matcher, opts.Imports, opts.HotPaths, opts.MessageRules, opts.Verbosity, opts.Jobs, opts.Cache)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(opts.Matchers) > 0 {
+		// Matched calls needn't have any word of the pattern
+		s.matchers, s.filter = opts.Matchers, nil
+	}
+	s.traceHelpers = opts.Helpers
+	s.onFile, s.onEntry, s.onWarning = opts.OnFileStart, opts.OnEntryFound, opts.OnWarning
+	if opts.FS != nil {
+		s.fsys = opts.FS
+		if opts.Files == nil {
+			return s, fsTreeWalker(opts.FS, opts.Root, opts.Excludes), nil
+		}
+		return s, fsFileWalker(opts.FS, opts.Root, opts.Files, opts.Excludes), nil
+	}
+	if opts.Files == nil {
+		return s, treeWalker(opts.Root, opts.Excludes), nil
+	}
+	walk, err := fileWalker(opts.Root, opts.Files, opts.Excludes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, walk, nil
+}
+
+// Run returns the log entries found with opts, sorted by file, line and
+// column. It stops early, with ctx.Err(), if ctx is cancelled.
+func Run(ctx context.Context, opts Options) ([]LogEntry, error) {
+	s, walk, err := opts.scanner()
+	if err != nil {
+		return nil, err
+	}
+	return s.all(ctx, walk)
+}
+
+// Scan returns the log entries found with opts as an iterator, in the order
+// Run returns them. Files are parsed as the loop consumes their entries, a
+// few files ahead, so only those are held in memory and breaking out of the
+// loop stops the scan. An error ending the scan, such as ctx.Err() when ctx
+// is cancelled, is yielded last, with a zero LogEntry.
+func Scan(ctx context.Context, opts Options) iter.Seq2[LogEntry, error] {
+	return func(yield func(LogEntry, error) bool) {
+		s, walk, err := opts.scanner()
+		if err != nil {
+			yield(LogEntry{}, err)
+			return
+		}
+		err = s.run(ctx, walk, func(entries []LogEntry) error {
+			for _, entry := range entries {
+				if !yield(entry, nil) {
+					return errStopped
+				}
+			}
+			return nil
+		})
+		if err != nil && err != errStopped {
+			yield(LogEntry{}, err)
+		}
+	}
+}
+
+// errStopped ends a scan whose loop was broken out of
+var errStopped = errors.New("scan stopped")
+
+// Collect writes the log entries found with opts to outputFile, a CSV file
+// or database (see table.IsDatabase). Rows are written as files are parsed,
+// so memory stays flat on large trees and an interrupted run leaves the
+// rows of the files it finished. Cancelling ctx stops the run the same way,
+// returning ctx.Err() once the rows so far are written.
+func Collect(ctx context.Context, outputFile string, opts Options) error {
+	s, walk, err := opts.scanner()
+	if err != nil {
+		return err
+	}
+	return s.collect(ctx, outputFile, walk)
+}
+
+// CollectTo is Collect writing to w in the format of c (codec.CSV writes
+// what Collect does), for callers that keep entries in memory or send them
+// elsewhere. Rows are flushed to w a file at a time.
+func CollectTo(ctx context.Context, w io.Writer, c codec.Codec, opts Options) error {
+	s, walk, err := opts.scanner()
+	if err != nil {
+		return err
+	}
+	enc := c.NewEncoder(w)
+	if err := enc.Write(header); err != nil {
+		return err
+	}
+	err = s.run(ctx, walk, func(entries []LogEntry) error {
+		if err := encode(enc, entries); err != nil {
+			return err
+		}
+		return enc.Flush()
+	})
+	if flushErr := enc.Flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+// WriteEntries writes entries to w in the format of c, with the columns
+// Collect writes, e.g. to save the result of Run after filtering it
+func WriteEntries(w io.Writer, c codec.Codec, entries []LogEntry) error {
+	enc := c.NewEncoder(w)
+	if err := enc.Write(header); err != nil {
+		return err
+	}
+	if err := encode(enc, entries); err != nil {
+		return err
+	}
+	return enc.Flush()
+}
+
+// encode writes the rows of entries to enc
+func encode(enc codec.Encoder, entries []LogEntry) error {
+	for _, row := range rows(entries) {
+		if err := enc.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walker calls fn for every file to scan
+type walker func(fn func(path string) error) error
+
+// treeWalker walks the Go files under rootPath (see WalkGoFiles)
+func treeWalker(rootPath string, excludes []string) walker {
+	return func(fn func(path string) error) error {
+		return WalkGoFiles(rootPath, excludes, fn)
+	}
+}
+
+// fileWalker walks the files of Options.Files
+func fileWalker(rootPath string, files []string, excludes []string) (walker, error) {
+	absRoot, err := resolve(rootPath)
+	if err != nil {
+		return nil, err
+	}
+	return func(fn func(path string) error) error {
+		for _, file := range files {
+			if !strings.HasSuffix(file, ".go") {
+				continue
+			}
+			abs, err := resolve(file)
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(absRoot, abs)
+			if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				continue
+			}
+			path := filepath.Join(rootPath, rel)
+			if isExcluded(rootPath, path, excludes) || excludedDir(rootPath, path, excludes) {
+				continue
+			}
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+			if err := fn(path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+// fsTreeWalker walks the Go files under root in fsys, as treeWalker does
+// on the OS
+func fsTreeWalker(fsys fs.FS, root string, excludes []string) walker {
+	return func(fn func(path string) error) error {
+		err := fs.WalkDir(fsys, root, func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if name != root && isExcluded(root, name, excludes) {
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() || !strings.HasSuffix(name, ".go") {
+				return nil
+			}
+			return fn(name)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to walk directory: %w", err)
+		}
+		return nil
+	}
+}
+
+// fsFileWalker walks the files of Options.Files in fsys, as fileWalker
+// does on the OS
+func fsFileWalker(fsys fs.FS, root string, files []string, excludes []string) walker {
+	root = path.Clean(root)
+	return func(fn func(path string) error) error {
+		for _, file := range files {
+			name := path.Clean(file)
+			if !strings.HasSuffix(name, ".go") || (root != "." && !strings.HasPrefix(name, root+"/")) {
+				continue
+			}
+			if isExcluded(root, name, excludes) || excludedDir(root, name, excludes) {
+				continue
+			}
+			if _, err := fs.Stat(fsys, name); err != nil {
+				continue
+			}
+			if err := fn(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// resolve returns the absolute path with symlinks resolved where possible,
+// so paths git reports compare equal to the ones given on the command line
+func resolve(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	return abs, nil
+}
+
+// excludedDir reports whether a directory between rootPath and path is
+// excluded, which WalkGoFiles would not have descended into
+func excludedDir(rootPath, path string, excludes []string) bool {
+	root := filepath.Clean(rootPath)
+	for dir := filepath.Dir(path); dir != root && dir != "." && dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if isExcluded(rootPath, dir, excludes) {
+			return true
+		}
+	}
+	return false
+}
+
+// scanner parses files for log entries
+type scanner struct {
+	pattern      *regexp.Regexp
+	keyStyle     string
+	matcherPath  string         // WASM matcher plugin, if set
+	matcher      *plugin.Plugin // Loaded from matcherPath by run
+	matchers     []CallMatcher  // Replace pattern when set
+	imports      []string       // Packages matched calls must belong to, if set
+	hotPaths     []string       // Functions whose calls are hot (see Options.HotPaths)
+	rules        []string       // Message style rules (see Options.MessageRules)
+	verbosity    map[string]int // V(n) levels of Debug and Trace (see Options.Verbosity)
+	helpers      *helperSet     // Found before the scan when traceHelpers
+	jobs         int
+	filter       prefilter // Skips files that can't match without parsing them
+	cache        *cache    // Nil when not caching
+	traceHelpers bool      // Whether calls to helpers are recorded
+	fsys         fs.FS     // Nil to read files from the OS
+
+	// Hooks (see Options)
+	onFile    func(path string)
+	onEntry   func(entry LogEntry)
+	onWarning func(err error)
+}
+
+// warn reports a problem that doesn't stop the scan
+func (s *scanner) warn(err error) {
+	if s.onWarning != nil {
+		s.onWarning(err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+}
+
+// newScanner checks the settings of a scan (see Options)
+func newScanner(pattern, keyStyle, matcherPlugin string, imports, hotPaths, messageRules []string, verbosity map[string]int, jobs int, cacheFile string) (*scanner, error) {
+	if keyStyle == "" {
+		keyStyle = naming.SnakeCase
+	} else if naming.Normalize(keyStyle) == "" {
+		return nil, fmt.Errorf("invalid key style: %s", keyStyle)
+	}
+
+	logPattern, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	if jobs <= 0 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
+	for _, p := range hotPaths {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid hot path %q: %w", p, err)
+		}
+	}
+	rules, err := lint.ParseRules(messageRules)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &scanner{pattern: logPattern, keyStyle: keyStyle, matcherPath: matcherPlugin, imports: imports, hotPaths: hotPaths, rules: rules, verbosity: verbosity, jobs: jobs, filter: newPrefilter(pattern)}
+	if cacheFile != "" {
+		if s.cache, err = loadCache(cacheFile, pattern, keyStyle, matcherPlugin, imports, hotPaths, rules, verbosity); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// all returns the entries of the files walk passes
+func (s *scanner) all(ctx context.Context, walk walker) ([]LogEntry, error) {
+	var entries []LogEntry
+	err := s.run(ctx, walk, func(fileEntries []LogEntry) error {
+		entries = append(entries, fileEntries...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// collect writes the entries of the files walk passes to a CSV file, a
+// file at a time
+func (s *scanner) collect(ctx context.Context, outputFile string, walk walker) error {
+	w, err := table.Create(outputFile, header)
+	if err != nil {
+		return err
+	}
+	err = s.run(ctx, walk, func(entries []LogEntry) error {
+		return w.Append(rows(entries))
+	})
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// run parses the files walk passes and calls emit with the entries of each
+// file. The files are parsed concurrently, but emit sees them sorted by
+// path, and each file's entries by line and column, so the output (and the
+// IDs and ClusterIDs given to entries) is the same from run to run whatever
+// the walk order or number of workers. Workers get at most a few files ahead of the next
+// file to emit, which bounds the results held for ordering. Cancelling ctx
+// stops the run after the file being emitted.
+func (s *scanner) run(ctx context.Context, walk walker, emit func(entries []LogEntry) error) error {
+	var paths []string
+	if err := walk(func(path string) error {
+		paths = append(paths, path)
+		return nil
+	}); err != nil {
+		return err
+	}
+	sort.Strings(paths)
+	paths = slices.Compact(paths)
+
+	// The matcher is loaded for the run and freed once its workers are done
+	if s.matcherPath != "" {
+		matcher, err := plugin.Load(ctx, s.matcherPath)
+		if err != nil {
+			return fmt.Errorf("failed to load matcher: %w", err)
+		}
+		defer matcher.Close(ctx)
+		s.matcher = matcher
+	}
+
+	if s.traceHelpers {
+		helpers, err := s.findHelpers(ctx, paths)
+		if err != nil {
+			return err
+		}
+		s.helpers = helpers
+		if s.filter != nil {
+			s.filter = append(s.filter, helpers.words()...)
+		}
+	}
+
+	results := make([]chan fileResult, len(paths))
+	for i := range results {
+		results[i] = make(chan fileResult, 1)
+	}
+	next := make(chan int)
+	window := make(chan struct{}, 4*s.jobs)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < s.jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				var r fileResult
+				r.entries, r.warnings, r.err = s.parse(ctx, paths[i])
+				results[i] <- r
+			}
+		}()
+	}
+	go func() {
+		defer close(next)
+		for i := range paths {
+			select {
+			case window <- struct{}{}:
+				next <- i
+			case <-stop:
+				return
+			}
+		}
+	}()
+	defer wg.Wait()
+	defer close(stop)
+
+	entryID := 1
+	clusters := cluster.NewClusters()
+	for i := range paths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		r := <-results[i]
+		<-window
+		if s.onFile != nil {
+			s.onFile(paths[i])
+		}
+		for _, warning := range r.warnings {
+			s.warn(warning)
+		}
+		if r.err != nil {
+			s.warn(&ParseError{Path: paths[i], Err: r.err})
+			continue
+		}
+		sortEntries(r.entries)
+		for j := range r.entries {
+			r.entries[j].ID = fmt.Sprintf("LOG-%04d", entryID)
+			r.entries[j].ClusterID = clusters.Add(r.entries[j].MessageTemplate)
+			entryID++
+			if s.onEntry != nil {
+				s.onEntry(r.entries[j])
+			}
+		}
+		if err := emit(r.entries); err != nil {
+			return err
+		}
+	}
+	if s.cache != nil {
+		if err := s.cache.save(); err != nil {
+			return fmt.Errorf("failed to write cache: %w", err)
+		}
+	}
+	return nil
+}
+
+// parse returns the entries of a file, from the cache if it hasn't changed,
+// and the problems met on the way. Files the prefilter rules out have none
+// and aren't parsed.
+func (s *scanner) parse(ctx context.Context, path string) ([]LogEntry, []error, error) {
+	var content []byte
+	if s.cache != nil {
+		entries, cached, ok := s.cache.lookup(path)
+		if ok {
+			return entries, nil, nil
+		}
+		content = cached
+	}
+	if content == nil {
+		var err error
+		if content, err = s.readFile(path); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var entries []LogEntry
+	var warnings []error
+	if s.filter.match(content) {
+		warn := func(err error) { warnings = append(warnings, err) }
+		var err error
+		if entries, err = parseFile(ctx, path, content, s.pattern, s.matchers, s.imports, s.hotPaths, s.rules, s.verbosity, s.helpers, s.keyStyle, s.matcher, warn); err != nil {
+			return nil, nil, err
+		}
+	}
+	// A file with problems (a failing matcher) is scanned again next time
+	if s.cache != nil && len(warnings) == 0 {
+		s.cache.add(path, content, entries)
+	}
+	return entries, warnings, nil
+}
+
+// readFile reads a file to scan
+func (s *scanner) readFile(path string) ([]byte, error) {
+	if s.fsys != nil {
+		return fs.ReadFile(s.fsys, path)
+	}
+	return os.ReadFile(path)
+}
+
+// sortEntries sorts the entries of a file by line, then column
+func sortEntries(entries []LogEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Line != entries[j].Line {
+			return entries[i].Line < entries[j].Line
+		}
+		return entries[i].Column < entries[j].Column
+	})
+}
+
+// ParseError is a Go file that couldn't be parsed. A scan skips the file
+// with a warning; a transform fails it.
+type ParseError struct {
+	Path string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("failed to parse %s: %v", e.Path, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// fileResult is what parse returned for one file
+type fileResult struct {
+	entries  []LogEntry
+	warnings []error
+	err      error
+}
+
+// WalkGoFiles calls fn for every .go file under rootPath that no exclude
+// pattern matches
+func WalkGoFiles(rootPath string, excludes []string, fn func(path string) error) error {
+	err := filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path != rootPath && isExcluded(rootPath, path, excludes) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Skip directories and non-Go files
+		if info.IsDir() || !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+
+		return fn(path)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to walk directory: %w", err)
+	}
+	return nil
+}
+
+// CallName returns the name collect gives a call and matches patterns
+// against, e.g. "log.Printf" or "logger.WithField(...).Info"
+func CallName(call *ast.CallExpr) string {
+	return getFunctionName(call)
+}
+
+// isExcluded reports whether path matches one of the exclude patterns. A
+// pattern matches the file or directory name ("vendor", "*_gen.go") or the
+// slash-separated path relative to rootPath ("internal/generated",
+// "cmd/*/testdata").
+func isExcluded(rootPath, path string, excludes []string) bool {
+	rel, err := filepath.Rel(rootPath, path)
+	if err != nil {
+		rel = path
+	}
+	rel = filepath.ToSlash(rel)
+	name := filepath.Base(path)
+
+	for _, pattern := range excludes {
+		pattern = strings.TrimSuffix(strings.TrimPrefix(filepath.ToSlash(pattern), "./"), "/")
+		if pattern == "" {
+			continue
+		}
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFile parses a single Go file and extracts log entries with full
+// argument details from its content. The entries are numbered by the
+// caller. Calls are matched with matchers if there are any, otherwise with
+// logPattern, then kept if they belong to one of imports (see
+// Options.Imports). Calls to helpers, if set, are recorded too (see
+// Options.Helpers). Calls a matcher fails on are skipped and passed to warn.
+func parseFile(ctx context.Context, filePath string, content []byte, logPattern *regexp.Regexp, matchers []CallMatcher, imports, hotPaths, rules []string, verbosity map[string]int, helpers *helperSet, keyStyle string, matcher *plugin.Plugin, warn func(error)) ([]LogEntry, error) {
+	fset := token.NewFileSet()
+	node, err := parser.ParseFile(fset, filePath, content, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []LogEntry
+	packageName := node.Name.Name
+	res := &resolver{fset: fset, file: node}
+
+	// Walk the AST, keeping the path from the file to the node visited
+	var path []ast.Node
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n == nil {
+			path = path[:len(path)-1]
+			return true
+		}
+		path = append(path, n)
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+
+		// Get the function selector
+		funcName := getFunctionName(call)
+		if funcName == "" {
+			return true
+		}
+		// A call returning the logger another call logs with, such as
+		// logrus.WithField(...) in logrus.WithField(...).Info(...), is part
+		// of that call
+		if chainLink(path) {
+			return true
+		}
+		// So is a slog attribute, such as slog.String("user", name), and
+		// klog's values and verbosity checks, such as klog.KObj(pod) and
+		// klog.V(4).Enabled(), don't log
+		if name, _ := logcall.SlogConstructor(call); name != "" || klogValue(call) || verbosityCheck(call) {
+			return true
+		}
+		if h := helpers.of(call, filePath, packageName, res); h != nil {
+			entry := h.entry(call, fset, packageName, keyStyle)
+			if entry.LogLevel == "Unknown" || entry.LogLevel == "Info" {
+				entry.SuggestedLevel, entry.LevelConfidence = suggestLevel(path, entry.Arguments)
+			}
+			entry.Closure = closure(path)
+			entry.InLoop = inLoop(path, packageName, hotPaths)
+			entry.Returns = returned(path)
+			markTimes(&entry, h.args(call), res)
+			markStructs(&entry, h.args(call), res.typeInfo())
+			quarantine(&entry, h.shift(call), res.typeInfo())
+			styleMessage(&entry, rules)
+			entries = append(entries, entry)
+			return true
+		}
+		if len(matchers) == 0 && !logPattern.MatchString(funcName) {
+			return true
+		}
+
+		// Extract position information
+		pos := fset.Position(call.Pos())
+
+		target, resolved := res.target(call)
+		library := ""
+		if resolved {
+			library = Library(target)
+		}
+		// go-kit's With, such as log.With(logger, "user", id), returns
+		// the logger a call logs with, like a chain link
+		if library == "go-kit" && logcall.GokitWith(call) {
+			return true
+		}
+
+		// Extract log level from the call if possible, or from the
+		// verbosity an if around it checks
+		logLevel := callLevel(call, funcName, library, verbosity)
+		if n, ok := guardVerbosity(path); ok && logLevel == "Info" {
+			logLevel = verbosityLevel(n, libraryVerbosity(library, verbosity))
+		}
+
+		if len(matchers) > 0 {
+			ok, level, err := matchCall(matchers, &Call{
+				Expr:    call,
+				Name:    funcName,
+				Level:   logLevel,
+				Path:    filePath,
+				Package: packageName,
+				File:    node,
+				Fset:    fset,
+				res:     res,
+			})
+			if err != nil {
+				warn(fmt.Errorf("matcher failed for %s:%d: %w", filePath, pos.Line, err))
+				return true
+			}
+			if !ok {
+				return true
+			}
+			logLevel = level
+		}
+
+		if len(imports) > 0 && resolved && !ImportMatch(imports, target) {
+			return true
+		}
+
+		if matcher != nil {
+			resp, err := pluginMatch(ctx, matcher, matchRequest{
+				File:    filePath,
+				Package: packageName,
+				Line:    pos.Line,
+				Column:  pos.Column,
+				Call:    funcName,
+				Level:   logLevel,
+				Args:    callArgs(call),
+			})
+			if err != nil {
+				warn(fmt.Errorf("matcher failed for %s:%d: %w", filePath, pos.Line, err))
+				return true
+			}
+			if !resp.Match {
+				return true
+			}
+			if resp.Level != "" {
+				logLevel = resp.Level
+			}
+		}
+
+		entry := newEntry(call, fset, packageName, keyStyle, library)
+		entry.LogLevel = logLevel
+		entry.SourceLibrary = library
+		entry.Receiver = res.receiver(call, library)
+		entry.Closure = closure(path)
+		entry.InLoop = inLoop(path, packageName, hotPaths)
+		entry.Returns = returned(path)
+		if logLevel == "Unknown" || logLevel == "Info" {
+			entry.SuggestedLevel, entry.LevelConfidence = suggestLevel(path, entry.Arguments)
+		}
+		shifted, _, _ := pairCall(call, library, keyStyle)
+		args := shifted.Args
+		if len(args) > 0 {
+			markTimes(&entry, args[1:], res)
+			markStructs(&entry, args[1:], res.typeInfo())
+		}
+		quarantine(&entry, args, res.typeInfo())
+		styleMessage(&entry, rules)
+		if h := helpers.wraps(filePath, pos); h != nil {
+			entry.Notes = joinNotes(entry.Notes, fmt.Sprintf("HELPER: wrapped by %s, whose calls are recorded as entries", h.name))
+			h.mark(&entry)
+		}
+		entries = append(entries, entry)
+
+		return true
+	})
+
+	return entries, nil
+}
+
+// Entry returns the entry collect records for a log call, without an ID.
+// The position and file path come from fset.
+func Entry(call *ast.CallExpr, fset *token.FileSet, packageName, keyStyle string) LogEntry {
+	return newEntry(call, fset, packageName, keyStyle, "")
+}
+
+// newEntry is Entry for a call belonging to library (see Library), which
+// tells how calls alike, such as logger.Info("login", "user", name), pass
+// their fields. With "" the call itself has to tell.
+func newEntry(call *ast.CallExpr, fset *token.FileSet, packageName, keyStyle, library string) LogEntry {
+	funcName := getFunctionName(call)
+	pos := fset.Position(call.Pos())
+	level := callLevel(call, funcName, library, nil)
+
+	// Extract message and all arguments; key/value pairs, such as slog's
+	// and klog's InfoS's, carry their keys
+	shifted, pairs, paired := pairCall(call, library, keyStyle)
+	call = shifted
+	messageTemplate, arguments := extractLogDetails(call, fset, keyStyle)
+	if paired {
+		arguments = pairs
+	}
+
+	// Messages holding key=value pairs start with their fields filled in,
+	// as do zap calls and calls logging pairs with their keys
+	fields, message := keyedFields(messageTemplate, arguments)
+	if fields == "" {
+		fields = zapFields(call, arguments)
+	}
+	if fields == "" && paired && !slices.ContainsFunc(arguments, unreadArgument) {
+		fields, _ = formatFields(arguments)
+	}
+
+	// So do calls setting fields on their logger, such as
+	// logrus.WithFields(...).Errorf(...), with those fields first and
+	// arguments they already log left out, unless an argument can't be
+	// mapped as it is
+	mismatch := printfNote(funcName, call)
+	chained, unread := chainedArguments(call, library)
+	status := ""
+	if len(unread) > 0 {
+		status = ManualReview
+	}
+	if len(chained) > 0 && len(unread) == 0 && mismatch == "" && !slices.ContainsFunc(arguments, unreadArgument) {
+		all := slices.Clone(chained)
+		for _, arg := range arguments {
+			if !slices.ContainsFunc(chained, func(c Argument) bool { return c.Expression == arg.Expression }) {
+				all = append(all, arg)
+			}
+		}
+		if chainFields, ok := formatFields(all); ok {
+			fields = chainFields
+		}
+	}
+
+	// Flag what shouldn't be migrated as is, and the values that make
+	// every message unique
+	var issues, interpolated []lint.Issue
+	for _, arg := range chained {
+		if issue, ok := lint.Field(arg.SuggestedKey, arg.Expression, arg.Type); ok {
+			issues = append(issues, issue)
+		}
+	}
+	for _, arg := range arguments {
+		if issue, ok := lint.Field(arg.SuggestedKey, arg.Expression, arg.Type); ok {
+			issues = append(issues, issue)
+		}
+		if arg.FormatVerb == "" {
+			continue
+		}
+		if issue, ok := lint.Interpolated(arg.SuggestedKey, arg.Expression, arg.Type); ok {
+			interpolated = append(interpolated, issue)
+		}
+	}
+
+	return LogEntry{
+		FilePath:         pos.Filename,
+		Line:             pos.Line,
+		Column:           pos.Column,
+		Package:          packageName,
+		OriginalCall:     funcName,
+		LogLevel:         level,
+		MessageTemplate:  messageTemplate,
+		Arguments:        arguments,
+		FieldConfidence:  fieldConfidence(messageTemplate, arguments),
+		NewCall:          "", // To be filled by user
+		NewMessage:       message,
+		StructuredFields: fields,
+		Status:           status,
+		Notes:            joinNotes(mismatch, zapNote(arguments), slogNote(arguments), pairNote(arguments), chainNote(unread), lint.Note(issues), lint.CardinalityNote(interpolated)),
+	}
+}
+
+// styleMessage checks the message of an entry against the style rules,
+// noting the rules it breaks and filling in SuggestedMessage
+func styleMessage(entry *LogEntry, rules []string) {
+	issues, fix := lint.Template(entry.MessageTemplate, rules)
+	entry.SuggestedMessage = fix
+	entry.Notes = joinNotes(entry.Notes, lint.StyleNote(issues, fix))
+}
+
+// getFunctionName extracts the function name from a call expression
+func getFunctionName(call *ast.CallExpr) string {
+	switch fun := call.Fun.(type) {
+	case *ast.SelectorExpr:
+		if ident, ok := fun.X.(*ast.Ident); ok {
+			return ident.Name + "." + fun.Sel.Name
+		}
+		// Handle chained calls like logger.WithField().Info()
+		return formatExpr(fun)
+	case *ast.Ident:
+		return fun.Name
+	}
+	return ""
+}
+
+// callLevel returns the level of a log call of library (see newEntry): the
+// one its zerolog event starts at (see logcall.ZerologEvent), its slog level
+// constant names or go-kit's level package wraps its logger in, or else the
+// one its name gives, which for Info calls made on V(n), such as
+// klog.V(4).Infof, is the one n stands for under verbosity (see
+// verbosityLevel and libraryVerbosity)
+func callLevel(call *ast.CallExpr, funcName, library string, verbosity map[string]int) string {
+	if _, level, _, ok := logcall.ZerologEvent(call); ok {
+		return level
+	}
+	if level, ok := slogLevel(call); ok {
+		return level
+	}
+	if _, level, _, ok := logcall.GokitLogger(call); ok && library == "go-kit" && level != "" {
+		return level
+	}
+	level := extractLogLevel(funcName)
+	if n, ok := callVerbosity(call); ok && level == "Info" {
+		return verbosityLevel(n, libraryVerbosity(library, verbosity))
+	}
+	return level
+}
+
+// extractLogLevel tries to extract the log level from the function name:
+// for a chain such as log.WithField("debug", on).Error, from the last call
+func extractLogLevel(funcName string) string {
+	if i := strings.LastIndex(funcName, ")."); i >= 0 {
+		funcName = funcName[i+2:]
+	}
+	funcLower := strings.ToLower(funcName)
+	
+	levels := []string{"trace", "debug", "info", "warn", "warning", "error", "fatal", "panic"}
+	for _, level := range levels {
+		if strings.Contains(funcLower, level) {
+			// Capitalize first letter
+			return strings.ToUpper(level[:1]) + level[1:]
+		}
+	}
+	
+	// Check for Print variants
+	if strings.Contains(funcLower, "print") {
+		return "Info"
+	}
+	
+	return "Unknown"
+}
+
+// extractLogDetails extracts the message template and all arguments with metadata
+func extractLogDetails(call *ast.CallExpr, fset *token.FileSet, keyStyle string) (string, []Argument) {
+	if len(call.Args) == 0 {
+		return "", nil
+	}
+
+	var messageTemplate string
+	var arguments []Argument
+	var formatVerbs []string
+	var verbUnits []string
+	var verbKeys []string
+
+	// First argument is usually the message or format string
+	firstArg := call.Args[0]
+	if lit, ok := firstArg.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+		messageTemplate = lit.Value
+		// Extract format verbs from the template
+		formatVerbs = extractFormatVerbs(messageTemplate)
+		verbUnits = extractVerbUnits(messageTemplate)
+		verbKeys = extractVerbKeys(messageTemplate)
+	} else {
+		// If first arg is not a string literal, it might be a variable
+		messageTemplate = formatExpr(firstArg)
+	}
+
+	// zap fields carry their keys and types
+	if args, ok := zapArguments(call, keyStyle); ok {
+		return messageTemplate, args
+	}
+
+	// Process remaining arguments
+	for i := 1; i < len(call.Args); i++ {
+		arg := call.Args[i]
+		expr := formatExpr(arg)
+		varName := extractVarName(expr)
+		inferredType := inferType(arg)
+		
+		// Match with format verb if available
+		formatVerb := ""
+		if i-1 < len(formatVerbs) {
+			formatVerb = formatVerbs[i-1]
+		}
+
+		// An integer printed as "%dms" is a duration in milliseconds
+		if i-1 < len(verbUnits) && verbUnits[i-1] == "ms" && strings.HasSuffix(formatVerb, "d") {
+			inferredType = "duration_ms"
+			if words := naming.Words(varName); len(words) == 0 || words[len(words)-1] != "ms" {
+				varName += "Ms"
+			}
+		}
+
+		// Suggest a field key name, unless the message gives one ("user=%s")
+		suggestedKey := generateFieldKey(varName, formatVerb, inferredType, keyStyle)
+		if i-1 < len(verbKeys) && verbKeys[i-1] != "" {
+			suggestedKey = naming.Convert(verbKeys[i-1], keyStyle)
+		}
+
+		arguments = append(arguments, Argument{
+			Index:        i - 1,
+			Expression:   expr,
+			VarName:      varName,
+			Type:         inferredType,
+			FormatVerb:   formatVerb,
+			SuggestedKey: suggestedKey,
+		})
+	}
+
+	return messageTemplate, arguments
+}
+
+// extractFormatVerbs extracts format verbs (%s, %v, %d, etc.) from a format string
+func extractFormatVerbs(formatStr string) []string {
+	// Remove quotes
+	cleanStr := strings.Trim(formatStr, `"'`+"`")
+	
+	// Regex to match format verbs
+	re := regexp.MustCompile(`%[-+# 0]*[\d]*\.?[\d]*[vTtbcdoqxXUeEfFgGsp]`)
+	matches := re.FindAllString(cleanStr, -1)
+	
+	return matches
+}
+
+// extractVerbUnits returns, for each format verb, the unit suffix written
+// directly after it ("ms" for "%dms"), or "" if there is none
+func extractVerbUnits(formatStr string) []string {
+	cleanStr := strings.Trim(formatStr, `"'`+"`")
+
+	re := regexp.MustCompile(`%[-+# 0]*[\d]*\.?[\d]*[vTtbcdoqxXUeEfFgGsp]`)
+	var units []string
+	for _, loc := range re.FindAllStringIndex(cleanStr, -1) {
+		unit := ""
+		if strings.HasPrefix(cleanStr[loc[1]:], "ms") {
+			unit = "ms"
+		}
+		units = append(units, unit)
+	}
+
+	return units
+}
+
+// printfNote checks the format of a printf-style call (one whose name ends
+// in "f", such as Infof) against its arguments the way go vet does, and
+// returns a note for the Notes column if they don't match, naming the
+// arguments no verb reads (see transformer.LogUpdate.Unmapped). Formats that
+// aren't string literals, calls passing args... and formats with explicit
+// argument indexes (%[1]d) aren't checked.
+func printfNote(funcName string, call *ast.CallExpr) string {
+	if !strings.HasSuffix(funcName, "f") || len(call.Args) == 0 || call.Ellipsis.IsValid() {
+		return ""
+	}
+	lit, ok := call.Args[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return ""
+	}
+	format, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return ""
+	}
+
+	want, ok := normalize.ArgCount(format)
+	if !ok {
+		return ""
+	}
+	if want < 0 {
+		return "MISMATCH: format ends with a % that has no verb"
+	}
+	got := len(call.Args) - 1
+	if want == got {
+		return ""
+	}
+	note := fmt.Sprintf("MISMATCH: format reads %d %s but the call passes %d", want, plural(want, "argument"), got)
+	if want < got {
+		var extra []string
+		for _, arg := range call.Args[want+1:] {
+			extra = append(extra, formatExpr(arg))
+		}
+		verb := "have"
+		if len(extra) == 1 {
+			verb = "has"
+		}
+		note += fmt.Sprintf("; %s %s no verb, so the call isn't auto-mapped", strings.Join(extra, ", "), verb)
+	}
+	return note
+}
+
+// plural returns word, with an s unless n is 1
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+// formatExpr converts an expression to a string representation
+func formatExpr(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		return e.Value
+	case *ast.Ident:
+		return e.Name
+	case *ast.SelectorExpr:
+		return formatExpr(e.X) + "." + e.Sel.Name
+	case *ast.CallExpr:
+		args := make([]string, len(e.Args))
+		for i, arg := range e.Args {
+			args[i] = formatExpr(arg)
+		}
+		return formatExpr(e.Fun) + "(" + strings.Join(args, ", ") + ")"
+	case *ast.IndexExpr:
+		// For array/slice access
+		return formatExpr(e.X) + "[...]"
+	case *ast.UnaryExpr:
+		return e.Op.String() + formatExpr(e.X)
+	case *ast.BinaryExpr:
+		return formatExpr(e.X) + " " + e.Op.String() + " " + formatExpr(e.Y)
+	default:
+		// For complex expressions, use type name
+		return fmt.Sprintf("<%T>", expr)
+	}
+}
+
+// extractVarName extracts a simple variable name from an expression
+func extractVarName(expr string) string {
+	// For "user.Name" -> "Name"
+	// For "err" -> "err"
+	// For "count" -> "count"
+	
+	parts := strings.Split(expr, ".")
+	if len(parts) > 0 {
+		return parts[len(parts)-1]
+	}
+	return expr
+}
+
+// inferType tries to infer the type from the expression
+func inferType(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		switch e.Kind {
+		case token.INT:
+			return "int"
+		case token.FLOAT:
+			return "float"
+		case token.STRING:
+			return "string"
+		case token.CHAR:
+			return "rune"
+		}
+	case *ast.Ident:
+		name := e.Name
+		if name == "true" || name == "false" {
+			return "bool"
+		}
+		if name == "nil" {
+			return "nil"
+		}
+		// Check common variable name patterns
+		if strings.HasPrefix(name, "is") || strings.HasPrefix(name, "has") {
+			return "bool"
+		}
+		if name == "err" || strings.HasSuffix(name, "Error") {
+			return "error"
+		}
+	case *ast.CallExpr:
+		// Well-known time helpers
+		switch formatExpr(e.Fun) {
+		case "time.Since", "time.Until":
+			return "time.Duration"
+		case "time.Now":
+			return "time.Time"
+		}
+		return "func_result"
+	}
+	return "unknown"
+}
+
+// generateFieldKey generates a suggested field key name for structured logging
+func generateFieldKey(varName, formatVerb, inferredType, keyStyle string) string {
+	words := naming.Words(varName)
+
+	// Remove common prefixes
+	if len(words) > 1 && (words[0] == "p" || words[0] == "m") {
+		words = words[1:]
+	}
+	key := strings.Join(words, "_")
+	
+	// Special handling for common names
+	if renamed, ok := naming.DefaultRenames[key]; ok {
+		key = renamed
+	}
+	
+	return naming.Convert(key, keyStyle)
+}
+
+// header are the columns collect writes
+var header = []string{
+	"ID",
+	"FilePath",
+	"Line",
+	"Column",
+	"Package",
+	"OriginalCall",
+	"SourceLibrary",
+	"Receiver",
+	"Closure",
+	"InLoop",
+	"Returns",
+	"Helper",
+	"LogLevel",
+	"SuggestedLevel",
+	"LevelConfidence",
+	"MessageTemplate",
+	"SuggestedMessage",
+	"ClusterID",
+	"ArgumentCount",
+	"ArgumentDetails",
+	"FieldConfidence",
+	"NewCall",
+	"NewMessage",
+	"StructuredFields",
+	"Notes",
+	"Status",
+	"Approved",
+}
+
+// rows returns the CSV rows for entries with enhanced columns
+func rows(entries []LogEntry) [][]string {
+	result := make([][]string, 0, len(entries))
+	for _, entry := range entries {
+		// Format argument details as a readable string
+		argDetails := FormatArgumentDetails(entry.Arguments)
+
+		result = append(result, []string{
+			entry.ID,
+			entry.FilePath,
+			strconv.Itoa(entry.Line),
+			strconv.Itoa(entry.Column),
+			entry.Package,
+			entry.OriginalCall,
+			entry.SourceLibrary,
+			entry.Receiver,
+			entry.Closure,
+			entry.InLoop,
+			entry.Returns,
+			entry.Helper,
+			entry.LogLevel,
+			entry.SuggestedLevel,
+			entry.LevelConfidence,
+			entry.MessageTemplate,
+			entry.SuggestedMessage,
+			entry.ClusterID,
+			strconv.Itoa(len(entry.Arguments)),
+			argDetails,
+			formatConfidence(entry.FieldConfidence),
+			entry.NewCall,
+			entry.NewMessage,
+			entry.StructuredFields,
+			entry.Notes,
+			entry.Status,
+			"", // Approved, set during review
+		})
+	}
+	return result
+}
+
+// FormatArgumentDetails formats the arguments as the ArgumentDetails column
+func FormatArgumentDetails(args []Argument) string {
+	if len(args) == 0 {
+		return ""
+	}
+
+	var parts []string
+	for _, arg := range args {
+		detail := fmt.Sprintf("%s(%s)=%s",
+			arg.SuggestedKey,
+			arg.Type,
+			arg.Expression,
+		)
+		if arg.FormatVerb != "" {
+			detail += fmt.Sprintf("[%s]", arg.FormatVerb)
+		}
+		parts = append(parts, detail)
+	}
+	
+	return strings.Join(parts, "; ")
+}
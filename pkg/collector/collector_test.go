@@ -0,0 +1,212 @@
+package collector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		imports string
+		body    string // Body of func f(path, name string, a, b int, err error) error
+		want    LogEntry
+		keys    []string // SuggestedKey of each argument
+	}{
+		{
+			name: "printf",
+			body: `log.Printf("user %s has %d items", name, a)`,
+			want: LogEntry{OriginalCall: "log.Printf", LogLevel: "Info", SourceLibrary: "log"},
+			keys: []string{"name", "a"},
+		},
+		{
+			name:    "klog verbosity",
+			imports: `"k8s.io/klog/v2"`,
+			body:    `klog.V(2).Infof("synced %s", name)`,
+			want:    LogEntry{OriginalCall: "klog.V(2).Infof"},
+		},
+		{
+			name: "format missing an argument",
+			body: `log.Printf("a=%d b=%d", a)`,
+			want: LogEntry{Notes: "MISMATCH: format reads 2 arguments but the call passes 1"},
+		},
+		{
+			name: "argument with no verb",
+			body: `log.Printf("a=%d", a, b)`,
+			want: LogEntry{Notes: "MISMATCH: format reads 1 argument but the call passes 2; b has no verb, so the call isn't auto-mapped"},
+		},
+		{
+			name: "in if err != nil",
+			body: `if err != nil {
+		log.Printf("sync failed: %v", err)
+	}`,
+			want: LogEntry{SuggestedLevel: "Error", LevelConfidence: ConfidenceHigh},
+		},
+		{
+			name: "followed by os.Exit",
+			body: `log.Printf("bad config")
+	os.Exit(1)`,
+			want: LogEntry{SuggestedLevel: "Fatal", LevelConfidence: ConfidenceHigh},
+		},
+		{
+			name: "logging an error",
+			body: `log.Printf("retrying: %v", err)`,
+			want: LogEntry{SuggestedLevel: "Error", LevelConfidence: ConfidenceLow},
+		},
+		{
+			name: "error returned after",
+			body: `log.Printf("open %s: %v", path, err)
+	return err`,
+			want: LogEntry{Returns: "err"},
+		},
+		{
+			name:    "zap fields",
+			imports: `"go.uber.org/zap"`,
+			body:    `logger.Info("login", zap.String("user", name), zap.Int("attempts", a), zap.Error(err))`,
+			want:    LogEntry{OriginalCall: "logger.Info", LogLevel: "Info"},
+			keys:    []string{"user", "attempts", "error"},
+		},
+		{
+			name:    "zerolog event",
+			imports: `"github.com/rs/zerolog/log"`,
+			body:    `log.Error().Err(err).Str("path", path).Msg("open failed")`,
+			want:    LogEntry{LogLevel: "Error", MessageTemplate: `"open failed"`, StructuredFields: "error=err, path=path"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			imports := tt.imports
+			if imports == "" {
+				imports = `"log"`
+			}
+			src := "package main\n\nimport " + imports + "\n\nfunc f(path, name string, a, b int, err error) error {\n\t" + tt.body + "\n\treturn nil\n}\n"
+			if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0644); err != nil {
+				t.Fatal(err)
+			}
+			entries, err := Run(context.Background(), Options{Root: dir})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("%d entries, want 1: %+v", len(entries), entries)
+			}
+			got := entries[0]
+			check := func(field, got, want string) {
+				if want != "" && got != want {
+					t.Errorf("%s = %q, want %q", field, got, want)
+				}
+			}
+			check("OriginalCall", got.OriginalCall, tt.want.OriginalCall)
+			check("LogLevel", got.LogLevel, tt.want.LogLevel)
+			check("SourceLibrary", got.SourceLibrary, tt.want.SourceLibrary)
+			check("MessageTemplate", got.MessageTemplate, tt.want.MessageTemplate)
+			check("SuggestedLevel", got.SuggestedLevel, tt.want.SuggestedLevel)
+			check("LevelConfidence", got.LevelConfidence, tt.want.LevelConfidence)
+			check("Returns", got.Returns, tt.want.Returns)
+			check("StructuredFields", got.StructuredFields, tt.want.StructuredFields)
+			if tt.want.Notes != "" && !strings.Contains(got.Notes, tt.want.Notes) {
+				t.Errorf("Notes = %q, want it to contain %q", got.Notes, tt.want.Notes)
+			}
+			if tt.keys != nil {
+				var keys []string
+				for _, arg := range got.Arguments {
+					keys = append(keys, arg.SuggestedKey)
+				}
+				if strings.Join(keys, ",") != strings.Join(tt.keys, ",") {
+					t.Errorf("keys = %v, want %v", keys, tt.keys)
+				}
+			}
+		})
+	}
+}
+
+func TestRunHelpers(t *testing.T) {
+	dir := t.TempDir()
+	src := `package main
+
+import "log"
+
+func logError(msg string, err error) {
+	log.Printf("%s: %v", msg, err)
+}
+
+func f(name string, err error) {
+	logError("lookup "+name, err)
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := Run(context.Background(), Options{Root: dir, Helpers: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("%d entries, want 2: %+v", len(entries), entries)
+	}
+	for _, entry := range entries {
+		if entry.Helper != "logError" {
+			t.Errorf("%s: Helper = %q, want logError", entry.OriginalCall, entry.Helper)
+		}
+		if entry.OriginalCall == "log.Printf" && (entry.SuggestedLevel != "Error" || entry.LevelConfidence != ConfidenceHigh) {
+			t.Errorf("log.Printf: SuggestedLevel = %q (%s), want Error (high)", entry.SuggestedLevel, entry.LevelConfidence)
+		}
+	}
+}
+
+func TestRunTimes(t *testing.T) {
+	tests := []struct {
+		name string
+		decl string // Declarations in f before the call
+		arg  string
+		want string
+	}{
+		{"duration parameter", "", "timeout", "time.Duration"},
+		{"int named like a duration", "", "timeoutSecs", "unknown"},
+		{"named like a time", "", "createdAt", "unknown"},
+		{"time.Since", "", "time.Since(start)", "time.Duration"},
+		{"set from time.Now", "now := time.Now()", "now", "time.Time"},
+		{"struct field", "var j job", "j.deadline", "time.Time"},
+		{"second result", "n, took := run()", "took", "unknown"},
+		{"conversion", "", "time.Duration(timeoutSecs) * time.Second", "unknown"},
+		{"conversion alone", "", "time.Duration(timeoutSecs)", "time.Duration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := `package main
+
+import (
+	"log"
+	"time"
+)
+
+type job struct{ deadline time.Time }
+
+func run() (int, time.Duration) { return 0, 0 }
+
+func f(start time.Time, timeout time.Duration, timeoutSecs int, createdAt string) {
+	` + tt.decl + `
+	log.Printf("value %v", ` + tt.arg + `)
+}
+`
+			if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0644); err != nil {
+				t.Fatal(err)
+			}
+			entries, err := Run(context.Background(), Options{Root: dir})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 || len(entries[0].Arguments) != 1 {
+				t.Fatalf("entries = %+v, want one with one argument", entries)
+			}
+			if got := entries[0].Arguments[0].Type; got != tt.want {
+				t.Errorf("Type = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package collector
+
+import "strconv"
+
+// fieldConfidence scores from 0 to 1 how likely the fields transform
+// derives from the arguments (-auto-map) are right. Each argument counts
+// equally: half for a known type, which picks the field constructor
+// (slog.String rather than slog.Any), and half for a format verb of its
+// own; an argument printed as a struct (see Struct) or one collect can't
+// turn into a key and a value (see unreadArgument) scores nothing, as it
+// needs splitting into fields first. Messages without verbs, such as log.Print's, are scored on
+// the types alone. A format whose verbs don't match the arguments one to one scores
+// at most 0.25, since the keys and values may be paired up wrong. Calls
+// without arguments score 1.
+func fieldConfidence(messageTemplate string, args []Argument) float64 {
+	if len(args) == 0 {
+		return 1
+	}
+	verbs := len(extractFormatVerbs(messageTemplate))
+
+	var score float64
+	for _, arg := range args {
+		if arg.Type == Struct || unreadArgument(arg) {
+			continue
+		}
+		typed := 0.0
+		if arg.Type != "unknown" && arg.Type != "func_result" {
+			typed = 1
+		}
+		if verbs == 0 {
+			score += typed
+			continue
+		}
+		score += typed / 2
+		if arg.FormatVerb != "" {
+			score += 0.5
+		}
+	}
+	score /= float64(len(args))
+
+	if verbs > 0 && verbs != len(args) {
+		score = min(score, 0.25)
+	}
+	return score
+}
+
+// formatConfidence writes a FieldConfidence for the CSV, e.g. "0.75"
+func formatConfidence(score float64) string {
+	return strconv.FormatFloat(score, 'f', 2, 64)
+}
@@ -0,0 +1,49 @@
+package collector
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// ManualReview is the Status collect starts an entry with when its message
+// isn't a constant, such as a variable, prefix + name or a function call:
+// MessageTemplate then holds the expression rather than the text, so
+// transform can't write a message from it. So do go-kit calls logging no
+// message (see gokitShift) and calls setting fields on their logger that
+// collect can't read, such as WithFields(fields) (see chainedArguments).
+// Transform holds these entries until they are approved.
+const ManualReview = "manual-review"
+
+// quarantine marks an entry whose message, the first of args, isn't a
+// constant for manual review. Constants are string literals, concatenations
+// of them and, as far as info knows the file, named constants.
+func quarantine(entry *LogEntry, args []ast.Expr, info *types.Info) {
+	if len(args) > 0 && args[0] == noMessage {
+		entry.Status = ManualReview
+		entry.Notes = joinNotes(entry.Notes, "MANUAL: the call logs no message; write NewMessage and approve the entry to migrate it")
+		return
+	}
+	if len(args) == 0 || constantMessage(args[0], info) {
+		return
+	}
+	entry.Status = ManualReview
+	entry.Notes = joinNotes(entry.Notes, fmt.Sprintf("MANUAL: message %s isn't a constant; write NewMessage and approve the entry to migrate it", entry.MessageTemplate))
+}
+
+// constantMessage reports whether a message is known before the program runs
+func constantMessage(expr ast.Expr, info *types.Info) bool {
+	if tv, ok := info.Types[expr]; ok && tv.Value != nil {
+		return true
+	}
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		return e.Kind == token.STRING
+	case *ast.ParenExpr:
+		return constantMessage(e.X, info)
+	case *ast.BinaryExpr:
+		return e.Op == token.ADD && constantMessage(e.X, info) && constantMessage(e.Y, info)
+	}
+	return false
+}
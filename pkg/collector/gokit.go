@@ -0,0 +1,60 @@
+package collector
+
+import (
+	"go/ast"
+	"go/token"
+	"slices"
+
+	"logrefactor/internal/logcall"
+)
+
+// gokitMessageKeys are the keys go-kit calls log their message under, by
+// convention
+var gokitMessageKeys = map[string]bool{"msg": true, "message": true}
+
+// noMessage stands in for the message of a go-kit call logging none (see
+// gokitShift)
+var noMessage = &ast.BasicLit{Kind: token.STRING, Value: `""`}
+
+// gokitShift returns a go-kit Log call of library (see newEntry), such as
+// level.Info(logger).Log("msg", "synced", "pod", name), with its message
+// first and the rest of its pairs after it, as helper.shift does. The
+// message is the value of the first msg or message pair; a call without
+// one gets noMessage. ok is false for other calls.
+func gokitShift(call *ast.CallExpr, library string) (shifted *ast.CallExpr, ok bool) {
+	if library != "go-kit" {
+		return call, false
+	}
+	if _, _, _, ok := logcall.GokitLogger(call); !ok {
+		return call, false
+	}
+	args := call.Args
+	s := *call
+	for i := 0; i+1 < len(args); i += 2 {
+		key, ok := logcall.StringLit(args[i])
+		if !ok {
+			break
+		}
+		if gokitMessageKeys[key] && !(call.Ellipsis.IsValid() && i+1 == len(args)-1) {
+			s.Args = append([]ast.Expr{args[i+1]}, slices.Concat(args[:i], args[i+2:])...)
+			return &s, true
+		}
+	}
+	s.Args = append([]ast.Expr{noMessage}, args...)
+	return &s, true
+}
+
+// gokitArguments returns the pairs the With calls of a go-kit Log call add
+// (see logcall.GokitLogger), with their keys as written, like chainedArguments
+func gokitArguments(call *ast.CallExpr) (args []Argument, unread []string) {
+	_, _, withs, _ := logcall.GokitLogger(call)
+	for _, with := range withs {
+		set, ok := slogWith(&ast.CallExpr{Fun: with.Fun, Args: with.Args[1:], Ellipsis: with.Ellipsis})
+		if !ok {
+			unread = append(unread, linkText(with))
+			continue
+		}
+		args = append(args, set...)
+	}
+	return args, unread
+}
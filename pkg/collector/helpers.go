@@ -0,0 +1,340 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// maxHelperStatements is the most statements a function wrapping a log call
+// may have to count as a helper
+const maxHelperStatements = 3
+
+// helper is a project-local function that is little more than a log call,
+// such as
+//
+//	func logError(msg string, err error) { log.Printf("%s: %v", msg, err) }
+//
+// Calls to it are log statements too (see Options.Helpers).
+type helper struct {
+	name    string
+	pkg     string // Package name
+	dir     string // Directory of the file declaring it
+	message int    // Parameter its callers pass the message in, or -1
+	level   string
+	wraps   LogEntry // The log call it wraps
+}
+
+// helperSet holds the helpers found in a scan
+type helperSet struct {
+	local    map[string]map[string]*helper   // Directory and package -> name -> helper
+	exported map[string]map[string][]*helper // Package name -> name -> helpers
+	wrapped  map[string]*helper              // Position of each wrapped call -> helper
+}
+
+// findHelpers looks for helpers in every file before the scan, so that
+// calls to them are found in any file. Files that don't parse are left to
+// the scan to report.
+func (s *scanner) findHelpers(ctx context.Context, paths []string) (*helperSet, error) {
+	found := make([][]*helper, len(paths))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < s.jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				content, err := s.readFile(paths[i])
+				if err != nil || !s.filter.match(content) {
+					continue
+				}
+				entries, err := parseFile(ctx, paths[i], content, s.pattern, s.matchers, s.imports, nil, nil, s.verbosity, nil, s.keyStyle, s.matcher, func(error) {})
+				if err == nil && len(entries) > 0 {
+					found[i] = fileHelpers(paths[i], content, entries)
+				}
+			}
+		}()
+	}
+	var err error
+	for i := range paths {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	set := &helperSet{
+		local:    make(map[string]map[string]*helper),
+		exported: make(map[string]map[string][]*helper),
+		wrapped:  make(map[string]*helper),
+	}
+	for _, helpers := range found {
+		for _, h := range helpers {
+			key := h.dir + "\x00" + h.pkg
+			if set.local[key] == nil {
+				set.local[key] = make(map[string]*helper)
+			}
+			set.local[key][h.name] = h
+			if ast.IsExported(h.name) {
+				if set.exported[h.pkg] == nil {
+					set.exported[h.pkg] = make(map[string][]*helper)
+				}
+				set.exported[h.pkg][h.name] = append(set.exported[h.pkg][h.name], h)
+			}
+			set.wrapped[position(h.wraps.FilePath, h.wraps.Line, h.wraps.Column)] = h
+		}
+	}
+	return set, nil
+}
+
+// words returns the prefilter alternatives for calls to the helpers: their
+// names
+func (set *helperSet) words() prefilter {
+	seen := make(map[string]bool)
+	var f prefilter
+	for _, names := range set.local {
+		for name := range names {
+			if !seen[name] {
+				seen[name] = true
+				f = append(f, []string{name})
+			}
+		}
+	}
+	return f
+}
+
+// wraps returns the helper wrapping the call at pos, or nil
+func (set *helperSet) wraps(filePath string, pos token.Position) *helper {
+	if set == nil {
+		return nil
+	}
+	return set.wrapped[position(filePath, pos.Line, pos.Column)]
+}
+
+// fileHelpers returns the helpers declared in a file, given its entries:
+// functions of at most maxHelperStatements statements with exactly one of
+// the entries, which passes it a parameter. Methods aren't helpers, since
+// the receiver's type isn't known at the call.
+func fileHelpers(filePath string, content []byte, entries []LogEntry) []*helper {
+	fset := token.NewFileSet()
+	node, err := parser.ParseFile(fset, filePath, content, parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+
+	var helpers []*helper
+	for _, decl := range node.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || fn.Body == nil || len(fn.Body.List) > maxHelperStatements {
+			continue
+		}
+		if fn.Name.Name == "main" || fn.Name.Name == "init" {
+			continue
+		}
+		var inside []LogEntry
+		for _, e := range entries {
+			if contains(fset, fn, e) {
+				inside = append(inside, e)
+			}
+		}
+		if len(inside) != 1 {
+			continue
+		}
+		wrapped := inside[0]
+
+		// A guarded call, as in if debug { log.Printf(format, args...) },
+		// must come last: encKV(enc, k, v), which logs k and v when verbose
+		// and then encodes them, isn't a helper
+		guarded := false
+		for _, stmt := range fn.Body.List[:len(fn.Body.List)-1] {
+			if _, plain := stmt.(*ast.ExprStmt); !plain && contains(fset, stmt, wrapped) {
+				guarded = true
+			}
+		}
+		if guarded {
+			continue
+		}
+
+		// Flatten the parameters, and find the ones passed to the log call
+		var names, types []string
+		for _, field := range fn.Type.Params.List {
+			for _, name := range field.Names {
+				names = append(names, name.Name)
+				types = append(types, formatExpr(field.Type))
+			}
+		}
+		passed := map[string]bool{wrapped.MessageTemplate: true}
+		for _, arg := range wrapped.Arguments {
+			passed[arg.Expression] = true
+		}
+		message, forwards := -1, false
+		for i, name := range names {
+			if passed[name] {
+				forwards = true
+			}
+			if name == wrapped.MessageTemplate {
+				message = i // The helper passes its format on
+			}
+		}
+		if !forwards {
+			continue
+		}
+		if message == -1 {
+			for i, t := range types {
+				if t == "string" {
+					message = i
+					break
+				}
+			}
+		}
+
+		level := extractLogLevel(fn.Name.Name)
+		if level == "Unknown" {
+			level = wrapped.LogLevel
+		}
+		helpers = append(helpers, &helper{
+			name:    fn.Name.Name,
+			pkg:     node.Name.Name,
+			dir:     filepath.Dir(filePath),
+			message: message,
+			level:   level,
+			wraps:   wrapped,
+		})
+	}
+	return helpers
+}
+
+// contains reports whether the call of an entry is in node
+func contains(fset *token.FileSet, node ast.Node, e LogEntry) bool {
+	start, end := fset.Position(node.Pos()), fset.Position(node.End())
+	return after(e.Line, e.Column, start.Line, start.Column) && after(end.Line, end.Column, e.Line, e.Column)
+}
+
+// after reports whether line:column is at or after the other position
+func after(line, column, otherLine, otherColumn int) bool {
+	return line > otherLine || line == otherLine && column >= otherColumn
+}
+
+// position is the key of a call's position in helperSet.wrapped
+func position(filePath string, line, column int) string {
+	return fmt.Sprintf("%s:%d:%d", filePath, line, column)
+}
+
+// of returns the helper call calls, or nil: a helper of the package by its
+// name, or an exported helper of an imported package. res tells functions
+// from variables of the same name, and which package a name imports.
+func (set *helperSet) of(call *ast.CallExpr, filePath, packageName string, res *resolver) *helper {
+	if set == nil {
+		return nil
+	}
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		h := set.local[filepath.Dir(filePath)+"\x00"+packageName][fun.Name]
+		if h == nil {
+			return nil
+		}
+		// Declared in another file of the package, or in this one
+		switch res.typeInfo().Uses[fun].(type) {
+		case nil, *types.Func:
+			return h
+		}
+	case *ast.SelectorExpr:
+		x, ok := fun.X.(*ast.Ident)
+		if !ok {
+			return nil
+		}
+		candidates := set.exported[x.Name][fun.Sel.Name]
+		if len(candidates) == 0 {
+			return nil
+		}
+		pkg, ok := res.typeInfo().Uses[x].(*types.PkgName)
+		if !ok {
+			return nil
+		}
+		// The directory is named after the import path, which a standard
+		// library package such as log, with no "/", can't be confused with
+		importPath := pkg.Imported().Path()
+		if !strings.Contains(importPath, "/") {
+			return nil
+		}
+		var match *helper
+		for _, h := range candidates {
+			if filepath.Base(h.dir) == path.Base(importPath) {
+				if match != nil {
+					return nil // Ambiguous
+				}
+				match = h
+			}
+		}
+		return match
+	}
+	return nil
+}
+
+// entry returns the entry for a call to the helper. The message and
+// arguments are read from the helper's message parameter on.
+func (h *helper) entry(call *ast.CallExpr, fset *token.FileSet, packageName, keyStyle string) LogEntry {
+	shifted := *call
+	shifted.Args = h.shift(call)
+	entry := Entry(&shifted, fset, packageName, keyStyle)
+	entry.LogLevel = h.level
+	entry.SourceLibrary = "custom"
+	entry.Notes = joinNotes(fmt.Sprintf("HELPER: %s wraps %s at %s:%d", h.name, h.wraps.OriginalCall, filepath.Base(h.wraps.FilePath), h.wraps.Line), entry.Notes)
+	if h.message == 0 {
+		entry.Helper = h.name
+	}
+	return entry
+}
+
+// mark sets the Helper of the entry of the call the helper wraps, when
+// transform can rewrite the helper (its message comes first), and suggests
+// the level the helper's name gives, which its callers log at
+func (h *helper) mark(entry *LogEntry) {
+	if h.message == 0 {
+		entry.Helper = h.name
+	}
+	if h.level != entry.LogLevel {
+		entry.SuggestedLevel, entry.LevelConfidence = h.level, ConfidenceHigh
+	}
+}
+
+// shift returns the arguments of a call to the helper from its message
+// parameter on
+func (h *helper) shift(call *ast.CallExpr) []ast.Expr {
+	if h.message > 0 && h.message < len(call.Args) {
+		return call.Args[h.message:]
+	}
+	return call.Args
+}
+
+// args returns the arguments of a call to the helper after its message, as
+// the entry's Arguments count them
+func (h *helper) args(call *ast.CallExpr) []ast.Expr {
+	if args := h.shift(call); len(args) > 0 {
+		return args[1:]
+	}
+	return nil
+}
+
+// joinNotes joins the notes that are set with "; "
+func joinNotes(notes ...string) string {
+	var set []string
+	for _, note := range notes {
+		if note != "" {
+			set = append(set, note)
+		}
+	}
+	return strings.Join(set, "; ")
+}
@@ -0,0 +1,86 @@
+package collector
+
+import (
+	"encoding/json"
+	"regexp"
+	"slices"
+	"strings"
+
+	"logrefactor/internal/normalize"
+)
+
+// keyedVerb matches a format verb written as the value of a key, as in
+// "user=%s action=%q failed", keeping the key
+var keyedVerb = regexp.MustCompile(`([\pL_][\pL\pN_.-]*)=(%[-+# 0]*[\d]*\.?[\d]*[vTtbcdoqxXUeEfFgGsp])`)
+
+// extractVerbKeys returns, for each format verb, the key written directly
+// before it ("user" for "user=%s"), or "" if there is none
+func extractVerbKeys(formatStr string) []string {
+	cleanStr := strings.Trim(formatStr, `"'`+"`")
+
+	re := regexp.MustCompile(`%[-+# 0]*[\d]*\.?[\d]*[vTtbcdoqxXUeEfFgGsp]`)
+	keyed := make(map[int]string)
+	for _, m := range keyedVerb.FindAllStringSubmatchIndex(cleanStr, -1) {
+		keyed[m[4]] = cleanStr[m[2]:m[3]]
+	}
+	var keys []string
+	for _, loc := range re.FindAllStringIndex(cleanStr, -1) {
+		keys = append(keys, keyed[loc[0]])
+	}
+
+	return keys
+}
+
+// keyedFields drafts StructuredFields and NewMessage for a printf-style
+// message that already holds key=value pairs, such as
+// "user=%s action=%s failed": every argument is mapped to its suggested
+// key, which for the values of pairs is the pair's key (see
+// extractLogDetails), and the message is what is left once the pairs are
+// gone, as normalize would suggest it ("failed"). It returns "", "" when the
+// message has no pairs, its verbs don't line up with the arguments or an
+// argument's expression isn't recorded in full.
+func keyedFields(messageTemplate string, args []Argument) (fields, message string) {
+	keys := extractVerbKeys(messageTemplate)
+	if len(keys) != len(args) || !slices.ContainsFunc(keys, func(key string) bool { return key != "" }) {
+		return "", ""
+	}
+
+	fields, ok := formatFields(args)
+	if !ok {
+		return "", ""
+	}
+	return fields, normalize.Message(keyedVerb.ReplaceAllString(messageTemplate, ""))
+}
+
+// formatFields writes arguments as StructuredFields, mapping each to its
+// suggested key: "key=expression, ..." or, when an expression holds a
+// comma or semicolon, JSON. ok is false when an argument's expression
+// isn't recorded in full (see formatExpr).
+func formatFields(args []Argument) (fields string, ok bool) {
+	type field struct {
+		Key        string `json:"key"`
+		Expression string `json:"expression"`
+	}
+	var mapped []field
+	var pairs []string
+	simple := true
+	for _, arg := range args {
+		if strings.Contains(arg.Expression, "[...]") || strings.Contains(arg.Expression, "<*ast.") {
+			return "", false
+		}
+		mapped = append(mapped, field{Key: arg.SuggestedKey, Expression: arg.Expression})
+		pairs = append(pairs, arg.SuggestedKey+"="+arg.Expression)
+		// The simple form is split on "," or ";"
+		if strings.ContainsAny(arg.Expression, ",;") {
+			simple = false
+		}
+	}
+	if simple {
+		return strings.Join(pairs, ", "), true
+	}
+	data, err := json.Marshal(mapped)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
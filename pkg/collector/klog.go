@@ -0,0 +1,137 @@
+package collector
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// KlogVerbosity are the V(n) levels Debug and Trace start at for klog and
+// glog when Options.Verbosity doesn't say, following the Kubernetes
+// convention: V(4) for debugging and V(5) for tracing. Lower levels are
+// Info.
+var KlogVerbosity = map[string]int{"Debug": 4, "Trace": 5}
+
+// klogStructured are klog's structured calls, by the position of their
+// message: InfoS("msg", kv...), ErrorS(err, "msg", kv...) and their Depth
+// variants, which take the depth first. The error of ErrorS and
+// ErrorSDepth comes right before the message.
+var klogStructured = map[string]int{"InfoS": 0, "ErrorS": 1, "InfoSDepth": 1, "ErrorSDepth": 2}
+
+// klogValues are klog's functions describing a value for a structured
+// call, such as klog.KObj(pod), rather than logging
+var klogValues = map[string]bool{"KObj": true, "KObjs": true, "KObjSlice": true, "KRef": true, "Format": true}
+
+// klogShift returns a structured klog call of library (see newEntry), such
+// as klog.ErrorS(err, "sync failed", "pod", name), with its arguments from
+// the message on, as helper.shift does, and the error it logs, if any. ok
+// is false for other calls.
+func klogShift(call *ast.CallExpr, library string) (shifted *ast.CallExpr, errExpr ast.Expr, ok bool) {
+	if library != "" && library != "klog" && library != "custom" {
+		return call, nil, false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return call, nil, false
+	}
+	message, ok := klogStructured[sel.Sel.Name]
+	if !ok || len(call.Args) <= message {
+		return call, nil, false
+	}
+	if strings.HasPrefix(sel.Sel.Name, "ErrorS") {
+		errExpr = call.Args[message-1]
+	}
+	s := *call
+	s.Args = call.Args[message:]
+	return &s, errExpr, true
+}
+
+// klogValue reports whether a call is to one of klogValues
+func klogValue(call *ast.CallExpr) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && pkg.Name == "klog" && klogValues[sel.Sel.Name]
+}
+
+// verbosityOf returns n for a V(n) call with a literal level, such as
+// klog.V(2), glog.V(2) or logger.V(2)
+func verbosityOf(expr ast.Expr) (int, bool) {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return 0, false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "V" {
+		return 0, false
+	}
+	lit, ok := call.Args[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.INT {
+		return 0, false
+	}
+	n, err := strconv.Atoi(lit.Value)
+	return n, err == nil
+}
+
+// callVerbosity returns n for a call made on V(n), such as
+// klog.V(2).Infof(...)
+func callVerbosity(call *ast.CallExpr) (int, bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return 0, false
+	}
+	return verbosityOf(sel.X)
+}
+
+// verbosityCheck reports whether a call checks a verbosity, as
+// klog.V(4).Enabled() does, rather than logging
+func verbosityCheck(call *ast.CallExpr) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Enabled" {
+		return false
+	}
+	_, ok = verbosityOf(sel.X)
+	return ok
+}
+
+// guardVerbosity returns n for a call in the body of an if statement
+// checking V(n), as in if klog.V(4).Enabled() { klog.Info(...) } or
+// glog's if glog.V(4) { glog.Info(...) }. path runs from the file down to
+// the call.
+func guardVerbosity(path []ast.Node) (int, bool) {
+	for i := len(path) - 2; i >= 1; i-- {
+		if _, ok := path[i].(*ast.FuncLit); ok {
+			return 0, false
+		}
+		ifStmt, ok := path[i-1].(*ast.IfStmt)
+		if !ok || path[i] != ifStmt.Body {
+			continue
+		}
+		cond := ifStmt.Cond
+		if call, ok := cond.(*ast.CallExpr); ok && verbosityCheck(call) {
+			cond = call.Fun.(*ast.SelectorExpr).X
+		}
+		if n, ok := verbosityOf(cond); ok {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// verbosityLevel returns the level of a V(n) call under verbosity (see
+// KlogVerbosity): Trace from the Trace level up, then Debug from the Debug
+// level up, and Info below both. Levels of 0 or less aren't used.
+func verbosityLevel(n int, verbosity map[string]int) string {
+	if verbosity == nil {
+		verbosity = KlogVerbosity
+	}
+	for _, level := range []string{"Trace", "Debug"} {
+		if v := verbosity[level]; v > 0 && n >= v {
+			return level
+		}
+	}
+	return "Info"
+}
@@ -0,0 +1,147 @@
+package collector
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// Confidence of a SuggestedLevel
+const (
+	ConfidenceHigh   = "high"
+	ConfidenceMedium = "medium"
+	ConfidenceLow    = "low"
+)
+
+// suggestLevel infers the level of a call from where it is, for calls whose
+// name gives no level or only Info. path runs from the file down to the
+// call. In order:
+//
+//   - followed by os.Exit or panic: Fatal (high)
+//   - in the body of an if on a debug or verbose flag: Debug (medium)
+//   - in the body of if err != nil: Error (high)
+//   - logging an error argument: Error (low)
+//
+// The innermost if decides between Debug and Error. It returns "", "" when
+// nothing points to a level.
+func suggestLevel(path []ast.Node, args []Argument) (level, confidence string) {
+	if exits(path) {
+		return "Fatal", ConfidenceHigh
+	}
+
+guards:
+	for i := len(path) - 2; i >= 0; i-- {
+		switch n := path[i].(type) {
+		case *ast.FuncDecl, *ast.FuncLit:
+			break guards // Guards outside the function don't apply
+		case *ast.IfStmt:
+			if path[i+1] != n.Body {
+				continue // In the condition or else branch
+			}
+			if debugGuard(n.Cond) {
+				return "Debug", ConfidenceMedium
+			}
+			if errGuard(n.Cond) {
+				return "Error", ConfidenceHigh
+			}
+		}
+	}
+
+	for _, arg := range args {
+		if arg.Type == "error" || errName(arg.Expression) {
+			return "Error", ConfidenceLow
+		}
+	}
+	return "", ""
+}
+
+// exits reports whether the statement after the call's is os.Exit(...) or
+// panic(...)
+func exits(path []ast.Node) bool {
+	if len(path) < 3 {
+		return false
+	}
+	stmt, ok := path[len(path)-2].(*ast.ExprStmt)
+	if !ok {
+		return false
+	}
+	var list []ast.Stmt
+	switch parent := path[len(path)-3].(type) {
+	case *ast.BlockStmt:
+		list = parent.List
+	case *ast.CaseClause:
+		list = parent.Body
+	case *ast.CommClause:
+		list = parent.Body
+	}
+	for i, s := range list {
+		if s != stmt || i+1 == len(list) {
+			continue
+		}
+		next, ok := list[i+1].(*ast.ExprStmt)
+		if !ok {
+			return false
+		}
+		call, ok := next.X.(*ast.CallExpr)
+		if !ok {
+			return false
+		}
+		switch fun := call.Fun.(type) {
+		case *ast.Ident:
+			return fun.Name == "panic"
+		case *ast.SelectorExpr:
+			pkg, ok := fun.X.(*ast.Ident)
+			return ok && pkg.Name == "os" && fun.Sel.Name == "Exit"
+		}
+	}
+	return false
+}
+
+// debugGuard reports whether cond tests a debug or verbose setting, such as
+// debug, *verbose or cfg.Debug
+func debugGuard(cond ast.Expr) bool {
+	found := false
+	ast.Inspect(cond, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			name := strings.ToLower(ident.Name)
+			if strings.Contains(name, "debug") || strings.Contains(name, "verbose") {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// errGuard reports whether cond is, or has as an && or || operand, a test
+// that an error is not nil, such as err != nil
+func errGuard(cond ast.Expr) bool {
+	switch e := cond.(type) {
+	case *ast.ParenExpr:
+		return errGuard(e.X)
+	case *ast.BinaryExpr:
+		switch e.Op {
+		case token.LAND, token.LOR:
+			return errGuard(e.X) || errGuard(e.Y)
+		case token.NEQ:
+			return isNil(e.Y) && errName(formatExpr(e.X)) || isNil(e.X) && errName(formatExpr(e.Y))
+		}
+	}
+	return false
+}
+
+// isNil reports whether e is the identifier nil
+func isNil(e ast.Expr) bool {
+	ident, ok := e.(*ast.Ident)
+	return ok && ident.Name == "nil"
+}
+
+// errName reports whether an expression names an error by convention: err,
+// writeErr, resp.Err, ...
+func errName(expr string) bool {
+	if dot := strings.LastIndex(expr, "."); dot != -1 {
+		expr = expr[dot+1:]
+	}
+	name := strings.ToLower(expr)
+	return strings.HasSuffix(name, "err") || name == "error"
+}
@@ -0,0 +1,50 @@
+package collector
+
+import "go/ast"
+
+// LogrVerbosity are the V(n) levels Debug and Trace start at for logr when
+// Options.Verbosity doesn't say: V(1) and V(2), as logr's own levels
+// count up from Info at V(0)
+var LogrVerbosity = map[string]int{"Debug": 1, "Trace": 2}
+
+// logrShift returns a logr call of library (see newEntry), such as
+// logger.Error(err, "sync failed", "pod", name), with its arguments from
+// the message on, as helper.shift does, and the error Error logs. ok is
+// false for other calls, including Info and Error of other libraries,
+// which logr's can't be told apart from.
+func logrShift(call *ast.CallExpr, library string) (shifted *ast.CallExpr, errExpr ast.Expr, ok bool) {
+	if library != "logr" {
+		return call, nil, false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return call, nil, false
+	}
+	message := 0
+	switch sel.Sel.Name {
+	case "Info":
+	case "Error":
+		message = 1
+	default:
+		return call, nil, false
+	}
+	if len(call.Args) <= message {
+		return call, nil, false
+	}
+	if message > 0 {
+		errExpr = call.Args[0]
+	}
+	s := *call
+	s.Args = call.Args[message:]
+	return &s, errExpr, true
+}
+
+// libraryVerbosity returns verbosity, or when it is nil the one Info calls
+// of library made on V(n) default to: LogrVerbosity for logr, and nil,
+// which stands for KlogVerbosity, for the others
+func libraryVerbosity(library string, verbosity map[string]int) map[string]int {
+	if verbosity == nil && library == "logr" {
+		return LogrVerbosity
+	}
+	return verbosity
+}
@@ -0,0 +1,79 @@
+package collector
+
+import (
+	"go/ast"
+	"path"
+)
+
+// InLoop values of a LogEntry
+const (
+	LoopFor   = "for"
+	LoopRange = "range"
+	LoopHot   = "hot" // Not in a loop, but in a function on Options.HotPaths
+)
+
+// inLoop returns LoopFor or LoopRange if the call is in the body of a for
+// or range statement, LoopHot if it isn't but its function matches
+// hotPaths (see hotPath), or "" otherwise. The innermost loop decides, and
+// calls in function literals count as their function's, since a closure
+// made in a loop usually runs once per iteration too. nodes run from the
+// file down to the call.
+func inLoop(nodes []ast.Node, packageName string, hotPaths []string) string {
+	for i := len(nodes) - 2; i >= 0; i-- {
+		switch n := nodes[i].(type) {
+		case *ast.FuncDecl:
+			if hotPath(n, packageName, hotPaths) {
+				return LoopHot
+			}
+			return ""
+		case *ast.ForStmt:
+			if nodes[i+1] == ast.Node(n.Body) {
+				return LoopFor
+			}
+		case *ast.RangeStmt:
+			if nodes[i+1] == ast.Node(n.Body) {
+				return LoopRange
+			}
+		}
+	}
+	return ""
+}
+
+// hotPath reports whether a function matches one of patterns, globs (see
+// path.Match) on its name as "Func", "Type.Method", or either qualified
+// with the package name ("server.Handle*", "*.ServeHTTP")
+func hotPath(fn *ast.FuncDecl, packageName string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+	name := fn.Name.Name
+	if fn.Recv != nil && len(fn.Recv.List) > 0 {
+		if recv := receiverType(fn.Recv.List[0].Type); recv != "" {
+			name = recv + "." + name
+		}
+	}
+	for _, pattern := range patterns {
+		for _, candidate := range []string{name, packageName + "." + name} {
+			if ok, _ := path.Match(pattern, candidate); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// receiverType returns the type name of a method receiver: Server for
+// (s *Server) or (s Set[T])
+func receiverType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverType(t.X)
+	case *ast.IndexExpr:
+		return receiverType(t.X)
+	case *ast.IndexListExpr:
+		return receiverType(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
@@ -0,0 +1,187 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path"
+	"regexp"
+	"strings"
+
+	"logrefactor/internal/plugin"
+)
+
+// CallMatcher decides whether a call is a log statement, in place of the
+// pattern (see Options.Matchers), e.g. by the import path of the package
+// the function belongs to or by the type of its receiver
+type CallMatcher interface {
+	// Match reports whether call is a log statement. It may change
+	// call.Level to the level to record; the change is kept only if it
+	// matches. An error skips the call with a warning.
+	Match(call *Call) (bool, error)
+}
+
+// MatcherFunc adapts a function to a CallMatcher
+type MatcherFunc func(call *Call) (bool, error)
+
+func (f MatcherFunc) Match(call *Call) (bool, error) {
+	return f(call)
+}
+
+// Call is a call a CallMatcher is asked about, with the file it is in
+type Call struct {
+	Expr    *ast.CallExpr
+	Name    string // As patterns see it, e.g. "log.Printf" (see CallName)
+	Level   string // Level guessed from the function name
+	Path    string // File path, as in LogEntry.FilePath
+	Package string // Package name
+	File    *ast.File
+	Fset    *token.FileSet
+
+	res *resolver // Shared by the calls of the file
+}
+
+// Info returns the types of the file, checked on its own the first time a
+// matcher asks: identifiers resolve to the file's imports and declarations,
+// but the contents of imported packages and of the package's other files
+// aren't known, so their types are invalid. The package's path is "".
+func (c *Call) Info() *types.Info {
+	return c.res.typeInfo()
+}
+
+// ImportPath returns the import path of the package the function belongs
+// to when the call starts with a package name, e.g. "log" for l.Printf
+// after import l "log", or for log.With(...).Info; otherwise ""
+func (c *Call) ImportPath() string {
+	expr := c.Expr.Fun
+	for {
+		switch e := expr.(type) {
+		case *ast.SelectorExpr:
+			expr = e.X
+			continue
+		case *ast.CallExpr:
+			expr = e.Fun
+			continue
+		case *ast.IndexExpr:
+			expr = e.X
+			continue
+		case *ast.Ident:
+			if pkg, ok := c.Info().Uses[e].(*types.PkgName); ok {
+				return pkg.Imported().Path()
+			}
+		}
+		return ""
+	}
+}
+
+// PatternMatcher returns a CallMatcher that matches calls whose Name
+// pattern matches, as Options.Pattern does, for combining the pattern with
+// other matchers
+func PatternMatcher(pattern string) (CallMatcher, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	return MatcherFunc(func(call *Call) (bool, error) {
+		return re.MatchString(call.Name), nil
+	}), nil
+}
+
+// matchCall asks matchers about a call in turn, and reports whether one
+// matched and the level to record
+func matchCall(matchers []CallMatcher, call *Call) (bool, string, error) {
+	guessed := call.Level
+	for _, m := range matchers {
+		call.Level = guessed
+		ok, err := m.Match(call)
+		if err != nil {
+			return false, "", err
+		}
+		if ok {
+			return true, call.Level, nil
+		}
+	}
+	return false, "", nil
+}
+
+// checkFile type-checks a file on its own for CallMatchers and
+// Options.Imports. Imports are resolved to empty packages, and the errors
+// that follow are ignored.
+func checkFile(fset *token.FileSet, file *ast.File) *types.Info {
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+	conf := types.Config{Importer: emptyImporter{}, Error: func(error) {}}
+	conf.Check("", fset, []*ast.File{file}, info)
+	return info
+}
+
+// emptyImporter imports every package as an empty one, named after its
+// path as go-hclog is hclog and klog/v2 is klog
+type emptyImporter struct{}
+
+func (emptyImporter) Import(importPath string) (*types.Package, error) {
+	name := path.Base(importPath)
+	if len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = path.Base(path.Dir(importPath))
+	}
+	name = strings.TrimPrefix(name, "go-")
+	if i := strings.Index(name, ".v"); i > 0 {
+		name = name[:i]
+	}
+	name = strings.ReplaceAll(name, "-", "_")
+	pkg := types.NewPackage(importPath, name)
+	pkg.MarkComplete()
+	return pkg, nil
+}
+
+// matchRequest is the JSON document a WASM matcher plugin reads from stdin
+type matchRequest struct {
+	File    string   `json:"file"`
+	Package string   `json:"package"`
+	Line    int      `json:"line"`
+	Column  int      `json:"column"`
+	Call    string   `json:"call"`  // e.g. "log.Printf" or "s.logger.Infof"
+	Level   string   `json:"level"` // Level guessed from the function name
+	Args    []string `json:"args"`  // Source of each argument
+}
+
+// matchResponse is what a matcher plugin writes to stdout. Level, if set,
+// replaces the guessed level.
+type matchResponse struct {
+	Match bool   `json:"match"`
+	Level string `json:"level,omitempty"`
+}
+
+// pluginMatch asks a matcher plugin whether a call that passed the pattern
+// is a log statement, and which level to record for it
+func pluginMatch(ctx context.Context, p *plugin.Plugin, req matchRequest) (matchResponse, error) {
+	var resp matchResponse
+	input, err := json.Marshal(req)
+	if err != nil {
+		return resp, err
+	}
+	output, err := p.Call(ctx, input)
+	if err != nil {
+		return resp, err
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(string(output))), &resp); err != nil {
+		return resp, fmt.Errorf("invalid matcher response %q: %w", output, err)
+	}
+	return resp, nil
+}
+
+// callArgs returns the source of each argument of call
+func callArgs(call *ast.CallExpr) []string {
+	args := make([]string, len(call.Args))
+	for i, arg := range call.Args {
+		args[i] = formatExpr(arg)
+	}
+	return args
+}
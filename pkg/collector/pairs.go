@@ -0,0 +1,113 @@
+package collector
+
+import (
+	"go/ast"
+
+	"logrefactor/internal/logcall"
+)
+
+// Unpaired is the Type of an argument of a call logging key/value pairs,
+// such as klog's InfoS, that collect can't pair with a key: a key that
+// isn't a string literal, a key without a value or a slice passed as
+// keysAndValues.... Their fields have to be written into StructuredFields
+// by hand (see transformer.LogUpdate.Unmapped).
+const Unpaired = "unpaired"
+
+// pairCall returns, for a call logging key/value pairs after its message,
+// the call with its arguments from the message on, as helper.shift does,
+// and the Arguments of its pairs. Those are slog calls (see slogCall),
+// klog's structured calls (see klogShift), the key/value methods of zap's
+// SugaredLogger, such as Infow, logr's Info and Error (see logrShift) and
+// go-kit's Log (see gokitShift). ok is false for other calls.
+func pairCall(call *ast.CallExpr, library, keyStyle string) (shifted *ast.CallExpr, args []Argument, ok bool) {
+	if slogCall(call, library) {
+		shifted = slogShift(call)
+		return shifted, slogArguments(shifted, keyStyle), true
+	}
+	if zapSugaredCall(call, library) {
+		return call, pairArguments(call.Args[1:], call.Ellipsis.IsValid(), keyStyle, Unpaired), true
+	}
+	if shifted, errExpr, ok := klogShift(call, library); ok {
+		return shifted, errorPairs(shifted, errExpr, "err", keyStyle), true
+	}
+	if shifted, errExpr, ok := logrShift(call, library); ok {
+		return shifted, errorPairs(shifted, errExpr, "error", keyStyle), true
+	}
+	if shifted, ok := gokitShift(call, library); ok {
+		return shifted, pairArguments(shifted.Args[1:], shifted.Ellipsis.IsValid(), keyStyle, Unpaired), true
+	}
+	return call, nil, false
+}
+
+// pairArguments returns the Arguments of the key/value pairs args, the
+// last of which is passed as args... when spread is set: one for each
+// pair, slog attribute (see logcall.SlogAttr) or zap field (see
+// logcall.ZapField), which a SugaredLogger takes among its pairs, with its
+// key and value, and one of Type unread for each argument collect can't read. Index counts
+// from the first of args.
+func pairArguments(args []ast.Expr, spread bool, keyStyle, unread string) []Argument {
+	var pairs []Argument
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		key, value, typ, ok := logcall.SlogAttr(arg)
+		if !ok {
+			key, value, typ, ok = logcall.ZapField(arg)
+		}
+		if ok {
+			expr := formatExpr(value)
+			pairs = append(pairs, Argument{Index: i, Expression: expr, VarName: extractVarName(expr), Type: typ, SuggestedKey: key})
+			continue
+		}
+		// A key is followed by its value; one without is logged under a
+		// placeholder, such as slog's !BADKEY
+		if key, ok := logcall.StringLit(arg); ok && i+1 < len(args) && !(spread && i+1 == len(args)-1) {
+			value := args[i+1]
+			expr := formatExpr(value)
+			pairs = append(pairs, Argument{Index: i + 1, Expression: expr, VarName: extractVarName(expr), Type: inferType(value), SuggestedKey: key})
+			i++
+			continue
+		}
+		expr := formatExpr(arg)
+		varName := extractVarName(expr)
+		if name, _ := logcall.SlogConstructor(arg); name != "" {
+			varName = name // slog.Group("http", ...) is a Group
+		} else if name, _ := logcall.ZapConstructor(arg); name != "" {
+			varName = name // zap.Object("user", u) is an Object
+		} else if key, ok := logcall.StringLit(arg); ok {
+			varName = key
+		}
+		if spread && i == len(args)-1 {
+			expr += "..."
+		}
+		pairs = append(pairs, Argument{Index: i, Expression: expr, VarName: varName, Type: unread, SuggestedKey: generateFieldKey(varName, "", unread, keyStyle)})
+	}
+	return pairs
+}
+
+// errorPairs returns the Arguments of a call logging an error and pairs
+// from its message on, such as klog's ErrorS or logr's Error: errExpr
+// under errKey, unless it is nil or missing, then one for each pair, and
+// one of Type Unpaired for each argument collect can't pair
+func errorPairs(call *ast.CallExpr, errExpr ast.Expr, errKey, keyStyle string) []Argument {
+	var args []Argument
+	if ident, ok := errExpr.(*ast.Ident); errExpr != nil && (!ok || ident.Name != "nil") {
+		args = append(args, chainedArgument(errKey, errExpr, "error"))
+	}
+	if len(call.Args) > 0 {
+		args = append(args, pairArguments(call.Args[1:], call.Ellipsis.IsValid(), keyStyle, Unpaired)...)
+	}
+	return args
+}
+
+// unreadArgument reports whether collect couldn't turn an argument into a
+// key and a value (see ZapField, SlogAttr and Unpaired)
+func unreadArgument(arg Argument) bool {
+	return arg.Type == ZapField || arg.Type == SlogAttr || arg.Type == Unpaired
+}
+
+// pairNote names the pair arguments of an entry collect couldn't read for
+// the Notes column, e.g. "PAIRS: keysAndValues... has no single value;
+// list its fields in StructuredFields". It is empty if there are none.
+func pairNote(args []Argument) string {
+	return unreadNote("PAIRS", Unpaired, args)
+}
@@ -0,0 +1,118 @@
+package collector
+
+import (
+	"bytes"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+)
+
+// maxAlternatives bounds the size of a prefilter; past it, parts of the
+// pattern are left out, which only makes the filter let more files through
+const maxAlternatives = 64
+
+// synthesized is text getFunctionName can put in a call name that isn't in
+// the source: the placeholders formatExpr uses for complex expressions
+const synthesized = "<*ast.ArrayType> <*ast.BadExpr> <*ast.ChanType> <*ast.CompositeLit> <*ast.Ellipsis> " +
+	"<*ast.FuncLit> <*ast.FuncType> <*ast.IndexListExpr> <*ast.InterfaceType> <*ast.KeyValueExpr> " +
+	"<*ast.MapType> <*ast.ParenExpr> <*ast.SliceExpr> <*ast.StarExpr> <*ast.StructType> <*ast.TypeAssertExpr>"
+
+// prefilter rules out files that can't contain a call matching the pattern
+// without parsing them. It is derived from the words the pattern requires:
+// call names are made of identifiers from the source, so every word a
+// match needs must appear somewhere in the file. Words can't span a "."
+// or "(", since "log.Printf" may be written "log.\n\tPrintf".
+//
+// The filter is a list of alternatives, each a list of words that must all
+// be present. A file passes if any alternative is satisfied.
+type prefilter [][]string
+
+// newPrefilter returns the filter for pattern, or nil if the pattern
+// doesn't require any words (or can't be analyzed)
+func newPrefilter(pattern string) prefilter {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil
+	}
+	f := required(re.Simplify())
+	for _, alt := range f {
+		if len(alt) == 0 {
+			return nil // Some match needs no words at all
+		}
+	}
+	return f
+}
+
+// match reports whether src may contain a matching call
+func (f prefilter) match(src []byte) bool {
+	if f == nil {
+		return true
+	}
+	for _, alt := range f {
+		ok := true
+		for _, word := range alt {
+			if !bytes.Contains(src, []byte(word)) && !strings.Contains(synthesized, word) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// anything is the filter that requires nothing
+var anything = prefilter{{}}
+
+// required returns the words any match of re must contain, as alternatives
+func required(re *syntax.Regexp) prefilter {
+	switch re.Op {
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase != 0 {
+			return anything
+		}
+		return prefilter{words(string(re.Rune))}
+	case syntax.OpCapture, syntax.OpPlus:
+		return required(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min > 0 {
+			return required(re.Sub[0])
+		}
+		return anything
+	case syntax.OpConcat:
+		result := anything
+		for _, sub := range re.Sub {
+			f := required(sub)
+			if len(result)*len(f) > maxAlternatives {
+				continue
+			}
+			var product prefilter
+			for _, a := range result {
+				for _, b := range f {
+					product = append(product, append(append([]string(nil), a...), b...))
+				}
+			}
+			result = product
+		}
+		return result
+	case syntax.OpAlternate:
+		var result prefilter
+		for _, sub := range re.Sub {
+			result = append(result, required(sub)...)
+			if len(result) > maxAlternatives {
+				return anything
+			}
+		}
+		return result
+	}
+	return anything
+}
+
+// words splits a literal into its runs of identifier characters
+func words(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+}
@@ -0,0 +1,376 @@
+package collector
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"logrefactor/internal/logcall"
+	"logrefactor/internal/scaffold"
+)
+
+// DefaultImports are the packages of the logging libraries transform
+// supports, what "default" stands for in Options.Imports
+var DefaultImports = []string{
+	"log",
+	"log/slog",
+	"github.com/sirupsen/logrus",
+	"go.uber.org/zap",
+	"github.com/rs/zerolog",
+	"github.com/rs/zerolog/log",
+	"k8s.io/klog",
+	"k8s.io/klog/v2",
+	"github.com/golang/glog",
+	"github.com/hashicorp/go-hclog",
+	"github.com/go-kit/log",
+	"github.com/go-kit/kit/log",
+	"github.com/go-kit/log/level",
+	"github.com/go-kit/kit/log/level",
+	"github.com/go-logr/logr",
+	"github.com/apex/log",
+	"github.com/inconshreveable/log15",
+	"github.com/inconshreveable/log15/v3",
+}
+
+// Testing is the SourceLibrary of calls on a *testing.T, B or F, such as
+// t.Logf: test output rather than logging, which transform leaves alone
+// unless asked (see transformer.Options.TestLogs)
+const Testing = "testing"
+
+// Library returns the name SourceLibrary records for calls belonging to
+// the package at importPath: the short name of a logging library ("log",
+// "slog", "logrus", "zap", "zerolog", "klog", ...), Testing, or "custom"
+// for other packages, such as an in-house wrapper or the package's own
+// types
+func Library(importPath string) string {
+	if importPath == "testing" {
+		return Testing
+	}
+	if name, ok := scaffold.LibraryName(importPath); ok {
+		return name
+	}
+	return "custom"
+}
+
+// maxDepth bounds how many declarations target follows to find where a
+// value comes from
+const maxDepth = 8
+
+// Target returns the import path of the package the called function or
+// method belongs to: the package for a package function, e.g. "log" for
+// l.Printf after import l "log", and the package of the receiver's type
+// for a method, e.g. "go.uber.org/zap" for logger.Info after
+// logger := zap.NewExample() or in a method of a struct with a
+// logger *zap.Logger field. Types of the package being scanned give "".
+// ok is false when the file doesn't say, such as for a logger declared in
+// another file of the package.
+func (c *Call) Target() (path string, ok bool) {
+	return c.res.target(c.Expr)
+}
+
+// CallTarget is Call.Target for a call in a file checked with info, such
+// as one a go/analysis pass has type-checked
+func CallTarget(call *ast.CallExpr, file *ast.File, info *types.Info) (path string, ok bool) {
+	r := &resolver{file: file, info: info}
+	return r.target(call)
+}
+
+// ImportMatch reports whether path is one of imports. An entry ending in
+// "/..." also matches the packages under it, and "default" stands for
+// DefaultImports.
+func ImportMatch(imports []string, path string) bool {
+	for _, entry := range imports {
+		switch {
+		case entry == "default":
+			if ImportMatch(DefaultImports, path) {
+				return true
+			}
+		case strings.HasSuffix(entry, "/..."):
+			prefix := strings.TrimSuffix(entry, "/...")
+			if path == prefix || strings.HasPrefix(path, prefix+"/") {
+				return true
+			}
+		case entry == path:
+			return true
+		}
+	}
+	return false
+}
+
+// receiver returns the logger a method call is made on when it is reached
+// through a struct field or an accessor, e.g. "s.logger" for
+// s.logger.Infof(...) and "h.log.Sugar()" for h.log.Sugar().Infow(...), so
+// that the replacement can log to the same logger. Calls with arguments
+// ending the chain, such as WithField(...) or V(2), are left out: their
+// fields and levels are the entry's, as are the calls building a zerolog
+// event, down to the one starting it, such as Info() in
+// s.logger.Info().Msg(...), and for a call of library "go-kit" the level
+// and With calls around its logger, as in level.Info(s.logger).Log(...).
+// It returns "" for package functions and for loggers held in plain
+// variables, which loggerVar names.
+func (r *resolver) receiver(call *ast.CallExpr, library string) string {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	x := sel.X
+	if start, _, _, ok := logcall.ZerologEvent(call); ok {
+		x = start.Fun.(*ast.SelectorExpr).X
+	}
+	if logger, _, _, ok := logcall.GokitLogger(call); ok && library == "go-kit" {
+		x = logger
+	}
+	for {
+		inner, ok := x.(*ast.CallExpr)
+		if !ok || len(inner.Args) == 0 {
+			break
+		}
+		fun, ok := inner.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return ""
+		}
+		x = fun.X
+	}
+	if _, ok := x.(*ast.Ident); ok {
+		return "" // log.Printf, or logger.Infof
+	}
+
+	// The receiver must be a chain of selectors and calls on a value, not
+	// a package such as zap in zap.L()
+	for e := x; ; {
+		switch v := e.(type) {
+		case *ast.SelectorExpr:
+			e = v.X
+			continue
+		case *ast.CallExpr:
+			e = v.Fun
+			continue
+		case *ast.Ident:
+			if _, ok := r.typeInfo().Uses[v].(*types.PkgName); ok {
+				return ""
+			}
+			return types.ExprString(x)
+		}
+		return ""
+	}
+}
+
+// resolver works out which packages the values and types of a file come
+// from, using its types and, where they are invalid because the file was
+// checked on its own, its declarations
+type resolver struct {
+	fset  *token.FileSet
+	file  *ast.File
+	info  *types.Info            // Checked on first use when nil
+	decls map[token.Pos]ast.Expr // Built on first use
+}
+
+// typeInfo returns the types of the file
+func (r *resolver) typeInfo() *types.Info {
+	if r.info == nil {
+		r.info = checkFile(r.fset, r.file)
+	}
+	return r.info
+}
+
+// target returns the package the function call calls belongs to (see
+// Call.Target)
+func (r *resolver) target(call *ast.CallExpr) (string, bool) {
+	fun := call.Fun
+unwrap:
+	for {
+		switch e := fun.(type) {
+		case *ast.ParenExpr:
+			fun = e.X
+		case *ast.IndexExpr: // Instantiation of a generic function
+			fun = e.X
+		case *ast.IndexListExpr:
+			fun = e.X
+		default:
+			break unwrap
+		}
+	}
+	info := r.typeInfo()
+	switch e := fun.(type) {
+	case *ast.Ident:
+		if fn, ok := info.Uses[e].(*types.Func); ok && fn.Pkg() != nil {
+			return fn.Pkg().Path(), true
+		}
+	case *ast.SelectorExpr:
+		if sel, ok := info.Selections[e]; ok && sel.Obj().Pkg() != nil {
+			return sel.Obj().Pkg().Path(), true
+		}
+		return r.resolve(e.X, 0)
+	}
+	return "", false
+}
+
+// resolve returns the package the value or type expr comes from
+func (r *resolver) resolve(expr ast.Expr, depth int) (string, bool) {
+	if depth > maxDepth {
+		return "", false
+	}
+	info := r.typeInfo()
+	switch e := expr.(type) {
+	case *ast.Ident:
+		switch obj := info.Uses[e].(type) {
+		case *types.PkgName:
+			return obj.Imported().Path(), true
+		case *types.Var:
+			return r.object(obj, depth)
+		case *types.Func:
+			return r.object(obj, depth)
+		case *types.TypeName:
+			return typePackage(obj.Type())
+		}
+	case *ast.SelectorExpr:
+		if sel, ok := info.Selections[e]; ok {
+			return r.object(sel.Obj(), depth)
+		}
+		if ident, ok := e.X.(*ast.Ident); ok {
+			if pkg, ok := info.Uses[ident].(*types.PkgName); ok {
+				return pkg.Imported().Path(), true
+			}
+		}
+		// A method of a value of unknown type, such as With on a logger
+		// from an imported constructor, is taken to belong to the
+		// value's package
+		return r.resolve(e.X, depth+1)
+	case *ast.CallExpr:
+		if path, ok := typePackage(info.TypeOf(e)); ok {
+			return path, true
+		}
+		// Constructors and methods returning loggers, e.g. zap.L() or
+		// klog.V(2), return a type of their own package
+		return r.resolve(e.Fun, depth+1)
+	case *ast.ParenExpr:
+		return r.resolve(e.X, depth)
+	case *ast.StarExpr:
+		return r.resolve(e.X, depth)
+	case *ast.UnaryExpr:
+		return r.resolve(e.X, depth)
+	case *ast.IndexExpr:
+		return r.resolve(e.X, depth)
+	case *ast.IndexListExpr:
+		return r.resolve(e.X, depth)
+	case *ast.ArrayType:
+		return r.resolve(e.Elt, depth)
+	case *ast.MapType:
+		return r.resolve(e.Value, depth)
+	case *ast.CompositeLit:
+		if e.Type != nil {
+			return r.resolve(e.Type, depth)
+		}
+	case *ast.TypeAssertExpr:
+		if e.Type != nil {
+			return r.resolve(e.Type, depth)
+		}
+	}
+	return "", false
+}
+
+// object returns the package of the type of a variable, or of the first
+// result of a function, from its type if it is valid, otherwise from its
+// declaration in the file
+func (r *resolver) object(obj types.Object, depth int) (string, bool) {
+	t := obj.Type()
+	if sig, ok := t.(*types.Signature); ok {
+		if sig.Results().Len() == 0 {
+			return "", false
+		}
+		t = sig.Results().At(0).Type()
+	}
+	if path, ok := typePackage(t); ok {
+		return path, true
+	}
+	if decl := r.decl(obj.Pos()); decl != nil {
+		return r.resolve(decl, depth+1)
+	}
+	return "", false
+}
+
+// typePackage returns the package of a named type, or of the elements of
+// a pointer, slice, array or map of one. Imported packages are empty when
+// files are checked on their own, so the types found then are the
+// package's own, whose path is "".
+func typePackage(t types.Type) (string, bool) {
+	for {
+		switch u := types.Unalias(t).(type) {
+		case *types.Pointer:
+			t = u.Elem()
+			continue
+		case *types.Slice:
+			t = u.Elem()
+			continue
+		case *types.Array:
+			t = u.Elem()
+			continue
+		case *types.Map:
+			t = u.Elem()
+			continue
+		case *types.Named:
+			if pkg := u.Obj().Pkg(); pkg != nil {
+				return pkg.Path(), true
+			}
+		}
+		return "", false
+	}
+}
+
+// decl returns the type a variable or function declared at pos is
+// declared with (a function's first result), or else the value it is
+// initialized with
+func (r *resolver) decl(pos token.Pos) ast.Expr {
+	if r.decls == nil {
+		r.decls = declarations(r.file)
+	}
+	return r.decls[pos]
+}
+
+// declarations maps the position of each name declared in file to its
+// type or value (see decl). Of the names set from one call returning
+// several values, only the first is mapped, to the call.
+func declarations(file *ast.File) map[token.Pos]ast.Expr {
+	decls := make(map[token.Pos]ast.Expr)
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ValueSpec:
+			for i, name := range n.Names {
+				if n.Type != nil {
+					decls[name.Pos()] = n.Type
+				} else if len(n.Values) == len(n.Names) {
+					decls[name.Pos()] = n.Values[i]
+				} else if len(n.Values) == 1 && i == 0 {
+					decls[name.Pos()] = n.Values[0]
+				}
+			}
+		case *ast.Field:
+			for _, name := range n.Names {
+				decls[name.Pos()] = n.Type
+			}
+		case *ast.AssignStmt:
+			if n.Tok != token.DEFINE {
+				break
+			}
+			for i, lhs := range n.Lhs {
+				name, ok := lhs.(*ast.Ident)
+				if !ok {
+					continue
+				}
+				if len(n.Rhs) == len(n.Lhs) {
+					decls[name.Pos()] = n.Rhs[i]
+				} else if len(n.Rhs) == 1 && i == 0 {
+					// l, err := zap.NewProduction(): l is its first result
+					decls[name.Pos()] = n.Rhs[0]
+				}
+			}
+		case *ast.FuncDecl:
+			if results := n.Type.Results; results != nil && len(results.List) > 0 {
+				decls[n.Name.Pos()] = results.List[0].Type
+			}
+		}
+		return true
+	})
+	return decls
+}
@@ -0,0 +1,22 @@
+package collector
+
+import (
+	"go/ast"
+
+	"logrefactor/internal/logcall"
+)
+
+// returned returns the error a log call logs and the next statement
+// returns (see LogAndReturn), or "". nodes run from the file down to the
+// call.
+func returned(nodes []ast.Node) string {
+	if len(nodes) < 3 {
+		return ""
+	}
+	call, ok := nodes[len(nodes)-1].(*ast.CallExpr)
+	if !ok {
+		return ""
+	}
+	_, name := logcall.LogAndReturn(call, nodes[len(nodes)-2], nodes[len(nodes)-3])
+	return name
+}
@@ -0,0 +1,137 @@
+package collector
+
+import (
+	"go/ast"
+	"strings"
+
+	"logrefactor/internal/logcall"
+)
+
+// SlogAttr is the Type of an argument of a slog call collect can't turn
+// into a key and a value, such as slog.Group("http", ...), an attribute
+// held in a variable, a key that isn't a string literal or a slice passed
+// as args.... Their fields have to be written into StructuredFields by
+// hand (see transformer.LogUpdate.Unmapped).
+const SlogAttr = "slog.Attr"
+
+// slogLevels are the slog.Level constants, by level
+var slogLevels = map[string]string{"LevelDebug": "Debug", "LevelInfo": "Info", "LevelWarn": "Warn", "LevelError": "Error"}
+
+// slogMethods are the slog functions and Logger methods logging, by
+// whether only slog has them
+var slogMethods = map[string]bool{
+	"Debug": false, "Info": false, "Warn": false, "Error": false, "Log": false,
+	"DebugContext": true, "InfoContext": true, "WarnContext": true, "ErrorContext": true, "LogAttrs": true,
+}
+
+// slogCall reports whether a call of library (see newEntry) logs slog
+// key/value pairs and attributes: it is one of slogMethods, and a slog
+// function such as slog.Info, a method of library "slog", an XxxContext
+// or LogAttrs call, a Log call at a slog level, or a call with an
+// attribute constructor after the message. Calls of other libraries
+// passing pairs alike, such as hclog's, are left to their own readers.
+func slogCall(call *ast.CallExpr, library string) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	only, logs := slogMethods[sel.Sel.Name]
+	switch {
+	case !logs:
+		return false
+	case library == "slog":
+		return true
+	case library != "" && library != "custom":
+		return false
+	case only:
+		return true
+	}
+	if pkg, ok := sel.X.(*ast.Ident); ok && pkg.Name == "slog" {
+		return true
+	}
+	if _, ok := slogLevel(call); ok {
+		return true
+	}
+	for i := 1; i < len(call.Args); i++ {
+		if name, _ := logcall.SlogConstructor(call.Args[i]); name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// slogShift returns a slog call taking a context, or a context and a
+// level, before its message, such as logger.InfoContext(ctx, "sync", ...)
+// or logger.Log(ctx, slog.LevelWarn, "slow", ...), with its arguments from
+// the message on, as helper.shift does. Other calls are returned as they
+// are.
+func slogShift(call *ast.CallExpr) *ast.CallExpr {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return call
+	}
+	skip := 0
+	switch name := sel.Sel.Name; {
+	case strings.HasSuffix(name, "Context"):
+		skip = 1
+	case name == "Log" || name == "LogAttrs":
+		skip = 2
+	}
+	if skip == 0 || len(call.Args) <= skip {
+		return call
+	}
+	shifted := *call
+	shifted.Args = call.Args[skip:]
+	return &shifted
+}
+
+// slogLevel returns the level of a slog Log or LogAttrs call whose level
+// is a slog.Level constant, such as slog.LevelWarn. ok is false for other
+// calls.
+func slogLevel(call *ast.CallExpr) (string, bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Log" && sel.Sel.Name != "LogAttrs" || len(call.Args) < 3 {
+		return "", false
+	}
+	level, ok := call.Args[1].(*ast.SelectorExpr)
+	if !ok {
+		return "", false
+	}
+	if pkg, ok := level.X.(*ast.Ident); !ok || pkg.Name != "slog" || slogLevels[level.Sel.Name] == "" {
+		return "", false
+	}
+	return slogLevels[level.Sel.Name], true
+}
+
+// slogArguments returns the Arguments of a slog call from its message on
+// (see slogShift), such as
+// logger.Info("login", "user", name, slog.Int("attempts", n)): one for
+// each pair or attribute, with its key and value, and one of Type SlogAttr
+// for each argument collect can't read.
+func slogArguments(call *ast.CallExpr, keyStyle string) []Argument {
+	if len(call.Args) == 0 {
+		return nil
+	}
+	return pairArguments(call.Args[1:], call.Ellipsis.IsValid(), keyStyle, SlogAttr)
+}
+
+// slogWith returns the fields a slog With call sets on its logger, such as
+// logger.With("user", id), as do a zap SugaredLogger's With and logr's
+// WithValues. ok is false when one can't be read.
+func slogWith(call *ast.CallExpr) (args []Argument, ok bool) {
+	for _, arg := range pairArguments(call.Args, call.Ellipsis.IsValid(), "", SlogAttr) {
+		if arg.Type == SlogAttr {
+			return nil, false
+		}
+		arg.Index = -1
+		args = append(args, arg)
+	}
+	return args, true
+}
+
+// slogNote names the slog arguments of an entry collect couldn't read for
+// the Notes column, e.g. "SLOG: slog.Group("http", r) has no single value;
+// list its fields in StructuredFields". It is empty if there are none.
+func slogNote(args []Argument) string {
+	return unreadNote("SLOG", SlogAttr, args)
+}
@@ -0,0 +1,85 @@
+package collector
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// Struct is the Type of an argument that is a struct, or a pointer to one,
+// printed whole with %v, %+v or %#v. Its fields belong in fields of their
+// own, so auto-map doesn't turn it into one field (see
+// transformer.LogUpdate.Unmapped).
+const Struct = "struct"
+
+// markStructs sets the Type of the arguments of an entry that print a
+// struct whole to Struct and notes them. args are the call's arguments
+// after the message, as Argument.Index counts them. Types come from info,
+// which only knows the types of the file itself, and failing that from
+// struct literals such as &User{ID: id}.
+func markStructs(entry *LogEntry, args []ast.Expr, info *types.Info) {
+	var names []string
+	for i, arg := range entry.Arguments {
+		if !strings.HasSuffix(arg.FormatVerb, "v") || arg.Index >= len(args) || !isStruct(args[arg.Index], info) {
+			continue
+		}
+		entry.Arguments[i].Type = Struct
+		names = append(names, arg.Expression)
+	}
+	if len(names) > 0 {
+		entry.FieldConfidence = fieldConfidence(entry.MessageTemplate, entry.Arguments)
+	}
+	switch {
+	case len(names) == 1:
+		entry.Notes = joinNotes(entry.Notes, fmt.Sprintf("STRUCT: %s is printed whole; list its fields in StructuredFields", names[0]))
+	case len(names) > 1:
+		entry.Notes = joinNotes(entry.Notes, fmt.Sprintf("STRUCT: %s are printed whole; list their fields in StructuredFields", strings.Join(names, ", ")))
+	}
+}
+
+// isStruct reports whether expr is a struct or a pointer to one
+func isStruct(expr ast.Expr, info *types.Info) bool {
+	if tv, ok := info.Types[expr]; ok && tv.Type != nil {
+		t := tv.Type
+		if ptr, ok := t.Underlying().(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+		if _, ok := t.Underlying().(*types.Struct); ok {
+			return true
+		}
+	}
+
+	for {
+		switch e := expr.(type) {
+		case *ast.ParenExpr:
+			expr = e.X
+			continue
+		case *ast.UnaryExpr:
+			if e.Op == token.AND {
+				expr = e.X
+				continue
+			}
+		case *ast.CompositeLit:
+			if _, ok := e.Type.(*ast.StructType); ok {
+				return true
+			}
+			// Only a struct literal has field names for keys
+			if len(e.Elts) == 0 {
+				return false
+			}
+			for _, elt := range e.Elts {
+				kv, ok := elt.(*ast.KeyValueExpr)
+				if !ok {
+					return false
+				}
+				if _, ok := kv.Key.(*ast.Ident); !ok {
+					return false
+				}
+			}
+			return true
+		}
+		return false
+	}
+}
@@ -0,0 +1,91 @@
+package collector
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+// durationConstants are the time package's constants of type Duration
+var durationConstants = map[string]bool{
+	"Nanosecond": true, "Microsecond": true, "Millisecond": true,
+	"Second": true, "Minute": true, "Hour": true,
+}
+
+// markTimes sets the Type of the arguments of an entry that are declared
+// as a time.Duration or time.Time, or come from time.Since, time.Until or
+// time.Now, so that transform logs them with Duration and Time fields.
+// args are the call's arguments after the message, as Argument.Index
+// counts them. Names such as timeout or createdAt aren't enough: an int
+// timeoutSecs would get a Duration field that doesn't compile.
+func markTimes(entry *LogEntry, args []ast.Expr, res *resolver) {
+	marked := false
+	for i, arg := range entry.Arguments {
+		if arg.Index >= len(args) || arg.Type != "unknown" && arg.Type != "func_result" {
+			continue
+		}
+		if typ := res.timeType(args[arg.Index], 0); typ != "" {
+			entry.Arguments[i].Type = typ
+			marked = true
+		}
+	}
+	if marked {
+		entry.FieldConfidence = fieldConfidence(entry.MessageTemplate, entry.Arguments)
+	}
+}
+
+// timeType returns "time.Duration" or "time.Time" if expr is one, or "".
+// The time package is empty when the file is checked on its own, so the
+// type comes from the declaration of expr in the file: a variable, field
+// or parameter declared with the type, or set from a function returning
+// it.
+func (r *resolver) timeType(expr ast.Expr, depth int) string {
+	if depth > maxDepth {
+		return ""
+	}
+	info := r.typeInfo()
+	switch e := expr.(type) {
+	case *ast.Ident:
+		switch obj := info.Uses[e].(type) {
+		case *types.Var, *types.Func:
+			if decl := r.decl(obj.Pos()); decl != nil {
+				return r.timeType(decl, depth+1)
+			}
+		}
+	case *ast.SelectorExpr:
+		if sel, ok := info.Selections[e]; ok {
+			if decl := r.decl(sel.Obj().Pos()); decl != nil {
+				return r.timeType(decl, depth+1)
+			}
+			return ""
+		}
+		if ident, ok := e.X.(*ast.Ident); ok {
+			if pkg, ok := info.Uses[ident].(*types.PkgName); ok && pkg.Imported().Path() == "time" {
+				switch {
+				case e.Sel.Name == "Duration" || e.Sel.Name == "Time":
+					return "time." + e.Sel.Name
+				case durationConstants[e.Sel.Name]:
+					return "time.Duration"
+				}
+			}
+		}
+	case *ast.CallExpr:
+		if sel, ok := e.Fun.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok {
+				if pkg, ok := info.Uses[ident].(*types.PkgName); ok && pkg.Imported().Path() == "time" {
+					switch sel.Sel.Name {
+					case "Since", "Until", "Duration":
+						return "time.Duration"
+					case "Now", "Unix", "UnixMilli", "Date":
+						return "time.Time"
+					}
+					return ""
+				}
+			}
+		}
+		// A call to a function of the file, or a conversion to a type
+		return r.timeType(e.Fun, depth+1)
+	case *ast.ParenExpr:
+		return r.timeType(e.X, depth)
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+package collector
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+
+	"logrefactor/internal/logcall"
+)
+
+// ZapField is the Type of an argument of a zap call that is a field, or
+// fields, collect can't turn into a key and a value, such as
+// zap.Object("user", u), zap.Namespace("http") or a variable holding fields
+// passed as fields.... Their fields have to be written into
+// StructuredFields by hand (see transformer.LogUpdate.Unmapped).
+const ZapField = "zap.Field"
+
+// zapArguments returns the Arguments of a call logging zap fields, such as
+// logger.Info("login", zap.String("user", name), zap.Error(err)): one for
+// each field, with the field's key and value, and one of Type ZapField for
+// each argument that isn't a field collect can read. ok is false when no
+// argument after the message is a zap field.
+func zapArguments(call *ast.CallExpr, keyStyle string) (args []Argument, ok bool) {
+	if !zapCall(call) {
+		return nil, false
+	}
+	for i, arg := range call.Args[1:] {
+		key, value, typ, isField := logcall.ZapField(arg)
+		if !isField {
+			expr := formatExpr(arg)
+			varName := extractVarName(expr)
+			if name, _ := logcall.ZapConstructor(arg); name != "" {
+				varName = name // zap.Object("user", u) is an Object
+			}
+			if i == len(call.Args)-2 && call.Ellipsis.IsValid() {
+				expr += "..."
+			}
+			args = append(args, Argument{Index: i, Expression: expr, VarName: varName, Type: ZapField, SuggestedKey: generateFieldKey(varName, "", ZapField, keyStyle)})
+			continue
+		}
+		expr := formatExpr(value)
+		args = append(args, Argument{Index: i, Expression: expr, VarName: extractVarName(expr), Type: typ, SuggestedKey: key})
+	}
+	return args, true
+}
+
+// zapWith returns the fields a zap With call sets on its logger, such as
+// logger.With(zap.String("user", id)). ok is false when one can't be read.
+func zapWith(call *ast.CallExpr) (args []Argument, ok bool) {
+	for _, arg := range call.Args {
+		key, value, typ, isField := logcall.ZapField(arg)
+		if !isField {
+			return nil, false
+		}
+		args = append(args, chainedArgument(key, value, typ))
+	}
+	return args, true
+}
+
+// zapSugaredMethods are the methods of zap's SugaredLogger logging
+// key/value pairs after their message, such as sugar.Infow("login",
+// "user", name)
+var zapSugaredMethods = map[string]bool{
+	"Debugw": true, "Infow": true, "Warnw": true, "Errorw": true, "DPanicw": true, "Panicw": true, "Fatalw": true,
+}
+
+// zapSugaredCall reports whether a call of library (see newEntry) is one of
+// zapSugaredMethods, made on a zap logger or one the file doesn't tell
+func zapSugaredCall(call *ast.CallExpr, library string) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || !zapSugaredMethods[sel.Sel.Name] || len(call.Args) == 0 {
+		return false
+	}
+	return library == "zap" || library == "" || library == "custom"
+}
+
+// zapCall reports whether an argument of a call after the message is a zap
+// field
+func zapCall(call *ast.CallExpr) bool {
+	for i := 1; i < len(call.Args); i++ {
+		if name, _ := logcall.ZapConstructor(call.Args[i]); name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// zapFields drafts StructuredFields for a zap call whose fields all have
+// a key and a value, keeping their keys, so they are carried over whether
+// or not transform auto-maps. It returns "" for other calls.
+func zapFields(call *ast.CallExpr, args []Argument) string {
+	if !zapCall(call) {
+		return ""
+	}
+	for _, arg := range args {
+		if arg.Type == ZapField {
+			return ""
+		}
+	}
+	fields, _ := formatFields(args)
+	return fields
+}
+
+// zapNote names the zap fields of an entry collect couldn't read for the
+// Notes column, e.g. "ZAP: zap.Object("user", u) has no single value; list
+// its fields in StructuredFields". It is empty if there are none.
+func zapNote(args []Argument) string {
+	return unreadNote("ZAP", ZapField, args)
+}
+
+// unreadNote names the arguments of Type typ for the Notes column, after
+// prefix, as zapNote does
+func unreadNote(prefix, typ string, args []Argument) string {
+	var fields []string
+	for _, arg := range args {
+		if arg.Type == typ {
+			fields = append(fields, arg.Expression)
+		}
+	}
+	switch {
+	case len(fields) == 1:
+		return fmt.Sprintf("%s: %s has no single value; list its fields in StructuredFields", prefix, fields[0])
+	case len(fields) > 1:
+		return fmt.Sprintf("%s: %s have no single value; list their fields in StructuredFields", prefix, strings.Join(fields, ", "))
+	}
+	return ""
+}
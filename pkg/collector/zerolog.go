@@ -0,0 +1,31 @@
+package collector
+
+import (
+	"go/ast"
+
+	"logrefactor/internal/logcall"
+)
+
+// zerologArguments returns the fields the calls building a zerolog event
+// add, with their keys as written, like chainedArguments. A Fields call
+// with a map literal of string keys adds one field per key.
+func zerologArguments(links []*ast.CallExpr) (args []Argument, unread []string) {
+	for _, link := range links {
+		if logcall.ZerologSilent(link) {
+			continue
+		}
+		name := link.Fun.(*ast.SelectorExpr).Sel.Name
+		if key, value, typ, ok := logcall.ZerologField(link); ok {
+			args = append(args, chainedArgument(key, value, typ))
+			continue
+		}
+		if name == "Fields" {
+			if set, ok := literalFields(onlyArg(link)); ok {
+				args = append(args, set...)
+				continue
+			}
+		}
+		unread = append(unread, linkText(link))
+	}
+	return args, unread
+}
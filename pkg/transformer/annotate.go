@@ -0,0 +1,174 @@
+package transformer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// AnnotateMarker starts every comment annotate inserts
+const AnnotateMarker = "// TODO(logrefactor): "
+
+// AnnotateOptions selects the entries to annotate. Empty lists match every
+// entry; an entry must match all non-empty ones.
+type AnnotateOptions struct {
+	IDs      []string
+	Levels   []string // LogLevel values, case-insensitive
+	Packages []string
+	Text     string // Follows the entry ID in the comment
+	Remove   bool   // Remove the markers of the selected entries instead
+	DryRun   bool
+}
+
+func (o AnnotateOptions) selects(update LogUpdate) bool {
+	return matchAny(o.IDs, update.ID, false) &&
+		matchAny(o.Levels, update.LogLevel, true) &&
+		matchAny(o.Packages, update.Package, false)
+}
+
+func matchAny(list []string, value string, fold bool) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, item := range list {
+		if item == value || (fold && strings.EqualFold(item, value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Annotate inserts a "// TODO(logrefactor): LOG-0042 <text>" comment above
+// each selected call in the CSV instead of rewriting it, for teams that
+// convert calls by hand. Entries whose marker is already in the file are
+// skipped, so annotate can be run again after collecting more entries. With
+// Remove set, the markers of the selected entries are deleted wherever they
+// are in the file. Inserting markers shifts the lines below them, so
+// re-collect (and merge) before running transform on the same files.
+func Annotate(csvFile string, opts AnnotateOptions) error {
+	updates, err := loadUpdates(csvFile)
+	if err != nil {
+		return fmt.Errorf("failed to load updates: %w", err)
+	}
+	if opts.Text == "" {
+		opts.Text = "convert to structured logging"
+	}
+
+	fileUpdates := make(map[string][]LogUpdate)
+	var files []string
+	for _, update := range updates {
+		if !opts.selects(update) {
+			continue
+		}
+		if _, ok := fileUpdates[update.FilePath]; !ok {
+			files = append(files, update.FilePath)
+		}
+		fileUpdates[update.FilePath] = append(fileUpdates[update.FilePath], update)
+	}
+	if len(files) == 0 {
+		fmt.Println("No entries selected")
+		return nil
+	}
+	sort.Strings(files)
+
+	for _, filePath := range files {
+		if err := annotateFile(filePath, fileUpdates[filePath], opts); err != nil {
+			return fmt.Errorf("failed to annotate %s: %w", filePath, err)
+		}
+	}
+	return nil
+}
+
+// annotateFile adds or removes the markers of one file's entries
+func annotateFile(filePath string, updates []LogUpdate, opts AnnotateOptions) error {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	src := loadSourceFile(filePath)
+	if src.err != nil && !opts.Remove {
+		return src.err
+	}
+
+	// Byte offset of the start of every line; lines are 1-based
+	starts := []int{0, 0}
+	for i, b := range content {
+		if b == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	line := func(n int) []byte {
+		if n < 1 || n >= len(starts) {
+			return nil
+		}
+		end := len(content)
+		if n+1 < len(starts) {
+			end = starts[n+1]
+		}
+		return content[starts[n]:end]
+	}
+
+	// Lines that already carry a marker, by entry ID
+	markers := make(map[string]int)
+	for n := 1; n < len(starts); n++ {
+		text := strings.TrimSpace(string(line(n)))
+		if rest, ok := strings.CutPrefix(text, AnnotateMarker); ok {
+			id, _, _ := strings.Cut(rest, " ")
+			markers[id] = n
+		}
+	}
+
+	var edits []edit
+	inserts := make(map[int]string)
+	var insertLines []int
+	for _, update := range updates {
+		marker, annotated := markers[update.ID]
+		if opts.Remove {
+			if !annotated {
+				continue
+			}
+			edits = append(edits, edit{start: starts[marker], end: starts[marker] + len(line(marker)), id: update.ID, line: marker})
+			fmt.Printf("%s:%d\n  Remove: %s\n\n", filePath, marker, strings.TrimSpace(string(line(marker))))
+			continue
+		}
+		if annotated {
+			continue
+		}
+
+		if _, ok := src.calls[fmt.Sprintf("%d:%d", update.Line, update.Column)]; !ok {
+			fmt.Fprintf(os.Stderr, "Warning: %s: no call at %s:%d:%d; re-run collect if the file changed\n",
+				update.ID, filePath, update.Line, update.Column)
+			continue
+		}
+		text := line(update.Line)
+		indent := text[:len(text)-len(bytes.TrimLeft(text, " \t"))]
+		comment := fmt.Sprintf("%s%s%s %s\n", indent, AnnotateMarker, update.ID, opts.Text)
+		if _, ok := inserts[update.Line]; !ok {
+			insertLines = append(insertLines, update.Line)
+		}
+		inserts[update.Line] += comment
+		fmt.Printf("%s:%d\n  Add: %s\n\n", filePath, update.Line, strings.TrimSpace(comment))
+	}
+	for _, n := range insertLines {
+		edits = append(edits, edit{start: starts[n], end: starts[n], code: inserts[n], line: n})
+	}
+
+	if len(edits) == 0 {
+		return nil
+	}
+	if opts.DryRun {
+		fmt.Printf("Would update: %s (%d changes)\n", filePath, len(edits))
+		return nil
+	}
+	updated, skipped := applyEdits(content, edits)
+	for _, e := range skipped {
+		warner(nil)(conflict(filePath, e))
+	}
+	if err := os.WriteFile(filePath, updated, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Updated: %s (%d changes)\n", filePath, len(edits))
+	return nil
+}
@@ -0,0 +1,190 @@
+package transformer
+
+import (
+	"go/ast"
+	"go/token"
+	"slices"
+	"strconv"
+
+	"logrefactor/internal/logcall"
+)
+
+// chainLibraries are the SourceLibrary values whose calls can set fields on
+// the logger in the call itself, e.g.
+// log.WithField("user", id).WithError(err).Errorf(...),
+// logger.With(zap.String("user", id)).Info(...),
+// logger.With("user", id).Info(...), sugar.With("user", id).Infow(...),
+// log.Error().Str("user", id).Msg(...),
+// logger.WithValues("user", id).Info(...) or
+// level.Info(log.With(logger, "user", id)).Log(...)
+var chainLibraries = map[string]bool{
+	"logrus": true, "apex/log": true, "zap": true, "slog": true, "zerolog": true, "logr": true, "go-kit": true,
+}
+
+// chainedFields returns the fields a logrus or apex/log call sets with
+// WithField, WithFields and WithError before logging, a zap or slog call
+// with With, a logr call with WithValues, a go-kit call with With around
+// its logger, or a zerolog call on its event, in source order, so that they
+// aren't lost with the receiver the new call replaces. Fields whose keys
+// aren't string literals are left out, as are zap, slog and zerolog
+// fields without a single value (see logcall.ZapField).
+func chainedFields(call *ast.CallExpr, fset *token.FileSet, content []byte, library string) []FieldMapping {
+	if !chainLibraries[library] {
+		return nil
+	}
+	source := func(e ast.Expr) string {
+		return string(content[fset.Position(e.Pos()).Offset:fset.Position(e.End()).Offset])
+	}
+	switch library {
+	case "zerolog":
+		return zerologChained(call, source)
+	case "go-kit":
+		var fields []FieldMapping
+		_, _, withs, _ := logcall.GokitLogger(call)
+		for _, with := range withs {
+			fields = append(fields, withFields(with.Args[1:], source)...)
+		}
+		return fields
+	}
+
+	var fields []FieldMapping
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	for ok {
+		inner, isCall := sel.X.(*ast.CallExpr)
+		if !isCall {
+			break
+		}
+		sel, ok = inner.Fun.(*ast.SelectorExpr)
+		if !ok {
+			break
+		}
+		// Walking outwards in, so each call's fields go before the ones
+		// found so far
+		var set []FieldMapping
+		switch sel.Sel.Name {
+		case "WithField":
+			if len(inner.Args) == 2 {
+				if key, ok := stringLit(inner.Args[0]); ok {
+					set = append(set, FieldMapping{Key: key, Expression: source(inner.Args[1])})
+				}
+			}
+		case "WithFields":
+			if len(inner.Args) == 1 {
+				if lit, ok := inner.Args[0].(*ast.CompositeLit); ok {
+					for _, elt := range lit.Elts {
+						kv, ok := elt.(*ast.KeyValueExpr)
+						if !ok {
+							continue
+						}
+						if key, ok := stringLit(kv.Key); ok {
+							set = append(set, FieldMapping{Key: key, Expression: source(kv.Value)})
+						}
+					}
+				}
+			}
+		case "WithError":
+			if len(inner.Args) == 1 {
+				set = append(set, FieldMapping{Key: "error", Expression: source(inner.Args[0]), Type: "error"})
+			}
+		case "With":
+			if library == "zap" || library == "slog" {
+				set = withFields(inner.Args, source)
+			}
+		case "WithValues":
+			if library == "logr" {
+				set = withFields(inner.Args, source)
+			}
+		}
+		fields = append(set, fields...)
+	}
+	return fields
+}
+
+// zerologChained returns the fields a zerolog call adds to its event (see
+// logcall.ZerologEvent), in source order
+func zerologChained(call *ast.CallExpr, source func(ast.Expr) string) []FieldMapping {
+	_, _, links, ok := logcall.ZerologEvent(call)
+	if !ok {
+		return nil
+	}
+	var fields []FieldMapping
+	for _, link := range links {
+		if key, value, typ, ok := logcall.ZerologField(link); ok {
+			fields = append(fields, FieldMapping{Key: key, Expression: source(value), Type: typ})
+			continue
+		}
+		if link.Fun.(*ast.SelectorExpr).Sel.Name != "Fields" || len(link.Args) != 1 {
+			continue
+		}
+		if lit, ok := link.Args[0].(*ast.CompositeLit); ok {
+			for _, elt := range lit.Elts {
+				kv, ok := elt.(*ast.KeyValueExpr)
+				if !ok {
+					continue
+				}
+				if key, ok := stringLit(kv.Key); ok {
+					fields = append(fields, FieldMapping{Key: key, Expression: source(kv.Value)})
+				}
+			}
+		}
+	}
+	return fields
+}
+
+// withFields returns the fields a zap or slog With call sets: zap fields,
+// slog attributes and key/value pairs, such as
+// logger.With("user", id, slog.Int("n", n)) or a SugaredLogger's
+// sugar.With("user", id)
+func withFields(args []ast.Expr, source func(ast.Expr) string) []FieldMapping {
+	var fields []FieldMapping
+	for i := 0; i < len(args); i++ {
+		key, value, typ, ok := logcall.SlogAttr(args[i])
+		if !ok {
+			key, value, typ, ok = logcall.ZapField(args[i])
+		}
+		if ok {
+			fields = append(fields, FieldMapping{Key: key, Expression: source(value), Type: typ})
+			continue
+		}
+		if key, ok := stringLit(args[i]); ok && i+1 < len(args) {
+			fields = append(fields, FieldMapping{Key: key, Expression: source(args[i+1])})
+			i++
+		}
+	}
+	return fields
+}
+
+// stringLit returns the value of a string literal
+func stringLit(e ast.Expr) (string, bool) {
+	lit, ok := e.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+// withChained puts the chained fields before fields, leaving out those
+// whose key fields already has, as when a reviewer wrote them into
+// StructuredFields. Such a field without a type of its own takes the
+// chained field's when they log the same expression, as Str("id", id) and
+// id=id do.
+func withChained(chained, fields []FieldMapping) []FieldMapping {
+	keys := make(map[string]bool, len(fields))
+	for i, f := range fields {
+		keys[f.Key] = true
+		if f.Type != "" && f.Type != "unknown" {
+			continue
+		}
+		if j := slices.IndexFunc(chained, func(c FieldMapping) bool { return c.Key == f.Key && c.Expression == f.Expression }); j >= 0 {
+			fields[i].Type = chained[j].Type
+		}
+	}
+	var merged []FieldMapping
+	for _, f := range chained {
+		if !keys[f.Key] {
+			merged = append(merged, f)
+		}
+	}
+	return append(merged, fields...)
+}
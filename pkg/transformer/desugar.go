@@ -0,0 +1,84 @@
+package transformer
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/types"
+	"slices"
+	"strings"
+)
+
+// zapMethods are the level methods of zap's Logger, which SugaredLogger
+// has too, along with their f, w and ln variants
+var zapMethods = []string{"Debug", "Info", "Warn", "Error", "DPanic", "Panic", "Fatal"}
+
+// zapSugaredMethod reports whether name is a method only zap's
+// SugaredLogger has, such as Infof, Infow or Infoln
+func zapSugaredMethod(name string) bool {
+	for _, suffix := range []string{"f", "w", "ln"} {
+		if base, ok := strings.CutSuffix(name, suffix); ok && slices.Contains(zapMethods, base) {
+			return true
+		}
+	}
+	return false
+}
+
+// desugared returns the typed *zap.Logger behind the SugaredLogger a zap
+// call is made on, as collect names the call: "h.log" for
+// h.log.Sugar().Infow(...) and "zap.L()" for zap.S().Info(...), and typed,
+// the variable holding the typed logger, for a SugaredLogger the call
+// doesn't show it for, such as sugar.Infow(...) or
+// sugar.With("user", id).Infof(...). sugar.Desugar() would do there too,
+// but it copies the logger on every call. Calls with arguments ending the
+// chain, such as With(...), are left out as in Receiver, since their
+// fields are the entry's. ok is false for other calls, including sugared
+// Info(args...) and its like on a logger that isn't Sugar() or zap.S(),
+// which can't be told apart from typed calls.
+func desugared(originalCall, typed string) (logger string, ok bool) {
+	expr, err := parser.ParseExpr(originalCall)
+	if err != nil {
+		return "", false
+	}
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return "", false
+	}
+	x := sel.X
+	for {
+		inner, ok := x.(*ast.CallExpr)
+		if !ok || len(inner.Args) == 0 {
+			break
+		}
+		fun, ok := inner.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return "", false
+		}
+		x = fun.X
+	}
+
+	if call, ok := x.(*ast.CallExpr); ok {
+		if fun, ok := call.Fun.(*ast.SelectorExpr); ok {
+			pkg, _ := fun.X.(*ast.Ident)
+			switch {
+			case fun.Sel.Name == "Sugar":
+				return types.ExprString(fun.X), true
+			case fun.Sel.Name == "S" && pkg != nil && pkg.Name == "zap":
+				return "zap.L()", true
+			}
+		}
+	}
+	if pkg, ok := x.(*ast.Ident); ok && pkg.Name == "zap" || !zapSugaredMethod(sel.Sel.Name) {
+		return "", false
+	}
+	return typed, true
+}
+
+// zapDPanic returns "DPanic" for a zap DPanic call, such as
+// sugar.DPanicw(...), still at the level collect records for it, Panic:
+// DPanic only panics in development, so it stays DPanic
+func zapDPanic(update LogUpdate, level string) string {
+	if strings.HasPrefix(lastSegment(update.OriginalCall), "DPanic") && strings.EqualFold(level, update.LogLevel) {
+		return "DPanic"
+	}
+	return level
+}
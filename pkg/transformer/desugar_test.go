@@ -0,0 +1,58 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDesugared(t *testing.T) {
+	tests := []struct {
+		call   string
+		want   string
+		wantOK bool
+	}{
+		{"sugar.Infow", "logger", true},
+		{"s.log.Errorf", "logger", true},
+		{`sugar.With("user", id).Infof`, "logger", true},
+		{"h.log.Sugar().Infow", "h.log", true},
+		{"base.Sugar().Info", "base", true},
+		{"zap.S().Info", "zap.L()", true},
+		{"sugar.Info", "", false},
+		{"zap.Infow", "", false},
+		{"logger.Info", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := desugared(tt.call, "logger")
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("desugared(%q) = %q, %v, want %q, %v", tt.call, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestTransformDesugar(t *testing.T) {
+	src := `package main
+
+func sync(sugar, base any, name string) {
+	sugar.Infow("synced", "name", name)
+	base.Sugar().Infow("login", "user", name)
+}
+`
+	updates := []LogUpdate{
+		{ID: "1", Line: 4, Column: 2, OriginalCall: "sugar.Infow", SourceLibrary: "zap", Receiver: "sugar", LogLevel: "Info",
+			MessageTemplate: `"synced"`, NewMessage: "synced", StructuredFields: "name=name"},
+		{ID: "2", Line: 5, Column: 2, OriginalCall: "base.Sugar().Infow", SourceLibrary: "zap", Receiver: "base.Sugar()", LogLevel: "Info",
+			MessageTemplate: `"login"`, NewMessage: "login", StructuredFields: "user=name"},
+	}
+	for _, tt := range []struct {
+		name, typed, want string
+	}{
+		{"loggerVar", "", "zl.Info(\"synced\""},
+		{"desugaredLogger", "typed", "typed.Info(\"synced\""},
+	} {
+		config := &TemplateConfig{Style: "zap", LoggerVar: "zl", Desugar: true, DesugaredLogger: tt.typed}
+		out, _ := applySource(t, src, append([]LogUpdate(nil), updates...), config, Options{})
+		if !strings.Contains(out, tt.want) || !strings.Contains(out, `base.Info("login"`) || strings.Contains(out, "Desugar()") {
+			t.Errorf("%s: got\n%s", tt.name, out)
+		}
+	}
+}
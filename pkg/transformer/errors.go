@@ -0,0 +1,59 @@
+package transformer
+
+import "fmt"
+
+// The problems that don't stop a run are reported as these types (and a
+// failed file's parse error as a *collector.ParseError), so callers can
+// tell them apart with errors.As. A run's warnings are also collected in
+// Report.Warnings.
+
+// RowError is a row of the updates that couldn't be read, and was skipped
+type RowError struct {
+	Row int // Line of the row, counting the header as 1
+	Err error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("skipping malformed row %d: %v", e.Row, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// GenerateError is an entry whose new call couldn't be generated, and was
+// skipped
+type GenerateError struct {
+	ID  string
+	Err error
+}
+
+func (e *GenerateError) Error() string {
+	return fmt.Sprintf("failed to generate code for %s: %v", e.ID, e.Err)
+}
+
+func (e *GenerateError) Unwrap() error {
+	return e.Err
+}
+
+// EditConflictError is an edit that overlaps another in the same file (or
+// falls outside it), and was left out rather than written over it
+type EditConflictError struct {
+	ID         string // Entry ID, empty for annotate markers
+	File       string
+	Line       int
+	Start, End int // Byte offsets of the replaced code
+}
+
+func (e *EditConflictError) Error() string {
+	what := "edit"
+	if e.ID != "" {
+		what = "edit for " + e.ID
+	}
+	return fmt.Sprintf("skipping overlapping or invalid %s at %s:%d (offset %d-%d)", what, e.File, e.Line, e.Start, e.End)
+}
+
+// conflict returns the error for an edit applyEdits skipped
+func conflict(filePath string, e edit) error {
+	return &EditConflictError{ID: e.id, File: filePath, Line: e.line, Start: e.start, End: e.end}
+}
@@ -0,0 +1,126 @@
+package transformer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"logrefactor/internal/plugin"
+)
+
+// execRequest is the JSON document exec generators and WASM generator
+// plugins read from stdin
+type execRequest struct {
+	Entry   LogUpdate   `json:"entry"`   // The CSV row, with the original column names
+	Logger  string      `json:"logger"`  // Configured loggerVar
+	Level   string      `json:"level"`   // Level after levelMap
+	Message string      `json:"message"` // NewMessage, or SuggestedMessage or MessageTemplate if empty
+	Fields  []execField `json:"fields"`  // Fields after renaming, key style and grouping
+}
+
+// execField is a FieldMapping with its key constant, which FieldMapping
+// leaves out of its own JSON form
+type execField struct {
+	FieldMapping
+	Kind     string `json:"kind"`
+	KeyConst string `json:"keyConst,omitempty"`
+}
+
+// generateExecCall runs an external generator. The request is written to
+// the program's stdin as JSON and the generated call is read from stdout;
+// a non-zero exit fails the entry with the program's stderr.
+func generateExecCall(command []string, update LogUpdate, loggerVar, level, message string, fields []FieldMapping) (string, error) {
+	if len(command) == 0 {
+		return "", fmt.Errorf("style exec needs a command")
+	}
+
+	input, err := marshalExecRequest(update, loggerVar, level, message, fields)
+	if err != nil {
+		return "", err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %w: %s", command[0], err, msg)
+		}
+		return "", fmt.Errorf("%s: %w", command[0], err)
+	}
+
+	code := strings.TrimSpace(stdout.String())
+	if code == "" {
+		return "", fmt.Errorf("%s produced no output", command[0])
+	}
+	return code, nil
+}
+
+// generateWasmCall runs a WASM generator plugin, which speaks the same
+// protocol as an exec generator: the one the run loaded, or outside a run
+// (see Generate) one loaded for the call
+func generateWasmCall(config *TemplateConfig, update LogUpdate, level, message string, fields []FieldMapping) (string, error) {
+	if config.Plugin == "" {
+		return "", fmt.Errorf("style wasm needs a plugin")
+	}
+	ctx := config.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	p := config.plugin
+	if p == nil {
+		var err error
+		if p, err = plugin.Load(ctx, config.Plugin); err != nil {
+			return "", err
+		}
+		defer p.Close(ctx)
+	}
+
+	input, err := marshalExecRequest(update, config.LoggerVar, level, message, fields)
+	if err != nil {
+		return "", err
+	}
+	output, err := p.Call(ctx, input)
+	if err != nil {
+		return "", err
+	}
+
+	code := strings.TrimSpace(string(output))
+	if code == "" {
+		return "", fmt.Errorf("%s produced no output", config.Plugin)
+	}
+	return code, nil
+}
+
+// usesPlugin reports whether the style, or that of an override, is wasm
+func (c *TemplateConfig) usesPlugin() bool {
+	if c.Style == "wasm" {
+		return true
+	}
+	for _, o := range c.Overrides {
+		if o.Style == "wasm" {
+			return true
+		}
+	}
+	return false
+}
+
+// marshalExecRequest builds the JSON request for an external generator
+func marshalExecRequest(update LogUpdate, loggerVar, level, message string, fields []FieldMapping) ([]byte, error) {
+	req := execRequest{
+		Entry:   update,
+		Logger:  loggerVar,
+		Level:   level,
+		Message: message,
+		Fields:  make([]execField, len(fields)),
+	}
+	for i, field := range fields {
+		req.Fields[i] = execField{FieldMapping: field, Kind: fieldKind(field), KeyConst: field.KeyConst}
+	}
+	return json.Marshal(req)
+}
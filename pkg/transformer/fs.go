@@ -0,0 +1,59 @@
+package transformer
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// WriteFS is a file system transform can read source files from and write
+// them back to (see Options.FS)
+type WriteFS interface {
+	fs.FS
+	// WriteFile replaces the contents of the named file, creating the file
+	// and its directory if needed
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+}
+
+// DirFS returns the tree rooted at dir as a WriteFS, the writable
+// counterpart of os.DirFS
+func DirFS(dir string) WriteFS {
+	return dirFS{FS: os.DirFS(dir), dir: dir}
+}
+
+type dirFS struct {
+	fs.FS
+	dir string
+}
+
+func (d dirFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	return writeFile(filepath.Join(d.dir, filepath.FromSlash(name)), data, perm)
+}
+
+// osFS reads and writes OS paths as they are, which is what transform does
+// without Options.FS. Unlike an fs.FS from os.DirFS, it takes absolute
+// paths and paths with "..", as the FilePath column may have.
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+func (osFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func (osFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return writeFile(name, data, perm)
+}
+
+// writeFile writes an OS file, creating its directory if needed
+func writeFile(path string, data []byte, perm fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, perm)
+}
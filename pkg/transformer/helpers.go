@@ -0,0 +1,251 @@
+package transformer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// helperCall reports whether the entry is a call to a logging helper (see
+// Helper), rather than the call the helper wraps
+func (u LogUpdate) helperCall() bool {
+	return u.Helper != "" && lastSegment(u.OriginalCall) == u.Helper
+}
+
+// inHelper reports whether the entry is the call a logging helper wraps
+func (u LogUpdate) inHelper() bool {
+	return u.Helper != "" && lastSegment(u.OriginalCall) != u.Helper
+}
+
+// helperMethod returns the method a rewritten helper logs its message and
+// key/value pairs with, for the styles whose calls take them after the
+// message: slog, zap-sugared, hclog and log15. ok is false for the others.
+func helperMethod(style, level string) (method string, ok bool) {
+	switch style {
+	case "slog":
+		return slogLevel(level), true
+	case "zap-sugared":
+		return zapSugaredLevel(level) + "w", true
+	case "hclog":
+		return hclogLevel(level), true
+	case "log15":
+		return log15Level(level), true
+	}
+	return "", false
+}
+
+// helperRewrites tracks, over the first pass of a run with
+// Options.RewriteHelpers, which helpers it may rewrite: those whose own
+// entries and every call to which are applied in the run or were before.
+// Rewriting a helper but not a call to it, or the other way round, would
+// leave the call passing the wrong arguments. Helpers are told apart by
+// name only, so two of the same name wait for each other.
+type helperRewrites map[string]*helperRewrite
+
+// helperRewrite counts the entries of one helper
+type helperRewrite struct {
+	entries, ready int        // Entries inside the helper, and those applied now or before
+	calls, pending int        // Entries calling it, and those that are neither
+	entry          *LogUpdate // The entry inside it the run applies
+	err            error      // Why its rewrite fails, found by verify
+}
+
+// add counts an entry with a Helper; applying tells whether the run
+// applies it
+func (h helperRewrites) add(update LogUpdate, applying bool) {
+	r := h[update.Helper]
+	if r == nil {
+		r = &helperRewrite{}
+		h[update.Helper] = r
+	}
+	done := applying || update.Applied != ""
+	if update.inHelper() {
+		r.entries++
+		if done {
+			r.ready++
+		}
+		if applying {
+			r.entry = &update
+		}
+		return
+	}
+	r.calls++
+	if !done {
+		r.pending++
+	}
+}
+
+// check returns why the run can't rewrite the helper name and the calls to
+// it, or nil if it can
+func (h helperRewrites) check(name string) error {
+	r := h[name]
+	switch {
+	case r == nil || r.entries == 0:
+		return fmt.Errorf("helper %s has no entry of its own to rewrite it with", name)
+	case r.ready < r.entries:
+		return fmt.Errorf("helper %s isn't rewritten in this run: approve its own entry too", name)
+	case r.pending > 0:
+		return fmt.Errorf("helper %s isn't rewritten in this run: %d of the calls to it aren't applied with it", name, r.pending)
+	}
+	return r.err
+}
+
+// verify tries the rewrite of each helper check lets through, so that the
+// calls to one that can't be rewritten, such as one using a parameter that
+// would go, are held along with it instead of being left calling it with
+// the wrong arguments
+func (h helperRewrites) verify(config *TemplateConfig) {
+	for name, r := range h {
+		if r.entry != nil && h.check(name) == nil {
+			r.err = verifyHelper(*r.entry, config.forFile(r.entry.FilePath))
+		}
+	}
+}
+
+// verifyHelper returns why the helper the entry's call is in can't be
+// rewritten, or nil if it can
+func verifyHelper(update LogUpdate, config *TemplateConfig) error {
+	content, err := fs.ReadFile(config.fsys, update.FilePath)
+	if err != nil {
+		return fmt.Errorf("helper %s: %w", update.Helper, err)
+	}
+	fset := token.NewFileSet()
+	node, err := parser.ParseFile(fset, update.FilePath, content, parser.SkipObjectResolution)
+	if err != nil {
+		return fmt.Errorf("helper %s: %w", update.Helper, err)
+	}
+	var found *ast.CallExpr
+	ast.Inspect(node, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if pos := fset.Position(call.Pos()); pos.Line == update.Line && pos.Column == update.Column {
+				found = call
+			}
+		}
+		return found == nil
+	})
+	if found == nil {
+		return fmt.Errorf("helper %s: no call at %s:%d:%d; the file changed since collect", update.Helper, update.FilePath, update.Line, update.Column)
+	}
+	path, _ := astutil.PathEnclosingInterval(node, found.Pos(), found.End())
+	_, err = rewriteHelper(update, found, path, fset, content, config)
+	return err
+}
+
+// rewriteHelperCall returns the edit that keeps a call to a helper going
+// through it, passing the entry's message and then its fields as key/value
+// pairs, such as logError("user not found", "user", name, "error", err)
+// for logError("user %s not found", name, err). The helper itself is
+// rewritten to take them (see rewriteHelper).
+func rewriteHelperCall(update LogUpdate, call *ast.CallExpr, fset *token.FileSet, content []byte, config *TemplateConfig, autoMap bool) (edit, error) {
+	message, err := entryMessage(update)
+	if err != nil {
+		return edit{}, err
+	}
+	fields := resolveFields(update, config, autoMap)
+	if config.keys != nil {
+		config.keys.assign(fields)
+	}
+
+	offset := func(p token.Pos) int { return fset.Position(p).Offset }
+	args := append([]string{fmt.Sprintf(`"%s"`, message)}, keyValueArgs(fields)...)
+	code := string(content[offset(call.Fun.Pos()):offset(call.Fun.End())]) + "(" + strings.Join(args, ", ") + ")"
+	start, end := offset(call.Pos()), offset(call.End())
+	return edit{start: start, end: end, code: wrapLongCall(code, content, start, end, config), id: update.ID, line: fset.Position(call.Pos()).Line}, nil
+}
+
+// rewriteHelper returns the edit that turns the helper the entry's call is
+// in into one taking a message and key/value pairs: its parameters become
+// its message parameter and args ...any, which the call logs with the
+// style's method, so
+//
+//	func logError(msg string, err error) {
+//		log.Printf("%s: %v", msg, err)
+//	}
+//
+// becomes
+//
+//	func logError(msg string, args ...any) {
+//		logger.Error(msg, args...)
+//	}
+//
+// The level is the entry's, after levelMap. A Fatal or Panic level the
+// style logs as Error gets its exit or panic, as any call does. path runs
+// from the call up to the file, as astutil.PathEnclosingInterval returns
+// it.
+func rewriteHelper(update LogUpdate, call *ast.CallExpr, path []ast.Node, fset *token.FileSet, content []byte, config *TemplateConfig) (edit, error) {
+	var fn *ast.FuncDecl
+	for _, n := range path {
+		if decl, ok := n.(*ast.FuncDecl); ok {
+			fn = decl
+			break
+		}
+	}
+	if fn == nil || fn.Name.Name != update.Helper {
+		return edit{}, fmt.Errorf("the call is no longer in helper %s", update.Helper)
+	}
+	params := fn.Type.Params.List
+	if len(params) == 0 || len(params[0].Names) == 0 {
+		return edit{}, fmt.Errorf("helper %s doesn't take its message first", update.Helper)
+	}
+	if typ, ok := params[0].Type.(*ast.Ident); !ok || typ.Name != "string" {
+		return edit{}, fmt.Errorf("helper %s doesn't take its message first", update.Helper)
+	}
+	message := params[0].Names[0].Name
+
+	// The other parameters go, so the helper may only have logged them
+	dropped := make(map[string]bool)
+	for i, field := range params {
+		for j, name := range field.Names {
+			if i > 0 || j > 0 {
+				dropped[name.Name] = true
+			}
+		}
+	}
+	used := ""
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if n == call || used != "" {
+			return false
+		}
+		if ident, ok := n.(*ast.Ident); ok && dropped[ident.Name] {
+			used = ident.Name
+		}
+		return true
+	})
+	if used != "" {
+		return edit{}, fmt.Errorf("helper %s uses %s besides logging it; rewrite it by hand", update.Helper, used)
+	}
+
+	level := mapLevel(update, config.LevelMap)
+	method, ok := helperMethod(config.Style, level)
+	if !ok {
+		return edit{}, fmt.Errorf("style %s doesn't take key/value pairs, so helper %s can't pass them on", config.Style, update.Helper)
+	}
+	args := "args"
+	if message == args {
+		args = "keysAndValues"
+	}
+	offset := func(p token.Pos) int { return fset.Position(p).Offset }
+	start, callStart := offset(fn.Type.Params.Opening), offset(call.Pos())
+	code := fmt.Sprintf("(%s string, %s ...any)", message, args) +
+		string(content[offset(fn.Type.Params.Closing)+1:callStart]) +
+		fmt.Sprintf("%s.%s(%s, %s...)", loggerFor(update, config), method, message, args)
+
+	// terminator would panic with the entry's message, which is the
+	// helper's parameter here
+	if title := strings.Title(strings.ToLower(level)); terminatingStyles[config.Style][title] && !exitFollows(path) {
+		stmt := "os.Exit(1)"
+		if title == "Panic" {
+			stmt = "panic(" + message + ")"
+		}
+		var err error
+		if code, err = terminate(code, stmt, path, content, callStart); err != nil {
+			return edit{}, err
+		}
+	}
+	return edit{start: start, end: offset(call.End()), code: code, id: update.ID, line: fset.Position(fn.Type.Params.Opening).Line}, nil
+}
@@ -0,0 +1,89 @@
+package transformer
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRewriteHelpers(t *testing.T) {
+	src := `package main
+
+import "log"
+
+func logError(msg string, err error) {
+	log.Printf("%s: %v", msg, err)
+}
+
+func main() {
+	logError("lookup failed", err)
+	logError("save failed", err)
+}
+`
+	entries := func() []LogUpdate {
+		return []LogUpdate{
+			{ID: "LOG-0001", Line: 6, Column: 2, OriginalCall: "log.Printf", Helper: "logError", LogLevel: "Info", SuggestedLevel: "Error", Approved: "yes"},
+			{ID: "LOG-0002", Line: 10, Column: 2, OriginalCall: "logError", Helper: "logError", LogLevel: "Error", MessageTemplate: `"lookup failed"`, NewMessage: "lookup failed", StructuredFields: "error=err"},
+			{ID: "LOG-0003", Line: 11, Column: 2, OriginalCall: "logError", Helper: "logError", LogLevel: "Error", MessageTemplate: `"save failed"`, NewMessage: "save failed", StructuredFields: "error=err"},
+		}
+	}
+	config := &TemplateConfig{Style: "slog", LoggerVar: "logger"}
+
+	got, report := applySource(t, src, entries(), config, Options{RewriteHelpers: true})
+	want := `func logError(msg string, args ...any) {
+	logger.Error(msg, args...)
+}
+
+func main() {
+	logError("lookup failed", "error", err)
+	logError("save failed", "error", err)
+}`
+	if !strings.Contains(got, want) {
+		t.Errorf("transformed file:\n%s\nwant it to contain:\n%s", got, want)
+	}
+	if len(report.Changes) != 3 {
+		t.Errorf("%d changes, want 3", len(report.Changes))
+	}
+
+	// A call left out would call the rewritten helper with the old arguments
+	got, report = applySource(t, src, entries(), config, Options{RewriteHelpers: true, IDs: []string{"LOG-0001", "LOG-0002"}})
+	if got != src || len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0].Error(), "1 of the calls") {
+		t.Errorf("file changed to:\n%s\nwarnings = %v; want it untouched, with one warning", got, report.Warnings)
+	}
+
+	// Without its own entry approved, the helper and its calls are held
+	unapproved := entries()
+	unapproved[0].Approved = ""
+	got, report = applySource(t, src, unapproved, config, Options{RewriteHelpers: true})
+	if got != src || report.Held != 3 {
+		t.Errorf("file changed to:\n%s\nheld = %d; want it untouched, with 3 held", got, report.Held)
+	}
+
+	if _, err := Apply(context.Background(), entries(), Options{RewriteHelpers: true, Config: &TemplateConfig{Style: "zap", LoggerVar: "logger"}}); err == nil {
+		t.Error("Apply with style zap succeeded, want an error")
+	}
+}
+
+func TestRewriteHelperUsesParameter(t *testing.T) {
+	src := `package main
+
+import "log"
+
+func logError(msg string, err error) {
+	log.Printf("%s: %v", msg, err)
+	report(err)
+}
+
+func main() {
+	logError("lookup failed", err)
+}
+`
+	updates := []LogUpdate{
+		{ID: "LOG-0001", Line: 6, Column: 2, OriginalCall: "log.Printf", Helper: "logError", LogLevel: "Error", Approved: "yes"},
+		{ID: "LOG-0002", Line: 11, Column: 2, OriginalCall: "logError", Helper: "logError", LogLevel: "Error", NewMessage: "lookup failed", StructuredFields: "error=err"},
+	}
+	got, report := applySource(t, src, updates, &TemplateConfig{Style: "slog", LoggerVar: "logger"}, Options{RewriteHelpers: true})
+	if got != src || len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0].Error(), "uses err") {
+		t.Errorf("file changed to:\n%s\nwarnings = %v; want it untouched, with a warning that the helper uses err", got, report.Warnings)
+	}
+}
@@ -0,0 +1,287 @@
+package transformer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultJournal is the name of the file transform records applied edits
+// in for revert, next to the entries file (see JournalFor)
+const DefaultJournal = "logrefactor-journal.jsonl"
+
+// JournalFor returns the default journal for the entries in csvFile:
+// DefaultJournal in the same directory
+func JournalFor(csvFile string) string {
+	return filepath.Join(filepath.Dir(csvFile), DefaultJournal)
+}
+
+// JournalEntry records one applied edit: the code that was replaced and the
+// code written in its place, so the edit can be undone on its own later
+type JournalEntry struct {
+	ID          string    `json:"id"`
+	File        string    `json:"file"`        // Absolute path, or the name in Options.FS
+	Line        int       `json:"line"`        // Line of the original call
+	Offset      int       `json:"offset"`      // Byte offset of Replacement when it was written
+	Original    string    `json:"original"`    // Exact source text that was replaced
+	Replacement string    `json:"replacement"` // Exact source text written by transform
+	Time        time.Time `json:"time"`
+}
+
+// journal appends the edits of a transform run to a journal file
+type journal struct {
+	path  string
+	names bool       // Record file names as given, for files in an Options.FS
+	mu    sync.Mutex // Serializes appends from files transformed in parallel
+}
+
+// record appends the edits applied to content (the file before the edits)
+// to the journal. Offsets are shifted to where each replacement ends up in
+// the new content.
+func (j *journal) record(filePath string, content []byte, edits []edit) error {
+	absFile, err := filepath.Abs(filePath)
+	if err != nil || j.names {
+		absFile = filePath
+	}
+
+	sorted := append([]edit(nil), edits...)
+	sort.Slice(sorted, func(i, k int) bool { return sorted[i].start < sorted[k].start })
+
+	var entries []JournalEntry
+	now := time.Now().UTC()
+	shift, limit := 0, 0
+	for _, e := range sorted {
+		if e.start < limit || e.end > len(content) || e.start > e.end {
+			continue // Skipped by applyEdits
+		}
+		entries = append(entries, JournalEntry{
+			ID:          e.id,
+			File:        absFile,
+			Line:        e.line,
+			Offset:      e.start + shift,
+			Original:    string(content[e.start:e.end]),
+			Replacement: e.code,
+			Time:        now,
+		})
+		shift += len(e.code) - (e.end - e.start)
+		limit = e.end
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	f, err := os.OpenFile(j.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if err := encodeJournal(f, entries); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func encodeJournal(f *os.File, entries []JournalEntry) error {
+	enc := json.NewEncoder(f)
+	enc.SetEscapeHTML(false)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadJournal reads a journal file, oldest entry first
+func LoadJournal(path string) ([]JournalEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal([]byte(text), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// RevertOptions selects the journal entries to undo. With All set every
+// entry is reverted; otherwise an entry is reverted if its ID is in IDs or
+// its file is in Files.
+type RevertOptions struct {
+	IDs    []string
+	Files  []string
+	All    bool
+	DryRun bool
+}
+
+func (o RevertOptions) selects(entry JournalEntry) bool {
+	if o.All {
+		return true
+	}
+	for _, id := range o.IDs {
+		if entry.ID == id {
+			return true
+		}
+	}
+	for _, file := range o.Files {
+		if abs, err := filepath.Abs(file); err == nil && abs == entry.File {
+			return true
+		}
+	}
+	return false
+}
+
+// Revert restores the original code of the selected journal entries and
+// removes them from the journal. Each replacement is looked up at its
+// recorded offset first and then anywhere in the file, nearest match first,
+// so edits made elsewhere in the file since transform don't get in the way.
+// Entries whose replacement is no longer in the file are reported and kept.
+func Revert(journalFile string, opts RevertOptions) error {
+	entries, err := LoadJournal(journalFile)
+	if err != nil {
+		return fmt.Errorf("failed to load journal: %w", err)
+	}
+
+	byFile := make(map[string][]int)
+	var files []string
+	for i, entry := range entries {
+		if !opts.selects(entry) {
+			continue
+		}
+		if _, ok := byFile[entry.File]; !ok {
+			files = append(files, entry.File)
+		}
+		byFile[entry.File] = append(byFile[entry.File], i)
+	}
+	if len(files) == 0 {
+		fmt.Println("No journal entries to revert")
+		return nil
+	}
+	sort.Strings(files)
+
+	reverted := make(map[int]bool)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+
+		// Newest first, and back to front within a run, so the recorded
+		// offsets of the remaining entries stay valid
+		indexes := byFile[file]
+		sort.SliceStable(indexes, func(a, b int) bool {
+			ea, eb := entries[indexes[a]], entries[indexes[b]]
+			if !ea.Time.Equal(eb.Time) {
+				return ea.Time.After(eb.Time)
+			}
+			return ea.Offset > eb.Offset
+		})
+
+		count := 0
+		for _, i := range indexes {
+			entry := entries[i]
+			pos := findReplacement(content, entry)
+			if pos < 0 {
+				fmt.Fprintf(os.Stderr, "Warning: %s: replacement not found in %s; was it edited after transform?\n", entry.ID, file)
+				continue
+			}
+			updated := make([]byte, 0, len(content)-len(entry.Replacement)+len(entry.Original))
+			updated = append(updated, content[:pos]...)
+			updated = append(updated, entry.Original...)
+			updated = append(updated, content[pos+len(entry.Replacement):]...)
+			content = updated
+
+			fmt.Printf("%s:%d\n  Revert: %s\n  To:     %s\n\n", filepath.Base(file), entry.Line,
+				truncateCode(entry.Replacement, 80), truncateCode(entry.Original, 80))
+			reverted[i] = true
+			count++
+		}
+
+		if count == 0 {
+			continue
+		}
+		if opts.DryRun {
+			fmt.Printf("Would revert: %s (%d changes)\n", file, count)
+			continue
+		}
+		if err := os.WriteFile(file, content, 0644); err != nil {
+			return err
+		}
+		fmt.Printf("Reverted: %s (%d changes)\n", file, count)
+	}
+
+	if opts.DryRun || len(reverted) == 0 {
+		return nil
+	}
+
+	var kept []JournalEntry
+	for i, entry := range entries {
+		if !reverted[i] {
+			kept = append(kept, entry)
+		}
+	}
+	f, err := os.Create(journalFile)
+	if err != nil {
+		return fmt.Errorf("failed to update journal: %w", err)
+	}
+	if err := encodeJournal(f, kept); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to update journal: %w", err)
+	}
+	return f.Close()
+}
+
+// findReplacement returns the offset of an entry's replacement in content:
+// the recorded offset if it still matches, otherwise the nearest occurrence,
+// or -1 if there is none
+func findReplacement(content []byte, entry JournalEntry) int {
+	repl := entry.Replacement
+	if repl == "" {
+		return -1
+	}
+	if entry.Offset >= 0 && entry.Offset+len(repl) <= len(content) && string(content[entry.Offset:entry.Offset+len(repl)]) == repl {
+		return entry.Offset
+	}
+
+	best, bestDist := -1, 0
+	text := string(content)
+	for from := 0; ; {
+		i := strings.Index(text[from:], repl)
+		if i < 0 {
+			break
+		}
+		pos := from + i
+		dist := pos - entry.Offset
+		if dist < 0 {
+			dist = -dist
+		}
+		if best < 0 || dist < bestDist {
+			best, bestDist = pos, dist
+		}
+		from = pos + 1
+	}
+	return best
+}
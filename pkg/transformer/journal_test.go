@@ -0,0 +1,99 @@
+package transformer
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJournalRevert(t *testing.T) {
+	src := `package main
+
+import "log"
+
+func main() {
+	log.Printf("user %s logged in", user)
+	if err := run(); err != nil {
+		log.Fatalf("run failed: %v", err)
+	}
+	log.Printf("done")
+}
+`
+	tests := []struct {
+		name    string
+		reverts []RevertOptions
+		kept    []string // Entries left in the journal
+		changed []string // Code still changed in the file
+	}{
+		{"all", []RevertOptions{{All: true}}, nil, nil},
+		{"by id", []RevertOptions{{IDs: []string{"LOG-0002"}}},
+			[]string{"LOG-0001", "LOG-0003"}, []string{`logger.Info("user logged in"`, `logger.Info("done")`}},
+		{"one then the rest", []RevertOptions{{IDs: []string{"LOG-0003"}}, {All: true}}, nil, nil},
+		{"by file", []RevertOptions{{Files: []string{"main.go"}}}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			file := filepath.Join(dir, "main.go")
+			journalFile := filepath.Join(dir, DefaultJournal)
+			if err := os.WriteFile(file, []byte(src), 0644); err != nil {
+				t.Fatal(err)
+			}
+			updates := []LogUpdate{
+				{ID: "LOG-0001", FilePath: file, Line: 6, Column: 2, OriginalCall: "log.Printf", LogLevel: "Info", NewMessage: "user logged in", StructuredFields: "user=user"},
+				{ID: "LOG-0002", FilePath: file, Line: 8, Column: 3, OriginalCall: "log.Fatalf", LogLevel: "Fatal", NewMessage: "run failed", StructuredFields: "error=err"},
+				{ID: "LOG-0003", FilePath: file, Line: 10, Column: 2, OriginalCall: "log.Printf", LogLevel: "Info", MessageTemplate: `"done"`, NewMessage: "done"},
+			}
+			config := &TemplateConfig{Style: "slog", LoggerVar: "logger"}
+			config.SetOutput(io.Discard)
+			if _, err := Apply(context.Background(), updates, Options{Config: config, Journal: journalFile, OnWarning: func(err error) { t.Error(err) }}); err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			if entries, err := LoadJournal(journalFile); err != nil || len(entries) != len(updates) {
+				t.Fatalf("journal has %d entries (%v), want %d", len(entries), err, len(updates))
+			}
+
+			// RevertOptions.Files are relative to the working directory
+			wd, _ := os.Getwd()
+			if err := os.Chdir(dir); err != nil {
+				t.Fatal(err)
+			}
+			defer os.Chdir(wd)
+			for _, opts := range tt.reverts {
+				if err := Revert(journalFile, opts); err != nil {
+					t.Fatalf("Revert(%+v): %v", opts, err)
+				}
+			}
+
+			got, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.changed == nil && string(got) != src {
+				t.Errorf("reverted file:\n%s\nwant the original:\n%s", got, src)
+			}
+			for _, code := range tt.changed {
+				if !strings.Contains(string(got), code) {
+					t.Errorf("reverted file:\n%s\nwant it to keep %s", got, code)
+				}
+			}
+			if tt.changed != nil && (strings.Contains(string(got), "os.Exit") || !strings.Contains(string(got), `log.Fatalf("run failed: %v", err)`)) {
+				t.Errorf("reverted file:\n%s\nwant LOG-0002 and its os.Exit reverted", got)
+			}
+			entries, err := LoadJournal(journalFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, e := range entries {
+				ids = append(ids, e.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.kept, ",") {
+				t.Errorf("journal keeps %v, want %v", ids, tt.kept)
+			}
+		})
+	}
+}
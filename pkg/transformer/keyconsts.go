@@ -0,0 +1,147 @@
+package transformer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+
+	"logrefactor/internal/naming"
+)
+
+// keyConstants tracks the field key constants written to a shared Go file
+// such as logkeys/keys.go. Existing constants in the file are kept.
+type keyConstants struct {
+	fsys    WriteFS
+	path    string
+	pkgName string
+	names   map[string]string // key -> constant name
+	mu      sync.Mutex        // Guards names while files are transformed in parallel
+}
+
+// loadKeyConstants reads the constants already declared in path, if it exists
+// in fsys. The package name is taken from the file or, for a new file, its
+// directory.
+func loadKeyConstants(fsys WriteFS, path string) (*keyConstants, error) {
+	keys := &keyConstants{
+		fsys:    fsys,
+		path:    path,
+		pkgName: filepath.Base(filepath.Dir(path)),
+		names:   make(map[string]string),
+	}
+	if dir, err := filepath.Abs(filepath.Dir(path)); err == nil {
+		keys.pkgName = filepath.Base(dir)
+	}
+
+	content, err := fs.ReadFile(fsys, path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return keys, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	node, err := parser.ParseFile(fset, path, content, 0)
+	if err != nil {
+		return nil, err
+	}
+	keys.pkgName = node.Name.Name
+
+	for _, decl := range node.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, name := range vs.Names {
+				if i >= len(vs.Values) {
+					continue
+				}
+				lit, ok := vs.Values[i].(*ast.BasicLit)
+				if !ok || lit.Kind != token.STRING {
+					continue
+				}
+				value, err := strconv.Unquote(lit.Value)
+				if err != nil {
+					continue
+				}
+				keys.names[value] = name.Name
+			}
+		}
+	}
+
+	return keys, nil
+}
+
+// constName returns the constant for key, registering a new one if needed
+func (k *keyConstants) constName(key string) string {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if name, ok := k.names[key]; ok {
+		return name
+	}
+
+	name := "Key" + naming.GoName(key)
+	taken := make(map[string]bool, len(k.names))
+	for _, n := range k.names {
+		taken[n] = true
+	}
+	for i := 2; taken[name]; i++ {
+		name = fmt.Sprintf("Key%s%d", naming.GoName(key), i)
+	}
+
+	k.names[key] = name
+	return name
+}
+
+// assign sets KeyConst on every leaf field, recursing into groups
+func (k *keyConstants) assign(fields []FieldMapping) {
+	for i := range fields {
+		if len(fields[i].Fields) > 0 {
+			k.assign(fields[i].Fields)
+			continue
+		}
+		fields[i].KeyConst = k.pkgName + "." + k.constName(fields[i].Key)
+	}
+}
+
+// write renders all constants to the keys file
+func (k *keyConstants) write() error {
+	src, err := k.render()
+	if err != nil {
+		return err
+	}
+	return k.fsys.WriteFile(k.path, src, 0644)
+}
+
+// render returns the keys file with all constants, sorted by name
+func (k *keyConstants) render() ([]byte, error) {
+	type constant struct{ name, key string }
+	var consts []constant
+	for key, name := range k.names {
+		consts = append(consts, constant{name, key})
+	}
+	sort.Slice(consts, func(i, j int) bool { return consts[i].name < consts[j].name })
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Package %s defines the structured logging field keys used in this project.\n", k.pkgName)
+	fmt.Fprintf(&buf, "// It is maintained by logrefactor; constants added by hand are preserved.\n")
+	fmt.Fprintf(&buf, "package %s\n\n", k.pkgName)
+	buf.WriteString("const (\n")
+	for _, c := range consts {
+		fmt.Fprintf(&buf, "\t%s = %s\n", c.name, strconv.Quote(c.key))
+	}
+	buf.WriteString(")\n")
+
+	return format.Source(buf.Bytes())
+}
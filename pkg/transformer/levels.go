@@ -0,0 +1,144 @@
+package transformer
+
+import "strings"
+
+// Each style maps the collected (or levelMap-translated) level onto the
+// methods its library provides. These functions are shared by the built-in
+// generators and by the template dump, so both follow the same rules. A
+// Fatal or Panic level a library has no method for is logged as Error, and
+// transform writes the exit or panic after the call (see terminator).
+
+// slogLevel returns the slog method for a level
+func slogLevel(level string) string {
+	// slog only has Debug, Info, Warn and Error
+	levelFunc := strings.Title(strings.ToLower(level))
+	switch levelFunc {
+	case "Warning":
+		levelFunc = "Warn"
+	case "Trace":
+		levelFunc = "Debug"
+	case "Fatal", "Panic":
+		levelFunc = "Error"
+	case "Debug", "Info", "Warn", "Error":
+	default:
+		levelFunc = "Info"
+	}
+	return levelFunc
+}
+
+// zapLevel returns the zap method for a level
+func zapLevel(level string) string {
+	levelFunc := strings.Title(strings.ToLower(level))
+	switch levelFunc {
+	case "Warning":
+		levelFunc = "Warn"
+	case "Dpanic":
+		levelFunc = "DPanic"
+	}
+	return levelFunc
+}
+
+// zapSugaredLevel returns the zap SugaredLogger ("w" suffix added by the caller) method for a level
+func zapSugaredLevel(level string) string {
+	levelFunc := strings.Title(strings.ToLower(level))
+	switch levelFunc {
+	case "Warning":
+		levelFunc = "Warn"
+	case "Trace":
+		levelFunc = "Debug"
+	case "Dpanic":
+		levelFunc = "DPanic"
+	case "Debug", "Info", "Warn", "Error", "Fatal", "Panic":
+	default:
+		levelFunc = "Info"
+	}
+	return levelFunc
+}
+
+// zerologLevel returns the zerolog method for a level
+func zerologLevel(level string) string {
+	levelFunc := strings.Title(strings.ToLower(level))
+	switch levelFunc {
+	case "Warning":
+		levelFunc = "Warn"
+	case "Trace", "Debug", "Info", "Warn", "Error", "Fatal", "Panic":
+	default:
+		levelFunc = "Info"
+	}
+	return levelFunc
+}
+
+// logrusLevel returns the logrus method for a level
+func logrusLevel(level string) string {
+	levelFunc := strings.Title(strings.ToLower(level))
+	if levelFunc == "Warning" {
+		levelFunc = "Warn"
+	}
+	return levelFunc
+}
+
+// hclogLevel returns the hclog method for a level
+func hclogLevel(level string) string {
+	levelFunc := strings.Title(strings.ToLower(level))
+	switch levelFunc {
+	case "Warning":
+		levelFunc = "Warn"
+	case "Fatal", "Panic":
+		levelFunc = "Error"
+	case "Trace", "Debug", "Info", "Warn", "Error":
+	default:
+		levelFunc = "Info"
+	}
+	return levelFunc
+}
+
+// gokitLevel returns the go-kit level helper method for a level
+func gokitLevel(level string) string {
+	levelFunc := strings.Title(strings.ToLower(level))
+	switch levelFunc {
+	case "Warning":
+		levelFunc = "Warn"
+	case "Trace":
+		levelFunc = "Debug"
+	case "Fatal", "Panic":
+		levelFunc = "Error"
+	case "Debug", "Info", "Warn", "Error":
+	default:
+		levelFunc = "Info"
+	}
+	return levelFunc
+}
+
+// apexLevel returns the apex/log method for a level
+func apexLevel(level string) string {
+	levelFunc := strings.Title(strings.ToLower(level))
+	switch levelFunc {
+	case "Warning":
+		levelFunc = "Warn"
+	case "Trace":
+		levelFunc = "Debug"
+	case "Panic":
+		levelFunc = "Error"
+	case "Debug", "Info", "Warn", "Error", "Fatal":
+	default:
+		levelFunc = "Info"
+	}
+	return levelFunc
+}
+
+// log15Level returns the log15 method for a level
+func log15Level(level string) string {
+	levelFunc := strings.Title(strings.ToLower(level))
+	switch levelFunc {
+	case "Warning":
+		levelFunc = "Warn"
+	case "Trace":
+		levelFunc = "Debug"
+	case "Fatal", "Panic":
+		levelFunc = "Crit"
+	case "Debug", "Info", "Warn", "Error", "Crit":
+	default:
+		levelFunc = "Info"
+	}
+	return levelFunc
+}
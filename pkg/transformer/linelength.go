@@ -0,0 +1,131 @@
+package transformer
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"unicode/utf8"
+)
+
+// keyValueStyles pass fields as alternating "key", value arguments; when
+// wrapped, each pair stays on one line
+var keyValueStyles = map[string]bool{
+	"klog":        true,
+	"hclog":       true,
+	"gokit":       true,
+	"logr":        true,
+	"log15":       true,
+	"zap-sugared": true,
+}
+
+// wrapLongCall breaks a generated call across lines if, placed at
+// content[start:end], its line would exceed config.MaxLineLength. Continuation
+// lines are indented one tab deeper than the line holding the original call.
+func wrapLongCall(code string, content []byte, start, end int, config *TemplateConfig) string {
+	if config.MaxLineLength <= 0 || strings.Contains(code, "\n") {
+		return code
+	}
+
+	lineStart := strings.LastIndexByte(string(content[:start]), '\n') + 1
+	lineEnd := len(content)
+	if i := strings.IndexByte(string(content[end:]), '\n'); i != -1 {
+		lineEnd = end + i
+	}
+	before := string(content[lineStart:start])
+	after := string(content[end:lineEnd])
+
+	if utf8.RuneCountInString(before+code+after) <= config.MaxLineLength {
+		return code
+	}
+
+	indent := before[:len(before)-len(strings.TrimLeft(before, " \t"))]
+
+	fset := token.NewFileSet()
+	expr, err := parser.ParseExprFrom(fset, "", code, 0)
+	if err != nil {
+		return code
+	}
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return code
+	}
+
+	src := func(n ast.Node) string {
+		return code[fset.Position(n.Pos()).Offset:fset.Position(n.End()).Offset]
+	}
+
+	// Chains ending in a single-argument call (zerolog, logrus, apex) break
+	// before each method; everything else puts arguments on their own lines
+	if len(call.Args) <= 1 {
+		if links := chainLinks(call, src); len(links) > 1 {
+			return strings.Join(links, ".\n"+indent+"\t")
+		}
+	}
+	if len(call.Args) < 2 {
+		return code
+	}
+
+	var lines []string
+	args := call.Args
+	if keyValueStyles[config.Style] {
+		// Keep the leading non-pair arguments (message, and for klog/logr
+		// the error) on the first line
+		lead := len(args) % 2
+		if lead == 0 {
+			lead = 2
+		}
+		if lead > len(args) {
+			lead = len(args)
+		}
+		lines = append(lines, src(call.Fun)+"("+joinSrc(args[:lead], src)+",")
+		for i := lead; i+1 < len(args); i += 2 {
+			lines = append(lines, indent+"\t"+src(args[i])+", "+src(args[i+1])+",")
+		}
+	} else {
+		lines = append(lines, src(call.Fun)+"("+src(args[0])+",")
+		for _, arg := range args[1:] {
+			lines = append(lines, indent+"\t"+src(arg)+",")
+		}
+	}
+	lines = append(lines, indent+")")
+
+	return strings.Join(lines, "\n")
+}
+
+// chainLinks splits a method chain like a.B().C(x).D(y) into "a.B()", "C(x)",
+// "D(y)". It returns nil if call is not a chain.
+func chainLinks(call *ast.CallExpr, src func(ast.Node) string) []string {
+	var links []string
+	var expr ast.Expr = call
+	for {
+		c, ok := expr.(*ast.CallExpr)
+		if !ok {
+			break
+		}
+		sel, ok := c.Fun.(*ast.SelectorExpr)
+		if !ok {
+			break
+		}
+		inner, ok := sel.X.(*ast.CallExpr)
+		if !ok {
+			break
+		}
+		link := src(c)[len(src(inner))+1:]
+		links = append([]string{link}, links...)
+		expr = inner
+	}
+	if len(links) == 0 {
+		return nil
+	}
+	return append([]string{src(expr)}, links...)
+}
+
+// joinSrc joins the source of several expressions with ", "
+func joinSrc(exprs []ast.Expr, src func(ast.Node) string) string {
+	parts := make([]string, len(exprs))
+	for i, e := range exprs {
+		parts[i] = src(e)
+	}
+	return strings.Join(parts, ", ")
+}
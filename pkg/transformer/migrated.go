@@ -0,0 +1,39 @@
+package transformer
+
+import (
+	"go/ast"
+	"go/parser"
+	"regexp"
+	"strings"
+
+	"logrefactor/internal/coverage"
+	"logrefactor/pkg/collector"
+)
+
+// alreadyMigrated reports whether the call at an entry's position is in the
+// target style already, so a transform run again, or with a CSV older than
+// the code, leaves it alone rather than rewriting it from stale data. It is
+// when the call, old, is the code the entry would be rewritten to, newCode,
+// or when it is no longer the OriginalCall collect found there but the call
+// newCode makes, or a call of the target style on the same logger as
+// coverage counts them, such as logger.Warn for an entry now meant to log
+// at Info.
+func alreadyMigrated(call *ast.CallExpr, update LogUpdate, old, newCode string, config *TemplateConfig) bool {
+	if strings.Join(strings.Fields(old), " ") == strings.Join(strings.Fields(newCode), " ") {
+		return true
+	}
+	name := collector.CallName(call)
+	if name == "" || name == update.OriginalCall {
+		return false
+	}
+	if expr, err := parser.ParseExpr(newCode); err == nil {
+		if generated, ok := expr.(*ast.CallExpr); ok && collector.CallName(generated) == name {
+			return true
+		}
+	}
+	pattern, err := coverage.TargetPattern(config.Style, loggerFor(update, config))
+	if err != nil {
+		return false
+	}
+	return regexp.MustCompile(pattern).MatchString(name)
+}
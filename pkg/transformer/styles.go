@@ -0,0 +1,66 @@
+package transformer
+
+import "fmt"
+
+// Generator writes the calls of a style registered with RegisterStyle.
+// Generate gets the entry, the settings for its file (LoggerVar and the
+// rest), the level after levelMap, the message, and the fields after
+// renaming, key style and grouping. Each field has its Type, and its
+// KeyConst when key constants are enabled; Kind and KeyExpr give what the
+// built-in styles use.
+type Generator interface {
+	Generate(update LogUpdate, config *TemplateConfig, level, message string, fields []FieldMapping) (string, error)
+}
+
+// GeneratorFunc adapts a function to a Generator
+type GeneratorFunc func(update LogUpdate, config *TemplateConfig, level, message string, fields []FieldMapping) (string, error)
+
+func (f GeneratorFunc) Generate(update LogUpdate, config *TemplateConfig, level, message string, fields []FieldMapping) (string, error) {
+	return f(update, config, level, message, fields)
+}
+
+// builtinStyles are the styles generateStructuredLogCall handles itself
+var builtinStyles = map[string]bool{
+	"slog": true, "zap": true, "zap-sugared": true, "zerolog": true, "logrus": true,
+	"klog": true, "hclog": true, "gokit": true, "logr": true, "apex": true,
+	"log15": true, "custom": true, "exec": true, "wasm": true,
+}
+
+// styles are the styles added with RegisterStyle
+var styles = map[string]Generator{}
+
+// knownStyle reports whether name is a built-in style or one added with
+// RegisterStyle
+func knownStyle(name string) bool {
+	_, ok := styles[name]
+	return builtinStyles[name] || ok
+}
+
+// RegisterStyle makes name a style that g generates the calls of, for a
+// wrapper library or in-house logger that no built-in style fits. It is
+// meant to be called from init functions or main, before transforming, e.g.
+// by a small main package that wraps this one. Registering a name again
+// replaces its generator; registering an empty name, a built-in style or a
+// nil generator panics.
+func RegisterStyle(name string, g Generator) {
+	if name == "" || builtinStyles[name] {
+		panic(fmt.Sprintf("transformer: can't register style %q", name))
+	}
+	if g == nil {
+		panic("transformer: RegisterStyle generator is nil")
+	}
+	styles[name] = g
+}
+
+// Kind returns the field kind the built-in styles use to pick a typed
+// constructor: error, string, strings, int, int64, uint, float, bool,
+// duration, time or any
+func (f FieldMapping) Kind() string {
+	return fieldKind(f)
+}
+
+// KeyExpr returns the field key as Go source: its constant when key
+// constants are enabled, otherwise the quoted key
+func (f FieldMapping) KeyExpr() string {
+	return keyExpr(f)
+}
@@ -0,0 +1,59 @@
+package transformer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRegisterStyleLoadTemplateConfig(t *testing.T) {
+	RegisterStyle("acme", GeneratorFunc(func(u LogUpdate, c *TemplateConfig, level, message string, fields []FieldMapping) (string, error) {
+		out := fmt.Sprintf("%s.%s(%q", c.LoggerVar, level, message)
+		for _, f := range fields {
+			out += fmt.Sprintf(", acme.F(%s, %s)", f.KeyExpr(), f.Expression)
+		}
+		return out + ")", nil
+	}))
+	t.Cleanup(func() { delete(styles, "acme") })
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "acme.json")
+	if err := os.WriteFile(file, []byte(`{"style": "acme", "loggerVar": "logger"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadTemplateConfig(file, nil)
+	if err != nil {
+		t.Fatalf("LoadTemplateConfig: %v", err)
+	}
+	if config.Style != "acme" {
+		t.Fatalf("Style = %q, want acme", config.Style)
+	}
+
+	update := LogUpdate{ID: "LOG-0001", FilePath: "main.go", LogLevel: "Info", NewMessage: "synced", StructuredFields: "pod=name"}
+	got, err := Generate(update, config, false)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if want := `logger.Info("synced", acme.F("pod", name))`; got != want {
+		t.Errorf("Generate = %s, want %s", got, want)
+	}
+}
+
+func TestLoadTemplateConfigUnknownStyle(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"style.json":    `{"style": "nosuch"}`,
+		"override.json": `{"style": "slog", "overrides": [{"path": "api", "style": "nosuch"}]}`,
+	} {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LoadTemplateConfig(file, nil)
+		if err == nil || !strings.Contains(err.Error(), `unknown style`) {
+			t.Errorf("%s: err = %v, want unknown style", name, err)
+		}
+	}
+}
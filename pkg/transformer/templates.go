@@ -0,0 +1,260 @@
+package transformer
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"logrefactor/internal/naming"
+	"logrefactor/pkg/collector"
+)
+
+// templateFuncs are the helper functions available to custom templates
+var templateFuncs = template.FuncMap{
+	// kind returns the field kind the built-in styles use to pick a typed
+	// constructor: error, string, strings, int, int64, uint, float, bool,
+	// duration, time or any
+	"kind": fieldKind,
+	// key returns the quoted field key, or its constant with -key-constants
+	"key": keyExpr,
+	// errorExpr returns the expression of the first error field, or "nil"
+	"errorExpr": func(fields []FieldMapping) string {
+		errExpr, _ := splitErrorField(fields)
+		return errExpr
+	},
+	// withoutError returns the fields without the one errorExpr picked
+	"withoutError": func(fields []FieldMapping) []FieldMapping {
+		_, rest := splitErrorField(fields)
+		return rest
+	},
+	// snakecase and camelcase convert a key, e.g. {{snakecase .Key}}
+	"snakecase": func(s string) string { return naming.Convert(s, naming.SnakeCase) },
+	"camelcase": func(s string) string { return naming.Convert(s, naming.CamelCase) },
+	// quote returns s as a Go string literal
+	"quote": strconv.Quote,
+	// title capitalizes a level name the way the built-in styles do: "WARN" -> "Warn"
+	"title": func(s string) string { return strings.Title(strings.ToLower(s)) },
+	// zapField renders a field as the zap.Field constructor the zap style uses
+	"zapField": zapField,
+	// levelMap returns the method a built-in style uses for a level, e.g.
+	// {{levelMap "slog" .Level}} is "Error" for Fatal
+	"levelMap": func(style, level string) (string, error) {
+		levelFunc, ok := styleLevels[style]
+		if !ok {
+			return "", fmt.Errorf("levelMap: no level table for style %q", style)
+		}
+		return levelFunc(level), nil
+	},
+	// joinFields renders every field with format and joins them with sep.
+	// format may use {key} (quoted key or constant), {name} (bare key),
+	// {expr}, {type}, {kind} and {verb}.
+	"joinFields": func(fields []FieldMapping, format, sep string) string {
+		parts := make([]string, len(fields))
+		for i, field := range fields {
+			parts[i] = strings.NewReplacer(
+				"{key}", keyExpr(field),
+				"{name}", field.Key,
+				"{expr}", field.Expression,
+				"{type}", field.Type,
+				"{kind}", fieldKind(field),
+				"{verb}", field.FormatVerb,
+			).Replace(format)
+		}
+		return strings.Join(parts, sep)
+	},
+}
+
+// styleLevels are the level tables of the styles that map a level straight
+// to a method name
+var styleLevels = map[string]func(string) string{
+	"slog":        slogLevel,
+	"zap":         zapLevel,
+	"zap-sugared": zapSugaredLevel,
+	"zerolog":     zerologLevel,
+	"logrus":      logrusLevel,
+	"hclog":       hclogLevel,
+	"gokit":       gokitLevel,
+	"apex":        apexLevel,
+	"log15":       log15Level,
+}
+
+// templateLevels are the levels a built-in template distinguishes
+var templateLevels = []string{"Trace", "Debug", "Info", "Warn", "Warning", "Error", "Fatal", "Panic"}
+
+// fieldKinds are the values returned by fieldKind
+var fieldKinds = []string{"error", "string", "strings", "int", "int64", "uint", "float", "bool", "duration", "time", "any"}
+
+// BuiltinTemplate returns a custom-style template that reproduces a built-in
+// style, for use as a starting point with "style": "custom". The level and
+// field constructor tables are generated from the rules the built-in
+// generator uses. Grouped keys (groupKeys), zerolog's Msgf and slog's
+// errorKey are handled before or outside the generator and are not part of
+// the template.
+func BuiltinTemplate(style string) (string, error) {
+	// Key/value arguments shared by several styles
+	kv := `{{range .Fields}}, {{key .}}, {{.Expression}}{{end}}`
+
+	switch style {
+	case "slog":
+		return `{{.Logger}}.` + levelChain(slogLevel) + `("{{.Message}}"` +
+			`{{range .Fields}}, slog.` + kindChain(getSlogAttrFunc) + `({{key .}}, {{.Expression}}){{end}})`, nil
+	case "zap":
+		return `{{.Logger}}.` + levelChain(zapLevel) + `("{{.Message}}"` +
+			`{{range .Fields}}, zap.` + kindChain(getZapFieldFunc) + `({{key .}}, {{.Expression}}){{end}})`, nil
+	case "zap-sugared":
+		return `{{.Logger}}.` + levelChain(zapSugaredLevel) + `w("{{.Message}}"` + kv + `)`, nil
+	case "zerolog":
+		return `{{.Logger}}.` + levelChain(zerologLevel) + `()` +
+			`{{$hasErr := false}}{{range .Fields}}` +
+			`{{if and (eq (kind .) "error") (not $hasErr)}}.Err({{.Expression}}){{$hasErr = true}}` +
+			`{{else if eq (kind .) "error"}}.AnErr({{key .}}, {{.Expression}})` +
+			`{{else}}.` + kindChain(getZerologFieldFunc) + `({{key .}}, {{.Expression}}){{end}}{{end}}` +
+			`.Msg("{{.Message}}")`, nil
+	case "logrus":
+		return `{{.Logger}}.{{if .Fields}}WithFields({{.Logger}}.Fields{` + fieldsMap(".Fields") + `}).{{end}}` +
+			levelChain(logrusLevel) + `("{{.Message}}")`, nil
+	case "klog":
+		return `{{if or (eq .Level "Error") (eq .Level "Fatal") (eq .Level "Panic")}}` +
+			`{{.Logger}}.ErrorS({{errorExpr .Fields}}, "{{.Message}}"{{range withoutError .Fields}}, {{key .}}, {{.Expression}}{{end}})` +
+			`{{else}}{{.Logger}}.` + verbosityChain(collector.KlogVerbosity) + `InfoS("{{.Message}}"` + kv + `){{end}}`, nil
+	case "hclog":
+		return `{{.Logger}}.` + levelChain(hclogLevel) + `("{{.Message}}"` + kv + `)`, nil
+	case "gokit":
+		return `level.` + levelChain(gokitLevel) + `({{.Logger}}).Log("msg", "{{.Message}}"` + kv + `)`, nil
+	case "logr":
+		return `{{if or (eq .Level "Error") (eq .Level "Fatal") (eq .Level "Panic")}}` +
+			`{{.Logger}}.Error({{errorExpr .Fields}}, "{{.Message}}"{{range withoutError .Fields}}, {{key .}}, {{.Expression}}{{end}})` +
+			`{{else}}{{.Logger}}.` + verbosityChain(collector.LogrVerbosity) + `Info("{{.Message}}"` + kv + `){{end}}`, nil
+	case "apex":
+		return `{{.Logger}}{{with errorExpr .Fields}}{{if ne . "nil"}}.WithError({{.}}){{end}}{{end}}` +
+			`{{with withoutError .Fields}}.WithFields({{$.Logger}}.Fields{` + fieldsMap(".") + `}){{end}}` +
+			`.` + levelChain(apexLevel) + `("{{.Message}}")`, nil
+	case "log15":
+		return `{{.Logger}}.` + levelChain(log15Level) + `("{{.Message}}"` + kv + `)`, nil
+	case "custom":
+		return "", fmt.Errorf("custom has no built-in template")
+	default:
+		return "", fmt.Errorf("unknown style: %s", style)
+	}
+}
+
+// levelChain renders a style's level mapping as a template if/else chain on
+// .Level. Levels the style passes through unchanged are left to the final
+// else, which is either .Level itself or the style's fallback method.
+func levelChain(levelFunc func(string) string) string {
+	const other = "Other"
+	fallback := "{{.Level}}"
+	passThrough := levelFunc(other) == other
+	if !passThrough {
+		fallback = levelFunc(other)
+	}
+
+	cases := make(map[string][]string)
+	var order []string
+	for _, level := range templateLevels {
+		method := levelFunc(level)
+		if (passThrough && method == level) || (!passThrough && method == fallback) {
+			continue
+		}
+		if _, ok := cases[method]; !ok {
+			order = append(order, method)
+		}
+		cases[method] = append(cases[method], level)
+	}
+	if len(order) == 0 {
+		return fallback
+	}
+
+	var b strings.Builder
+	for i, method := range order {
+		if i == 0 {
+			b.WriteString("{{if ")
+		} else {
+			b.WriteString("{{else if ")
+		}
+		b.WriteString(anyEq(".Level", cases[method]))
+		b.WriteString("}}" + method)
+	}
+	b.WriteString("{{else}}" + fallback + "{{end}}")
+	return b.String()
+}
+
+// kindChain renders a field kind to constructor mapping as a template
+// if/else chain on the kind of the current field
+func kindChain(constructor func(string) string) string {
+	fallback := constructor("any")
+
+	cases := make(map[string][]string)
+	var order []string
+	for _, kind := range fieldKinds {
+		name := constructor(kind)
+		if name == fallback {
+			continue
+		}
+		if _, ok := cases[name]; !ok {
+			order = append(order, name)
+		}
+		cases[name] = append(cases[name], kind)
+	}
+
+	var b strings.Builder
+	for i, name := range order {
+		if i == 0 {
+			b.WriteString("{{if ")
+		} else {
+			b.WriteString("{{else if ")
+		}
+		b.WriteString(anyEq("(kind .)", cases[name]))
+		b.WriteString("}}" + name)
+	}
+	b.WriteString("{{else}}" + fallback + "{{end}}")
+	return b.String()
+}
+
+// verbosityChain renders klog's and logr's V(n) selection for the non-error
+// levels
+func verbosityChain(verbosity map[string]int) string {
+	levels := make([]string, 0, len(verbosity))
+	for level, v := range verbosity {
+		if v > 0 {
+			levels = append(levels, level)
+		}
+	}
+	if len(levels) == 0 {
+		return ""
+	}
+	sort.Strings(levels)
+
+	var b strings.Builder
+	for i, level := range levels {
+		if i == 0 {
+			b.WriteString("{{if ")
+		} else {
+			b.WriteString("{{else if ")
+		}
+		b.WriteString(fmt.Sprintf(`eq .Level %q}}V(%d).`, level, verbosity[level]))
+	}
+	b.WriteString("{{end}}")
+	return b.String()
+}
+
+// fieldsMap renders the fields at dot as "key": value pairs of a map literal.
+// It follows an opening brace, so the action trims the space that keeps "{{{"
+// from being read as a delimiter.
+func fieldsMap(dot string) string {
+	return ` {{- range $i, $f := ` + dot + `}}{{if $i}}, {{end}}{{key $f}}: {{$f.Expression}}{{end}}`
+}
+
+// anyEq renders a template condition that is true when expr equals any value
+func anyEq(expr string, values []string) string {
+	if len(values) == 1 {
+		return fmt.Sprintf("eq %s %q", expr, values[0])
+	}
+	conds := make([]string, len(values))
+	for i, v := range values {
+		conds[i] = fmt.Sprintf("(eq %s %q)", expr, v)
+	}
+	return "or " + strings.Join(conds, " ")
+}
@@ -0,0 +1,105 @@
+package transformer
+
+import (
+	"fmt"
+	"go/ast"
+	"strconv"
+	"strings"
+)
+
+// terminatingStyles are the built-in styles without a method that exits or
+// panics for the levels listed, which log those levels as Error (Crit for
+// log15): transform writes the exit or panic after the call instead
+var terminatingStyles = map[string]map[string]bool{
+	"slog":  {"Fatal": true, "Panic": true},
+	"klog":  {"Fatal": true, "Panic": true},
+	"hclog": {"Fatal": true, "Panic": true},
+	"gokit": {"Fatal": true, "Panic": true},
+	"logr":  {"Fatal": true, "Panic": true},
+	"log15": {"Fatal": true, "Panic": true},
+	"apex":  {"Panic": true},
+}
+
+// terminator returns the statement that keeps a Fatal or Panic entry
+// exiting or panicking once its call is logged at a level that doesn't:
+// os.Exit(1) (klog.FlushAndExit for klog, so buffered logs are written)
+// or panic with the message. It is "" for other levels, for styles whose
+// method does it (zap, zerolog, logrus, Fatal for apex) and for the
+// styles transform doesn't write, which get the level as it is. DPanic,
+// which only panics in development, doesn't get one either.
+func terminator(update LogUpdate, config *TemplateConfig) (string, error) {
+	level := strings.Title(strings.ToLower(mapLevel(update, config.LevelMap)))
+	if !terminatingStyles[config.Style][level] || strings.HasPrefix(lastSegment(update.OriginalCall), "DPanic") {
+		return "", nil
+	}
+	if level == "Panic" {
+		message, err := entryMessage(update)
+		if err != nil {
+			return "", err
+		}
+		return "panic(" + strconv.Quote(message) + ")", nil
+	}
+	if config.Style == "klog" {
+		return "klog.FlushAndExit(klog.ExitFlushTimeout, 1)", nil
+	}
+	return "os.Exit(1)", nil
+}
+
+// terminate returns code followed by the terminator, on a line of its own
+// indented as the call's line, for the call at path[0] (as
+// astutil.PathEnclosingInterval returns it). The call must be a statement
+// of its own for there to be room for the terminator.
+func terminate(code, stmt string, path []ast.Node, content []byte, start int) (string, error) {
+	if len(path) < 2 {
+		return "", fmt.Errorf("the call isn't a statement of its own, so %s can't follow it", stmt)
+	}
+	if expr, ok := path[1].(*ast.ExprStmt); !ok || expr.X != path[0] {
+		return "", fmt.Errorf("the call isn't a statement of its own, so %s can't follow it", stmt)
+	}
+	line := string(content[strings.LastIndexByte(string(content[:start]), '\n')+1 : start])
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	return code + "\n" + indent + stmt, nil
+}
+
+// exitFollows reports whether the statement after the call at path[0] (as
+// astutil.PathEnclosingInterval returns it) is os.Exit(...) or panic(...),
+// which already ends the program as the source meant it to
+func exitFollows(path []ast.Node) bool {
+	if len(path) < 3 {
+		return false
+	}
+	stmt, ok := path[1].(*ast.ExprStmt)
+	if !ok || stmt.X != path[0] {
+		return false
+	}
+	var list []ast.Stmt
+	switch parent := path[2].(type) {
+	case *ast.BlockStmt:
+		list = parent.List
+	case *ast.CaseClause:
+		list = parent.Body
+	case *ast.CommClause:
+		list = parent.Body
+	}
+	for i, s := range list {
+		if s != ast.Stmt(stmt) || i+1 == len(list) {
+			continue
+		}
+		next, ok := list[i+1].(*ast.ExprStmt)
+		if !ok {
+			return false
+		}
+		call, ok := next.X.(*ast.CallExpr)
+		if !ok {
+			return false
+		}
+		switch fun := call.Fun.(type) {
+		case *ast.Ident:
+			return fun.Name == "panic"
+		case *ast.SelectorExpr:
+			pkg, ok := fun.X.(*ast.Ident)
+			return ok && pkg.Name == "os" && fun.Sel.Name == "Exit"
+		}
+	}
+	return false
+}
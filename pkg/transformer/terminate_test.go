@@ -0,0 +1,120 @@
+package transformer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// applySource writes src to main.go in a temporary directory, applies the
+// updates to it with config and returns the file afterwards and the report
+func applySource(t *testing.T, src string, updates []LogUpdate, config *TemplateConfig, opts Options) (string, Report) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for i := range updates {
+		updates[i].FilePath = "main.go"
+	}
+	opts.Config = config
+	opts.FS = DirFS(dir)
+	opts.OnWarning = func(error) {}
+	report, err := Apply(context.Background(), updates, opts)
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	out, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out), report
+}
+
+func TestTerminator(t *testing.T) {
+	tests := []struct {
+		style, level, call string
+		want               string
+	}{
+		{"slog", "Fatal", "log.Fatalf", "os.Exit(1)"},
+		{"slog", "Panic", "log.Panicf", `panic("failed")`},
+		{"hclog", "Fatal", "log.Fatal", "os.Exit(1)"},
+		{"gokit", "Panic", "log.Panic", `panic("failed")`},
+		{"log15", "Fatal", "log.Fatal", "os.Exit(1)"},
+		{"logr", "Fatal", "log.Fatal", "os.Exit(1)"},
+		{"klog", "Fatal", "klog.Fatalf", "klog.FlushAndExit(klog.ExitFlushTimeout, 1)"},
+		{"apex", "Panic", "log.Panic", `panic("failed")`},
+		{"apex", "Fatal", "log.Fatal", ""},
+		{"zap", "Fatal", "log.Fatal", ""},
+		{"zerolog", "Panic", "log.Panic", ""},
+		{"logrus", "Fatal", "log.Fatal", ""},
+		{"slog", "Error", "log.Printf", ""},
+		{"slog", "Panic", "sugar.DPanicw", ""},
+		{"custom", "Fatal", "log.Fatal", ""},
+	}
+	for _, tt := range tests {
+		update := LogUpdate{LogLevel: tt.level, OriginalCall: tt.call, NewMessage: "failed"}
+		got, err := terminator(update, &TemplateConfig{Style: tt.style})
+		if err != nil || got != tt.want {
+			t.Errorf("terminator(%s, %s, %s) = %q, %v; want %q", tt.style, tt.level, tt.call, got, err, tt.want)
+		}
+	}
+}
+
+func TestTransformKeepsFatal(t *testing.T) {
+	src := `package main
+
+import "log"
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatalf("startup failed: %v", err)
+	}
+	defer log.Fatal("deferred")
+}
+`
+	updates := []LogUpdate{
+		{ID: "LOG-0001", Line: 7, Column: 3, OriginalCall: "log.Fatalf", LogLevel: "Fatal", NewMessage: "startup failed", StructuredFields: "error=err"},
+		{ID: "LOG-0002", Line: 9, Column: 8, OriginalCall: "log.Fatal", LogLevel: "Fatal", NewMessage: "deferred"},
+	}
+	got, report := applySource(t, src, updates, &TemplateConfig{Style: "slog", LoggerVar: "logger"}, Options{})
+
+	want := `		logger.Error("startup failed", slog.Any("error", err))
+		os.Exit(1)
+	}
+	defer log.Fatal("deferred")`
+	if !strings.Contains(got, want) {
+		t.Errorf("transformed file:\n%s\nwant it to contain:\n%s", got, want)
+	}
+	if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0].Error(), "LOG-0002") {
+		t.Errorf("warnings = %v, want one for LOG-0002", report.Warnings)
+	}
+}
+
+func TestTransformKeepsFollowingExit(t *testing.T) {
+	src := `package main
+
+import (
+	"log"
+	"os"
+)
+
+func main() {
+	log.Printf("bad config")
+	os.Exit(2)
+}
+`
+	updates := []LogUpdate{
+		{ID: "LOG-0001", Line: 9, Column: 2, OriginalCall: "log.Printf", LogLevel: "Info", SuggestedLevel: "Fatal", LevelConfidence: "high", Approved: "yes", NewMessage: "bad config"},
+	}
+	got, _ := applySource(t, src, updates, &TemplateConfig{Style: "slog", LoggerVar: "logger"}, Options{})
+
+	want := `	logger.Error("bad config")
+	os.Exit(2)
+}`
+	if !strings.Contains(got, want) {
+		t.Errorf("transformed file:\n%s\nwant it to contain:\n%s", got, want)
+	}
+}
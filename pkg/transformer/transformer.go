@@ -0,0 +1,2429 @@
+// Package transformer rewrites the log calls described by edited entries
+// (LogUpdate) into structured calls for the configured logging library.
+// Transform applies the entries of a CSV file; Apply applies entries held
+// in memory.
+package transformer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"golang.org/x/tools/go/ast/astutil"
+
+	"logrefactor/internal/lint"
+	"logrefactor/internal/naming"
+	"logrefactor/internal/normalize"
+	"logrefactor/internal/plugin"
+	"logrefactor/internal/schema"
+	"logrefactor/internal/table"
+	"logrefactor/pkg/codec"
+	"logrefactor/pkg/collector"
+)
+
+// LogUpdate represents an update to apply
+type LogUpdate struct {
+	ID               string
+	FilePath         string
+	Line             int
+	Column           int
+	OriginalCall     string
+	SourceLibrary    string // Logging library of the call, as collect found it
+	Receiver         string // Logger the call is made on, used instead of LoggerVar when set
+	InLoop           string // for, range or hot when the call runs in a loop or on a hot path
+	Returns          string // Error the function returns right after logging it (see Options.LogAndReturn)
+	Helper           string // Logging helper the call is to or inside (see Options.RewriteHelpers)
+	Package          string
+	LogLevel         string
+	SuggestedLevel   string // Level collect inferred from the call site, used instead of LogLevel once approved (see suggestedLevel)
+	LevelConfidence  string // How sure collect is of SuggestedLevel: high, medium or low
+	MessageTemplate  string
+	SuggestedMessage string // MessageTemplate with the style rules applied, used when NewMessage is empty
+	ClusterID        string // Cluster of entries with alike messages (see cluster.Clusters)
+	ArgumentDetails  string
+	FieldConfidence  string // How likely the fields auto-map derives from ArgumentDetails are right, 0 to 1
+	NewCall          string
+	NewMessage       string
+	StructuredFields string
+	Status           string // Review status, see the Status* constants
+	Approved         string // Who approved the entry, or yes/true
+	Applied          string // When transform applied the entry, if the entries are in a database
+
+	chained []FieldMapping // Fields the call sets on its logger (see chainedFields)
+}
+
+// Review statuses for the Status column. Entries marked rejected or skip are
+// never applied, nor are those marked manual-review until approved; with
+// -only-approved only approved entries are.
+const (
+	StatusTodo         = "todo"
+	StatusReview       = "review"
+	StatusApproved     = "approved"
+	StatusRejected     = "rejected"
+	StatusSkip         = "skip"
+	StatusManualReview = collector.ManualReview // Set by collect when the message isn't a constant
+)
+
+// statuses are the recognized Status values
+var statuses = map[string]bool{
+	"": true, StatusTodo: true, StatusReview: true, StatusApproved: true, StatusRejected: true, StatusSkip: true,
+	StatusManualReview: true,
+}
+
+// edited reports whether the entry asks for a change: NewMessage or NewCall
+// is filled in and differs from the original
+func (u LogUpdate) edited() bool {
+	if u.NewMessage == "" && u.NewCall == "" {
+		return false
+	}
+	return u.NewMessage != u.MessageTemplate || u.NewCall != u.OriginalCall
+}
+
+// held reports whether review keeps the entry from being applied: it is
+// rejected or skipped, or awaits manual review and isn't approved
+func (u LogUpdate) held() bool {
+	status := strings.ToLower(strings.TrimSpace(u.Status))
+	return status == StatusRejected || status == StatusSkip || status == StatusManualReview && !u.approved()
+}
+
+// confident reports whether the entry's fields are trusted enough to apply
+// without review: they aren't derived from ArgumentDetails, or its
+// FieldConfidence is at least min. Entries without a score (older CSVs)
+// aren't trusted.
+func (u LogUpdate) confident(autoMap bool, min float64) bool {
+	if min <= 0 || !autoMap || u.StructuredFields != "" || u.ArgumentDetails == "" {
+		return true
+	}
+	score, err := strconv.ParseFloat(strings.TrimSpace(u.FieldConfidence), 64)
+	return err == nil && score >= min
+}
+
+// Unmapped returns the expressions of the arguments auto-map can't turn
+// into fields as they are: those a printf format has no verb for, which
+// the call only logs as %!(EXTRA ...), structs printed whole (see
+// collector.Struct), whose fields belong in fields of their own, and zap
+// fields, slog attributes and key/value pairs collect couldn't read (see
+// collector.ZapField, collector.SlogAttr and collector.Unpaired)
+func (u LogUpdate) Unmapped() []string {
+	args := autoGenerateFieldsFromArguments(u.ArgumentDetails)
+	printf := u.printf()
+	var unmapped []string
+	for _, arg := range args {
+		if arg.Type == collector.Struct || arg.Type == collector.ZapField || arg.Type == collector.SlogAttr || arg.Type == collector.Unpaired || printf && arg.FormatVerb == "" {
+			unmapped = append(unmapped, arg.Expression)
+		}
+	}
+	return unmapped
+}
+
+// printf reports whether the entry's message is a printf format: a string
+// literal of a call whose name ends in "f", or one with verbs in it
+func (u LogUpdate) printf() bool {
+	if _, err := strconv.Unquote(u.MessageTemplate); err != nil {
+		return false
+	}
+	return strings.HasSuffix(u.OriginalCall, "f") || len(formatVerbPattern.FindAllString(u.MessageTemplate, -1)) > 0
+}
+
+// missing reports whether the entry's printf format reads more arguments
+// than the call passes, or ends in a bare %, as the MISMATCH note collect
+// writes says: the call logs %!d(MISSING) for the verbs nothing fills, so
+// the fields auto-map derives can't stand for what it logged
+func (u LogUpdate) missing() bool {
+	if !u.printf() {
+		return false
+	}
+	format, _ := strconv.Unquote(u.MessageTemplate)
+	want, ok := normalize.ArgCount(format)
+	return ok && (want < 0 || want > len(autoGenerateFieldsFromArguments(u.ArgumentDetails)))
+}
+
+// mapped reports whether transform can write the entry's fields: they
+// aren't derived from ArgumentDetails, or no argument is Unmapped and the
+// format isn't missing any
+func (u LogUpdate) mapped(autoMap bool) bool {
+	return !autoMap || u.StructuredFields != "" || len(u.Unmapped()) == 0 && !u.missing()
+}
+
+// Issues returns the fields transform would write for the entry (see
+// Fields) that look like sensitive data, such as passwords or e-mail
+// addresses (see lint.Field)
+func (u LogUpdate) Issues(autoMap bool) []lint.Issue {
+	var issues []lint.Issue
+	var check func(fields []FieldMapping)
+	check = func(fields []FieldMapping) {
+		for _, f := range fields {
+			if issue, ok := lint.Field(f.Key, f.Expression, f.Type); ok {
+				issues = append(issues, issue)
+			}
+			check(f.Fields)
+		}
+	}
+	check(u.Fields(autoMap))
+	return issues
+}
+
+// KeyUses returns the keys of the fields transform would write for the
+// entry (see Fields), with the keys of groups in front ("user.id"), to
+// find the keys logged under several names or types (see lint.Collisions)
+func (u LogUpdate) KeyUses(autoMap bool) []lint.KeyUse {
+	var uses []lint.KeyUse
+	var walk func(prefix string, fields []FieldMapping)
+	walk = func(prefix string, fields []FieldMapping) {
+		for _, f := range fields {
+			if len(f.Fields) > 0 {
+				walk(prefix+f.Key+".", f.Fields)
+				continue
+			}
+			uses = append(uses, lint.KeyUse{
+				Key:        prefix + f.Key,
+				Type:       f.Type,
+				Expression: f.Expression,
+				Package:    u.Package,
+				ID:         u.ID,
+				Location:   fmt.Sprintf("%s:%d", u.FilePath, u.Line),
+			})
+		}
+	}
+	walk("", u.Fields(autoMap))
+	return uses
+}
+
+// Interpolated returns the arguments of an entry still to be migrated that
+// a format verb puts into the message text and that look like
+// high-cardinality values, such as IDs or URLs (see lint.Interpolated).
+// Edited and applied entries get a constant message, so they have none.
+func (u LogUpdate) Interpolated() []lint.Issue {
+	if u.edited() || u.Applied != "" {
+		return nil
+	}
+	var issues []lint.Issue
+	for _, arg := range autoGenerateFieldsFromArguments(u.ArgumentDetails) {
+		if arg.FormatVerb == "" {
+			continue
+		}
+		if issue, ok := lint.Interpolated(arg.Key, arg.Expression, arg.Type); ok {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// Style returns the message style rules the entry breaks: NewMessage's
+// when it is filled in, otherwise MessageTemplate's for an entry still to
+// be migrated (see lint.Template)
+func (u LogUpdate) Style(rules []string) []lint.Issue {
+	if u.Applied != "" {
+		return nil
+	}
+	if u.NewMessage != "" {
+		return lint.Message(lint.Unescape(strings.Trim(u.NewMessage, `"'`+"`")), rules)
+	}
+	if u.edited() {
+		return nil
+	}
+	issues, _ := lint.Template(u.MessageTemplate, rules)
+	return issues
+}
+
+// Lint returns every issue of the entry: its sensitive fields (see
+// Issues), high-cardinality values in its message (see Interpolated),
+// the message style rules it breaks (see Style) and, if it runs in a loop
+// or on a hot path, that
+func (u LogUpdate) Lint(autoMap bool, rules []string) []lint.Issue {
+	issues := append(u.Issues(autoMap), u.Interpolated()...)
+	issues = append(issues, u.Style(rules)...)
+	level := u.LogLevel
+	if suggested := u.suggestedLevel(); suggested != "" {
+		level = suggested
+	}
+	if issue, ok := lint.Hot(u.OriginalCall, u.InLoop, level); ok {
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// sensitive reports whether a field transform would write for the entry
+// still looks like a credential, so the leak isn't carried over into the
+// structured call. Reviewers drop or redact the field in StructuredFields
+// to release the entry.
+func (u LogUpdate) sensitive(autoMap bool) bool {
+	return lint.Severity(u.Issues(autoMap)) == lint.High
+}
+
+// approved reports whether the entry is approved: Status is "approved" or
+// Approved names a reviewer (anything but no/false/0)
+func (u LogUpdate) approved() bool {
+	if strings.EqualFold(strings.TrimSpace(u.Status), StatusApproved) {
+		return true
+	}
+	switch strings.ToLower(strings.TrimSpace(u.Approved)) {
+	case "", "no", "n", "false", "0":
+		return false
+	}
+	return true
+}
+
+// suggestedLevel returns the SuggestedLevel transform uses instead of
+// LogLevel: the one on an approved entry, whose reviewer kept it, however
+// confident collect is. It is "" for the others, which keep LogLevel until
+// a reviewer approves or clears them.
+func (u LogUpdate) suggestedLevel() string {
+	if u.approved() {
+		return u.SuggestedLevel
+	}
+	return ""
+}
+
+// Fields returns the entry's structured fields as transform reads them: from
+// StructuredFields (JSON or key=value), or with autoMap from ArgumentDetails
+// when StructuredFields is empty, after the fields a logrus or apex/log call
+// sets with WithField and the like. Keys are as written, before key style
+// and renames are applied.
+func (u LogUpdate) Fields(autoMap bool) []FieldMapping {
+	var fields []FieldMapping
+	if u.StructuredFields != "" {
+		if err := json.Unmarshal([]byte(u.StructuredFields), &fields); err != nil {
+			// Try parsing as simple key=value format
+			fields = parseSimpleFields(u.StructuredFields)
+		}
+	} else if autoMap && u.ArgumentDetails != "" {
+		// Auto-generate field mappings from ArgumentDetails if StructuredFields is empty
+		fields = autoGenerateFieldsFromArguments(u.ArgumentDetails)
+	}
+
+	// Hand-written fields don't carry type information; borrow it from the
+	// collected argument with the same expression.
+	if u.StructuredFields != "" && u.ArgumentDetails != "" {
+		fields = enrichFieldsFromArguments(fields, autoGenerateFieldsFromArguments(u.ArgumentDetails))
+	}
+	if len(u.chained) > 0 {
+		fields = withChained(u.chained, fields)
+	}
+	return fields
+}
+
+// FieldMapping represents a structured logging field
+type FieldMapping struct {
+	Key        string `json:"key"`
+	Expression string `json:"expression"`
+	Type       string `json:"type"`
+	FormatVerb string `json:"formatVerb,omitempty"`
+	// Fields makes this mapping a group (slog.Group, zerolog Dict, zap namespace)
+	// named Key; Expression and Type are unused for groups
+	Fields []FieldMapping `json:"fields,omitempty"`
+	// KeyConst is the key constant to reference instead of a string literal
+	// when key constants are enabled (e.g. "logkeys.KeyUserID")
+	KeyConst string `json:"-"`
+}
+
+// TemplateConfig defines how to generate structured logging calls
+type TemplateConfig struct {
+	Style           string            `json:"style" yaml:"style"`                     // "slog", "zap", "zap-sugared", "zerolog", "logrus", "klog", "hclog", "gokit", "logr", "apex", "log15", "custom", "exec", "wasm", or one added with RegisterStyle
+	LoggerVar       string            `json:"loggerVar" yaml:"loggerVar"`             // Variable name for logger (e.g., "log", "logger")
+	Template        string            `json:"template" yaml:"template"`               // Custom template if style is "custom"
+	Command         []string          `json:"command" yaml:"command"`                 // Generator program and arguments if style is "exec"
+	Plugin          string            `json:"plugin" yaml:"plugin"`                   // WASM generator module if style is "wasm"
+	Verbosity       map[string]int    `json:"verbosity" yaml:"verbosity"`             // klog and logr: V(n) verbosity per level, e.g. {"Debug": 1, "Trace": 2}; collect reads V(n) calls with it too
+	ErrorKey        string            `json:"errorKey" yaml:"errorKey"`               // Key used for error fields (slog); defaults to the field's own key
+	MillisecondInts string            `json:"millisecondInts" yaml:"millisecondInts"` // How to emit %dms integers: "int" (default) or "duration"
+	GroupKeys       bool              `json:"groupKeys" yaml:"groupKeys"`             // Nest dotted keys like "http.method" into groups (slog, zap, zerolog)
+	KeyStyle        string            `json:"keyStyle" yaml:"keyStyle"`               // Field key convention: "snake_case", "camelCase", "kebab-case", "SCREAMING"; empty keeps keys as written
+	KeyRenames      map[string]string `json:"keyRenames" yaml:"keyRenames"`           // Keys to rename, e.g. {"err": "error", "uid": "user_id"}
+	ForbiddenKeys   []string          `json:"forbiddenKeys" yaml:"forbiddenKeys"`     // Keys that produce a warning when generated
+	LevelMap        []LevelRule       `json:"levelMap" yaml:"levelMap"`               // Source level/function -> target level rules, first match wins
+	MaxLineLength   int               `json:"maxLineLength" yaml:"maxLineLength"`     // Break generated calls across lines when the line would be longer (0 = never)
+	Desugar         bool              `json:"desugar" yaml:"desugar"`                 // zap: write calls made on a SugaredLogger, such as sugar.Infow, on its typed logger rather than loggerVar
+	DesugaredLogger string            `json:"desugaredLogger" yaml:"desugaredLogger"` // zap with desugar: typed *zap.Logger variable for SugaredLoggers whose typed logger the call doesn't show; defaults to loggerVar
+
+	Overrides []PathOverride `json:"overrides" yaml:"overrides"` // Per-directory settings, most specific path wins
+
+	keys     *keyConstants                      // Set by Transform when key constants are enabled
+	journal  *journal                           // Set by Transform when applied edits are journaled
+	fsys     WriteFS                            // Set from Options.FS
+	changes  func(Change)                       // Set by OnChange
+	warning  func(error)                        // Set from Options.OnWarning
+	files    func(path string, old, new []byte) // Set by OnFile
+	migrated func(id string)                    // Set by Transform for the entries already migrated
+	out      io.Writer                          // Set by SetOutput
+	jobs     int                                // Set by SetJobs
+	ctx      context.Context                    // Set by apply, for the calls to Plugin
+	plugin   *plugin.Plugin                     // Set by apply when a style is wasm, and closed when the run ends
+
+	wrapReturns bool           // Set from Options.LogAndReturn
+	helpers     helperRewrites // Set by apply from Options.RewriteHelpers, once it knows which it can rewrite
+}
+
+// Change is a replacement transform made, or would make in a dry run. Start
+// and End are the byte offsets of Old in the file before the change.
+type Change struct {
+	ID     string `json:"id"`
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+	Old    string `json:"old"`
+	New    string `json:"new"`
+}
+
+// OnChange makes Transform call fn for every replacement it makes
+func (c *TemplateConfig) OnChange(fn func(Change)) {
+	c.changes = fn
+}
+
+// OnFile makes Transform call fn with the content of every file it changes,
+// before and after the change; old is nil for a new file. fn is called in
+// dry runs too, which is how a patch is produced without touching the tree.
+func (c *TemplateConfig) OnFile(fn func(path string, old, new []byte)) {
+	c.files = fn
+}
+
+// SetJobs sets how many files Transform works on at once; 0 (the default)
+// means GOMAXPROCS and 1 transforms one file at a time. The output and
+// hooks are delivered in file order either way.
+func (c *TemplateConfig) SetJobs(n int) {
+	c.jobs = n
+}
+
+// SetOutput sends the progress Transform prints (the changes and the files
+// updated) to w instead of standard output. Warnings still go to standard
+// error, unless Options.OnWarning takes them.
+func (c *TemplateConfig) SetOutput(w io.Writer) {
+	c.out = w
+}
+
+// output is where Transform prints progress
+func (c *TemplateConfig) output() io.Writer {
+	if c.out == nil {
+		return os.Stdout
+	}
+	return c.out
+}
+
+// warn reports a problem that doesn't stop the run
+func (c *TemplateConfig) warn(err error) {
+	warner(c.warning)(err)
+}
+
+// warner returns fn, or if it is nil a function printing warnings to
+// standard error
+func warner(fn func(error)) func(error) {
+	if fn != nil {
+		return fn
+	}
+	return func(err error) {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// PathOverride changes template settings for files under Path (a directory
+// or a single file). Empty settings are inherited; LevelMap rules are checked
+// before the inherited ones.
+type PathOverride struct {
+	Path      string      `json:"path" yaml:"path"`
+	Style     string      `json:"style,omitempty" yaml:"style,omitempty"`
+	LoggerVar string      `json:"loggerVar,omitempty" yaml:"loggerVar,omitempty"`
+	Template  string      `json:"template,omitempty" yaml:"template,omitempty"`
+	KeyStyle  string      `json:"keyStyle,omitempty" yaml:"keyStyle,omitempty"`
+	LevelMap  []LevelRule `json:"levelMap,omitempty" yaml:"levelMap,omitempty"`
+}
+
+// LevelRule maps a source level or logging function to a target level.
+// From matches the collected level ("Warning"), the function name ("Print"
+// matches Print, Printf and Println), the full original call ("log.Printf"),
+// or a verbosity selector ("V(2)"). Package optionally limits the rule to
+// entries from one package.
+type LevelRule struct {
+	From    string `json:"from" yaml:"from"`
+	To      string `json:"to" yaml:"to"`
+	Package string `json:"package,omitempty" yaml:"package,omitempty"`
+}
+
+// defaultLevelMap is applied after the configured rules
+var defaultLevelMap = []LevelRule{
+	{From: "Warning", To: "Warn"},
+	{From: "Unknown", To: "Info"},
+}
+
+// formatVerbPattern matches printf-style format verbs in a message
+var formatVerbPattern = regexp.MustCompile(`%[-+# 0]*[\d]*\.?[\d]*[vTtbcdoqxXUeEfFgGsp]`)
+
+// verbosityPattern matches the V(n) a call such as klog.V(2).Infof is made on
+var verbosityPattern = regexp.MustCompile(`(?:^|\.)V\((\d+)\)\.`)
+
+// sourceVerbosity returns verbosity with level at the V(n) the original
+// call was made on, so a klog or glog call migrating to klog or logr keeps
+// its verbosity rather than the one config maps its level to. Calls without
+// V(n) and levels changed by a rule or suggestion keep verbosity as is.
+func sourceVerbosity(update LogUpdate, level string, verbosity map[string]int) map[string]int {
+	m := verbosityPattern.FindStringSubmatch(update.OriginalCall)
+	if m == nil || !strings.EqualFold(level, update.LogLevel) {
+		return verbosity
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return verbosity
+	}
+	return map[string]int{strings.Title(strings.ToLower(level)): n}
+}
+
+// Options configure Transform and Apply
+type Options struct {
+	Config       *TemplateConfig // Usually from LoadTemplateConfig (default: the slog style)
+	DryRun       bool            // Report the changes without writing any file
+	AutoMap      bool            // Map ArgumentDetails to fields for entries without StructuredFields
+	KeyConstants string          // Go file for shared field key constants
+	Journal      string          // File recording applied edits, for Revert
+	OnlyApproved bool            // Apply only approved entries
+	TestLogs     bool            // Also apply entries of test output, such as t.Logf (see collector.Testing)
+	// MinConfidence, if set, holds the entries whose fields auto-map would
+	// derive with a FieldConfidence below it, for review
+	MinConfidence float64
+	// AllowSensitive also applies the entries whose fields look like
+	// credentials, which are held by default
+	AllowSensitive bool
+	// LogAndReturn is what to do with entries that log an error their
+	// function then returns (LogUpdate.Returns): LogAndReturnKeep (the
+	// default) migrates the call like any other, LogAndReturnWrap drops it
+	// and returns the error wrapped with fmt.Errorf, leaving the logging to
+	// the caller. Imports of fmt are left to goimports.
+	LogAndReturn string
+	// RewriteHelpers rewrites the logging helpers (LogUpdate.Helper)
+	// instead of migrating the calls to them: each call passes the entry's
+	// message and fields as key/value pairs to the helper, which takes them
+	// as (msg string, args ...any) and logs them with the style's method. A
+	// helper is rewritten only along with every call to it, and only once
+	// its own entry is approved; the style must be slog, zap-sugared,
+	// hclog or log15.
+	RewriteHelpers bool
+	// MessageRules are the message style rules every NewMessage must keep
+	// (see lint.ParseRules; nil for all of them). Entries whose NewMessage
+	// breaks one are held; SuggestedMessage has a fix.
+	MessageRules []string
+	IDs          []string // If set, apply only these entries
+
+	// FS, if set, is where source files are read and written instead of
+	// the OS, such as DirFS of a copy of the tree or a file system held in
+	// memory. The FilePath of the updates and KeyConstants are then names
+	// in it, and the journal records them as they are.
+	FS WriteFS
+
+	// Hooks for progress and diagnostics. They are called one at a time, in
+	// file order: OnFileStart for each file with updates, then
+	// OnEditApplied for each replacement written to it (or, in a dry run,
+	// that would be). OnWarning gets the problems that don't stop the run,
+	// such as malformed rows and calls that can't be generated; without it
+	// they are printed to standard error.
+	OnFileStart   func(path string)
+	OnEditApplied func(change Change)
+	OnWarning     func(err error)
+}
+
+// Report is the outcome of Apply
+type Report struct {
+	Changes        []Change      // Replacements made, or in a dry run that would be, by file, line and column
+	Files          []string      // Files changed, sorted
+	Held           int           // Edited entries held back: rejected, skipped, not approved, test output, below MinConfidence, with Unmapped arguments, logging credentials or breaking a message rule
+	AlreadyApplied int           // Edited entries skipped because they are marked Applied
+	Migrated       []string      // IDs of the entries left alone because their call is in the target style already, by file
+	Outcomes       []FileOutcome // Every file the run worked on, sorted, with its entries
+	Warnings       []error       // The problems that didn't stop the run, as OnWarning gets them
+}
+
+// FileOutcome is what a run did with one file. Err is why the file failed,
+// such as a *collector.ParseError; a failed file's changes are left out of
+// the report.
+type FileOutcome struct {
+	Path    string
+	Entries []EntryOutcome // By line and column
+	Err     error
+}
+
+// EntryOutcome is what a run did with one of the entries it applied
+type EntryOutcome struct {
+	ID     string
+	Line   int
+	Column int
+	Status string // EntryApplied, EntryMigrated, EntryNotFound or EntryFailed
+	Err    error  // Why the entry failed: a *GenerateError, an *EditConflictError or the file's error
+}
+
+// Entry statuses
+const (
+	EntryApplied  = "applied"   // Replaced, or in a dry run would be
+	EntryMigrated = "migrated"  // Left alone: its call is in the target style already (see alreadyMigrated)
+	EntryNotFound = "not-found" // No call at its line and column; the file changed since collect
+	EntryFailed   = "failed"
+)
+
+// Transform applies the updates in a CSV file (or database, see
+// table.IsDatabase) to the source files. If opts.KeyConstants is set,
+// generated calls reference key constants and the constants are written
+// (or merged) into that Go file. If opts.Journal is set, applied edits are
+// appended to it for Revert. Entries already marked Applied are skipped,
+// and when csvFile is a database the entries a run applies to the OS (not
+// to an opts.FS), or finds already migrated, are marked.
+// Cancelling ctx stops it as it stops Apply; the files already started are
+// still finished, journaled and marked.
+func Transform(ctx context.Context, csvFile string, opts Options) error {
+	source := func(warn func(error), fn func(LogUpdate) error) error {
+		return eachUpdate(csvFile, warn, fn)
+	}
+	report, err := apply(ctx, source, csvFile, opts)
+	if opts.DryRun || opts.FS != nil || len(report.Changes)+len(report.Migrated) == 0 || !table.IsDatabase(csvFile) {
+		return err
+	}
+	applied := make([]string, 0, len(report.Changes)+len(report.Migrated))
+	for _, change := range report.Changes {
+		applied = append(applied, change.ID)
+	}
+	applied = append(applied, report.Migrated...)
+	if markErr := markApplied(csvFile, applied); markErr != nil {
+		err = errors.Join(err, fmt.Errorf("failed to mark applied entries in %s: %w", csvFile, markErr))
+	}
+	return err
+}
+
+// Apply applies updates to the source files they name, as Transform does
+// with the updates in a CSV file, and reports what changed. Files that fail
+// don't stop the others: their errors are joined in the returned error and
+// their changes are left out of the report. If ctx is cancelled, no further
+// files are started and the error includes ctx.Err().
+func Apply(ctx context.Context, updates []LogUpdate, opts Options) (Report, error) {
+	source := func(_ func(error), fn func(LogUpdate) error) error {
+		for _, update := range updates {
+			if err := fn(update); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return apply(ctx, source, "the updates", opts)
+}
+
+// updateSource calls fn for every update, in the same order each time it
+// is called, and passes the rows it skips to warn if it isn't nil
+type updateSource func(warn func(error), fn func(LogUpdate) error) error
+
+// apply is Transform and Apply. from names the source in warnings.
+func apply(ctx context.Context, source updateSource, from string, opts Options) (Report, error) {
+	var report Report
+	config := opts.Config
+	if config == nil {
+		var err error
+		if config, err = LoadTemplateConfig("", nil); err != nil {
+			return report, err
+		}
+	}
+	if err := config.validate(); err != nil {
+		return report, fmt.Errorf("invalid template config: %w", err)
+	}
+	// The run's key constants and journal are its own
+	runConfig := *config
+	config = &runConfig
+	dryRun, autoMap, keysFile, ids := opts.DryRun, opts.AutoMap, opts.KeyConstants, opts.IDs
+
+	config.fsys = opts.FS
+	if config.fsys == nil {
+		config.fsys = osFS{}
+	}
+	config.ctx, config.plugin = ctx, nil
+	if config.usesPlugin() {
+		p, err := plugin.Load(ctx, config.Plugin)
+		if err != nil {
+			return report, err
+		}
+		defer p.Close(ctx)
+		config.plugin = p
+	}
+	switch opts.LogAndReturn {
+	case "", LogAndReturnKeep:
+		config.wrapReturns = false
+	case LogAndReturnWrap:
+		config.wrapReturns = true
+	default:
+		return report, fmt.Errorf("invalid log-and-return policy: %s (use keep or wrap)", opts.LogAndReturn)
+	}
+	config.helpers = nil
+	var helpers helperRewrites
+	if opts.RewriteHelpers {
+		if _, ok := helperMethod(config.Style, "Info"); !ok {
+			return report, fmt.Errorf("style %s can't rewrite helpers (use slog, zap-sugared, hclog or log15)", config.Style)
+		}
+		helpers = make(helperRewrites)
+	}
+	rules, err := lint.ParseRules(opts.MessageRules)
+	if err != nil {
+		return report, err
+	}
+
+	config.keys = nil
+	if keysFile != "" {
+		config.keys, err = loadKeyConstants(config.fsys, keysFile)
+		if err != nil {
+			return report, fmt.Errorf("failed to load key constants: %w", err)
+		}
+	}
+
+	// Warnings are reported as they come and collected in the report. They
+	// come from this goroutine, and during the second pass only from the
+	// one replaying the results of files.
+	warn := warner(opts.OnWarning)
+	config.warning = func(err error) {
+		report.Warnings = append(report.Warnings, err)
+		warn(err)
+	}
+	config.journal = nil
+	if opts.Journal != "" && !dryRun {
+		config.journal = &journal{path: opts.Journal, names: opts.FS != nil}
+	}
+
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	// The entry a helper wraps is applied once approved, edited or not,
+	// since the helper's rewrite doesn't take anything from it but its level
+	eligible := func(update LogUpdate) bool {
+		if helpers != nil && update.inHelper() {
+			return (len(ids) == 0 || wanted[update.ID]) && update.Applied == "" && !update.held() && update.approved()
+		}
+		return (len(ids) == 0 || wanted[update.ID]) && update.edited() && update.Applied == "" &&
+			!update.held() && (!opts.OnlyApproved || update.approved()) &&
+			(opts.TestLogs || update.SourceLibrary != collector.Testing) &&
+			update.confident(opts.AutoMap, opts.MinConfidence) && update.mapped(opts.AutoMap) &&
+			(opts.AllowSensitive || !update.sensitive(opts.AutoMap)) &&
+			len(update.Style(rules)) == 0
+	}
+	include := func(update LogUpdate) bool {
+		if !eligible(update) {
+			return false
+		}
+		return helpers == nil || update.Helper == "" || helpers.check(update.Helper) == nil
+	}
+
+	// The updates are streamed twice, so the CSV never has to fit in
+	// memory: the first pass finds the last update of each file, and the
+	// second groups updates by file and starts on a file as soon as its
+	// last update has been read.
+	last := make(map[string]int)
+	found := make(map[string]bool, len(ids))
+	held, applied, n := 0, 0, 0
+	// The entries of helpers wait for the end of the pass, which tells
+	// whether their helper can be rewritten
+	type waiting struct {
+		filePath, helper string
+		n                int
+	}
+	var wait []waiting
+	// Malformed rows are reported on the first of the two passes
+	err = source(config.warn, func(update LogUpdate) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n++
+		if helpers != nil && update.Helper != "" {
+			helpers.add(update, eligible(update))
+		}
+		if len(ids) > 0 {
+			if !wanted[update.ID] {
+				return nil
+			}
+			found[update.ID] = true
+		}
+		if !update.edited() && (helpers == nil || !update.inHelper()) {
+			return nil
+		}
+		if update.Applied != "" {
+			applied++
+			return nil
+		}
+		if !eligible(update) {
+			held++
+			return nil
+		}
+		if helpers != nil && update.Helper != "" {
+			wait = append(wait, waiting{update.FilePath, update.Helper, n})
+			return nil
+		}
+		last[update.FilePath] = n
+		return nil
+	})
+	if err != nil {
+		return report, fmt.Errorf("failed to load updates: %w", err)
+	}
+	if helpers != nil {
+		helpers.verify(config)
+	}
+	warned := make(map[string]bool)
+	for _, w := range wait {
+		if err := helpers.check(w.helper); err != nil {
+			if !warned[w.helper] {
+				warned[w.helper] = true
+				config.warn(err)
+			}
+			held++
+			continue
+		}
+		last[w.filePath] = max(last[w.filePath], w.n)
+	}
+	config.helpers = helpers
+
+	for _, id := range ids {
+		if !found[id] {
+			config.warn(fmt.Errorf("entry %s not found in %s", id, from))
+		}
+	}
+	report.Held, report.AlreadyApplied = held, applied
+	if applied > 0 {
+		fmt.Fprintf(config.output(), "Skipping %d entries already applied\n", applied)
+	}
+	if held > 0 {
+		fmt.Fprintf(config.output(), "Holding back %d edited entries (rejected, skipped, not approved, test output, low confidence, unmapped arguments, credentials, message style or helpers not rewritten whole)\n", held)
+	}
+	if len(last) == 0 {
+		fmt.Fprintln(config.output(), "No updates to apply")
+		return report, nil
+	}
+
+	// Files are independent, so they are transformed in parallel. Output and
+	// hooks are buffered per file and replayed in path order, so they read
+	// the same as a serial run.
+	paths := make([]string, 0, len(last))
+	for filePath := range last {
+		paths = append(paths, filePath)
+	}
+	sort.Strings(paths)
+	index := make(map[string]int, len(paths))
+	results := make([]chan fileResult, len(paths))
+	for i, filePath := range paths {
+		index[filePath] = i
+		results[i] = make(chan fileResult, 1)
+	}
+
+	type job struct {
+		i       int
+		updates []LogUpdate
+	}
+	jobs := config.jobs
+	if jobs <= 0 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
+	next := make(chan job)
+	var wg sync.WaitGroup
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range next {
+				results[j.i] <- transformBuffered(paths[j.i], j.updates, config, dryRun, autoMap)
+			}
+		}()
+	}
+
+	var errs []error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i, filePath := range paths {
+			r := <-results[i]
+			if r.skipped {
+				continue
+			}
+			if opts.OnFileStart != nil {
+				opts.OnFileStart(filePath)
+			}
+			for _, warning := range r.warnings {
+				config.warn(warning)
+			}
+			if config.changes != nil {
+				for _, change := range r.changes {
+					config.changes(change)
+				}
+			}
+			if r.err == nil {
+				report.Migrated = append(report.Migrated, r.migrated...)
+			}
+			if r.err == nil && len(r.changes) > 0 {
+				report.Changes = append(report.Changes, r.changes...)
+				report.Files = append(report.Files, filePath)
+				if opts.OnEditApplied != nil {
+					for _, change := range r.changes {
+						opts.OnEditApplied(change)
+					}
+				}
+			}
+			report.Outcomes = append(report.Outcomes, FileOutcome{Path: filePath, Entries: r.entries, Err: r.err})
+			if r.updated != nil {
+				config.files(filePath, r.content, r.updated)
+			}
+			config.output().Write(r.out.Bytes())
+			if r.err != nil {
+				errs = append(errs, fmt.Errorf("failed to transform %s: %w", filePath, r.err))
+			}
+		}
+	}()
+
+	groups := make(map[string][]LogUpdate)
+	dispatched := make([]bool, len(paths))
+	dispatch := func(filePath string) {
+		i := index[filePath]
+		next <- job{i, groups[filePath]}
+		dispatched[i] = true
+		delete(groups, filePath)
+	}
+	n = 0
+	readErr := source(nil, func(update LogUpdate) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n++
+		if _, ok := index[update.FilePath]; !ok || !include(update) {
+			return nil
+		}
+		groups[update.FilePath] = append(groups[update.FilePath], update)
+		if last[update.FilePath] == n {
+			dispatch(update.FilePath)
+		}
+		return nil
+	})
+	// Left over only if the CSV changed between the passes
+	for _, filePath := range paths {
+		if _, ok := groups[filePath]; ok {
+			dispatch(filePath)
+		}
+	}
+	close(next)
+	wg.Wait()
+	for i := range paths {
+		if !dispatched[i] {
+			results[i] <- fileResult{skipped: true}
+		}
+	}
+	<-done
+	if len(report.Migrated) > 0 {
+		fmt.Fprintf(config.output(), "Left %d entries alone, already migrated\n", len(report.Migrated))
+	}
+	if ctx.Err() != nil {
+		errs = append(errs, ctx.Err())
+	} else if readErr != nil {
+		errs = append(errs, fmt.Errorf("failed to load updates: %w", readErr))
+	}
+
+	// The key constants are written even if some files failed, since the
+	// files that were updated already reference them
+	if config.keys != nil {
+		if config.files != nil {
+			src, err := config.keys.render()
+			if err != nil {
+				return report, fmt.Errorf("failed to render key constants: %w", err)
+			}
+			old, _ := fs.ReadFile(config.fsys, keysFile)
+			if !bytes.Equal(old, src) {
+				config.files(keysFile, old, src)
+			}
+		}
+		if dryRun {
+			fmt.Fprintf(config.output(), "Would update: %s (%d keys)\n", keysFile, len(config.keys.names))
+			return report, errors.Join(errs...)
+		}
+		if err := config.keys.write(); err != nil {
+			return report, fmt.Errorf("failed to write key constants: %w", err)
+		}
+		fmt.Fprintf(config.output(), "Updated: %s (%d keys)\n", keysFile, len(config.keys.names))
+	}
+
+	return report, errors.Join(errs...)
+}
+
+// markApplied sets the Applied column of the entries with the given IDs to
+// the current time
+func markApplied(file string, ids []string) error {
+	return table.Mark(file, "Applied", time.Now().UTC().Format(time.RFC3339), ids)
+}
+
+// fileResult is the outcome of transforming one file, held back until it
+// can be reported in order
+type fileResult struct {
+	out              bytes.Buffer
+	changes          []Change
+	entries          []EntryOutcome
+	content, updated []byte   // For the OnFile hook; updated is nil if nothing changed
+	migrated         []string // Entries already migrated
+	warnings         []error
+	err              error
+	skipped          bool // The file's updates couldn't be read
+}
+
+// transformBuffered runs transformFile with the output and hooks captured
+// in the result instead of delivered
+func transformBuffered(filePath string, updates []LogUpdate, config *TemplateConfig, dryRun, autoMap bool) fileResult {
+	var r fileResult
+	fileConfig := *config.forFile(filePath)
+	fileConfig.out = &r.out
+	fileConfig.changes = func(c Change) { r.changes = append(r.changes, c) }
+	fileConfig.warning = func(err error) { r.warnings = append(r.warnings, err) }
+	fileConfig.migrated = func(id string) { r.migrated = append(r.migrated, id) }
+	if config.files != nil {
+		fileConfig.files = func(_ string, old, new []byte) { r.content, r.updated = old, new }
+	}
+	r.err = transformFile(filePath, updates, &fileConfig, dryRun, autoMap)
+	r.entries = outcomes(updates, r)
+	return r
+}
+
+// outcomes returns the outcome of each of a file's updates, from what
+// transformFile made of them
+func outcomes(updates []LogUpdate, r fileResult) []EntryOutcome {
+	applied := make(map[string]bool, len(r.changes))
+	for _, change := range r.changes {
+		applied[change.ID] = true
+	}
+	migrated := make(map[string]bool, len(r.migrated))
+	for _, id := range r.migrated {
+		migrated[id] = true
+	}
+	failed := make(map[string]error)
+	for _, warning := range r.warnings {
+		var genErr *GenerateError
+		var editErr *EditConflictError
+		switch {
+		case errors.As(warning, &genErr):
+			failed[genErr.ID] = warning
+		case errors.As(warning, &editErr):
+			failed[editErr.ID] = warning
+		}
+	}
+
+	entries := make([]EntryOutcome, 0, len(updates))
+	for _, update := range updates {
+		o := EntryOutcome{ID: update.ID, Line: update.Line, Column: update.Column}
+		switch {
+		case r.err != nil:
+			o.Status, o.Err = EntryFailed, r.err
+		case failed[update.ID] != nil:
+			o.Status, o.Err = EntryFailed, failed[update.ID]
+		case applied[update.ID]:
+			o.Status = EntryApplied
+		case migrated[update.ID]:
+			o.Status = EntryMigrated
+		default:
+			o.Status = EntryNotFound
+		}
+		entries = append(entries, o)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Line != entries[j].Line {
+			return entries[i].Line < entries[j].Line
+		}
+		return entries[i].Column < entries[j].Column
+	})
+	return entries
+}
+
+// LoadTemplateConfig loads a JSON template configuration. Settings in the file
+// override those in base (e.g. from the project configuration); settings
+// missing from both default to the slog style with a "log" logger variable.
+func LoadTemplateConfig(configFile string, base *TemplateConfig) (*TemplateConfig, error) {
+	var config TemplateConfig
+	if base != nil {
+		config = *base
+	}
+
+	if configFile != "" {
+		data, err := os.ReadFile(configFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := schema.Check(configFile, data); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, err
+		}
+	}
+
+	// Default to slog style
+	if config.Style == "" {
+		config.Style = "slog"
+	}
+	if config.LoggerVar == "" {
+		config.LoggerVar = "log"
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// forFile returns the configuration to use for filePath, with the most
+// specific matching override applied. Override paths and filePath are
+// compared as absolute paths.
+func (c *TemplateConfig) forFile(filePath string) *TemplateConfig {
+	absFile, err := filepath.Abs(filePath)
+	if err != nil {
+		return c
+	}
+
+	var best *PathOverride
+	bestLen := -1
+	for i := range c.Overrides {
+		o := &c.Overrides[i]
+		absPath, err := filepath.Abs(o.Path)
+		if err != nil {
+			continue
+		}
+		if absFile != absPath && !strings.HasPrefix(absFile, absPath+string(filepath.Separator)) {
+			continue
+		}
+		if len(absPath) > bestLen {
+			best, bestLen = o, len(absPath)
+		}
+	}
+	if best == nil {
+		return c
+	}
+
+	fileConfig := *c
+	if best.Style != "" {
+		fileConfig.Style = best.Style
+	}
+	if best.LoggerVar != "" {
+		fileConfig.LoggerVar = best.LoggerVar
+	}
+	if best.Template != "" {
+		fileConfig.Template = best.Template
+	}
+	if best.KeyStyle != "" {
+		fileConfig.KeyStyle = best.KeyStyle
+	}
+	if len(best.LevelMap) > 0 {
+		fileConfig.LevelMap = append(append([]LevelRule{}, best.LevelMap...), c.LevelMap...)
+	}
+	return &fileConfig
+}
+
+// validate checks settings that would otherwise silently produce bad output
+func (c *TemplateConfig) validate() error {
+	if !knownStyle(c.Style) {
+		return fmt.Errorf("unknown style: %s", c.Style)
+	}
+	if c.Style == "exec" && len(c.Command) == 0 {
+		return fmt.Errorf("style exec needs a command")
+	}
+	if c.Style == "wasm" && c.Plugin == "" {
+		return fmt.Errorf("style wasm needs a plugin")
+	}
+	if c.KeyStyle != "" && naming.Normalize(c.KeyStyle) == "" {
+		return fmt.Errorf("invalid keyStyle: %s", c.KeyStyle)
+	}
+	for _, o := range c.Overrides {
+		if o.Path == "" {
+			return fmt.Errorf("override without a path")
+		}
+		if o.Style != "" && !knownStyle(o.Style) {
+			return fmt.Errorf("unknown style for %s: %s", o.Path, o.Style)
+		}
+		if o.KeyStyle != "" && naming.Normalize(o.KeyStyle) == "" {
+			return fmt.Errorf("invalid keyStyle for %s: %s", o.Path, o.KeyStyle)
+		}
+	}
+	return nil
+}
+
+// loadUpdates reads the CSV file and returns a list of updates. Columns are
+// looked up by header name, so they may be reordered and extra columns are
+// ignored; Status and Approved are optional.
+func loadUpdates(csvFile string) ([]LogUpdate, error) {
+	var updates []LogUpdate
+	err := eachUpdate(csvFile, warner(nil), func(update LogUpdate) error {
+		updates = append(updates, update)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+// ReadUpdates reads the updates in r, written in the format of c (a CSV
+// file, as collect writes, with codec.CSV), for running Apply without a
+// file on disk. Columns are looked up by name as loadUpdates does, and
+// malformed rows are skipped with a warning.
+func ReadUpdates(r io.Reader, c codec.Codec) ([]LogUpdate, error) {
+	dec := c.NewDecoder(r)
+	header, err := dec.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("input is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+	t := table.New(header)
+	if err := t.Require(csvColumns...); err != nil {
+		return nil, err
+	}
+	var updates []LogUpdate
+	n, err := decodeUpdates(t, dec.Read, warner(nil), func(update LogUpdate) error {
+		updates = append(updates, update)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("input has no data rows")
+	}
+	return updates, nil
+}
+
+// eachUpdate streams the updates in the CSV file to fn, a row at a time.
+// Malformed rows are skipped, and passed to warn if it isn't nil.
+func eachUpdate(csvFile string, warn func(error), fn func(LogUpdate) error) error {
+	r, err := table.Open(csvFile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err := r.Require(csvColumns...); err != nil {
+		return err
+	}
+
+	n, err := decodeUpdates(r.Table, r.Next, warn, fn)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("CSV file is empty or has no data rows")
+	}
+	return nil
+}
+
+// decodeUpdates passes the rows next returns, until io.EOF, to fn as
+// updates of table t, and returns the number of rows read. Malformed rows
+// are skipped, and passed to warn if it isn't nil.
+func decodeUpdates(t *table.Table, next func() ([]string, error), warn func(error), fn func(LogUpdate) error) (int, error) {
+	line := 1
+	for {
+		row, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return line - 1, err
+		}
+		line++
+		update, err := ParseUpdate(t, row)
+		if err != nil {
+			if warn != nil {
+				warn(&RowError{Row: line, Err: err})
+			}
+			continue
+		}
+		if err := fn(update); err != nil {
+			return line - 1, err
+		}
+	}
+	return line - 1, nil
+}
+
+// transformFile applies updates to a single file
+func transformFile(filePath string, updates []LogUpdate, config *TemplateConfig, dryRun bool, autoMap bool) error {
+	// Read the original file content
+	content, err := fs.ReadFile(config.fsys, filePath)
+	if err != nil {
+		return err
+	}
+
+	// Parse the file
+	fset := token.NewFileSet()
+	node, err := parser.ParseFile(fset, filePath, content, parser.ParseComments)
+	if err != nil {
+		return &collector.ParseError{Path: filePath, Err: err}
+	}
+
+	// Create a map of line:column -> update
+	updateMap := make(map[string]LogUpdate)
+	for _, update := range updates {
+		key := fmt.Sprintf("%d:%d", update.Line, update.Column)
+		updateMap[key] = update
+	}
+
+	// Track modifications
+	type modification struct {
+		change Change
+		text   string
+	}
+	var modifications []modification
+	var edits []edit
+	// record adds an edit that isn't a call replaced by another, at the
+	// line and column it starts
+	record := func(e edit, column int) {
+		edits = append(edits, e)
+		modifications = append(modifications, modification{
+			change: Change{
+				ID:     e.id,
+				File:   filePath,
+				Line:   e.line,
+				Column: column,
+				Start:  e.start,
+				End:    e.end,
+				Old:    string(content[e.start:e.end]),
+				New:    e.code,
+			},
+			text: fmt.Sprintf("%s:%d:%d\n  Old: %s\n  New: %s",
+				filepath.Base(filePath), e.line, column,
+				truncateCode(strings.Join(strings.Fields(string(content[e.start:e.end])), " "), 80),
+				truncateCode(e.code, 80)),
+		})
+	}
+
+	// Walk the AST and collect replacements
+	ast.Inspect(node, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+
+		startPos := fset.Position(call.Pos())
+		key := fmt.Sprintf("%d:%d", startPos.Line, startPos.Column)
+
+		update, exists := updateMap[key]
+		if !exists {
+			return true
+		}
+
+		// The call is to a helper, or in one, that is rewritten instead
+		if update.Helper != "" && config.helpers != nil {
+			path, _ := astutil.PathEnclosingInterval(node, call.Pos(), call.End())
+			var e edit
+			var err error
+			if update.helperCall() {
+				e, err = rewriteHelperCall(update, call, fset, content, config, autoMap)
+			} else {
+				e, err = rewriteHelper(update, call, path, fset, content, config)
+			}
+			if err != nil {
+				config.warn(&GenerateError{ID: update.ID, Err: err})
+				return true
+			}
+			file := fset.File(call.Pos())
+			record(e, file.Position(file.Pos(e.start)).Column)
+			return false
+		}
+
+		// The error is logged and returned: drop the call and wrap the error
+		if update.Returns != "" && config.wrapReturns {
+			path, _ := astutil.PathEnclosingInterval(node, call.Pos(), call.End())
+			e, err := wrapReturn(update, call, path, fset, content)
+			if err != nil {
+				config.warn(&GenerateError{ID: update.ID, Err: err})
+				return true
+			}
+			record(e, fset.Position(path[1].Pos()).Column)
+			return false
+		}
+
+		// Generate the new log call
+		update.chained = chainedFields(call, fset, content, update.SourceLibrary)
+		newCode, err := generateStructuredLogCall(update, config, autoMap)
+		if err != nil {
+			config.warn(&GenerateError{ID: update.ID, Err: err})
+			return true
+		}
+
+		e := edit{
+			start: startPos.Offset,
+			end:   fset.Position(call.End()).Offset,
+			id:    update.ID,
+			line:  startPos.Line,
+		}
+		e.code = wrapLongCall(newCode, content, e.start, e.end, config)
+		if old := string(content[e.start:e.end]); alreadyMigrated(call, update, old, e.code, config) {
+			if config.migrated != nil {
+				config.migrated(update.ID)
+			}
+			fmt.Fprintf(config.output(), "%s:%d:%d\n  Already migrated: %s\n\n",
+				filepath.Base(filePath), startPos.Line, startPos.Column, truncateCode(formatCallExpr(call, fset), 80))
+			return false
+		}
+		stmt, err := terminator(update, config)
+		if err == nil && stmt != "" {
+			path, _ := astutil.PathEnclosingInterval(node, call.Pos(), call.End())
+			if exitFollows(path) {
+				stmt = "" // Keep the call's own exit code or panic
+			} else {
+				e.code, err = terminate(e.code, stmt, path, content, e.start)
+			}
+		}
+		if err != nil {
+			config.warn(&GenerateError{ID: update.ID, Err: err})
+			return true
+		}
+		if stmt != "" {
+			newCode += "; " + stmt
+		}
+		edits = append(edits, e)
+
+		// Record the modification
+		modifications = append(modifications, modification{
+			change: Change{
+				ID:     update.ID,
+				File:   filePath,
+				Line:   startPos.Line,
+				Column: startPos.Column,
+				Start:  e.start,
+				End:    e.end,
+				Old:    string(content[e.start:e.end]),
+				New:    e.code,
+			},
+			text: fmt.Sprintf("%s:%d:%d\n  Old: %s\n  New: %s",
+				filepath.Base(filePath), startPos.Line, startPos.Column,
+				truncateCode(formatCallExpr(call, fset), 80),
+				truncateCode(newCode, 80)),
+		})
+
+		// The whole call is replaced, so calls nested inside it (such as the
+		// V(2) in klog.V(2).Infof) must not be replaced separately
+		return false
+	})
+
+	// Edits that would overwrite each other are left out, and so are their
+	// modifications
+	var updated []byte
+	if len(edits) > 0 {
+		var skipped []edit
+		updated, skipped = applyEdits(content, edits)
+		for _, e := range skipped {
+			config.warn(conflict(filePath, e))
+			modifications = slices.DeleteFunc(modifications, func(m modification) bool {
+				return m.change.ID == e.id && m.change.Start == e.start
+			})
+		}
+	}
+
+	// Report modifications by line and column, which doesn't rely on the
+	// order ast.Inspect visits calls in
+	sort.SliceStable(modifications, func(i, j int) bool {
+		a, b := modifications[i].change, modifications[j].change
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	for _, mod := range modifications {
+		if config.changes != nil {
+			config.changes(mod.change)
+		}
+		fmt.Fprintln(config.output(), mod.text)
+		fmt.Fprintln(config.output())
+	}
+
+	if len(edits) > 0 && config.files != nil {
+		config.files(filePath, content, updated)
+	}
+
+	// Write back if modified and not dry run
+	if len(edits) > 0 && !dryRun {
+		if err := config.fsys.WriteFile(filePath, updated, 0644); err != nil {
+			return err
+		}
+		if config.journal != nil {
+			if err := config.journal.record(filePath, content, edits); err != nil {
+				return fmt.Errorf("failed to write journal: %w", err)
+			}
+		}
+		fmt.Fprintf(config.output(), "Updated: %s (%d changes)\n", filePath, len(modifications))
+	} else if len(modifications) > 0 && dryRun {
+		fmt.Fprintf(config.output(), "Would update: %s (%d changes)\n", filePath, len(modifications))
+	}
+
+	return nil
+}
+
+// Generate returns the call transform would write for an entry, using the
+// settings that apply to the entry's file, followed on a line of its own by
+// the os.Exit or panic that keeps a Fatal or Panic entry exiting or
+// panicking in a style that logs it as Error
+func Generate(update LogUpdate, config *TemplateConfig, autoMap bool) (string, error) {
+	if err := config.validate(); err != nil {
+		return "", fmt.Errorf("invalid template config: %w", err)
+	}
+	config = config.forFile(update.FilePath)
+	code, err := generateStructuredLogCall(update, config, autoMap)
+	if err != nil {
+		return "", err
+	}
+	stmt, err := terminator(update, config)
+	if err != nil || stmt == "" {
+		return code, err
+	}
+	return code + "\n" + stmt, nil
+}
+
+// generateStructuredLogCall generates the new structured logging call based on template
+func generateStructuredLogCall(update LogUpdate, config *TemplateConfig, autoMap bool) (string, error) {
+	if logger := loggerFor(update, config); logger != config.LoggerVar {
+		withReceiver := *config
+		withReceiver.LoggerVar = logger
+		config = &withReceiver
+	}
+	fields := resolveFields(update, config, autoMap)
+
+	if config.GroupKeys {
+		switch config.Style {
+		case "slog", "zap", "zerolog":
+			fields = groupDottedFields(fields)
+		}
+	}
+
+	if config.keys != nil {
+		config.keys.assign(fields)
+	}
+
+	message, err := entryMessage(update)
+	if err != nil {
+		return "", err
+	}
+
+	level := mapLevel(update, config.LevelMap)
+	if update.SourceLibrary == "zap" && (config.Style == "zap" || config.Style == "zap-sugared") {
+		level = zapDPanic(update, level)
+	}
+
+	// Generate based on style
+	switch config.Style {
+	case "slog":
+		return generateSlogCall(config.LoggerVar, level, message, fields), nil
+	case "zap":
+		return generateZapCall(config.LoggerVar, level, message, fields), nil
+	case "zap-sugared":
+		return generateZapSugaredCall(config.LoggerVar, level, message, fields), nil
+	case "zerolog":
+		formatArgs := messageFormatArgs(message, autoGenerateFieldsFromArguments(update.ArgumentDetails))
+		return generateZerologCall(config.LoggerVar, level, message, fields, formatArgs), nil
+	case "logrus":
+		return generateLogrusCall(config.LoggerVar, level, message, fields), nil
+	case "klog":
+		return generateKlogCall(config.LoggerVar, level, message, fields, sourceVerbosity(update, level, config.Verbosity)), nil
+	case "hclog":
+		return generateHclogCall(config.LoggerVar, level, message, fields), nil
+	case "gokit":
+		return generateGokitCall(config.LoggerVar, level, message, fields), nil
+	case "logr":
+		return generateLogrCall(config.LoggerVar, level, message, fields, sourceVerbosity(update, level, config.Verbosity)), nil
+	case "apex":
+		return generateApexCall(config.LoggerVar, level, message, fields), nil
+	case "log15":
+		return generateLog15Call(config.LoggerVar, level, message, fields), nil
+	case "custom":
+		return generateCustomCall(config.Template, config.LoggerVar, level, message, fields)
+	case "exec":
+		return generateExecCall(config.Command, update, config.LoggerVar, level, message, fields)
+	case "wasm":
+		return generateWasmCall(config, update, level, message, fields)
+	default:
+		if g, ok := styles[config.Style]; ok {
+			return g.Generate(update, config, level, message, fields)
+		}
+		return "", fmt.Errorf("unknown style: %s", config.Style)
+	}
+}
+
+// resolveFields returns the fields of an entry's new call, with duration
+// hints applied and their keys renamed, warning of forbidden ones
+func resolveFields(update LogUpdate, config *TemplateConfig, autoMap bool) []FieldMapping {
+	fields := update.Fields(autoMap)
+	fields = applyDurationHints(fields, config.MillisecondInts)
+
+	for i := range fields {
+		fields[i].Key = resolveFieldKey(fields[i].Key, config)
+		if config.Style == "slog" && config.ErrorKey != "" && fieldKind(fields[i]) == "error" {
+			fields[i].Key = config.ErrorKey
+		}
+		if isForbiddenKey(fields[i].Key, config.ForbiddenKeys) {
+			config.warn(fmt.Errorf("%s uses forbidden key %q", update.ID, fields[i].Key))
+		}
+	}
+	return fields
+}
+
+// entryMessage returns the message of an entry's new call: NewMessage if
+// provided, otherwise SuggestedMessage or MessageTemplate. A template that
+// isn't a string literal is the expression the message is built from, not
+// its text.
+func entryMessage(update LogUpdate) (string, error) {
+	message := update.NewMessage
+	if message == "" {
+		message = update.SuggestedMessage
+	}
+	if message == "" {
+		if _, err := strconv.Unquote(update.MessageTemplate); err != nil && update.MessageTemplate != "" {
+			return "", fmt.Errorf("message %s isn't a string literal; fill in NewMessage", update.MessageTemplate)
+		}
+		message = update.MessageTemplate
+	}
+	return strings.Trim(message, `"'`+"`"), nil
+}
+
+// loggerFor returns the logger an entry's new call logs to: the struct
+// field or accessor the call used, or with Desugar the typed logger behind
+// the SugaredLogger it used (DesugaredLogger or LoggerVar when the call
+// doesn't show it), and otherwise LoggerVar
+func loggerFor(update LogUpdate, config *TemplateConfig) string {
+	if config.Desugar && config.Style == "zap" && update.SourceLibrary == "zap" {
+		typed := config.DesugaredLogger
+		if typed == "" {
+			typed = config.LoggerVar
+		}
+		if logger, ok := desugared(update.OriginalCall, typed); ok {
+			return logger
+		}
+	}
+	if update.Receiver != "" {
+		return update.Receiver
+	}
+	return config.LoggerVar
+}
+
+// generateSlogCall generates a slog-style structured log call
+func generateSlogCall(loggerVar, level, message string, fields []FieldMapping) string {
+	levelFunc := slogLevel(level)
+
+	var parts []string
+	parts = append(parts, fmt.Sprintf(`%s.%s("%s"`, loggerVar, levelFunc, message))
+	parts = append(parts, slogAttrs(fields)...)
+
+	return strings.Join(parts, ", ") + ")"
+}
+
+// slogAttrs renders fields as slog attributes, recursing into groups
+func slogAttrs(fields []FieldMapping) []string {
+	var attrs []string
+	for _, field := range fields {
+		if len(field.Fields) > 0 {
+			children := slogAttrs(field.Fields)
+			attrs = append(attrs, fmt.Sprintf(`slog.Group(%s, %s)`, keyExpr(field), strings.Join(children, ", ")))
+			continue
+		}
+		attrs = append(attrs, fmt.Sprintf(`slog.%s(%s, %s)`, getSlogAttrFunc(slogKind(field)), keyExpr(field), field.Expression))
+	}
+	return attrs
+}
+
+// generateZapCall generates a zap-style structured log call.
+// zap.Namespace nests every field that follows it, so a single group is emitted
+// last behind a Namespace; with several groups each one becomes a zap.Dict.
+func generateZapCall(loggerVar, level, message string, fields []FieldMapping) string {
+	levelFunc := zapLevel(level)
+
+	var parts []string
+	parts = append(parts, fmt.Sprintf(`%s.%s("%s"`, loggerVar, levelFunc, message))
+
+	var flat, groups []FieldMapping
+	for _, field := range fields {
+		if len(field.Fields) > 0 {
+			groups = append(groups, field)
+		} else {
+			flat = append(flat, field)
+		}
+	}
+
+	parts = append(parts, zapFields(flat)...)
+	if len(groups) == 1 && !hasNestedGroups(groups[0].Fields) {
+		parts = append(parts, fmt.Sprintf(`zap.Namespace(%s)`, keyExpr(groups[0])))
+		parts = append(parts, zapFields(groups[0].Fields)...)
+	} else {
+		parts = append(parts, zapFields(groups)...)
+	}
+
+	return strings.Join(parts, ", ") + ")"
+}
+
+// zapFields renders fields as zap.Field constructors; groups become zap.Dict
+func zapFields(fields []FieldMapping) []string {
+	var parts []string
+	for _, field := range fields {
+		if len(field.Fields) > 0 {
+			children := zapFields(field.Fields)
+			parts = append(parts, fmt.Sprintf(`zap.Dict(%s, %s)`, keyExpr(field), strings.Join(children, ", ")))
+			continue
+		}
+		parts = append(parts, zapField(field))
+	}
+	return parts
+}
+
+// zapField renders a field as a zap.Field constructor. An error under the
+// key "error" is zap.Error(err), which logs it under that key.
+func zapField(field FieldMapping) string {
+	zapFunc := getZapFieldFunc(fieldKind(field))
+	if zapFunc == "NamedError" && field.Key == "error" && field.KeyConst == "" {
+		return fmt.Sprintf("zap.Error(%s)", field.Expression)
+	}
+	return fmt.Sprintf("zap.%s(%s, %s)", zapFunc, keyExpr(field), field.Expression)
+}
+
+// generateZapSugaredCall generates a call on zap's SugaredLogger using the
+// "w" variants, which take loosely-typed key/value pairs instead of zap.Field.
+func generateZapSugaredCall(loggerVar, level, message string, fields []FieldMapping) string {
+	levelFunc := zapSugaredLevel(level)
+
+	args := []string{fmt.Sprintf(`"%s"`, message)}
+	args = append(args, keyValueArgs(fields)...)
+
+	return fmt.Sprintf("%s.%sw(%s)", loggerVar, levelFunc, strings.Join(args, ", "))
+}
+
+// generateZerologCall generates a zerolog-style structured log call.
+// Fields use zerolog's typed helpers; the first error goes through Err() and any
+// further errors through AnErr(). If the message still contains format verbs,
+// the chain ends with Msgf and formatArgs instead of Msg.
+func generateZerologCall(loggerVar, level, message string, fields []FieldMapping, formatArgs []string) string {
+	levelFunc := zerologLevel(level)
+
+	parts := []string{fmt.Sprintf("%s.%s()", loggerVar, levelFunc)}
+	parts = append(parts, zerologFields(fields)...)
+
+	if len(formatArgs) > 0 {
+		parts = append(parts, fmt.Sprintf(`Msgf("%s", %s)`, message, strings.Join(formatArgs, ", ")))
+	} else {
+		parts = append(parts, fmt.Sprintf(`Msg("%s")`, message))
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// generateLogrusCall generates a logrus-style structured log call
+func generateLogrusCall(loggerVar, level, message string, fields []FieldMapping) string {
+	levelFunc := logrusLevel(level)
+
+	if len(fields) == 0 {
+		return fmt.Sprintf(`%s.%s("%s")`, loggerVar, levelFunc, message)
+	}
+
+	// Build fields map
+	var fieldPairs []string
+	for _, field := range fields {
+		fieldPairs = append(fieldPairs, fmt.Sprintf(`%s: %s`, keyExpr(field), field.Expression))
+	}
+
+	return fmt.Sprintf(`%s.WithFields(%s.Fields{%s}).%s("%s")`,
+		loggerVar, fieldsPackage(loggerVar, "logrus"), strings.Join(fieldPairs, ", "), levelFunc, message)
+}
+
+// fieldsPackage returns the package qualifier of the Fields type in logrus
+// and apex/log calls: loggerVar when it is a name, such as logrus, or pkg
+// for a receiver such as s.logger
+func fieldsPackage(loggerVar, pkg string) string {
+	if token.IsIdentifier(loggerVar) {
+		return loggerVar
+	}
+	return pkg
+}
+
+// generateKlogCall generates a klog-style structured log call.
+// klog only has structured variants for Info and Error; ErrorS takes the error
+// as its first argument (nil when there is none), and Debug/Trace map to V levels
+// using the configured mapping (default: collector.KlogVerbosity, V(4) and V(5)).
+func generateKlogCall(loggerVar, level, message string, fields []FieldMapping, verbosity map[string]int) string {
+	if verbosity == nil {
+		verbosity = collector.KlogVerbosity
+	}
+
+	var prefix string
+	var args []string
+
+	switch strings.ToLower(level) {
+	case "error", "fatal", "panic":
+		errExpr, rest := splitErrorField(fields)
+		prefix = fmt.Sprintf("%s.ErrorS(%s, ", loggerVar, errExpr)
+		fields = rest
+	default:
+		if v := verbosity[strings.Title(strings.ToLower(level))]; v > 0 {
+			prefix = fmt.Sprintf("%s.V(%d).InfoS(", loggerVar, v)
+		} else {
+			prefix = fmt.Sprintf("%s.InfoS(", loggerVar)
+		}
+	}
+
+	args = append(args, fmt.Sprintf(`"%s"`, message))
+	args = append(args, keyValueArgs(fields)...)
+
+	return prefix + strings.Join(args, ", ") + ")"
+}
+
+// generateHclogCall generates an hclog-style structured log call.
+// hclog has no Fatal or Panic methods, so those levels are logged as Error
+// (see terminator).
+func generateHclogCall(loggerVar, level, message string, fields []FieldMapping) string {
+	levelFunc := hclogLevel(level)
+
+	args := []string{fmt.Sprintf(`"%s"`, message)}
+	args = append(args, keyValueArgs(fields)...)
+
+	return fmt.Sprintf("%s.%s(%s)", loggerVar, levelFunc, strings.Join(args, ", "))
+}
+
+// generateGokitCall generates a go-kit log call. go-kit has no printf variants
+// and no message parameter: the message is just another key/value pair under "msg".
+// go-kit's level package only provides Debug, Info, Warn and Error.
+func generateGokitCall(loggerVar, level, message string, fields []FieldMapping) string {
+	levelFunc := gokitLevel(level)
+
+	args := []string{fmt.Sprintf(`"msg", "%s"`, message)}
+	args = append(args, keyValueArgs(fields)...)
+
+	return fmt.Sprintf("level.%s(%s).Log(%s)", levelFunc, loggerVar, strings.Join(args, ", "))
+}
+
+// generateLogrCall generates a logr-style structured log call.
+// logr only has Info and Error; Error takes the error as its first argument.
+// Other levels are expressed as verbosity via V(n), using the configured mapping.
+func generateLogrCall(loggerVar, level, message string, fields []FieldMapping, verbosity map[string]int) string {
+	if verbosity == nil {
+		verbosity = collector.LogrVerbosity
+	}
+
+	levelName := strings.Title(strings.ToLower(level))
+	if levelName == "Warning" {
+		levelName = "Warn"
+	}
+
+	var prefix string
+	switch levelName {
+	case "Error", "Fatal", "Panic":
+		errExpr, rest := splitErrorField(fields)
+		prefix = fmt.Sprintf("%s.Error(%s, ", loggerVar, errExpr)
+		fields = rest
+	default:
+		if v, ok := verbosity[levelName]; ok && v > 0 {
+			prefix = fmt.Sprintf("%s.V(%d).Info(", loggerVar, v)
+		} else {
+			prefix = fmt.Sprintf("%s.Info(", loggerVar)
+		}
+	}
+
+	args := []string{fmt.Sprintf(`"%s"`, message)}
+	args = append(args, keyValueArgs(fields)...)
+
+	return prefix + strings.Join(args, ", ") + ")"
+}
+
+// generateApexCall generates an apex/log-style structured log call.
+// The error is attached with WithError and other fields with WithFields.
+// apex/log has no Trace or Panic level; they map to Debug and Error (see
+// terminator).
+func generateApexCall(loggerVar, level, message string, fields []FieldMapping) string {
+	levelFunc := apexLevel(level)
+
+	var chain []string
+	errExpr, rest := splitErrorField(fields)
+	if errExpr != "nil" {
+		chain = append(chain, fmt.Sprintf("WithError(%s)", errExpr))
+	}
+	if len(rest) > 0 {
+		var fieldPairs []string
+		for _, field := range rest {
+			fieldPairs = append(fieldPairs, fmt.Sprintf(`%s: %s`, keyExpr(field), field.Expression))
+		}
+		chain = append(chain, fmt.Sprintf("WithFields(%s.Fields{%s})", fieldsPackage(loggerVar, "log"), strings.Join(fieldPairs, ", ")))
+	}
+	chain = append(chain, fmt.Sprintf(`%s("%s")`, levelFunc, message))
+
+	return loggerVar + "." + strings.Join(chain, ".")
+}
+
+// generateLog15Call generates a log15-style structured log call.
+// log15 uses Crit as its most severe level; Trace maps to Debug.
+func generateLog15Call(loggerVar, level, message string, fields []FieldMapping) string {
+	levelFunc := log15Level(level)
+
+	args := []string{fmt.Sprintf(`"%s"`, message)}
+	args = append(args, keyValueArgs(fields)...)
+
+	return fmt.Sprintf("%s.%s(%s)", loggerVar, levelFunc, strings.Join(args, ", "))
+}
+
+// keyValueArgs renders fields as alternating "key", value arguments
+func keyValueArgs(fields []FieldMapping) []string {
+	var args []string
+	for _, field := range fields {
+		args = append(args, fmt.Sprintf(`%s, %s`, keyExpr(field), field.Expression))
+	}
+	return args
+}
+
+// splitErrorField pulls the first error-typed field out of fields for libraries
+// that take the error as a dedicated argument. It returns "nil" if there is none.
+func splitErrorField(fields []FieldMapping) (string, []FieldMapping) {
+	for i, field := range fields {
+		if field.Type == "error" || field.Key == "error" || field.Key == "err" {
+			rest := make([]FieldMapping, 0, len(fields)-1)
+			rest = append(rest, fields[:i]...)
+			rest = append(rest, fields[i+1:]...)
+			return field.Expression, rest
+		}
+	}
+	return "nil", fields
+}
+
+// generateCustomCall generates a custom template-based log call
+func generateCustomCall(tmplStr, loggerVar, level, message string, fields []FieldMapping) (string, error) {
+	tmpl, err := template.New("log").Funcs(templateFuncs).Parse(tmplStr)
+	if err != nil {
+		return "", err
+	}
+
+	data := map[string]interface{}{
+		"Logger":  loggerVar,
+		"Level":   level,
+		"Message": message,
+		"Fields":  fields,
+	}
+
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// parseSimpleFields parses simple key=value field format
+func parseSimpleFields(fieldsStr string) []FieldMapping {
+	var fields []FieldMapping
+	
+	// Split by semicolon or comma
+	parts := strings.Split(fieldsStr, ";")
+	if len(parts) == 1 {
+		parts = strings.Split(fieldsStr, ",")
+	}
+
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		// Parse "key=expression" or "key:expression"
+		var key, expr string
+		if strings.Contains(part, "=") {
+			kv := strings.SplitN(part, "=", 2)
+			key = strings.TrimSpace(kv[0])
+			expr = strings.TrimSpace(kv[1])
+		} else if strings.Contains(part, ":") {
+			kv := strings.SplitN(part, ":", 2)
+			key = strings.TrimSpace(kv[0])
+			expr = strings.TrimSpace(kv[1])
+		} else {
+			// Assume it's just a variable name
+			key = part
+			expr = part
+		}
+
+		fields = append(fields, FieldMapping{
+			Key:        key,
+			Expression: expr,
+			Type:       "unknown",
+		})
+	}
+
+	return fields
+}
+
+// autoGenerateFieldsFromArguments parses ArgumentDetails and auto-generates field mappings
+// ArgumentDetails format: "key(type)=expression[formatVerb]; key2(type2)=expression2[formatVerb2]"
+// Example: "error(error)=err[%v]; username(unknown)=user.Name[%s]"
+func autoGenerateFieldsFromArguments(argumentDetails string) []FieldMapping {
+	var fields []FieldMapping
+	
+	// Split by semicolon
+	parts := strings.Split(argumentDetails, ";")
+	
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		
+		// Parse: "key(type)=expression[formatVerb]"
+		// Example: "error(error)=err[%v]"
+		
+		// Find the key (everything before '(')
+		openParen := strings.Index(part, "(")
+		if openParen == -1 {
+			continue
+		}
+		key := strings.TrimSpace(part[:openParen])
+		
+		// Find the type (between '(' and ')')
+		closeParen := strings.Index(part, ")")
+		if closeParen == -1 || closeParen <= openParen {
+			continue
+		}
+		typ := strings.TrimSpace(part[openParen+1 : closeParen])
+		
+		// Find the expression (between '=' and '[' or end of string)
+		equals := strings.Index(part, "=")
+		if equals == -1 || equals <= closeParen {
+			continue
+		}
+		
+		// Extract expression (might have [formatVerb] at the end)
+		exprPart := strings.TrimSpace(part[equals+1:])
+		openBracket := strings.LastIndex(exprPart, "[")
+		
+		var expr, verb string
+		if openBracket != -1 && strings.HasSuffix(exprPart, "]") && strings.HasPrefix(exprPart[openBracket+1:], "%") {
+			expr = strings.TrimSpace(exprPart[:openBracket])
+			verb = exprPart[openBracket+1 : len(exprPart)-1]
+		} else {
+			expr = exprPart
+		}
+		
+		fields = append(fields, FieldMapping{
+			Key:        key,
+			Expression: expr,
+			Type:       typ,
+			FormatVerb: verb,
+		})
+	}
+	
+	return fields
+}
+
+// mapLevel returns the target level for an update: the first configured rule
+// that matches wins, then the defaults; otherwise the collected level is kept.
+// A SuggestedLevel in use (see suggestedLevel) is more specific than rules
+// for the function (such as from: Print), so only rules naming the level
+// itself apply to it.
+func mapLevel(update LogUpdate, rules []LevelRule) string {
+	if suggested := update.suggestedLevel(); suggested != "" {
+		for _, ruleSet := range [][]LevelRule{rules, defaultLevelMap} {
+			for _, rule := range ruleSet {
+				if (rule.Package == "" || rule.Package == update.Package) && strings.EqualFold(rule.From, suggested) {
+					return rule.To
+				}
+			}
+		}
+		return suggested
+	}
+	for _, ruleSet := range [][]LevelRule{rules, defaultLevelMap} {
+		for _, rule := range ruleSet {
+			if rule.Package != "" && rule.Package != update.Package {
+				continue
+			}
+			if levelRuleMatches(rule.From, update) {
+				return rule.To
+			}
+		}
+	}
+	return update.LogLevel
+}
+
+// levelRuleMatches reports whether a LevelRule's From matches the update's
+// level, function name, original call, or verbosity selector
+func levelRuleMatches(from string, update LogUpdate) bool {
+	if from == "" {
+		return false
+	}
+	if strings.EqualFold(from, update.LogLevel) || from == update.OriginalCall {
+		return true
+	}
+	if strings.HasPrefix(from, "V(") {
+		return strings.Contains(update.OriginalCall, "."+from+".") || strings.HasPrefix(update.OriginalCall, from+".")
+	}
+
+	funcName := update.OriginalCall
+	if dot := strings.LastIndex(funcName, "."); dot != -1 {
+		funcName = funcName[dot+1:]
+	}
+	if funcName == from {
+		return true
+	}
+	// "Print" also matches Printf and Println
+	return strings.TrimSuffix(strings.TrimSuffix(funcName, "f"), "ln") == from
+}
+
+// keyExpr returns the Go expression for a field's key: its key constant when
+// one was assigned, otherwise a quoted string literal
+func keyExpr(field FieldMapping) string {
+	if field.KeyConst != "" {
+		return field.KeyConst
+	}
+	return fmt.Sprintf(`"%s"`, field.Key)
+}
+
+// resolveFieldKey applies the configured rename map and key style to a key.
+// A rename of the key as written wins; otherwise the key is converted to the
+// key style and the converted key may be renamed.
+func resolveFieldKey(key string, config *TemplateConfig) string {
+	if renamed, ok := config.KeyRenames[key]; ok {
+		return renamed
+	}
+	key = naming.Convert(key, config.KeyStyle)
+	if renamed, ok := config.KeyRenames[key]; ok {
+		return renamed
+	}
+	return key
+}
+
+// isForbiddenKey reports whether key, or any segment of a dotted key, is in
+// the forbidden list
+func isForbiddenKey(key string, forbidden []string) bool {
+	for _, f := range forbidden {
+		if key == f {
+			return true
+		}
+		for _, segment := range strings.Split(key, ".") {
+			if segment == f {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// groupDottedFields nests fields with dotted keys into groups, so "http.method"
+// and "http.status" become a group "http" with fields "method" and "status".
+// Groups keep the position of their first field.
+func groupDottedFields(fields []FieldMapping) []FieldMapping {
+	var result []FieldMapping
+	groupIndex := make(map[string]int)
+
+	for _, field := range fields {
+		dot := strings.Index(field.Key, ".")
+		if dot <= 0 || dot == len(field.Key)-1 {
+			result = append(result, field)
+			continue
+		}
+
+		name := field.Key[:dot]
+		child := field
+		child.Key = field.Key[dot+1:]
+
+		idx, ok := groupIndex[name]
+		if !ok {
+			idx = len(result)
+			groupIndex[name] = idx
+			result = append(result, FieldMapping{Key: name})
+		}
+		result[idx].Fields = append(result[idx].Fields, child)
+	}
+
+	for i := range result {
+		if len(result[i].Fields) > 0 {
+			result[i].Fields = groupDottedFields(result[i].Fields)
+		}
+	}
+
+	return result
+}
+
+// hasNestedGroups reports whether any of the fields is itself a group
+func hasNestedGroups(fields []FieldMapping) bool {
+	for _, field := range fields {
+		if len(field.Fields) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// applyDurationHints resolves fields the collector marked as "duration_ms"
+// (integers printed with %dms). With mode "duration" they are converted to a
+// time.Duration; otherwise they stay plain integers, of a type collect
+// doesn't know.
+func applyDurationHints(fields []FieldMapping, mode string) []FieldMapping {
+	for i, field := range fields {
+		if field.Type != "duration_ms" {
+			continue
+		}
+		if mode == "duration" {
+			fields[i].Expression = fmt.Sprintf("time.Duration(%s)*time.Millisecond", field.Expression)
+			fields[i].Type = "time.Duration"
+		} else {
+			fields[i].Type = "unknown"
+		}
+	}
+	return fields
+}
+
+// messageFormatArgs returns the expressions to pass alongside a message that
+// still contains format verbs, taken in order from the collected arguments.
+// It returns nil when the message needs no formatting.
+func messageFormatArgs(message string, args []FieldMapping) []string {
+	verbs := formatVerbPattern.FindAllString(strings.ReplaceAll(message, "%%", ""), -1)
+	if len(verbs) == 0 || len(verbs) > len(args) {
+		return nil
+	}
+
+	var exprs []string
+	for _, arg := range args[:len(verbs)] {
+		exprs = append(exprs, arg.Expression)
+	}
+	return exprs
+}
+
+// enrichFieldsFromArguments fills in Type and FormatVerb for fields whose
+// expression matches one of the collected arguments
+func enrichFieldsFromArguments(fields, args []FieldMapping) []FieldMapping {
+	for i, field := range fields {
+		for _, arg := range args {
+			if arg.Expression != field.Expression {
+				continue
+			}
+			if field.Type == "" || field.Type == "unknown" {
+				fields[i].Type = arg.Type
+			}
+			if field.FormatVerb == "" {
+				fields[i].FormatVerb = arg.FormatVerb
+			}
+			break
+		}
+	}
+	return fields
+}
+
+// fieldKind classifies a field as "error", "string", "strings", "int", "int64",
+// "uint", "float", "bool", "duration", "time" or "any" by its inferred type.
+// The typed constructors the kinds stand for, such as slog.Int or
+// zap.Uint64, take exactly int, int64, uint64 and float64, so other sizes
+// (int32, uint16, float32, ...) are "any", and so are fields of unknown
+// type: their format verb doesn't say which of them they are (%d prints an
+// int8 or a []int, %s a string or an error).
+func fieldKind(field FieldMapping) string {
+	switch field.Type {
+	case "error":
+		return "error"
+	case "string":
+		return "string"
+	case "[]string":
+		return "strings"
+	case "int":
+		return "int"
+	case "int64":
+		return "int64"
+	case "uint64":
+		return "uint"
+	case "float", "float64":
+		return "float"
+	case "bool":
+		return "bool"
+	case "time.Duration", "duration":
+		return "duration"
+	case "time.Time", "time":
+		return "time"
+	}
+	return "any"
+}
+
+// slogKind returns the field kind slogAttrs picks the constructor by: the
+// one of its type (see fieldKind), or for a field whose type collect
+// couldn't infer the one its format verb prints: %s and %q a string, %d an
+// int, %t a bool, %f, %e and %g a float. Other verbs, such as %v, print
+// anything, and so are "any"; so are known types without a constructor of
+// their own, such as int32.
+func slogKind(field FieldMapping) string {
+	switch field.Type {
+	case "", "unknown", "func_result":
+	default:
+		return fieldKind(field)
+	}
+	verb := field.FormatVerb
+	if verb == "" {
+		return "any"
+	}
+	switch verb[len(verb)-1] {
+	case 's', 'q':
+		return "string"
+	case 'd':
+		return "int"
+	case 't':
+		return "bool"
+	case 'f', 'F', 'e', 'E', 'g', 'G':
+		return "float"
+	}
+	return "any"
+}
+
+// getSlogAttrFunc returns the slog attribute constructor for a field kind
+func getSlogAttrFunc(kind string) string {
+	switch kind {
+	case "string":
+		return "String"
+	case "int":
+		return "Int"
+	case "int64":
+		return "Int64"
+	case "uint":
+		return "Uint64"
+	case "float":
+		return "Float64"
+	case "bool":
+		return "Bool"
+	case "duration":
+		return "Duration"
+	case "time":
+		return "Time"
+	default:
+		return "Any"
+	}
+}
+
+// getZapFieldFunc returns the zap field constructor for a field kind
+func getZapFieldFunc(kind string) string {
+	switch kind {
+	case "string":
+		return "String"
+	case "strings":
+		return "Strings"
+	case "int":
+		return "Int"
+	case "int64":
+		return "Int64"
+	case "uint":
+		return "Uint64"
+	case "float":
+		return "Float64"
+	case "bool":
+		return "Bool"
+	case "error":
+		return "NamedError"
+	case "duration":
+		return "Duration"
+	case "time":
+		return "Time"
+	default:
+		return "Any"
+	}
+}
+
+// zerologFields renders fields as zerolog event methods; groups become
+// Dict("key", zerolog.Dict()...)
+func zerologFields(fields []FieldMapping) []string {
+	var parts []string
+	hasErr := false
+	for _, field := range fields {
+		if len(field.Fields) > 0 {
+			children := append([]string{"zerolog.Dict()"}, zerologFields(field.Fields)...)
+			parts = append(parts, fmt.Sprintf(`Dict(%s, %s)`, keyExpr(field), strings.Join(children, ".")))
+			continue
+		}
+		kind := fieldKind(field)
+		if kind == "error" {
+			if !hasErr {
+				parts = append(parts, fmt.Sprintf("Err(%s)", field.Expression))
+				hasErr = true
+				continue
+			}
+			parts = append(parts, fmt.Sprintf(`AnErr(%s, %s)`, keyExpr(field), field.Expression))
+			continue
+		}
+		zerologFunc := getZerologFieldFunc(kind)
+		parts = append(parts, fmt.Sprintf(`%s(%s, %s)`, zerologFunc, keyExpr(field), field.Expression))
+	}
+	return parts
+}
+
+// getZerologFieldFunc returns the zerolog event method for a field kind
+func getZerologFieldFunc(kind string) string {
+	switch kind {
+	case "string":
+		return "Str"
+	case "strings":
+		return "Strs"
+	case "int":
+		return "Int"
+	case "int64":
+		return "Int64"
+	case "uint":
+		return "Uint64"
+	case "float":
+		return "Float64"
+	case "bool":
+		return "Bool"
+	case "duration":
+		return "Dur"
+	case "time":
+		return "Time"
+	default:
+		return "Interface"
+	}
+}
+
+// formatCallExpr formats a call expression back to code
+func formatCallExpr(call *ast.CallExpr, fset *token.FileSet) string {
+	var buf strings.Builder
+	printer.Fprint(&buf, fset, call)
+	return buf.String()
+}
+
+// edit replaces content[start:end] with code
+type edit struct {
+	start int
+	end   int
+	code  string
+	id    string // Entry ID, for the journal
+	line  int
+}
+
+// applyEdits applies non-overlapping edits back to front, so the byte offsets
+// of earlier edits stay valid even when replacements change the line count.
+// It returns the edits it had to skip.
+func applyEdits(content []byte, edits []edit) ([]byte, []edit) {
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+
+	result := content
+	limit := len(content)
+	var skipped []edit
+	for _, e := range edits {
+		if e.start < 0 || e.end > limit || e.start > e.end {
+			skipped = append(skipped, e)
+			continue
+		}
+		updated := make([]byte, 0, len(result)-(e.end-e.start)+len(e.code))
+		updated = append(updated, result[:e.start]...)
+		updated = append(updated, e.code...)
+		updated = append(updated, result[e.end:]...)
+		result = updated
+		limit = e.start
+	}
+
+	return result, skipped
+}
+
+// truncateCode truncates code to maxLen characters
+func truncateCode(code string, maxLen int) string {
+	// Remove extra whitespace
+	code = strings.Join(strings.Fields(code), " ")
+	
+	if len(code) <= maxLen {
+		return code
+	}
+	return code[:maxLen-3] + "..."
+}
@@ -0,0 +1,247 @@
+package transformer
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFieldKind(t *testing.T) {
+	tests := []struct {
+		typ, verb string
+		want      string
+	}{
+		{"string", "%s", "string"},
+		{"int", "%d", "int"},
+		{"int64", "%d", "int64"},
+		{"uint64", "%d", "uint"},
+		{"float64", "%f", "float"},
+		{"float", "%f", "float"},
+		{"bool", "%t", "bool"},
+		{"error", "%v", "error"},
+		{"[]string", "%v", "strings"},
+		{"time.Duration", "%s", "duration"},
+		{"time.Time", "%s", "time"},
+		// The typed constructors take exactly int, int64, uint64 and float64
+		{"int8", "%d", "any"},
+		{"int16", "%d", "any"},
+		{"int32", "%d", "any"},
+		{"uint", "%d", "any"},
+		{"uint8", "%d", "any"},
+		{"uint16", "%d", "any"},
+		{"uint32", "%d", "any"},
+		{"float32", "%f", "any"},
+		// The verb of an unknown type doesn't say which it is
+		{"unknown", "%d", "any"},
+		{"unknown", "%f", "any"},
+		{"unknown", "%s", "any"},
+		{"", "%d", "any"},
+	}
+	for _, tt := range tests {
+		if got := fieldKind(FieldMapping{Key: "k", Expression: "v", Type: tt.typ, FormatVerb: tt.verb}); got != tt.want {
+			t.Errorf("fieldKind(%s, %s) = %s, want %s", tt.typ, tt.verb, got, tt.want)
+		}
+	}
+}
+
+func TestSlogVerbs(t *testing.T) {
+	tests := []struct {
+		typ, verb string
+		want      string
+	}{
+		{"unknown", "%s", `slog.String("v", v)`},
+		{"unknown", "%q", `slog.String("v", v)`},
+		{"unknown", "%d", `slog.Int("v", v)`},
+		{"unknown", "%5d", `slog.Int("v", v)`},
+		{"unknown", "%t", `slog.Bool("v", v)`},
+		{"unknown", "%f", `slog.Float64("v", v)`},
+		{"unknown", "%.2f", `slog.Float64("v", v)`},
+		{"unknown", "%e", `slog.Float64("v", v)`},
+		{"unknown", "%g", `slog.Float64("v", v)`},
+		{"unknown", "%v", `slog.Any("v", v)`},
+		{"unknown", "%+v", `slog.Any("v", v)`},
+		{"unknown", "%x", `slog.Any("v", v)`},
+		{"unknown", "", `slog.Any("v", v)`},
+		{"", "%d", `slog.Int("v", v)`},
+		{"func_result", "%s", `slog.String("v", v)`},
+		// A known type decides over the verb
+		{"int64", "%d", `slog.Int64("v", v)`},
+		{"int32", "%d", `slog.Any("v", v)`},
+		{"error", "%s", `slog.Any("v", v)`},
+		{"time.Duration", "%s", `slog.Duration("v", v)`},
+		{"bool", "%v", `slog.Bool("v", v)`},
+	}
+	for _, tt := range tests {
+		got := generateSlogCall("log", "Info", "done", []FieldMapping{{Key: "v", Expression: "v", Type: tt.typ, FormatVerb: tt.verb}})
+		if want := `log.Info("done", ` + tt.want + `)`; got != want {
+			t.Errorf("%s %q: got %s, want %s", tt.typ, tt.verb, got, want)
+		}
+	}
+}
+
+func TestTypedConstructors(t *testing.T) {
+	fields := []FieldMapping{
+		{Key: "code", Expression: "code", Type: "int32", FormatVerb: "%d"},
+		{Key: "ratio", Expression: "ratio", Type: "float32", FormatVerb: "%f"},
+		{Key: "port", Expression: "port", Type: "uint16", FormatVerb: "%d"},
+		{Key: "n", Expression: "n", Type: "int", FormatVerb: "%d"},
+		{Key: "size", Expression: "size", Type: "uint64", FormatVerb: "%d"},
+	}
+	tests := []struct {
+		style string
+		want  string
+	}{
+		{"slog", `log.Info("done", slog.Any("code", code), slog.Any("ratio", ratio), slog.Any("port", port), slog.Int("n", n), slog.Uint64("size", size))`},
+		{"zap", `log.Info("done", zap.Any("code", code), zap.Any("ratio", ratio), zap.Any("port", port), zap.Int("n", n), zap.Uint64("size", size))`},
+		{"zerolog", `log.Info().Interface("code", code).Interface("ratio", ratio).Interface("port", port).Int("n", n).Uint64("size", size).Msg("done")`},
+	}
+	for _, tt := range tests {
+		var got string
+		switch tt.style {
+		case "slog":
+			got = generateSlogCall("log", "Info", "done", fields)
+		case "zap":
+			got = generateZapCall("log", "Info", "done", fields)
+		case "zerolog":
+			got = generateZerologCall("log", "Info", "done", fields, nil)
+		}
+		if got != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestMapped(t *testing.T) {
+	tests := []struct {
+		name   string
+		update LogUpdate
+		want   bool
+	}{
+		{"matching format", LogUpdate{OriginalCall: "log.Printf", MessageTemplate: `"a=%d b=%s"`, ArgumentDetails: "a(int)=a[%d]; b(string)=b[%s]"}, true},
+		{"escaped percent", LogUpdate{OriginalCall: "log.Printf", MessageTemplate: `"100%% of %s"`, ArgumentDetails: "s(string)=s[%s]"}, true},
+		{"missing argument", LogUpdate{OriginalCall: "log.Printf", MessageTemplate: `"a=%d b=%d"`, ArgumentDetails: "a(int)=a[%d]"}, false},
+		{"missing star width", LogUpdate{OriginalCall: "log.Printf", MessageTemplate: `"%*d"`, ArgumentDetails: "a(int)=a[%d]"}, false},
+		{"bare percent", LogUpdate{OriginalCall: "log.Printf", MessageTemplate: `"at 100%"`}, false},
+		{"extra argument", LogUpdate{OriginalCall: "log.Printf", MessageTemplate: `"a=%d"`, ArgumentDetails: "a(int)=a[%d]; b(int)=b"}, false},
+		{"struct printed whole", LogUpdate{OriginalCall: "log.Printf", MessageTemplate: `"cfg %v"`, ArgumentDetails: "cfg(struct)=cfg[%v]"}, false},
+		{"not printf", LogUpdate{OriginalCall: "log.Print", MessageTemplate: `"done"`, ArgumentDetails: "a(int)=a"}, true},
+		{"fields written by hand", LogUpdate{OriginalCall: "log.Printf", MessageTemplate: `"a=%d b=%d"`, ArgumentDetails: "a(int)=a[%d]", StructuredFields: "a=a"}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.update.mapped(true); got != tt.want {
+			t.Errorf("%s: mapped = %v, want %v", tt.name, got, tt.want)
+		}
+		if !tt.update.mapped(false) {
+			t.Errorf("%s: mapped without auto-map = false, want true", tt.name)
+		}
+	}
+}
+
+func TestMapLevel(t *testing.T) {
+	rules := []LevelRule{
+		{From: "Print", To: "Debug"},
+		{From: "Fatal", To: "Error", Package: "worker"},
+		{From: "V(2)", To: "Trace"},
+		{From: "Critical", To: "Error"},
+	}
+	tests := []struct {
+		name   string
+		update LogUpdate
+		want   string
+	}{
+		{"level kept", LogUpdate{OriginalCall: "log.Warn", LogLevel: "Warn"}, "Warn"},
+		{"default rule", LogUpdate{OriginalCall: "log.Warning", LogLevel: "Warning"}, "Warn"},
+		{"function rule", LogUpdate{OriginalCall: "log.Printf", LogLevel: "Info"}, "Debug"},
+		{"verbosity rule", LogUpdate{OriginalCall: "klog.V(2).Infof", LogLevel: "Info"}, "Trace"},
+		{"package rule", LogUpdate{OriginalCall: "log.Fatal", LogLevel: "Fatal", Package: "worker"}, "Error"},
+		{"package rule for another package", LogUpdate{OriginalCall: "log.Fatal", LogLevel: "Fatal", Package: "api"}, "Fatal"},
+		{"high suggestion", LogUpdate{OriginalCall: "log.Printf", LogLevel: "Info", SuggestedLevel: "Error", LevelConfidence: "high"}, "Debug"},
+		{"high Fatal suggestion", LogUpdate{OriginalCall: "log.Printf", LogLevel: "Info", SuggestedLevel: "Fatal", LevelConfidence: "high"}, "Debug"},
+		{"high suggestion approved", LogUpdate{OriginalCall: "log.Printf", LogLevel: "Info", SuggestedLevel: "Error", LevelConfidence: "high", Approved: "yes"}, "Error"},
+		{"approved suggestion through a level rule", LogUpdate{OriginalCall: "log.Printf", LogLevel: "Info", SuggestedLevel: "Critical", LevelConfidence: "high", Approved: "yes"}, "Error"},
+		{"low suggestion", LogUpdate{OriginalCall: "log.Printf", LogLevel: "Info", SuggestedLevel: "Error", LevelConfidence: "low"}, "Debug"},
+		{"medium suggestion", LogUpdate{OriginalCall: "log.Println", LogLevel: "Info", SuggestedLevel: "Debug", LevelConfidence: "medium"}, "Debug"},
+		{"suggestion without confidence", LogUpdate{OriginalCall: "log.Print", LogLevel: "Unknown", SuggestedLevel: "Error"}, "Debug"},
+		{"low suggestion approved", LogUpdate{OriginalCall: "log.Printf", LogLevel: "Info", SuggestedLevel: "Error", LevelConfidence: "low", Approved: "yes"}, "Error"},
+		{"medium suggestion with approved status", LogUpdate{OriginalCall: "log.Printf", LogLevel: "Info", SuggestedLevel: "Warn", LevelConfidence: "medium", Status: StatusApproved}, "Warn"},
+		{"low suggestion on unknown level", LogUpdate{OriginalCall: "log.Output", LogLevel: "Unknown", SuggestedLevel: "Error", LevelConfidence: "low"}, "Info"},
+	}
+	for _, tt := range tests {
+		if got := mapLevel(tt.update, rules); got != tt.want {
+			t.Errorf("%s: mapLevel = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyOrderWithJobs(t *testing.T) {
+	dir := t.TempDir()
+	var updates []LogUpdate
+	// Updates of later files first, and of each file last line first, so
+	// the order of the report can't come from the input
+	for i := 7; i >= 0; i-- {
+		name := fmt.Sprintf("f%d.go", i)
+		src := "package main\n\nimport \"log\"\n\nfunc f(id int) {\n\tlog.Printf(\"start %d\", id)\n\tlog.Printf(\"stop %d\", id)\n}\n"
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range []int{7, 6} {
+			updates = append(updates, LogUpdate{
+				ID: fmt.Sprintf("LOG-%d-%d", i, line), FilePath: name, Line: line, Column: 2,
+				OriginalCall: "log.Printf", LogLevel: "Info", MessageTemplate: `"x %d"`,
+				NewMessage: fmt.Sprintf("line %d", line), StructuredFields: "id=id",
+			})
+		}
+	}
+
+	run := func(jobs int) (Report, []string, []Change) {
+		config := &TemplateConfig{Style: "slog", LoggerVar: "logger"}
+		config.SetJobs(jobs)
+		config.SetOutput(io.Discard)
+		var started []string
+		var applied []Change
+		report, err := Apply(context.Background(), updates, Options{
+			Config:        config,
+			DryRun:        true,
+			FS:            DirFS(dir),
+			OnFileStart:   func(path string) { started = append(started, path) },
+			OnEditApplied: func(change Change) { applied = append(applied, change) },
+			OnWarning:     func(err error) { t.Errorf("jobs %d: %v", jobs, err) },
+		})
+		if err != nil {
+			t.Fatalf("jobs %d: Apply: %v", jobs, err)
+		}
+		return report, started, applied
+	}
+
+	serial, serialStarted, _ := run(1)
+	if len(serial.Changes) != len(updates) {
+		t.Fatalf("jobs 1: %d changes, want %d", len(serial.Changes), len(updates))
+	}
+	for _, jobs := range []int{2, 4, 16} {
+		report, started, applied := run(jobs)
+		if !slices.IsSortedFunc(report.Changes, func(a, b Change) int {
+			return cmp.Or(strings.Compare(a.File, b.File), cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+		}) {
+			t.Errorf("jobs %d: changes not by file, line and column: %v", jobs, report.Changes)
+		}
+		if !reflect.DeepEqual(report.Changes, serial.Changes) {
+			t.Errorf("jobs %d: changes differ from jobs 1", jobs)
+		}
+		if !reflect.DeepEqual(applied, report.Changes) {
+			t.Errorf("jobs %d: OnEditApplied order differs from the report", jobs)
+		}
+		if !reflect.DeepEqual(started, serialStarted) {
+			t.Errorf("jobs %d: OnFileStart order %v, want %v", jobs, started, serialStarted)
+		}
+		if !reflect.DeepEqual(report.Outcomes, serial.Outcomes) || !reflect.DeepEqual(report.Files, serial.Files) {
+			t.Errorf("jobs %d: outcomes or files differ from jobs 1", jobs)
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package transformer
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"logrefactor/internal/table"
+)
+
+// Issue is a problem found in an edited CSV. Errors would make transform
+// produce broken code or skip the entry; warnings are worth a look but don't
+// stop the run.
+type Issue struct {
+	Row     int    // CSV line number; the header is line 1
+	ID      string // Entry ID, if the row has one
+	Warning bool
+	Message string
+}
+
+func (i Issue) String() string {
+	severity := "error"
+	if i.Warning {
+		severity = "warning"
+	}
+	if i.ID == "" {
+		return fmt.Sprintf("%d: %s: %s", i.Row, severity, i.Message)
+	}
+	return fmt.Sprintf("%d: %s: %s: %s", i.Row, i.ID, severity, i.Message)
+}
+
+// csvColumns are the columns loadUpdates requires; it finds them by name
+var csvColumns = []string{"ID", "FilePath", "Line", "Column", "Package", "OriginalCall", "LogLevel",
+	"MessageTemplate", "ArgumentDetails", "NewCall", "NewMessage", "StructuredFields"}
+
+// validLevels are the LogLevel values collect produces, lower-cased
+var validLevels = map[string]bool{
+	"trace": true, "debug": true, "info": true, "warn": true, "warning": true,
+	"error": true, "fatal": true, "panic": true, "unknown": true,
+}
+
+// leftoverVerbPattern matches printf verbs left in a message
+var leftoverVerbPattern = regexp.MustCompile(`%[-+# 0]*[0-9]*(\.[0-9]+)?[a-zA-Z]`)
+
+// ValidateCSV checks an edited CSV before it is transformed: column counts,
+// duplicate IDs, levels, StructuredFields syntax, format verbs left in
+// NewMessage, arguments no field uses, and whether each entry and the
+// expressions its fields reference still exist in the source.
+func ValidateCSV(csvFile string) ([]Issue, error) {
+	t, err := table.Read(csvFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []Issue
+	for _, name := range csvColumns {
+		if !t.Has(name) {
+			issues = append(issues, Issue{Row: 1, Message: fmt.Sprintf("missing column %s", name)})
+		}
+	}
+	if len(issues) > 0 {
+		return issues, nil
+	}
+
+	sources := make(map[string]*sourceFile)
+	seen := make(map[string]int)
+	for i, record := range t.Rows {
+		row := i + 2
+		report := func(warning bool, format string, args ...interface{}) {
+			issues = append(issues, Issue{Row: row, ID: t.Get(record, "ID"), Warning: warning, Message: fmt.Sprintf(format, args...)})
+		}
+
+		if len(record) != len(t.Header) {
+			report(false, "has %d columns, header has %d", len(record), len(t.Header))
+		}
+
+		update, err := ParseUpdate(t, record)
+		if err != nil {
+			report(false, "%v", err)
+			continue
+		}
+
+		if update.ID == "" {
+			report(false, "missing ID")
+		} else if first, ok := seen[update.ID]; ok {
+			report(false, "duplicate ID (first used on line %d)", first)
+		} else {
+			seen[update.ID] = row
+		}
+
+		if !validLevels[strings.ToLower(update.LogLevel)] {
+			report(false, "unknown LogLevel %q", update.LogLevel)
+		}
+		if update.SuggestedLevel != "" && !validLevels[strings.ToLower(update.SuggestedLevel)] {
+			report(false, "unknown SuggestedLevel %q", update.SuggestedLevel)
+		}
+
+		if !statuses[strings.ToLower(strings.TrimSpace(update.Status))] {
+			report(true, "unknown Status %q (expected todo, review, approved, rejected, skip or manual-review)", update.Status)
+		}
+
+		if leftoverVerbPattern.MatchString(strings.ReplaceAll(update.NewMessage, "%%", "")) {
+			report(true, "NewMessage %q still contains format verbs; only zerolog keeps them (with Msgf)", update.NewMessage)
+		}
+
+		fields, ok := validateFields(update, report)
+		if !ok {
+			continue
+		}
+		if update.Applied != "" {
+			continue // The call has already been replaced
+		}
+
+		src, ok := sources[update.FilePath]
+		if !ok {
+			src = loadSourceFile(update.FilePath)
+			sources[update.FilePath] = src
+		}
+		if src.err != nil {
+			report(false, "%v", src.err)
+			continue
+		}
+		src.check(update, fields, report)
+	}
+
+	return issues, nil
+}
+
+// ParseUpdate converts a row of an entries table to a LogUpdate
+func ParseUpdate(t *table.Table, record []string) (LogUpdate, error) {
+	line, err := strconv.Atoi(t.Get(record, "Line"))
+	if err != nil {
+		return LogUpdate{}, fmt.Errorf("invalid Line %q", t.Get(record, "Line"))
+	}
+	column, err := strconv.Atoi(t.Get(record, "Column"))
+	if err != nil {
+		return LogUpdate{}, fmt.Errorf("invalid Column %q", t.Get(record, "Column"))
+	}
+	return LogUpdate{
+		ID:               t.Get(record, "ID"),
+		FilePath:         t.Get(record, "FilePath"),
+		Line:             line,
+		Column:           column,
+		Package:          t.Get(record, "Package"),
+		OriginalCall:     t.Get(record, "OriginalCall"),
+		SourceLibrary:    t.Get(record, "SourceLibrary"),
+		Receiver:         t.Get(record, "Receiver"),
+		Returns:          t.Get(record, "Returns"),
+		Helper:           t.Get(record, "Helper"),
+		InLoop:           t.Get(record, "InLoop"),
+		LogLevel:         t.Get(record, "LogLevel"),
+		SuggestedLevel:   t.Get(record, "SuggestedLevel"),
+		LevelConfidence:  t.Get(record, "LevelConfidence"),
+		MessageTemplate:  t.Get(record, "MessageTemplate"),
+		SuggestedMessage: t.Get(record, "SuggestedMessage"),
+		ClusterID:        t.Get(record, "ClusterID"),
+		ArgumentDetails:  t.Get(record, "ArgumentDetails"),
+		FieldConfidence:  t.Get(record, "FieldConfidence"),
+		NewCall:          t.Get(record, "NewCall"),
+		NewMessage:       t.Get(record, "NewMessage"),
+		StructuredFields: t.Get(record, "StructuredFields"),
+		Status:           t.Get(record, "Status"),
+		Approved:         t.Get(record, "Approved"),
+		Applied:          t.Get(record, "Applied"),
+	}, nil
+}
+
+// validateFields parses StructuredFields the way transform does, reporting
+// syntax problems and arguments no field uses. It returns the fields whose
+// expressions should exist in the source.
+func validateFields(update LogUpdate, report func(bool, string, ...interface{})) ([]FieldMapping, bool) {
+	arguments := autoGenerateFieldsFromArguments(update.ArgumentDetails)
+	text := strings.TrimSpace(update.StructuredFields)
+	if text == "" {
+		return arguments, true
+	}
+
+	var fields []FieldMapping
+	if strings.HasPrefix(text, "[") || strings.HasPrefix(text, "{") {
+		if err := json.Unmarshal([]byte(text), &fields); err != nil {
+			report(false, "StructuredFields is not valid JSON: %v", err)
+			return nil, false
+		}
+	} else {
+		for _, part := range splitSimpleFields(text) {
+			if !strings.ContainsAny(part, "=:") {
+				report(false, "StructuredFields entry %q is not key=expression", part)
+				return nil, false
+			}
+		}
+		fields = parseSimpleFields(text)
+	}
+
+	ok := true
+	for _, field := range flattenFields(fields) {
+		if field.Key == "" {
+			report(false, "StructuredFields has a field without a key")
+			ok = false
+		}
+		if field.Expression == "" {
+			report(false, "field %q has no expression", field.Key)
+			ok = false
+		} else if _, err := parser.ParseExpr(field.Expression); err != nil {
+			report(false, "field %q: %q is not a valid Go expression", field.Key, field.Expression)
+			ok = false
+		}
+	}
+
+	// Arguments of the original call that no field passes on are dropped
+	used := make(map[string]bool)
+	for _, field := range flattenFields(fields) {
+		used[strings.ReplaceAll(field.Expression, " ", "")] = true
+	}
+	for _, arg := range arguments {
+		if !used[strings.ReplaceAll(arg.Expression, " ", "")] {
+			report(true, "argument %s is not used by any field and will be dropped", arg.Expression)
+		}
+	}
+
+	return fields, ok
+}
+
+// splitSimpleFields splits "k=v; k2=v2" the same way parseSimpleFields does
+func splitSimpleFields(text string) []string {
+	parts := strings.Split(text, ";")
+	if len(parts) == 1 {
+		parts = strings.Split(text, ",")
+	}
+	var result []string
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
+
+// flattenFields returns the leaf fields, descending into groups
+func flattenFields(fields []FieldMapping) []FieldMapping {
+	var flat []FieldMapping
+	for _, field := range fields {
+		if len(field.Fields) > 0 {
+			flat = append(flat, flattenFields(field.Fields)...)
+			continue
+		}
+		flat = append(flat, field)
+	}
+	return flat
+}
+
+// sourceFile is a parsed Go file referenced by the CSV
+type sourceFile struct {
+	fset  *token.FileSet
+	calls map[string]*ast.CallExpr // "line:column" -> call
+	names map[string]bool          // Every identifier in the file
+	err   error
+}
+
+func loadSourceFile(path string) *sourceFile {
+	src := &sourceFile{fset: token.NewFileSet()}
+	node, err := parser.ParseFile(src.fset, path, nil, 0)
+	if err != nil {
+		src.err = fmt.Errorf("cannot read source: %v", err)
+		return src
+	}
+
+	src.calls = make(map[string]*ast.CallExpr)
+	src.names = make(map[string]bool)
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			pos := src.fset.Position(n.Pos())
+			key := fmt.Sprintf("%d:%d", pos.Line, pos.Column)
+			if _, ok := src.calls[key]; !ok {
+				src.calls[key] = n
+			}
+		case *ast.Ident:
+			src.names[n.Name] = true
+		}
+		return true
+	})
+	return src
+}
+
+// check reports entries whose call moved or changed since collect, and
+// field expressions that use identifiers the file no longer has
+func (src *sourceFile) check(update LogUpdate, fields []FieldMapping, report func(bool, string, ...interface{})) {
+	call, ok := src.calls[fmt.Sprintf("%d:%d", update.Line, update.Column)]
+	if !ok {
+		report(false, "no call at %s:%d:%d; re-run collect if the file changed", update.FilePath, update.Line, update.Column)
+		return
+	}
+	if name := calledName(call); name != lastSegment(update.OriginalCall) {
+		report(false, "call at %s:%d:%d is %s, not %s; re-run collect if the file changed",
+			update.FilePath, update.Line, update.Column, name, update.OriginalCall)
+		return
+	}
+
+	for _, field := range flattenFields(fields) {
+		expr, err := parser.ParseExpr(field.Expression)
+		if err != nil {
+			continue // Already reported
+		}
+		for _, name := range rootIdents(expr) {
+			if !src.names[name] && types.Universe.Lookup(name) == nil {
+				report(false, "field %q uses %s, which no longer exists in %s", field.Key, name, update.FilePath)
+			}
+		}
+	}
+}
+
+// calledName returns the name of the function or method a call invokes
+func calledName(call *ast.CallExpr) string {
+	switch fun := call.Fun.(type) {
+	case *ast.SelectorExpr:
+		return fun.Sel.Name
+	case *ast.Ident:
+		return fun.Name
+	}
+	return ""
+}
+
+// lastSegment returns the part of a dotted call after the last dot
+func lastSegment(s string) string {
+	if dot := strings.LastIndex(s, "."); dot != -1 {
+		return s[dot+1:]
+	}
+	return s
+}
+
+// rootIdents returns the identifiers an expression looks up in scope:
+// selector names (the Name in user.Name) and composite literal keys are
+// left out
+func rootIdents(expr ast.Expr) []string {
+	var names []string
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			names = append(names, rootIdents(n.X)...)
+			return false
+		case *ast.KeyValueExpr:
+			names = append(names, rootIdents(n.Value)...)
+			return false
+		case *ast.Ident:
+			names = append(names, n.Name)
+		}
+		return true
+	})
+	return names
+}
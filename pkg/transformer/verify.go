@@ -0,0 +1,81 @@
+package transformer
+
+import (
+	"path/filepath"
+	"strconv"
+
+	"logrefactor/pkg/collector"
+)
+
+// VerifyResult is what Verify found after a transform run
+type VerifyResult struct {
+	Checked int // Entries the CSV asked to change
+	// NotReplaced are entries the CSV asked to change whose original call is
+	// still in the source
+	NotReplaced []LogUpdate
+	// Remaining are other calls that still use one of the CSV's original
+	// functions (e.g. log.Printf): entries left unedited or added since collect
+	Remaining []collector.LogEntry
+}
+
+// OK reports whether every edited entry was replaced
+func (r *VerifyResult) OK() bool {
+	return len(r.NotReplaced) == 0
+}
+
+// Verify compares the CSV with a fresh scan of the source. An entry counts as
+// replaced when no call in its file has the same function and message
+// template any more, so the check still works after transform moved calls by
+// wrapping them across lines.
+func Verify(csvFile string, scanned []collector.LogEntry) (*VerifyResult, error) {
+	updates, err := loadUpdates(csvFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Index the current calls by file, function and message
+	type callKey struct{ file, call, message string }
+	keyOf := func(file, call, message string) callKey {
+		if abs, err := filepath.Abs(file); err == nil {
+			file = abs
+		}
+		return callKey{file, call, message}
+	}
+	current := make(map[callKey][]int)
+	for i, entry := range scanned {
+		k := keyOf(entry.FilePath, entry.OriginalCall, entry.MessageTemplate)
+		current[k] = append(current[k], i)
+	}
+
+	result := &VerifyResult{}
+	matched := make(map[int]bool)
+	oldCalls := make(map[string]bool)
+	newMessages := make(map[callKey]bool) // file and new message; call unused
+	for _, update := range updates {
+		oldCalls[update.OriginalCall] = true
+		if !update.edited() || update.held() {
+			continue
+		}
+		result.Checked++
+		newMessages[keyOf(update.FilePath, "", update.NewMessage)] = true
+
+		k := keyOf(update.FilePath, update.OriginalCall, update.MessageTemplate)
+		if indexes := current[k]; len(indexes) > 0 {
+			matched[indexes[0]] = true
+			current[k] = indexes[1:]
+			result.NotReplaced = append(result.NotReplaced, update)
+		}
+	}
+
+	for i, entry := range scanned {
+		if matched[i] || !oldCalls[entry.OriginalCall] {
+			continue
+		}
+		// A replacement that kept the old function name (log.Info -> log.Info)
+		if message, err := strconv.Unquote(entry.MessageTemplate); err == nil && newMessages[keyOf(entry.FilePath, "", message)] {
+			continue
+		}
+		result.Remaining = append(result.Remaining, entry)
+	}
+	return result, nil
+}
@@ -0,0 +1,90 @@
+package transformer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"logrefactor/internal/logcall"
+)
+
+// Policies for entries that log an error and then return it (see
+// LogUpdate.Returns)
+const (
+	LogAndReturnKeep = "keep" // Migrate the log call like any other
+	LogAndReturnWrap = "wrap" // Drop the log call and wrap the returned error
+)
+
+// wrapReturn returns the edit that drops a log call whose function then
+// returns the error it logged, and wraps the error in the return statement
+// instead (see wrapError), leaving the logging to the caller. A result that
+// already wraps the error, such as fmt.Errorf("open: %w", err), is kept as
+// it is. path runs from the call up to the file, as
+// astutil.PathEnclosingInterval returns it.
+func wrapReturn(update LogUpdate, call *ast.CallExpr, path []ast.Node, fset *token.FileSet, content []byte) (edit, error) {
+	if len(path) < 3 {
+		return edit{}, fmt.Errorf("the call is no longer followed by return %s", update.Returns)
+	}
+	ret, name := logcall.LogAndReturn(call, path[1], path[2])
+	if ret == nil || name != update.Returns {
+		return edit{}, fmt.Errorf("the call is no longer followed by return %s", update.Returns)
+	}
+
+	offset := func(p token.Pos) int { return fset.Position(p).Offset }
+	stmt := path[1]
+	start, retStart, end := offset(stmt.Pos()), offset(ret.Pos()), offset(ret.End())
+	if strings.TrimSpace(string(content[offset(stmt.End()):retStart])) != "" {
+		return edit{}, fmt.Errorf("the comment between the call and return %s would be lost", name)
+	}
+
+	code := string(content[retStart:end])
+	for _, result := range ret.Results {
+		if ident, ok := result.(*ast.Ident); ok && ident.Name == name {
+			code = string(content[retStart:offset(result.Pos())]) + wrapError(update, call, name, content, offset) + string(content[offset(result.End()):end])
+			break
+		}
+	}
+	return edit{start: start, end: end, code: code, id: update.ID, line: fset.Position(stmt.Pos()).Line}, nil
+}
+
+// wrapError returns the expression a log-and-return entry returns its error
+// as: fmt.Errorf with the call's format, the error's verb turned into %w,
+// and the call's arguments, so fmt.Errorf("open %s: %w", path, err) for
+// log.Printf("open %s: %v", path, err). When the format has no verb for
+// the error, the message is NewMessage, or failing that SuggestedMessage or
+// the original message, followed by ": %w".
+func wrapError(update LogUpdate, call *ast.CallExpr, name string, content []byte, offset func(token.Pos) int) string {
+	if len(call.Args) > 1 {
+		var args []string
+		at := -1
+		for i, arg := range call.Args[1:] {
+			args = append(args, string(content[offset(arg.Pos()):offset(arg.End())]))
+			if ident, ok := arg.(*ast.Ident); ok && ident.Name == name && at < 0 {
+				at = i
+			}
+		}
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		if ok && lit.Kind == token.STRING && at >= 0 {
+			verbs := formatVerbPattern.FindAllStringIndex(lit.Value, -1)
+			if len(verbs) == len(args) {
+				format := lit.Value[:verbs[at][0]] + "%w" + lit.Value[verbs[at][1]:]
+				// Errors don't end in a newline
+				if strings.HasSuffix(format, `\n"`) {
+					format = strings.TrimSuffix(format, `\n"`) + `"`
+				}
+				return "fmt.Errorf(" + format + ", " + strings.Join(args, ", ") + ")"
+			}
+		}
+	}
+
+	message := strings.Trim(update.NewMessage, "\"'`")
+	if message == "" {
+		message = update.SuggestedMessage
+	}
+	if message == "" {
+		message = strings.TrimRight(strings.Trim(update.MessageTemplate, "\"'`"), ": ")
+	}
+	return fmt.Sprintf("fmt.Errorf(%s, %s)", strconv.Quote(message+": %w"), name)
+}